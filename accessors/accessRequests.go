@@ -0,0 +1,225 @@
+package accessors
+
+import (
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Kinds of access a user can ask for.
+const (
+	AccessGroup      = "group"
+	AccessPermission = "permission"
+)
+
+// States an access request moves through.
+const (
+	AccessPending  = "pending"
+	AccessApproved = "approved"
+	AccessDenied   = "denied"
+	AccessExpired  = "expired"
+)
+
+// AccessRequest struct that reflects the accessRequest table. Group is set
+// for group requests and Verb/Resource for permission requests.
+// Duration is the number of hours the access should last once approved
+// (0 for permanent) and Expires the unix time it lapses (0 for never).
+// Granted is whether approving it added the membership or policy row, as
+// opposed to finding the user already had it; only then does expiry take
+// it away.
+type AccessRequest struct {
+	Guid     string
+	NetId    string
+	Area     string
+	Type     string
+	Group    string
+	Verb     string
+	Resource string
+	Reason   string
+	Status   string
+	Reviewer string
+	Duration int64
+	Expires  int64
+	Granted  bool
+}
+
+type AccessRequestAccessor struct {
+	DB *sql.DB // Database connection
+}
+
+// Returns a new access request accessor.
+func NewAccessRequestAccessor(db *sql.DB) *AccessRequestAccessor {
+	return &AccessRequestAccessor{db}
+}
+
+const accessRequestColumns = "guid, netId, area, type, groupGuid, verb, resource, reason, status, reviewer, duration, expires, granted"
+
+func scanAccessRequest(scanner interface {
+	Scan(dest ...interface{}) error
+}) (AccessRequest, error) {
+	r := AccessRequest{}
+	err := scanner.Scan(&r.Guid, &r.NetId, &r.Area, &r.Type, &r.Group, &r.Verb, &r.Resource, &r.Reason, &r.Status, &r.Reviewer, &r.Duration, &r.Expires, &r.Granted)
+	return r, err
+}
+
+// Store a new pending access request and return its guid.
+func (ar *AccessRequestAccessor) Create(r AccessRequest) (string, error) {
+	stmt, err := ar.DB.Prepare("INSERT INTO accessRequest (" + accessRequestColumns + ") VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?)")
+	if err != nil {
+		return "", err
+	}
+
+	guid := NewGuid()
+	_, err = stmt.Exec(guid, r.NetId, r.Area, r.Type, r.Group, r.Verb, r.Resource, r.Reason, AccessPending, "", r.Duration, 0, false)
+	return guid, err
+}
+
+// Gets the access request with the given id.
+func (ar *AccessRequestAccessor) Get(guid string) (AccessRequest, error) {
+	stmt, err := ar.DB.Prepare("SELECT " + accessRequestColumns + " FROM accessRequest WHERE guid=?")
+	if err != nil {
+		return AccessRequest{}, err
+	}
+
+	return scanAccessRequest(stmt.QueryRow(guid))
+}
+
+func (ar *AccessRequestAccessor) query(query string, args ...interface{}) ([]AccessRequest, error) {
+	requests := make([]AccessRequest, 0)
+	stmt, err := ar.DB.Prepare(query)
+	if err != nil {
+		return requests, err
+	}
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return requests, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r, err := scanAccessRequest(rows)
+		if err != nil {
+			return requests, err
+		}
+		requests = append(requests, r)
+	}
+
+	return requests, nil
+}
+
+// Gets every pending access request in an area.
+func (ar *AccessRequestAccessor) GetPendingByArea(area string) ([]AccessRequest, error) {
+	return ar.query("SELECT "+accessRequestColumns+" FROM accessRequest WHERE area=? AND status=?", area, AccessPending)
+}
+
+// Gets the pending requests to join groups the user owns in an area.
+func (ar *AccessRequestAccessor) GetPendingForOwner(netId, area string) ([]AccessRequest, error) {
+	return ar.query("SELECT accessRequest.guid, accessRequest.netId, accessRequest.area, accessRequest.type, accessRequest.groupGuid, accessRequest.verb, accessRequest.resource, accessRequest.reason, accessRequest.status, accessRequest.reviewer, accessRequest.duration, accessRequest.expires, accessRequest.granted FROM accessRequest JOIN groupOwners ON accessRequest.groupGuid = groupOwners.groupGuid WHERE groupOwners.netId=? AND accessRequest.area=? AND accessRequest.status=?", netId, area, AccessPending)
+}
+
+// Gets approved requests whose access lapsed at or before the given unix time.
+func (ar *AccessRequestAccessor) GetExpired(now int64) ([]AccessRequest, error) {
+	return ar.query("SELECT "+accessRequestColumns+" FROM accessRequest WHERE status=? AND expires>0 AND expires<=?", AccessApproved, now)
+}
+
+// Move a request from one state to another, recording who did it and when
+// the access lapses, without acting on it, as for a denial. Returns false
+// if the request was no longer in the expected state.
+func (ar *AccessRequestAccessor) Resolve(guid, from, to, reviewer string, expires int64) (bool, error) {
+	stmt, err := ar.DB.Prepare("UPDATE accessRequest SET status=?, reviewer=?, expires=? WHERE guid=? AND status=?")
+	if err != nil {
+		return false, err
+	}
+
+	res, err := stmt.Exec(to, reviewer, expires, guid, from)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Approve a pending request and grant the access it asked for, in one
+// transaction so that a grant that fails leaves the request pending.
+// Permissions are granted for the request's area only. Expires is the unix
+// time the access lapses, 0 for never. Returns false if the request had
+// already been decided by someone else.
+func (ar *AccessRequestAccessor) Approve(r AccessRequest, reviewer string, expires int64) (bool, error) {
+	tx, err := ar.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	claimed, err := approveAccessTx(tx, r, reviewer, expires)
+	if err != nil || !claimed {
+		tx.Rollback()
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Approves a pending request in tx and grants the access it asked for.
+// Returns false if the request was no longer pending.
+func approveAccessTx(tx *sql.Tx, r AccessRequest, reviewer string, expires int64) (bool, error) {
+	claimed, err := resolveAccessTx(tx, r.Guid, AccessPending, AccessApproved, reviewer, expires)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	var n int64
+	if r.Type == AccessGroup {
+		n, err = execPublishing(tx, EventMemberAdd, EventData{Group: r.Group, NetId: r.NetId}, addMemberQuery, r.NetId, r.Group, r.NetId, r.Group)
+	} else {
+		n, err = grantTx(tx, r.NetId, r.Verb, r.Resource, "", r.Area)
+	}
+	if err == nil && n > 0 {
+		_, err = tx.Exec("UPDATE accessRequest SET granted=? WHERE guid=?", true, r.Guid)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Mark an approved request expired and take away the access it granted,
+// in one transaction. Access the user already had before the request is
+// left alone. Returns false if the request was no longer approved.
+func (ar *AccessRequestAccessor) Expire(r AccessRequest) (bool, error) {
+	tx, err := ar.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	claimed, err := resolveAccessTx(tx, r.Guid, AccessApproved, AccessExpired, r.Reviewer, r.Expires)
+	if err != nil || !claimed {
+		tx.Rollback()
+		return false, err
+	}
+
+	if r.Granted {
+		if r.Type == AccessGroup {
+			_, err = execPublishing(tx, EventMemberRemove, EventData{Group: r.Group, NetId: r.NetId}, removeMemberQuery, r.NetId, r.Group)
+		} else {
+			_, err = revokeTx(tx, r.NetId, r.Verb, r.Resource)
+		}
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+func resolveAccessTx(tx *sql.Tx, guid, from, to, reviewer string, expires int64) (bool, error) {
+	res, err := tx.Exec("UPDATE accessRequest SET status=?, reviewer=?, expires=? WHERE guid=? AND status=?", to, reviewer, expires, guid, from)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
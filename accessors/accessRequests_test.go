@@ -0,0 +1,168 @@
+package accessors
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+var accessRequestColumnNames = []string{"guid", "netId", "area", "type", "groupGuid", "verb", "resource", "reason", "status", "reviewer", "duration", "expires", "granted"}
+
+func TestCreateAccessRequest(t *testing.T) {
+	NewGuid = func() string {
+		return "ar1"
+	}
+
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an access request accessor %v", err)
+		return
+	}
+
+	ar := NewAccessRequestAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectExec("INSERT INTO accessRequest .+ VALUES .+").
+		WithArgs("ar1", "netId", "area", "group", "g1", "", "", "night shift", "pending", "", 24, 0, false).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	guid, err := ar.Create(AccessRequest{NetId: "netId", Area: "area", Type: AccessGroup, Group: "g1", Reason: "night shift", Duration: 24})
+	if err != nil {
+		t.Errorf("An unexpected error occurred while creating an access request: %v", err)
+	}
+
+	if guid != "ar1" {
+		t.Errorf("Expected ar1 but got %v", guid)
+	}
+
+	if err := ar.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestGetPendingForOwner(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an access request accessor %v", err)
+		return
+	}
+
+	ar := NewAccessRequestAccessor(db)
+
+	expected := []AccessRequest{AccessRequest{"ar1", "netId", "area", "group", "g1", "", "", "", "pending", "", 0, 0, false}}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM accessRequest JOIN groupOwners ON .+ WHERE groupOwners.netId=. AND accessRequest.area=. AND accessRequest.status=.").
+		WithArgs("owner", "area", "pending").
+		WillReturnRows(sqlmock.NewRows(accessRequestColumnNames).FromCSVString("ar1,netId,area,group,g1,,,,pending,,0,0,0"))
+
+	requests, err := ar.GetPendingForOwner("owner", "area")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while getting access requests %v", err)
+	}
+
+	if len(requests) != len(expected) {
+		t.Errorf("Expected %v but got %v", expected, requests)
+		return
+	}
+	for i := 0; i < len(requests); i++ {
+		if requests[i] != expected[i] {
+			t.Errorf("Expected %v but got %v", expected, requests)
+		}
+	}
+
+	if err := ar.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestGetExpiredAccessRequests(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an access request accessor %v", err)
+		return
+	}
+
+	ar := NewAccessRequestAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM accessRequest WHERE status=. AND expires>0 AND expires<=.").
+		WithArgs("approved", 1000).
+		WillReturnRows(sqlmock.NewRows(accessRequestColumnNames).FromCSVString("ar1,netId,area,permission,,edit,res,,approved,boss,1,900,1"))
+
+	requests, err := ar.GetExpired(1000)
+	if err != nil {
+		t.Errorf("An unexpected error occurred while getting access requests %v", err)
+	}
+
+	if len(requests) != 1 || requests[0].Expires != 900 || requests[0].Reviewer != "boss" || !requests[0].Granted {
+		t.Errorf("Expected one expired request but got %v", requests)
+	}
+
+	if err := ar.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestExpireAccessRequestAlreadyMember(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an access request accessor %v", err)
+		return
+	}
+
+	ar := NewAccessRequestAccessor(db)
+
+	// The user was in the group before the request, so expiry leaves them there
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("UPDATE accessRequest SET status=., reviewer=., expires=. WHERE guid=. AND status=.").
+		WithArgs("expired", "boss", 900, "ar1", "approved").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectCommit()
+
+	expired, err := ar.Expire(AccessRequest{Guid: "ar1", NetId: "netId", Type: AccessGroup, Group: "g1", Status: AccessApproved, Reviewer: "boss", Expires: 900})
+	if err != nil || !expired {
+		t.Errorf("Expected the request to expire but got %v, %v", expired, err)
+	}
+
+	if err := ar.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestApproveAccessRequestPermission(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an access request accessor %v", err)
+		return
+	}
+
+	ar := NewAccessRequestAccessor(db)
+
+	// The permission only applies in the request's area
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("UPDATE accessRequest SET status=., reviewer=., expires=. WHERE guid=. AND status=.").
+		WithArgs("approved", "boss", 0, "ar1", "pending").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO policy (.+) SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("netId", "edit", "shifts", "netId", "edit", "shifts").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO policyArea").
+		WithArgs("netId", "edit", "shifts", "area").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("UPDATE accessRequest SET granted=. WHERE guid=.").
+		WithArgs(true, "ar1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectCommit()
+
+	approved, err := ar.Approve(AccessRequest{Guid: "ar1", NetId: "netId", Area: "area", Type: AccessPermission, Verb: "edit", Resource: "shifts", Status: AccessPending}, "boss", 0)
+	if err != nil || !approved {
+		t.Errorf("Expected the request to be approved but got %v, %v", approved, err)
+	}
+
+	if err := ar.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
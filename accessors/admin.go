@@ -4,16 +4,35 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 )
 
-// Tells whether or not a user is an admin.
+// Grants that leave an existing admin or superuser row as it is.
+const (
+	addAdminQuery = "INSERT INTO admin (netId, area) SELECT ?,? FROM DUAL WHERE NOT EXISTS (SELECT netId FROM admin WHERE netId=? AND area=?)"
+	addSUQuery    = "INSERT INTO superuser (netId) SELECT ? FROM DUAL WHERE NOT EXISTS (SELECT netId FROM superuser WHERE netId=?)"
+)
+
+// Tells whether or not a user is an admin. Admins of an area are admins
+// of every area below it too.
 func (pa *PermissionAccessor) IsAdmin(netId, areaGuid string) (bool, error) {
+	lineage, err := NewAreaAccessor(pa.DB).Lineage(areaGuid)
+	if err != nil {
+		return false, err
+	}
+	areas := AdminAreas(areaGuid, lineage)
 
 	// execute query
-	stmt, err := pa.DB.Prepare("SELECT * FROM admin WHERE netId=? AND area=?")
+	query := "SELECT * FROM admin WHERE netId=? AND area IN (?"
+	params := []interface{}{netId, areas[0]}
+	for i := 1; i < len(areas); i++ {
+		query += ",?"
+		params = append(params, areas[i])
+	}
+	query += ")"
+	stmt, err := pa.DB.Prepare(query)
 	if err != nil {
 		return false, err
 	}
 
-	rows, err := stmt.Query(netId, areaGuid)
+	rows, err := stmt.Query(params...)
 	if err != nil {
 		return false, err
 	}
@@ -57,6 +76,35 @@ func (pa *PermissionAccessor) GetAdmins(area string) ([]string, error) {
 	return users, nil
 }
 
+// An admin of an area and the area they were made admin in, which is
+// either the area itself or one of its ancestors.
+type AreaAdmin struct {
+	NetId string
+	Area  string
+}
+
+// Gets the admins of an area including those inherited from its ancestors.
+func (pa *PermissionAccessor) GetInheritedAdmins(area string) ([]AreaAdmin, error) {
+	admins := make([]AreaAdmin, 0)
+
+	lineage, err := NewAreaAccessor(pa.DB).Lineage(area)
+	if err != nil {
+		return admins, err
+	}
+
+	for _, a := range AdminAreas(area, lineage) {
+		users, err := pa.GetAdmins(a)
+		if err != nil {
+			return admins, err
+		}
+		for _, netId := range users {
+			admins = append(admins, AreaAdmin{netId, a})
+		}
+	}
+
+	return admins, nil
+}
+
 // Tells whether or not a user has elevated to superuser rights
 func (pa *PermissionAccessor) GetAllSU() ([]string, error) {
 	users := make([]string, 0)
@@ -141,68 +189,36 @@ func (pa *PermissionAccessor) CanSuperuser(netId string) (bool, error) {
 	return false, nil
 }
 
-// Grant admin access.
-func (pa *PermissionAccessor) AddAdmin(netId, areaGuid string) error {
-	stmt, err := pa.DB.Prepare("INSERT INTO admin (netId, area) VALUES (?,?)")
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(netId, areaGuid)
-	return err
+// Grant admin access. Returns the number of rows inserted, 0 if the user
+// was already an admin of the area.
+func (pa *PermissionAccessor) AddAdmin(netId, areaGuid string) (int64, error) {
+	return execEvent(pa.DB, EventAdminAdd, EventData{NetId: netId, Area: areaGuid}, addAdminQuery, netId, areaGuid, netId, areaGuid)
 }
 
-// Revoke admin access.
-func (pa *PermissionAccessor) DeleteAdmin(netId, areaGuid string) error {
-	stmt, err := pa.DB.Prepare("DELETE FROM admin WHERE netId=? AND area=?")
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(netId, areaGuid)
-	return err
+// Revoke admin access. Returns the number of rows deleted.
+func (pa *PermissionAccessor) DeleteAdmin(netId, areaGuid string) (int64, error) {
+	return execEvent(pa.DB, EventAdminRemove, EventData{NetId: netId, Area: areaGuid}, "DELETE FROM admin WHERE netId=? AND area=?", netId, areaGuid)
 }
 
-// Grant superuser access.
-func (pa *PermissionAccessor) AddSU(netId string) error {
-	stmt, err := pa.DB.Prepare("INSERT INTO superuser (netId) VALUES (?)")
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(netId)
-	return err
+// Grant superuser access. Returns the number of rows inserted, 0 if the
+// user was already a superuser.
+func (pa *PermissionAccessor) AddSU(netId string) (int64, error) {
+	return execEvent(pa.DB, EventSuperuserAdd, EventData{NetId: netId}, addSUQuery, netId, netId)
 }
 
 // Elevate to superuser access.
 func (pa *PermissionAccessor) ElevateToSU(netId string) error {
-	stmt, err := pa.DB.Prepare("UPDATE superuser SET active=1 WHERE netId=?")
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(netId)
+	_, err := execEvent(pa.DB, EventSuperuserElevate, EventData{NetId: netId}, "UPDATE superuser SET active=1 WHERE netId=?", netId)
 	return err
 }
 
 // Return to normal user status.
 func (pa *PermissionAccessor) StopSU(netId string) error {
-	stmt, err := pa.DB.Prepare("UPDATE superuser SET active=0 WHERE netId=?")
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(netId)
+	_, err := execEvent(pa.DB, EventSuperuserStop, EventData{NetId: netId}, "UPDATE superuser SET active=0 WHERE netId=?", netId)
 	return err
 }
 
-// Revoke superuser access.
-func (pa *PermissionAccessor) DeleteSU(netId string) error {
-	stmt, err := pa.DB.Prepare("DELETE FROM superuser WHERE netId=?")
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(netId)
-	return err
+// Revoke superuser access. Returns the number of rows deleted.
+func (pa *PermissionAccessor) DeleteSU(netId string) (int64, error) {
+	return execEvent(pa.DB, EventSuperuserRemove, EventData{NetId: netId}, "DELETE FROM superuser WHERE netId=?", netId)
 }
@@ -0,0 +1,253 @@
+package accessors
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Kinds of grants that require a second person's approval.
+const (
+	ApprovalAdmin      = "admin"
+	ApprovalSuperuser  = "superuser"
+	ApprovalPermission = "permission"
+
+	// Access requests for sensitive resources
+	ApprovalAccessRequest = "access.request"
+
+	// Roles whose resource patterns cover a sensitive resource
+	ApprovalRolePermission = "role.permission"
+	ApprovalRoleBinding    = "role.binding"
+)
+
+// States a pending request moves through.
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// Approval struct that reflects the approval table. Only the fields
+// relevant to the request's Type are populated: NetId for admin and
+// superuser grants, Actor/Verb/Resource/Condition for permissions,
+// Role/Verb/Resource for permissions added to a role, Role/Actor for
+// role bindings and NetId/Verb/Resource for access requests, whose guid
+// is kept in Actor. Area is the area being granted for admin requests, the
+// role's area for role requests and the requester's area otherwise.
+type Approval struct {
+	Guid      string
+	Type      string
+	Requester string
+	Area      string
+	NetId     string
+	Role      string
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string // stored in the expression column
+	Status    string
+	Reviewer  string
+}
+
+const approvalColumns = "guid, type, requester, area, netId, role, actor, verb, resource, expression, status, reviewer"
+
+type ApprovalAccessor struct {
+	DB *sql.DB // Database connection
+}
+
+// Returns a new approval accessor.
+func NewApprovalAccessor(db *sql.DB) *ApprovalAccessor {
+	return &ApprovalAccessor{db}
+}
+
+// Store a new pending request and return its guid.
+func (aa *ApprovalAccessor) Create(a Approval) (string, error) {
+	stmt, err := aa.DB.Prepare("INSERT INTO approval (" + approvalColumns + ") VALUES (?,?,?,?,?,?,?,?,?,?,?,?)")
+	if err != nil {
+		return "", err
+	}
+
+	guid := NewGuid()
+	_, err = stmt.Exec(guid, a.Type, a.Requester, a.Area, a.NetId, a.Role, a.Actor, a.Verb, a.Resource, a.Condition, ApprovalPending, "")
+	return guid, err
+}
+
+// Records a pending request in tx and returns its id.
+func createApprovalTx(tx *sql.Tx, a Approval) (string, error) {
+	guid := NewGuid()
+	_, err := tx.Exec("INSERT INTO approval ("+approvalColumns+") VALUES (?,?,?,?,?,?,?,?,?,?,?,?)", guid, a.Type, a.Requester, a.Area, a.NetId, a.Role, a.Actor, a.Verb, a.Resource, a.Condition, ApprovalPending, "")
+	return guid, err
+}
+
+// Gets the request with the given id.
+func (aa *ApprovalAccessor) Get(guid string) (Approval, error) {
+	a := Approval{}
+	stmt, err := aa.DB.Prepare("SELECT " + approvalColumns + " FROM approval WHERE guid=?")
+	if err != nil {
+		return a, err
+	}
+
+	row := stmt.QueryRow(guid)
+	err = row.Scan(&a.Guid, &a.Type, &a.Requester, &a.Area, &a.NetId, &a.Role, &a.Actor, &a.Verb, &a.Resource, &a.Condition, &a.Status, &a.Reviewer)
+	return a, err
+}
+
+// Gets all requests still waiting on a decision in an area.
+func (aa *ApprovalAccessor) GetPending(area string) ([]Approval, error) {
+	approvals := make([]Approval, 0)
+	stmt, err := aa.DB.Prepare("SELECT " + approvalColumns + " FROM approval WHERE area=? AND status=?")
+	if err != nil {
+		return approvals, err
+	}
+
+	rows, err := stmt.Query(area, ApprovalPending)
+	if err != nil {
+		return approvals, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := Approval{}
+		err = rows.Scan(&a.Guid, &a.Type, &a.Requester, &a.Area, &a.NetId, &a.Role, &a.Actor, &a.Verb, &a.Resource, &a.Condition, &a.Status, &a.Reviewer)
+		approvals = append(approvals, a)
+	}
+
+	return approvals, nil
+}
+
+// Record the decision on a pending request without acting on it, as for a
+// rejection. Returns false if the request had already been decided by
+// someone else.
+func (aa *ApprovalAccessor) Resolve(guid, status, reviewer string) (bool, error) {
+	stmt, err := aa.DB.Prepare("UPDATE approval SET status=?, reviewer=? WHERE guid=? AND status=?")
+	if err != nil {
+		return false, err
+	}
+
+	res, err := stmt.Exec(status, reviewer, guid, ApprovalPending)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Approve a pending request and perform the grant it was waiting on.
+// Both happen in one transaction, so a grant that fails leaves the request
+// pending to be approved again. Grants already in place are left as they
+// are. Returns false if the request had already been decided by someone
+// else.
+func (aa *ApprovalAccessor) Approve(a Approval, reviewer string) (bool, error) {
+	tx, err := aa.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := tx.Exec("UPDATE approval SET status=?, reviewer=? WHERE guid=? AND status=?", ApprovalApproved, reviewer, a.Guid, ApprovalPending)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		tx.Rollback()
+		return false, err
+	}
+
+	switch a.Type {
+	case ApprovalAdmin:
+		_, err = execPublishing(tx, EventAdminAdd, EventData{NetId: a.NetId, Area: a.Area}, addAdminQuery, a.NetId, a.Area, a.NetId, a.Area)
+	case ApprovalSuperuser:
+		_, err = execPublishing(tx, EventSuperuserAdd, EventData{NetId: a.NetId}, addSUQuery, a.NetId, a.NetId)
+	case ApprovalPermission:
+		_, err = grantTx(tx, a.Actor, a.Verb, a.Resource, a.Condition, "")
+	case ApprovalRolePermission:
+		_, err = execPublishing(tx, EventRolePermissionAdd, EventData{Role: a.Role, Verb: a.Verb, Resource: a.Resource}, addRolePermissionQuery, a.Role, a.Verb, a.Resource)
+	case ApprovalRoleBinding:
+		_, err = execPublishing(tx, EventRoleBind, EventData{Role: a.Role, Actor: a.Actor}, bindRoleQuery, a.Role, a.Actor)
+	case ApprovalAccessRequest:
+		var r AccessRequest
+		r, err = scanAccessRequest(tx.QueryRow("SELECT "+accessRequestColumns+" FROM accessRequest WHERE guid=?", a.Actor))
+		if err != nil {
+			break
+		}
+		var expires int64
+		if r.Duration > 0 {
+			expires = time.Now().Add(time.Duration(r.Duration) * time.Hour).Unix()
+		}
+		var claimed bool
+		claimed, err = approveAccessTx(tx, r, reviewer, expires)
+		if err == nil && !claimed {
+			// The access request was decided while this waited
+			tx.Rollback()
+			return false, nil
+		}
+	default:
+		err = fmt.Errorf("unknown approval type %q", a.Type)
+	}
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Tells whether or not grants on a resource need a second approver.
+func (aa *ApprovalAccessor) IsSensitive(resource string) (bool, error) {
+	stmt, err := aa.DB.Prepare("SELECT resource FROM sensitiveResource WHERE resource=?")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(resource)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	return rows.Next(), nil
+}
+
+// Tells whether a role's resource pattern covers a sensitive resource, in
+// which case granting it needs a second approver just as granting the
+// resource itself would.
+func (aa *ApprovalAccessor) CoversSensitive(pattern string) (bool, error) {
+	stmt, err := aa.DB.Prepare("SELECT resource FROM sensitiveResource")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var resource string
+		if err := rows.Scan(&resource); err != nil {
+			return false, err
+		}
+		if MatchResource(pattern, resource) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// Flag a resource as sensitive.
+func (aa *ApprovalAccessor) MarkSensitive(resource string) error {
+	_, err := execEvent(aa.DB, EventSensitiveMark, EventData{Resource: resource}, "INSERT INTO sensitiveResource (resource) VALUES (?)", resource)
+	return err
+}
+
+// Remove the sensitive flag from a resource.
+func (aa *ApprovalAccessor) UnmarkSensitive(resource string) error {
+	_, err := execEvent(aa.DB, EventSensitiveUnmark, EventData{Resource: resource}, "DELETE FROM sensitiveResource WHERE resource=?", resource)
+	return err
+}
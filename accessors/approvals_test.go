@@ -0,0 +1,161 @@
+package accessors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestCreateApproval(t *testing.T) {
+	NewGuid = func() string {
+		return "req1"
+	}
+
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an approval accessor %v", err)
+		return
+	}
+
+	aa := NewApprovalAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectExec("INSERT INTO approval .+ VALUES .+").
+		WithArgs("req1", "admin", "requester", "area", "netId", "", "", "", "", "", "pending", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	guid, err := aa.Create(Approval{Type: ApprovalAdmin, Requester: "requester", Area: "area", NetId: "netId"})
+	if err != nil {
+		t.Errorf("An unexpected error occurred while creating an approval: %v", err)
+	}
+
+	if guid != "req1" {
+		t.Errorf("Expected req1 but got %v", guid)
+	}
+
+	if err := aa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestGetPendingApprovals(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an approval accessor %v", err)
+		return
+	}
+
+	aa := NewApprovalAccessor(db)
+
+	expected := []Approval{
+		Approval{"req1", "admin", "requester", "area", "netId", "", "", "", "", "", "pending", ""},
+		Approval{"req2", "permission", "requester", "area", "", "", "group", "edit", "res", "time.hour < 17", "pending", ""},
+	}
+	columns := []string{"guid", "type", "requester", "area", "netId", "role", "actor", "verb", "resource", "expression", "status", "reviewer"}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM approval WHERE area=. AND status=.").
+		WithArgs("area", "pending").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("req1,admin,requester,area,netId,,,,,,pending,\nreq2,permission,requester,area,,,group,edit,res,time.hour < 17,pending,"))
+
+	approvals, err := aa.GetPending("area")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while getting approvals %v", err)
+	}
+
+	for i := 0; i < len(approvals); i++ {
+		if approvals[i] != expected[i] {
+			t.Errorf("Expected %v but got %v", expected, approvals)
+		}
+	}
+
+	if err := aa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestResolveApprovalAlreadyDecided(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an approval accessor %v", err)
+		return
+	}
+
+	aa := NewApprovalAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectExec("UPDATE approval SET status=., reviewer=. WHERE guid=. AND status=.").
+		WithArgs("approved", "reviewer", "req1", "pending").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	claimed, err := aa.Resolve("req1", ApprovalApproved, "reviewer")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while resolving an approval: %v", err)
+	}
+
+	if claimed {
+		t.Error("Expected false but got true")
+	}
+
+	if err := aa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestApproveGrantFails(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an approval accessor %v", err)
+		return
+	}
+
+	aa := NewApprovalAccessor(db)
+
+	// The request is only marked approved if the grant goes through
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("UPDATE approval SET status=., reviewer=. WHERE guid=. AND status=.").
+		WithArgs("approved", "reviewer", "req1", "pending").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO admin .+ SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("netId", "area", "netId", "area").
+		WillReturnError(errors.New("lost connection"))
+	sqlmock.ExpectRollback()
+
+	claimed, err := aa.Approve(Approval{Guid: "req1", Type: ApprovalAdmin, Area: "area", NetId: "netId"}, "reviewer")
+	if err == nil || claimed {
+		t.Errorf("Expected the approval to fail but got %v, %v", claimed, err)
+	}
+
+	if err := aa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestIsSensitive(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an approval accessor %v", err)
+		return
+	}
+
+	aa := NewApprovalAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT resource FROM sensitiveResource WHERE resource=.").
+		WithArgs("res").
+		WillReturnRows(sqlmock.NewRows([]string{"resource"}).FromCSVString("res"))
+
+	sensitive, err := aa.IsSensitive("res")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while checking a resource: %v", err)
+	}
+
+	if !sensitive {
+		t.Error("Expected true but got false")
+	}
+
+	if err := aa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
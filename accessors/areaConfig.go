@@ -0,0 +1,417 @@
+package accessors
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/membership"
+)
+
+// An area's permission configuration: its groups with their direct
+// members, its admins and the policy rows held by the area or its groups.
+// Everything is sorted so that exporting the same configuration always
+// gives the same document. A configuration without Admins, as opposed to
+// an empty list of them, leaves the area's admins alone when imported.
+type AreaConfig struct {
+	Area   string         `yaml:"Area"`
+	Name   string         `yaml:"Name"`
+	Groups []GroupConfig  `yaml:"Groups"`
+	Admins []string       `yaml:"Admins"`
+	Policy []PolicyConfig `yaml:"Policy"`
+}
+
+// A group in an AreaConfig. Members are the users added to it directly.
+type GroupConfig struct {
+	Guid        string   `yaml:"Guid"`
+	Name        string   `yaml:"Name"`
+	Description string   `yaml:"Description"`
+	Tags        []string `yaml:"Tags"`
+	Members     []string `yaml:"Members"`
+}
+
+// A policy row in an AreaConfig along with the condition it is granted
+// under, if any.
+type PolicyConfig struct {
+	Actor     string `yaml:"Actor"`
+	Verb      string `yaml:"Verb"`
+	Resource  string `yaml:"Resource"`
+	Condition string `yaml:"Condition"`
+}
+
+// A user added directly to a group.
+type Membership struct {
+	Group string
+	NetId string
+}
+
+// The changes that make an area match an AreaConfig. Policy rows whose
+// condition changes are removed and added again.
+type ConfigChanges struct {
+	CreateGroups  []GroupConfig
+	UpdateGroups  []GroupConfig
+	DeleteGroups  []string
+	AddMembers    []Membership
+	RemoveMembers []Membership
+	AddAdmins     []string
+	RemoveAdmins  []string
+	AddPolicy     []PolicyConfig
+	RemovePolicy  []PolicyConfig
+}
+
+// Tells whether applying the changes would do nothing.
+func (cc ConfigChanges) Empty() bool {
+	return len(cc.CreateGroups)+len(cc.UpdateGroups)+len(cc.DeleteGroups)+
+		len(cc.AddMembers)+len(cc.RemoveMembers)+len(cc.AddAdmins)+
+		len(cc.RemoveAdmins)+len(cc.AddPolicy)+len(cc.RemovePolicy) == 0
+}
+
+// How PlanImport matches a configuration to an area.
+type ImportOptions struct {
+	Replace bool              // remove what the configuration doesn't have
+	Remap   map[string]string // guids in the configuration and what to use instead
+}
+
+// Gets an area's configuration.
+func (aa *AreaAccessor) Export(guid string) (AreaConfig, error) {
+	config := AreaConfig{Area: guid, Groups: make([]GroupConfig, 0), Admins: make([]string, 0), Policy: make([]PolicyConfig, 0)}
+
+	area, err := aa.Get(guid)
+	if err != nil {
+		return config, err
+	}
+	config.Name = area.Name
+
+	groups, err := queryGroups(aa.DB, "SELECT "+groupColumns+" FROM groups WHERE area=? ORDER BY name, guid", guid)
+	if err != nil {
+		return config, err
+	}
+	index := make(map[string]int)
+	for _, g := range groups {
+		index[g.Guid] = len(config.Groups)
+		config.Groups = append(config.Groups, GroupConfig{g.Guid, g.Name, g.Description, g.Tags, make([]string, 0)})
+	}
+
+	rows, err := aa.query("SELECT groupMembers.groupGuid, groupMembers.netId FROM groupMembers JOIN groups ON groups.guid = groupMembers.groupGuid WHERE groups.area=? ORDER BY groupMembers.netId", guid)
+	if err != nil {
+		return config, err
+	}
+	for _, row := range rows {
+		if i, ok := index[row[0]]; ok {
+			config.Groups[i].Members = append(config.Groups[i].Members, row[1])
+		}
+	}
+
+	rows, err = aa.query("SELECT netId FROM admin WHERE area=? ORDER BY netId", guid)
+	if err != nil {
+		return config, err
+	}
+	for _, row := range rows {
+		config.Admins = append(config.Admins, row[0])
+	}
+
+	rows, err = aa.query("SELECT policy.actor, policy.verb, policy.resource, COALESCE(policyCondition.expression, '') FROM policy LEFT JOIN policyCondition ON policyCondition.actor = policy.actor AND policyCondition.verb = policy.verb AND policyCondition.resource = policy.resource WHERE policy.actor=? OR policy.actor IN (SELECT guid FROM groups WHERE area=?) ORDER BY policy.actor, policy.verb, policy.resource", guid, guid)
+	if err != nil {
+		return config, err
+	}
+	for _, row := range rows {
+		config.Policy = append(config.Policy, PolicyConfig{row[0], row[1], row[2], row[3]})
+	}
+
+	return config, nil
+}
+
+// Runs a query whose columns are all strings and returns its rows.
+func (aa *AreaAccessor) query(query string, args ...interface{}) ([][]string, error) {
+	result := make([][]string, 0)
+	stmt, err := aa.DB.Prepare(query)
+	if err != nil {
+		return result, err
+	}
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return result, err
+	}
+	for rows.Next() {
+		row := make([]string, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return result, err
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+// Works out the changes that make the area whose configuration is current
+// match config. Guids in config are first replaced following opts.Remap and
+// config's own area becomes current's. Groups are matched by guid, then by
+// name; groups left unmatched are created under new guids, which policy
+// rows naming them follow. Policy rows may name their group by guid or by
+// name. Groups, members, admins and policy rows that current has and
+// config doesn't are only removed with opts.Replace.
+func PlanImport(current, config AreaConfig, opts ImportOptions) (ConfigChanges, error) {
+	changes := ConfigChanges{
+		CreateGroups:  make([]GroupConfig, 0),
+		UpdateGroups:  make([]GroupConfig, 0),
+		DeleteGroups:  make([]string, 0),
+		AddMembers:    make([]Membership, 0),
+		RemoveMembers: make([]Membership, 0),
+		AddAdmins:     make([]string, 0),
+		RemoveAdmins:  make([]string, 0),
+		AddPolicy:     make([]PolicyConfig, 0),
+		RemovePolicy:  make([]PolicyConfig, 0),
+	}
+
+	remap := func(guid string) string {
+		if to, ok := opts.Remap[guid]; ok {
+			return to
+		}
+		if guid == config.Area {
+			return current.Area
+		}
+		return guid
+	}
+
+	// Match groups
+	byGuid := make(map[string]GroupConfig)
+	byName := make(map[string]GroupConfig)
+	for _, g := range current.Groups {
+		byGuid[g.Guid] = g
+		byName[g.Name] = g
+	}
+
+	guids := make(map[string]string)
+	named := make(map[string]string)
+	matched := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, g := range config.Groups {
+		existing, ok := byGuid[remap(g.Guid)]
+		if !ok {
+			existing, ok = byName[g.Name]
+		}
+		if ok && matched[existing.Guid] {
+			return changes, fmt.Errorf("groups %q and another group in the configuration both match group %s", g.Name, existing.Guid)
+		}
+		if names[g.Name] {
+			return changes, ErrDuplicateGroup
+		}
+		names[g.Name] = true
+
+		group := GroupConfig{g.Guid, g.Name, g.Description, g.Tags, nil}
+		if group.Tags == nil {
+			group.Tags = make([]string, 0)
+		}
+		var members []string
+		if ok {
+			group.Guid = existing.Guid
+			members = existing.Members
+			if existing.Name != group.Name || existing.Description != group.Description || strings.Join(existing.Tags, ",") != strings.Join(group.Tags, ",") {
+				changes.UpdateGroups = append(changes.UpdateGroups, group)
+			}
+		} else {
+			group.Guid = NewGuid()
+			changes.CreateGroups = append(changes.CreateGroups, group)
+		}
+		if g.Guid != "" {
+			guids[g.Guid] = group.Guid
+		}
+		named[g.Name] = group.Guid
+		matched[group.Guid] = true
+
+		add, remove := membership.Diff(members, g.Members)
+		for _, netId := range add {
+			changes.AddMembers = append(changes.AddMembers, Membership{group.Guid, netId})
+		}
+		if opts.Replace {
+			for _, netId := range remove {
+				changes.RemoveMembers = append(changes.RemoveMembers, Membership{group.Guid, netId})
+			}
+		}
+	}
+
+	for _, g := range current.Groups {
+		if matched[g.Guid] {
+			continue
+		}
+		if opts.Replace {
+			changes.DeleteGroups = append(changes.DeleteGroups, g.Guid)
+			for _, netId := range g.Members {
+				changes.RemoveMembers = append(changes.RemoveMembers, Membership{g.Guid, netId})
+			}
+		} else if names[g.Name] {
+			return changes, ErrDuplicateGroup
+		}
+	}
+
+	// Admins
+	admins := make(map[string]bool)
+	for _, netId := range current.Admins {
+		admins[netId] = true
+	}
+	wanted := make(map[string]bool)
+	for _, netId := range config.Admins {
+		if !admins[netId] && !wanted[netId] {
+			changes.AddAdmins = append(changes.AddAdmins, netId)
+		}
+		wanted[netId] = true
+	}
+	if opts.Replace && config.Admins != nil {
+		for _, netId := range current.Admins {
+			if !wanted[netId] {
+				changes.RemoveAdmins = append(changes.RemoveAdmins, netId)
+			}
+		}
+	}
+
+	// Policy rows
+	key := func(p PolicyConfig) string { return p.Actor + "\x00" + p.Verb + "\x00" + p.Resource }
+	rows := make(map[string]PolicyConfig)
+	for _, p := range current.Policy {
+		rows[key(p)] = p
+	}
+	kept := make(map[string]bool)
+	for _, p := range config.Policy {
+		actor, ok := guids[p.Actor]
+		if !ok {
+			actor, ok = named[p.Actor]
+		}
+		if !ok {
+			if actor = remap(p.Actor); actor != current.Area {
+				return changes, fmt.Errorf("policy actor %s is neither the area nor one of its groups", p.Actor)
+			}
+		}
+		row := PolicyConfig{actor, p.Verb, remap(p.Resource), p.Condition}
+		if kept[key(row)] {
+			continue
+		}
+		kept[key(row)] = true
+
+		existing, ok := rows[key(row)]
+		if ok && existing.Condition == row.Condition {
+			continue
+		}
+		if ok {
+			changes.RemovePolicy = append(changes.RemovePolicy, existing)
+		}
+		changes.AddPolicy = append(changes.AddPolicy, row)
+	}
+	if opts.Replace {
+		for _, p := range current.Policy {
+			if !kept[key(p)] {
+				changes.RemovePolicy = append(changes.RemovePolicy, p)
+			}
+		}
+	}
+
+	sort.SliceStable(changes.RemovePolicy, func(i, j int) bool {
+		return key(changes.RemovePolicy[i]) < key(changes.RemovePolicy[j])
+	})
+	return changes, nil
+}
+
+// The statements that delete a group along with its members, owners,
+// rule, policy rows and role bindings. Each takes the group's guid; the
+// group itself goes last.
+var deleteGroupSteps = []string{
+	"DELETE FROM groupMembers WHERE groupGuid=?",
+	"DELETE FROM groupOwners WHERE groupGuid=?",
+	"DELETE FROM groupRuleMember WHERE groupGuid=?",
+	"DELETE FROM groupRule WHERE groupGuid=?",
+	"DELETE FROM policyCondition WHERE actor=?",
+	"DELETE FROM policy WHERE actor=?",
+	"DELETE FROM roleBinding WHERE actor=?",
+	"DELETE FROM groups WHERE guid=?",
+}
+
+// Applies changes worked out by PlanImport to an area in a single
+// transaction. Groups it creates get the created time and creator given.
+// Admins aren't added directly: each addition is recorded as a pending
+// approval requested by createdBy, and their ids are returned.
+func (aa *AreaAccessor) ApplyConfig(area string, changes ConfigChanges, created int64, createdBy string) ([]string, error) {
+	pending := make([]string, 0)
+
+	tx, err := aa.DB.Begin()
+	if err != nil {
+		return pending, err
+	}
+
+	exec := func(eventType string, data EventData, query string, args ...interface{}) bool {
+		if _, err = execPublishing(tx, eventType, data, query, args...); err != nil {
+			tx.Rollback()
+			return false
+		}
+		return true
+	}
+
+	for _, guid := range changes.DeleteGroups {
+		for _, query := range deleteGroupSteps[:len(deleteGroupSteps)-1] {
+			if _, err = tx.Exec(query, guid); err != nil {
+				tx.Rollback()
+				return pending, err
+			}
+		}
+		if !exec(EventGroupDelete, EventData{Group: guid}, deleteGroupSteps[len(deleteGroupSteps)-1], guid) {
+			return pending, err
+		}
+	}
+	for _, g := range changes.UpdateGroups {
+		if !exec(EventGroupUpdate, EventData{Group: g.Guid, Name: g.Name}, "UPDATE groups SET name=?, description=?, tags=? WHERE guid=?", g.Name, g.Description, strings.Join(g.Tags, ","), g.Guid) {
+			return pending, err
+		}
+	}
+	for _, g := range changes.CreateGroups {
+		if !exec(EventGroupCreate, EventData{Group: g.Guid, Area: area, Name: g.Name}, "INSERT INTO groups (guid, area, name, description, tags, created, createdBy) VALUES (?,?,?,?,?,?,?)", g.Guid, area, g.Name, g.Description, strings.Join(g.Tags, ","), created, createdBy) {
+			return pending, err
+		}
+	}
+	for _, m := range changes.RemoveMembers {
+		if !exec(EventMemberRemove, EventData{Group: m.Group, NetId: m.NetId}, removeMemberQuery, m.NetId, m.Group) {
+			return pending, err
+		}
+	}
+	for _, m := range changes.AddMembers {
+		if !exec(EventMemberAdd, EventData{Group: m.Group, NetId: m.NetId}, addMemberQuery, m.NetId, m.Group, m.NetId, m.Group) {
+			return pending, err
+		}
+	}
+	for _, p := range changes.RemovePolicy {
+		if _, err = revokeTx(tx, p.Actor, p.Verb, p.Resource); err != nil {
+			tx.Rollback()
+			return pending, err
+		}
+	}
+	for _, p := range changes.AddPolicy {
+		if _, err = grantTx(tx, p.Actor, p.Verb, p.Resource, p.Condition, ""); err != nil {
+			tx.Rollback()
+			return pending, err
+		}
+	}
+	for _, netId := range changes.RemoveAdmins {
+		if !exec(EventAdminRemove, EventData{NetId: netId, Area: area}, "DELETE FROM admin WHERE netId=? AND area=?", netId, area) {
+			return pending, err
+		}
+	}
+	// Making someone an admin takes a second approver, here as anywhere
+	for _, netId := range changes.AddAdmins {
+		guid, err := createApprovalTx(tx, Approval{Type: ApprovalAdmin, Requester: createdBy, Area: area, NetId: netId})
+		if err != nil {
+			tx.Rollback()
+			return pending, err
+		}
+		pending = append(pending, guid)
+	}
+
+	return pending, tx.Commit()
+}
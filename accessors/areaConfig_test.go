@@ -0,0 +1,147 @@
+package accessors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+var stagingConfig = AreaConfig{
+	Area: "staging",
+	Groups: []GroupConfig{
+		{Guid: "s-desk", Name: "desk", Members: []string{"alice", "bob"}},
+		{Guid: "s-night", Name: "night", Tags: []string{"shift"}, Members: []string{"carol"}},
+	},
+	Admins: []string{"erin"},
+	Policy: []PolicyConfig{
+		{Actor: "staging", Verb: "view", Resource: "s-res"},
+		{Actor: "s-desk", Verb: "edit", Resource: "s-res", Condition: "time.hour >= 9"},
+		{Actor: "s-night", Verb: "edit", Resource: "s-res"},
+	},
+}
+
+var productionConfig = AreaConfig{
+	Area: "prod",
+	Groups: []GroupConfig{
+		{Guid: "p-desk", Name: "desk", Tags: []string{}, Members: []string{"alice", "dave"}},
+		{Guid: "p-old", Name: "old", Tags: []string{}, Members: []string{"frank"}},
+	},
+	Admins: []string{"erin", "gina"},
+	Policy: []PolicyConfig{
+		{Actor: "p-desk", Verb: "edit", Resource: "p-res"},
+		{Actor: "p-old", Verb: "view", Resource: "p-res"},
+	},
+}
+
+func TestPlanImportMerge(t *testing.T) {
+	changes, err := PlanImport(productionConfig, stagingConfig, ImportOptions{Remap: map[string]string{"s-res": "p-res"}})
+	if err != nil {
+		t.Fatalf("An unexpected error occurred while planning an import: %v", err)
+	}
+
+	if len(changes.CreateGroups) != 1 || changes.CreateGroups[0].Name != "night" {
+		t.Fatalf("Expected to create group night but got %v", changes.CreateGroups)
+	}
+	night := changes.CreateGroups[0].Guid
+
+	expected := ConfigChanges{
+		CreateGroups:  []GroupConfig{{Guid: night, Name: "night", Tags: []string{"shift"}}},
+		UpdateGroups:  []GroupConfig{},
+		DeleteGroups:  []string{},
+		AddMembers:    []Membership{{"p-desk", "bob"}, {night, "carol"}},
+		RemoveMembers: []Membership{},
+		AddAdmins:     []string{},
+		RemoveAdmins:  []string{},
+		AddPolicy: []PolicyConfig{
+			{Actor: "prod", Verb: "view", Resource: "p-res"},
+			{Actor: "p-desk", Verb: "edit", Resource: "p-res", Condition: "time.hour >= 9"},
+			{Actor: night, Verb: "edit", Resource: "p-res"},
+		},
+		RemovePolicy: []PolicyConfig{{Actor: "p-desk", Verb: "edit", Resource: "p-res"}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v but got %v", expected, changes)
+	}
+}
+
+func TestPlanImportReplace(t *testing.T) {
+	changes, err := PlanImport(productionConfig, stagingConfig, ImportOptions{Replace: true, Remap: map[string]string{"s-res": "p-res"}})
+	if err != nil {
+		t.Fatalf("An unexpected error occurred while planning an import: %v", err)
+	}
+
+	if !reflect.DeepEqual(changes.DeleteGroups, []string{"p-old"}) {
+		t.Errorf("Expected to delete group p-old but got %v", changes.DeleteGroups)
+	}
+	if !reflect.DeepEqual(changes.RemoveMembers, []Membership{{"p-desk", "dave"}, {"p-old", "frank"}}) {
+		t.Errorf("Expected to remove dave and frank but got %v", changes.RemoveMembers)
+	}
+	if !reflect.DeepEqual(changes.RemoveAdmins, []string{"gina"}) {
+		t.Errorf("Expected to remove admin gina but got %v", changes.RemoveAdmins)
+	}
+	if len(changes.RemovePolicy) != 2 {
+		t.Errorf("Expected to remove 2 policy rows but got %v", changes.RemovePolicy)
+	}
+}
+
+func TestPlanImportUnknownActor(t *testing.T) {
+	config := AreaConfig{Area: "staging", Policy: []PolicyConfig{{Actor: "someone", Verb: "view", Resource: "res"}}}
+	if _, err := PlanImport(productionConfig, config, ImportOptions{}); err == nil {
+		t.Errorf("Expected policy held by someone outside the area to be refused")
+	}
+}
+
+func TestPlanImportGroupNames(t *testing.T) {
+	config := AreaConfig{
+		Area:   "prod",
+		Groups: []GroupConfig{{Name: "desk", Members: []string{"alice", "dave"}}},
+		Policy: []PolicyConfig{{Actor: "desk", Verb: "edit", Resource: "p-res"}},
+	}
+
+	changes, err := PlanImport(productionConfig, config, ImportOptions{Replace: true})
+	if err != nil {
+		t.Fatalf("An unexpected error occurred while planning an import: %v", err)
+	}
+
+	if len(changes.AddPolicy) != 0 || !reflect.DeepEqual(changes.RemovePolicy, []PolicyConfig{{Actor: "p-old", Verb: "view", Resource: "p-res"}}) {
+		t.Errorf("Expected only p-old's row to be removed but got %v and %v", changes.AddPolicy, changes.RemovePolicy)
+	}
+	if len(changes.AddAdmins) != 0 || len(changes.RemoveAdmins) != 0 {
+		t.Errorf("Expected admins to be left alone but got %v and %v", changes.AddAdmins, changes.RemoveAdmins)
+	}
+}
+
+func TestApplyConfigAdmins(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an area accessor %v", err)
+		return
+	}
+	NewGuid = func() string {
+		return "req1"
+	}
+
+	aa := NewAreaAccessor(db)
+
+	// The admin is requested for approval, not added
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO approval .+ VALUES .+").
+		WithArgs("req1", "admin", "importer", "prod", "gina", "", "", "", "", "", "pending", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	changes := ConfigChanges{AddAdmins: []string{"gina"}}
+	pending, err := aa.ApplyConfig("prod", changes, 1500000000, "importer")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while applying a configuration %v", err)
+	}
+	if !reflect.DeepEqual(pending, []string{"req1"}) {
+		t.Errorf("Expected [req1] but got %v", pending)
+	}
+
+	if err := aa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package accessors
+
+import (
+	"database/sql"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/pdp"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Area struct that reflects the areas table. Groups, admins, roles, policy
+// rows and the rest of an area's data refer to it by guid. Parent is the
+// guid of the area containing this one, or empty for a top level area.
+// Admins of a parent are admins of all its descendants; area level policy
+// rows of the parent only apply in this area when Inherit is set.
+type Area struct {
+	Guid        string
+	Name        string
+	Description string
+	Parent      string
+	Inherit     bool
+}
+
+type AreaAccessor struct {
+	DB *sql.DB // Database connection
+}
+
+// Returns a new area accessor.
+func NewAreaAccessor(db *sql.DB) *AreaAccessor {
+	return &AreaAccessor{db}
+}
+
+// Create a new area and return its guid.
+func (aa *AreaAccessor) Create(area Area) (string, error) {
+	guid := NewGuid()
+	_, err := execEvent(aa.DB, EventAreaCreate, EventData{Area: guid, Name: area.Name}, "INSERT INTO areas (guid, name, description, parent, inherit) VALUES (?,?,?,?,?)", guid, area.Name, area.Description, area.Parent, area.Inherit)
+	return guid, err
+}
+
+// Gets the area with the given id.
+func (aa *AreaAccessor) Get(guid string) (Area, error) {
+	a := Area{}
+	stmt, err := aa.DB.Prepare("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=?")
+	if err != nil {
+		return a, err
+	}
+
+	err = stmt.QueryRow(guid).Scan(&a.Guid, &a.Name, &a.Description, &a.Parent, &a.Inherit)
+	return a, err
+}
+
+// Gets every area.
+func (aa *AreaAccessor) GetAll() ([]Area, error) {
+	areas := make([]Area, 0)
+	stmt, err := aa.DB.Prepare("SELECT guid, name, description, parent, inherit FROM areas ORDER BY name")
+	if err != nil {
+		return areas, err
+	}
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return areas, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := Area{}
+		err = rows.Scan(&a.Guid, &a.Name, &a.Description, &a.Parent, &a.Inherit)
+		areas = append(areas, a)
+	}
+
+	return areas, nil
+}
+
+// Gets the areas directly below an area.
+func (aa *AreaAccessor) Children(guid string) ([]Area, error) {
+	areas := make([]Area, 0)
+	stmt, err := aa.DB.Prepare("SELECT guid, name, description, parent, inherit FROM areas WHERE parent=? ORDER BY name")
+	if err != nil {
+		return areas, err
+	}
+
+	rows, err := stmt.Query(guid)
+	if err != nil {
+		return areas, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := Area{}
+		err = rows.Scan(&a.Guid, &a.Name, &a.Description, &a.Parent, &a.Inherit)
+		areas = append(areas, a)
+	}
+
+	return areas, nil
+}
+
+// Tells whether an area with the given id exists.
+func (aa *AreaAccessor) Exists(guid string) (bool, error) {
+	stmt, err := aa.DB.Prepare("SELECT guid FROM areas WHERE guid=?")
+	if err != nil {
+		return false, err
+	}
+
+	var found string
+	err = stmt.QueryRow(guid).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Update an area's name, description, parent and inheritance.
+func (aa *AreaAccessor) Update(area Area) error {
+	_, err := execEvent(aa.DB, EventAreaUpdate, EventData{Area: area.Guid, Name: area.Name}, "UPDATE areas SET name=?, description=?, parent=?, inherit=? WHERE guid=?", area.Name, area.Description, area.Parent, area.Inherit, area.Guid)
+	return err
+}
+
+// Gets an area followed by its parent, its parent's parent and so on up to
+// a top level area. An unknown area has an empty lineage.
+func (aa *AreaAccessor) Lineage(guid string) ([]Area, error) {
+	lineage := make([]Area, 0)
+	seen := make(map[string]bool)
+	for guid != "" && !seen[guid] {
+		seen[guid] = true
+
+		a, err := aa.Get(guid)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			return lineage, err
+		}
+		lineage = append(lineage, a)
+		guid = a.Parent
+	}
+
+	return lineage, nil
+}
+
+// The areas whose admins are admins of area: the area itself and every
+// ancestor in its lineage.
+func AdminAreas(area string, lineage []Area) []string {
+	return pdp.AdminAreas(area, pdpLineage(lineage))
+}
+
+// The areas whose area level policy rows apply in area: the area itself,
+// then each ancestor for as long as the area below it inherits.
+func PolicyAreas(area string, lineage []Area) []string {
+	return pdp.PolicyAreas(area, pdpLineage(lineage))
+}
+
+// A lineage as the decision library takes it.
+func pdpLineage(lineage []Area) []pdp.Area {
+	areas := make([]pdp.Area, 0, len(lineage))
+	for _, a := range lineage {
+		areas = append(areas, pdp.Area{Guid: a.Guid, Parent: a.Parent, Inherit: a.Inherit})
+	}
+	return areas
+}
+
+// The statements run, in order, to decommission an area, keyed by what
+// they remove. Each takes the area's guid once per placeholder. Steps
+// removing something decisions depend on publish Event for each row Rows,
+// which takes the same placeholders, selects before they run.
+var decommissionSteps = []struct {
+	Name  string
+	Query string
+	Args  int
+	Event string
+	Rows  string
+	Data  func(row []string) EventData
+}{
+	{"groupMembers", "DELETE FROM groupMembers WHERE groupGuid IN (SELECT guid FROM groups WHERE area=?)", 1,
+		EventMemberRemove, "SELECT groupGuid, netId FROM groupMembers WHERE groupGuid IN (SELECT guid FROM groups WHERE area=?)",
+		func(row []string) EventData { return EventData{Group: row[0], NetId: row[1]} }},
+	{"groupOwners", "DELETE FROM groupOwners WHERE groupGuid IN (SELECT guid FROM groups WHERE area=?)", 1,
+		EventOwnerRemove, "SELECT groupGuid, netId FROM groupOwners WHERE groupGuid IN (SELECT guid FROM groups WHERE area=?)",
+		func(row []string) EventData { return EventData{Group: row[0], NetId: row[1]} }},
+	{"policyCondition", "DELETE FROM policyCondition WHERE actor=? OR actor IN (SELECT guid FROM groups WHERE area=?) OR (actor, verb, resource) IN (SELECT actor, verb, resource FROM policyArea WHERE area=?)", 3, "", "", nil},
+	{"policy", "DELETE FROM policy WHERE actor=? OR actor IN (SELECT guid FROM groups WHERE area=?) OR (actor, verb, resource) IN (SELECT actor, verb, resource FROM policyArea WHERE area=?)", 3,
+		EventRevoke, "SELECT actor, verb, resource FROM policy WHERE actor=? OR actor IN (SELECT guid FROM groups WHERE area=?) OR (actor, verb, resource) IN (SELECT actor, verb, resource FROM policyArea WHERE area=?)",
+		func(row []string) EventData { return EventData{Actor: row[0], Verb: row[1], Resource: row[2]} }},
+	{"policyArea", "DELETE FROM policyArea WHERE area=?", 1, "", "", nil},
+	{"roleBinding", "DELETE FROM roleBinding WHERE actor=? OR actor IN (SELECT guid FROM groups WHERE area=?) OR roleGuid IN (SELECT guid FROM role WHERE area=?)", 3,
+		EventRoleUnbind, "SELECT roleGuid, actor FROM roleBinding WHERE actor=? OR actor IN (SELECT guid FROM groups WHERE area=?) OR roleGuid IN (SELECT guid FROM role WHERE area=?)",
+		func(row []string) EventData { return EventData{Role: row[0], Actor: row[1]} }},
+	{"rolePermission", "DELETE FROM rolePermission WHERE roleGuid IN (SELECT guid FROM role WHERE area=?)", 1,
+		EventRolePermissionRemove, "SELECT roleGuid, verb, resource FROM rolePermission WHERE roleGuid IN (SELECT guid FROM role WHERE area=?)",
+		func(row []string) EventData { return EventData{Role: row[0], Verb: row[1], Resource: row[2]} }},
+	{"role", "DELETE FROM role WHERE area=?", 1,
+		EventRoleDelete, "SELECT guid FROM role WHERE area=?",
+		func(row []string) EventData { return EventData{Role: row[0]} }},
+	{"sodRule", "DELETE FROM sodRule WHERE area=?", 1, "", "", nil},
+	{"admin", "DELETE FROM admin WHERE area=?", 1,
+		EventAdminRemove, "SELECT netId, area FROM admin WHERE area=?",
+		func(row []string) EventData { return EventData{NetId: row[0], Area: row[1]} }},
+	{"approval", "UPDATE approval SET status='" + ApprovalRejected + "' WHERE area=? AND status='" + ApprovalPending + "'", 1, "", "", nil},
+	{"accessRequest", "UPDATE accessRequest SET status='" + AccessDenied + "' WHERE area=? AND status='" + AccessPending + "'", 1, "", "", nil},
+	{"reviewCampaign", "UPDATE reviewCampaign SET status='" + ReviewClosed + "' WHERE area=? AND status='" + ReviewOpen + "'", 1, "", "", nil},
+	{"groups", "DELETE FROM groups WHERE area=?", 1,
+		EventGroupDelete, "SELECT guid FROM groups WHERE area=?",
+		func(row []string) EventData { return EventData{Group: row[0]} }},
+	{"areas", "DELETE FROM areas WHERE guid=?", 1, "", "", nil},
+}
+
+// Remove an area and everything that belongs to it: its groups with their
+// members and owners, policy rows and role bindings held by the area or its
+// groups, rows granted to users for the area, its roles, separation-of-duties rules and admins. Pending
+// approvals and access requests are rejected and open reviews are closed
+// rather than deleted so they stay in the record. Everything removed is
+// published as it would be if it were removed on its own, followed by the
+// area's deletion. Returns the number of rows affected in each table.
+func (aa *AreaAccessor) Decommission(guid string) (map[string]int64, error) {
+	affected := make(map[string]int64)
+
+	tx, err := aa.DB.Begin()
+	if err != nil {
+		return affected, err
+	}
+
+	for _, step := range decommissionSteps {
+		args := make([]interface{}, step.Args)
+		for i := range args {
+			args[i] = guid
+		}
+
+		if step.Event != "" {
+			if err := publishRows(tx, step.Event, step.Data, step.Rows, args...); err != nil {
+				tx.Rollback()
+				return affected, err
+			}
+		}
+
+		res, err := tx.Exec(step.Query, args...)
+		if err != nil {
+			tx.Rollback()
+			return affected, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			affected[step.Name] = n
+		}
+	}
+	if err := publish(tx, EventAreaDelete, EventData{Area: guid}); err != nil {
+		tx.Rollback()
+		return affected, err
+	}
+
+	return affected, tx.Commit()
+}
@@ -0,0 +1,140 @@
+package accessors
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestAreaExists(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an area accessor %v", err)
+		return
+	}
+
+	aa := NewAreaAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid FROM areas WHERE guid=.").
+		WithArgs("typo").
+		WillReturnRows(sqlmock.NewRows([]string{"guid"}).FromCSVString(""))
+
+	exists, err := aa.Exists("typo")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while looking up an area %v", err)
+	}
+	if exists {
+		t.Errorf("Expected area typo not to exist")
+	}
+
+	if err := aa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestDecommissionArea(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an area accessor %v", err)
+		return
+	}
+
+	aa := NewAreaAccessor(db)
+
+	// The area has one group with nothing in it
+	sqlmock.ExpectBegin()
+	for i, step := range decommissionSteps {
+		args := make([]driver.Value, step.Args)
+		for j := range args {
+			args[j] = "a1"
+		}
+		if step.Name == "groups" {
+			sqlmock.ExpectQuery("SELECT guid FROM groups WHERE area=.").
+				WithArgs(args...).
+				WillReturnRows(sqlmock.NewRows([]string{"guid"}).FromCSVString("g1"))
+			sqlmock.ExpectExec("INSERT INTO event").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+		} else if step.Event != "" {
+			sqlmock.ExpectQuery("SELECT .+").
+				WithArgs(args...).
+				WillReturnRows(sqlmock.NewRows([]string{"guid"}).FromCSVString(""))
+		}
+		sqlmock.ExpectExec(".+").
+			WithArgs(args...).
+			WillReturnResult(sqlmock.NewResult(0, int64(i)))
+	}
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	affected, err := aa.Decommission("a1")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while decommissioning an area %v", err)
+	}
+	if affected["groups"] != int64(len(decommissionSteps)-2) || affected["areas"] != int64(len(decommissionSteps)-1) {
+		t.Errorf("Unexpected affected row counts %v", affected)
+	}
+
+	if err := aa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestLineage(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an area accessor %v", err)
+		return
+	}
+
+	aa := NewAreaAccessor(db)
+
+	columns := []string{"guid", "name", "description", "parent", "inherit"}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("team").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("team,Team,,dept,1"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("dept").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("dept,Department,,org,0"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("org").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("org,Organization,,,0"))
+
+	lineage, err := aa.Lineage("team")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while walking the area hierarchy %v", err)
+	}
+	if len(lineage) != 3 {
+		t.Errorf("Expected 3 areas but got %v", lineage)
+		return
+	}
+
+	admins := AdminAreas("team", lineage)
+	if !reflect.DeepEqual(admins, []string{"team", "dept", "org"}) {
+		t.Errorf("Expected admins of team, dept and org but got %v", admins)
+	}
+
+	// team inherits from dept, dept doesn't inherit from org
+	policy := PolicyAreas("team", lineage)
+	if !reflect.DeepEqual(policy, []string{"team", "dept"}) {
+		t.Errorf("Expected area rows of team and dept but got %v", policy)
+	}
+
+	if err := aa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestPolicyAreasOfUnknownArea(t *testing.T) {
+	areas := PolicyAreas("legacy", []Area{})
+	if !reflect.DeepEqual(areas, []string{"legacy"}) {
+		t.Errorf("Expected only legacy but got %v", areas)
+	}
+}
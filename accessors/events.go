@@ -0,0 +1,274 @@
+package accessors
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Event types. Each names the fields of EventData it sets.
+const (
+	EventGrant                = "grant"                  // Actor, Verb, Resource, Condition
+	EventRevoke               = "revoke"                 // Actor, Verb, Resource
+	EventMemberAdd            = "member.add"             // Group, NetId
+	EventMemberRemove         = "member.remove"          // Group, NetId
+	EventAdminAdd             = "admin.add"              // NetId, Area
+	EventAdminRemove          = "admin.remove"           // NetId, Area
+	EventSuperuserAdd         = "superuser.add"          // NetId
+	EventSuperuserRemove      = "superuser.remove"       // NetId
+	EventSuperuserElevate     = "superuser.elevate"      // NetId
+	EventSuperuserStop        = "superuser.stop"         // NetId
+	EventGroupCreate          = "group.create"           // Group, Area, Name; a clone's members, grants and bindings follow as events of their own
+	EventGroupUpdate          = "group.update"           // Group, Name; renames and description or tag changes
+	EventGroupMove            = "group.move"             // Group, Area
+	EventGroupDelete          = "group.delete"           // Group
+	EventAreaCreate           = "area.create"            // Area, Name
+	EventAreaUpdate           = "area.update"            // Area, Name; including its parent or inheritance
+	EventOwnerAdd             = "owner.add"              // Group, NetId
+	EventOwnerRemove          = "owner.remove"           // Group, NetId
+	EventAreaDelete           = "area.delete"            // Area; after events removing everything in it
+	EventRolePermissionAdd    = "role.permission.add"    // Role, Verb, Resource
+	EventRolePermissionRemove = "role.permission.remove" // Role, Verb, Resource
+	EventRoleBind             = "role.bind"              // Role, Actor
+	EventRoleUnbind           = "role.unbind"            // Role, Actor
+	EventRoleDelete           = "role.delete"            // Role
+	EventSensitiveMark        = "sensitive.mark"         // Resource
+	EventSensitiveUnmark      = "sensitive.unmark"       // Resource
+	EventTupleAdd             = "tuple.add"              // Object, Relation, Subject
+	EventTupleRemove          = "tuple.remove"           // Object, Relation, Subject
+	EventNamespaceSet         = "namespace.set"          // Name; created or reconfigured
+)
+
+// A change that can affect permission decisions, as published to the change
+// feed. Events are written to the event table, an outbox, in the same
+// transaction as the change, so there's an event for every change that is
+// committed and none for changes that aren't. Seq orders events and is what
+// readers resume from; Created is the unix time of the change.
+type Event struct {
+	Seq     int64
+	Type    string
+	Created int64
+	EventData
+}
+
+// What changed. Fields the event's type doesn't use are empty.
+type EventData struct {
+	Area      string
+	Group     string
+	Role      string
+	NetId     string
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string
+	Name      string
+	Object    string
+	Relation  string
+	Subject   string
+}
+
+// Something statements can be run on: a *sql.DB or a *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Publishes an event for each row a query selects in tx, made from the
+// row's columns by data. Rows are read before any event is written, so
+// run it before the statement that changes them.
+func publishRows(tx *sql.Tx, eventType string, data func(row []string) EventData, query string, args ...interface{}) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return err
+	}
+
+	events := make([]EventData, 0)
+	for rows.Next() {
+		row := make([]string, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return err
+		}
+		events = append(events, data(row))
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for _, e := range events {
+		if err := publish(tx, eventType, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes an event to the outbox. Call it with the transaction making the
+// change.
+func publish(ex execer, eventType string, data EventData) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = ex.Exec("INSERT INTO event (type, data, created) VALUES (?,?,?)", eventType, string(b), time.Now().Unix())
+	return err
+}
+
+// Runs a write in tx, publishing an event if it affected any rows. Returns
+// the number of rows affected.
+func execPublishing(tx *sql.Tx, eventType string, data EventData, query string, args ...interface{}) (int64, error) {
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n == 0 {
+		return n, err
+	}
+	return n, publish(tx, eventType, data)
+}
+
+// Runs a write in a transaction of its own, publishing an event if it
+// affected any rows. Returns the number of rows affected.
+func execEvent(db *sql.DB, eventType string, data EventData, query string, args ...interface{}) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := execPublishing(tx, eventType, data, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+type EventAccessor struct {
+	DB *sql.DB // Database connection
+}
+
+// Returns a new event accessor.
+func NewEventAccessor(db *sql.DB) *EventAccessor {
+	return &EventAccessor{db}
+}
+
+// Gets up to limit events after seq, in order. Sequence numbers are taken
+// when an event is written but become visible when its transaction
+// commits, so a reader can see seq 12 before 11. Each gap is filled by
+// reading the missing sequence numbers again with a locking read, which
+// waits for the transactions still writing them; whatever is missing after
+// that was rolled back or pruned. So a reader resuming from the last event
+// it got never skips one committed late.
+func (ea *EventAccessor) Since(seq int64, limit int) ([]Event, error) {
+	events := make([]Event, 0)
+	stmt, err := ea.DB.Prepare("SELECT seq, type, data, created FROM event WHERE seq>? ORDER BY seq LIMIT ?")
+	if err != nil {
+		return events, err
+	}
+
+	rows, err := stmt.Query(seq, limit)
+	if err != nil {
+		return events, err
+	}
+	page, err := scanEvents(rows)
+	if err != nil {
+		return events, err
+	}
+
+	for _, e := range page {
+		if e.Seq != seq+1 {
+			missing, err := ea.between(seq, e.Seq)
+			if err != nil {
+				return events, err
+			}
+			events = append(events, missing...)
+		}
+		events = append(events, e)
+		seq = e.Seq
+	}
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// Gets the events strictly between two sequence numbers, waiting for the
+// transactions still writing any of them to finish.
+func (ea *EventAccessor) between(after, before int64) ([]Event, error) {
+	tx, err := ea.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query("SELECT seq, type, data, created FROM event WHERE seq>? AND seq<? ORDER BY seq LOCK IN SHARE MODE", after, before)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	events, err := scanEvents(rows)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return events, tx.Commit()
+}
+
+// Reads events from rows and closes them.
+func scanEvents(rows *sql.Rows) ([]Event, error) {
+	defer rows.Close()
+
+	events := make([]Event, 0)
+	for rows.Next() {
+		var e Event
+		var data string
+		if err := rows.Scan(&e.Seq, &e.Type, &data, &e.Created); err != nil {
+			return events, err
+		}
+		if err := json.Unmarshal([]byte(data), &e.EventData); err != nil {
+			return events, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Gets the sequence number of the latest event, 0 if there are none.
+func (ea *EventAccessor) Latest() (int64, error) {
+	stmt, err := ea.DB.Prepare("SELECT COALESCE(MAX(seq), 0) FROM event")
+	if err != nil {
+		return 0, err
+	}
+
+	var seq int64
+	err = stmt.QueryRow().Scan(&seq)
+	return seq, err
+}
+
+// Drop events written at or before the given unix time. Readers that fall
+// further behind than that should start again from a snapshot.
+func (ea *EventAccessor) DeleteBefore(created int64) (int64, error) {
+	stmt, err := ea.DB.Prepare("DELETE FROM event WHERE created<=?")
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := stmt.Exec(created)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
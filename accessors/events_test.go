@@ -0,0 +1,96 @@
+package accessors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestEventsSince(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an event accessor %v", err)
+		return
+	}
+
+	ea := NewEventAccessor(db)
+	columns := []string{"seq", "type", "data", "created"}
+
+	// Seq 7 was taken by a transaction that hadn't committed yet when the
+	// page was read; reading it again waits for it
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT seq, type, data, created FROM event WHERE seq>(.) ORDER BY seq LIMIT (.)").
+		WithArgs(4, 100).
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString(`5,member.add,"{""Group"":""desk"",""NetId"":""alice""}",100
+6,grant,"{""Actor"":""desk""}",100
+8,revoke,{},102`))
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectQuery("SELECT seq, type, data, created FROM event WHERE seq>. AND seq<. ORDER BY seq LOCK IN SHARE MODE").
+		WithArgs(6, 8).
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString(`7,member.remove,"{""Group"":""desk"",""NetId"":""bob""}",101`))
+	sqlmock.ExpectCommit()
+
+	events, err := ea.Since(4, 100)
+	if err != nil {
+		t.Errorf("An unexpected error occurred while getting events %v", err)
+	}
+	expected := []Event{
+		{5, EventMemberAdd, 100, EventData{Group: "desk", NetId: "alice"}},
+		{6, EventGrant, 100, EventData{Actor: "desk"}},
+		{7, EventMemberRemove, 101, EventData{Group: "desk", NetId: "bob"}},
+		{8, EventRevoke, 102, EventData{}},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected %v but got %v", expected, events)
+	}
+
+	// Seq 9 is still missing once nothing is writing it, so it was rolled back
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT seq, type, data, created FROM event WHERE seq>(.) ORDER BY seq LIMIT (.)").
+		WithArgs(8, 100).
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString(`10,group.delete,"{""Group"":""desk""}",103`))
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectQuery("SELECT seq, type, data, created FROM event WHERE seq>. AND seq<. ORDER BY seq LOCK IN SHARE MODE").
+		WithArgs(8, 10).
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString(""))
+	sqlmock.ExpectCommit()
+
+	events, err = ea.Since(8, 100)
+	if err != nil {
+		t.Errorf("An unexpected error occurred while getting events %v", err)
+	}
+	expected = []Event{{10, EventGroupDelete, 103, EventData{Group: "desk"}}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected %v but got %v", expected, events)
+	}
+
+	if err := ea.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestDeleteEventsBefore(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating an event accessor %v", err)
+		return
+	}
+
+	ea := NewEventAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectExec("DELETE FROM event WHERE created<=(.)").
+		WithArgs(1000).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := ea.DeleteBefore(1000)
+	if err != nil || n != 3 {
+		t.Errorf("Expected 3 events to be deleted but got %d, %v", n, err)
+	}
+
+	if err := ea.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package accessors
+
+import (
+	"database/sql"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
+	"github.com/byu-oit-ssengineering/tmt-permissions/pdp"
+)
+
+// A policy row or role binding through which an actor holds a permission.
+// Resource is the role's pattern for role bindings. Applies is false when
+// the policy row's condition doesn't hold.
+type Grant = pdp.Grant
+
+// Why a user does or doesn't have a permission in an area. Actors are the
+// user's groups, the areas whose policy rows apply and the user, as
+// checked by GET /permission.
+type Explanation = pdp.Decision
+
+// Explains whether a user has permission to use verb on a resource in an
+// area, with conditions evaluated against ctx. Returns sql.ErrNoRows for an
+// unknown area.
+func (pa *PermissionAccessor) Explain(netId, area, obj, verb string, ctx conditions.Context) (Explanation, error) {
+	e := Explanation{Actors: make([]string, 0), Grants: make([]Grant, 0)}
+
+	lineage, err := NewAreaAccessor(pa.DB).Lineage(area)
+	if err != nil {
+		return e, err
+	}
+	if len(lineage) == 0 {
+		return e, sql.ErrNoRows
+	}
+
+	superuser, err := pa.IsSuperuser(netId)
+	if err != nil {
+		return e, err
+	}
+	admin, err := pa.IsAdmin(netId, area)
+	if err != nil {
+		return e, err
+	}
+
+	groups, err := pa.Get(area, netId)
+	if err != nil {
+		return e, err
+	}
+	guids := make([]string, 0, len(groups))
+	for _, g := range groups {
+		guids = append(guids, g.Guid)
+	}
+	actors := pdp.Actors(netId, area, guids, pdpLineage(lineage))
+
+	return pa.decide(superuser, admin, area, actors, obj, verb, ctx)
+}
+
+// Decides whether a user with the given actors may use verb on a resource
+// in an area, from the policy rows and role bindings the actors have.
+func (pa *PermissionAccessor) decide(superuser, admin bool, area string, actors []string, obj, verb string, ctx conditions.Context) (Explanation, error) {
+	e := Explanation{Actors: make([]string, 0), Grants: make([]Grant, 0)}
+
+	// Policy rows and their conditions and areas
+	query := "SELECT policy.actor, COALESCE(policyCondition.expression, ''), COALESCE(policyArea.area, '') FROM policy LEFT JOIN policyCondition ON policyCondition.actor = policy.actor AND policyCondition.verb = policy.verb AND policyCondition.resource = policy.resource LEFT JOIN policyArea ON policyArea.actor = policy.actor AND policyArea.verb = policy.verb AND policyArea.resource = policy.resource WHERE policy.verb=? AND policy.resource=? AND policy.actor IN (?"
+	params := []interface{}{verb, obj, actors[0]}
+	for i := 1; i < len(actors); i++ {
+		query += ",?"
+		params = append(params, actors[i])
+	}
+	query += ")"
+
+	stmt, err := pa.DB.Prepare(query)
+	if err != nil {
+		return e, err
+	}
+
+	rows, err := stmt.Query(params...)
+	if err != nil {
+		return e, err
+	}
+	policies := make([]pdp.Policy, 0)
+	for rows.Next() {
+		p := pdp.Policy{Verb: verb, Resource: obj}
+		if err := rows.Scan(&p.Actor, &p.Condition, &p.Area); err != nil {
+			rows.Close()
+			return e, err
+		}
+		policies = append(policies, p)
+	}
+	rows.Close()
+
+	// Bindings of the area's roles
+	bound, err := NewRoleAccessor(pa.DB).GetBoundPermissions(area, actors)
+	if err != nil {
+		return e, err
+	}
+	bindings := make([]pdp.Binding, 0, len(bound))
+	for _, p := range bound {
+		bindings = append(bindings, pdp.Binding{Actor: p.Actor, Verb: p.Verb, Resource: p.Resource, Area: area})
+	}
+
+	return pdp.Decide(superuser, admin, area, actors, policies, bindings, verb, obj, ctx), nil
+}
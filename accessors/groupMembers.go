@@ -5,6 +5,13 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// Changes to a user's direct membership of a group. Adding leaves an
+// existing membership as it is.
+const (
+	addMemberQuery    = "INSERT INTO groupMembers (netId, groupGuid) SELECT ?,? FROM DUAL WHERE NOT EXISTS (SELECT netId FROM groupMembers WHERE netId=? AND groupGuid=?)"
+	removeMemberQuery = "DELETE FROM groupMembers WHERE netId=? AND groupGuid=?"
+)
+
 type MembersAccessor struct {
 	DB *sql.DB // Database connection
 }
@@ -14,8 +21,32 @@ func NewMembersAccessor(db *sql.DB) *MembersAccessor {
 	return &MembersAccessor{db}
 }
 
-// Gets all members of a group.
+// Gets all members of a group, including those matched by its rule.
 func (ga *MembersAccessor) GetGroupMembers(group string) ([]string, error) {
+	members := make([]string, 0)
+	stmt, err := ga.DB.Prepare("SELECT netId FROM groupMembers WHERE groupGuid=? UNION SELECT netId FROM groupRuleMember WHERE groupGuid=?")
+	if err != nil {
+		return members, err
+	}
+
+	rows, err := stmt.Query(group, group)
+	if err != nil {
+		return members, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var user string
+		rows.Scan(&user)
+		members = append(members, user)
+	}
+
+	return members, nil
+}
+
+// Gets the members added to a group directly, leaving out those its rule
+// matches.
+func (ga *MembersAccessor) GetDirectMembers(group string) ([]string, error) {
 	members := make([]string, 0)
 	stmt, err := ga.DB.Prepare("SELECT netId FROM groupMembers WHERE groupGuid=?")
 	if err != nil {
@@ -37,15 +68,16 @@ func (ga *MembersAccessor) GetGroupMembers(group string) ([]string, error) {
 	return members, nil
 }
 
-// Gets a list of all the groups a user belongs to.
+// Gets a list of all the groups a user belongs to, including those whose
+// rules match the user.
 func (ga *MembersAccessor) GetUserGroups(netId string) ([]string, error) {
 	groups := make([]string, 0)
-	stmt, err := ga.DB.Prepare("SELECT groupGuid FROM groupMembers WHERE netId=?")
+	stmt, err := ga.DB.Prepare("SELECT groupGuid FROM groupMembers WHERE netId=? UNION SELECT groupGuid FROM groupRuleMember WHERE netId=?")
 	if err != nil {
 		return groups, err
 	}
 
-	rows, err := stmt.Query(netId)
+	rows, err := stmt.Query(netId, netId)
 	if err != nil {
 		return groups, err
 	}
@@ -59,35 +91,105 @@ func (ga *MembersAccessor) GetUserGroups(netId string) ([]string, error) {
 	return groups, err
 }
 
-// Add a user to a group.
-func (ga *MembersAccessor) AddToGroup(netId, group string) error {
-	stmt, err := ga.DB.Prepare("INSERT INTO groupMembers (netId, groupGuid) VALUES (?,?)")
+// Gets every user who is a member of at least one group in an area.
+func (ga *MembersAccessor) GetAreaMembers(area string) ([]string, error) {
+	members := make([]string, 0)
+	stmt, err := ga.DB.Prepare("SELECT groupMembers.netId FROM groupMembers JOIN groups ON groupMembers.groupGuid = groups.guid WHERE groups.area=? UNION SELECT groupRuleMember.netId FROM groupRuleMember JOIN groups ON groupRuleMember.groupGuid = groups.guid WHERE groups.area=?")
+	if err != nil {
+		return members, err
+	}
+
+	rows, err := stmt.Query(area, area)
 	if err != nil {
-		return err
+		return members, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var user string
+		rows.Scan(&user)
+		members = append(members, user)
 	}
 
-	_, err = stmt.Exec(netId, group)
-	return err
+	return members, nil
 }
 
-// Remove a user from a group.
-func (ga *MembersAccessor) RemoveFromGroup(netId, group string) error {
-	stmt, err := ga.DB.Prepare("DELETE FROM groupMembers WHERE netId=? AND groupGuid=?")
+// Add a user to a group. Returns the number of rows inserted, 0 if the
+// user was already a member.
+func (ga *MembersAccessor) AddToGroup(netId, group string) (int64, error) {
+	return execEvent(ga.DB, EventMemberAdd, EventData{Group: group, NetId: netId}, addMemberQuery, netId, group, netId, group)
+}
+
+// Remove a user from a group. Returns the number of rows deleted.
+func (ga *MembersAccessor) RemoveFromGroup(netId, group string) (int64, error) {
+	return execEvent(ga.DB, EventMemberRemove, EventData{Group: group, NetId: netId}, removeMemberQuery, netId, group)
+}
+
+// Adds and removes direct members of a group in a single transaction.
+// Returns the users actually added and removed, leaving out those a
+// concurrent change had already taken care of.
+func (ga *MembersAccessor) UpdateMembers(group string, add, remove []string) ([]string, []string, error) {
+	added := make([]string, 0)
+	removed := make([]string, 0)
+
+	tx, err := ga.DB.Begin()
 	if err != nil {
-		return err
+		return added, removed, err
+	}
+
+	for _, netId := range add {
+		n, err := execPublishing(tx, EventMemberAdd, EventData{Group: group, NetId: netId}, addMemberQuery, netId, group, netId, group)
+		if err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+		if n > 0 {
+			added = append(added, netId)
+		}
 	}
 
-	_, err = stmt.Exec(netId, group)
-	return err
+	for _, netId := range remove {
+		n, err := execPublishing(tx, EventMemberRemove, EventData{Group: group, NetId: netId}, removeMemberQuery, netId, group)
+		if err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+		if n > 0 {
+			removed = append(removed, netId)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return added, removed, nil
 }
 
 // Remove a user from all his/her groups.
 func (ga *MembersAccessor) RemoveAllGroups(netId string) error {
-	stmt, err := ga.DB.Prepare("DELETE FROM groupMembers WHERE netId=?")
+	tx, err := ga.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT groupGuid FROM groupMembers WHERE netId=?", netId)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
+	groups := make([]string, 0)
+	for rows.Next() {
+		var group string
+		rows.Scan(&group)
+		groups = append(groups, group)
+	}
+	rows.Close()
 
-	_, err = stmt.Exec(netId)
-	return err
+	for _, group := range groups {
+		if _, err := execPublishing(tx, EventMemberRemove, EventData{Group: group, NetId: netId}, removeMemberQuery, netId, group); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
 }
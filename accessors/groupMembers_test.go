@@ -1,6 +1,7 @@
 package accessors
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -20,7 +21,7 @@ func TestGetGroupMembers(t *testing.T) {
 	columns := []string{"netId"}
 	sqlmock.ExpectPrepare()
 	sqlmock.ExpectQuery("SELECT netId FROM groupMembers WHERE groupGuid=(.)").
-		WithArgs("1").
+		WithArgs("1", "1").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("netId\nsomeone"))
 	members, err := ma.GetGroupMembers("1")
 	if err != nil {
@@ -52,7 +53,7 @@ func TestGetUserGroups(t *testing.T) {
 	columns := []string{"groupId"}
 	sqlmock.ExpectPrepare()
 	sqlmock.ExpectQuery("SELECT groupGuid FROM groupMembers WHERE netId=(.)").
-		WithArgs("netId").
+		WithArgs("netId", "netId").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1\n2"))
 
 	groups, err := ma.GetUserGroups("netId")
@@ -81,15 +82,19 @@ func TestAddToGroup(t *testing.T) {
 
 	ma := NewMembersAccessor(db)
 
-	sqlmock.ExpectPrepare()
-	sqlmock.ExpectExec("INSERT INTO groupMembers .+ VALUES .+").
-		WithArgs("netId", "1").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO groupMembers .+ SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("netId", "1", "netId", "1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectCommit()
 
-	err = ma.AddToGroup("netId", "1")
+	n, err := ma.AddToGroup("netId", "1")
 	if err != nil {
 		t.Error("An unexpected error occurred while getting a group:\n %s", err.Error())
 	}
+	if n != 0 {
+		t.Errorf("Expected an existing membership to add 0 rows but got %d", n)
+	}
 
 	if err := ma.DB.Close(); err != nil {
 		t.Errorf("An error occurred: %v", err)
@@ -105,12 +110,15 @@ func TestRemoveFromGroup(t *testing.T) {
 
 	ma := NewMembersAccessor(db)
 
-	sqlmock.ExpectPrepare()
+	sqlmock.ExpectBegin()
 	sqlmock.ExpectExec("DELETE FROM groupMembers WHERE netId=(.) AND groupGuid=(.)").
 		WithArgs("netId", "1").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
-	err = ma.RemoveFromGroup("netId", "1")
+	_, err = ma.RemoveFromGroup("netId", "1")
 	if err != nil {
 		t.Error("An unexpected error occurred while getting a group:\n %s", err.Error())
 	}
@@ -119,3 +127,41 @@ func TestRemoveFromGroup(t *testing.T) {
 		t.Errorf("An error occurred: %v", err)
 	}
 }
+
+func TestUpdateMembers(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred when creating a group accessor %v", err)
+		return
+	}
+
+	ma := NewMembersAccessor(db)
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO groupMembers .+ SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("dave", "1", "dave", "1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO groupMembers .+ SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("erin", "1", "erin", "1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("DELETE FROM groupMembers WHERE netId=(.) AND groupGuid=(.)").
+		WithArgs("bob", "1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	added, removed, err := ma.UpdateMembers("1", []string{"dave", "erin"}, []string{"bob"})
+	if err != nil {
+		t.Error("An unexpected error occurred while updating members %v", err)
+	}
+	if !reflect.DeepEqual(added, []string{"dave"}) || !reflect.DeepEqual(removed, []string{"bob"}) {
+		t.Errorf("Expected to add [dave] and remove [bob] but got %v and %v", added, removed)
+	}
+
+	if err := ma.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package accessors
+
+import (
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Gets all owners of a group.
+func (ga *MembersAccessor) GetGroupOwners(group string) ([]string, error) {
+	owners := make([]string, 0)
+	stmt, err := ga.DB.Prepare("SELECT netId FROM groupOwners WHERE groupGuid=?")
+	if err != nil {
+		return owners, err
+	}
+
+	rows, err := stmt.Query(group)
+	if err != nil {
+		return owners, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var user string
+		rows.Scan(&user)
+		owners = append(owners, user)
+	}
+
+	return owners, nil
+}
+
+// Tells whether or not a user owns a group.
+func (ga *MembersAccessor) IsOwner(netId, group string) (bool, error) {
+	stmt, err := ga.DB.Prepare("SELECT netId FROM groupOwners WHERE netId=? AND groupGuid=?")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(netId, group)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	return rows.Next(), nil
+}
+
+// Tells whether or not a user owns a group in an area.
+func (ga *MembersAccessor) OwnsInArea(netId, area string) (bool, error) {
+	stmt, err := ga.DB.Prepare("SELECT groupOwners.netId FROM groupOwners JOIN groups ON groupOwners.groupGuid = groups.guid WHERE groupOwners.netId=? AND groups.area=?")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(netId, area)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	return rows.Next(), nil
+}
+
+// Make a user an owner of a group.
+func (ga *MembersAccessor) AddOwner(netId, group string) error {
+	_, err := execEvent(ga.DB, EventOwnerAdd, EventData{Group: group, NetId: netId}, "INSERT INTO groupOwners (netId, groupGuid) VALUES (?,?)", netId, group)
+	return err
+}
+
+// Remove a user as owner of a group.
+func (ga *MembersAccessor) RemoveOwner(netId, group string) error {
+	_, err := execEvent(ga.DB, EventOwnerRemove, EventData{Group: group, NetId: netId}, "DELETE FROM groupOwners WHERE netId=? AND groupGuid=?", netId, group)
+	return err
+}
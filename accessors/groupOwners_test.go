@@ -0,0 +1,93 @@
+package accessors
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestGetGroupOwners(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a members accessor %v", err)
+		return
+	}
+
+	ma := NewMembersAccessor(db)
+
+	expected := []string{"owner1", "owner2"}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT netId FROM groupOwners WHERE groupGuid=.").
+		WithArgs("group").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("owner1\nowner2"))
+
+	owners, err := ma.GetGroupOwners("group")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while getting owners %v", err)
+	}
+
+	for i := 0; i < len(owners); i++ {
+		if owners[i] != expected[i] {
+			t.Errorf("Expected %v but got %v", expected, owners)
+		}
+	}
+
+	if err := ma.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestIsOwner(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a members accessor %v", err)
+		return
+	}
+
+	ma := NewMembersAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT netId FROM groupOwners WHERE netId=. AND groupGuid=.").
+		WithArgs("owner", "group").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString(""))
+
+	owner, err := ma.IsOwner("owner", "group")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while checking ownership %v", err)
+	}
+
+	if owner {
+		t.Error("Expected false but got true")
+	}
+
+	if err := ma.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestAddOwner(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a members accessor %v", err)
+		return
+	}
+
+	ma := NewMembersAccessor(db)
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO groupOwners (.+) VALUES (.+)").
+		WithArgs("owner", "group").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	if err := ma.AddOwner("owner", "group"); err != nil {
+		t.Errorf("An unexpected error occurred while adding an owner: %v", err)
+	}
+
+	if err := ma.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
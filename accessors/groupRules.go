@@ -0,0 +1,295 @@
+package accessors
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/membership"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+var ErrRuleCycle = errors.New("group rule refers back to its own group")
+
+// GroupRule struct that reflects the groupRule table. A group with a rule
+// has the users the rule matches as members on top of the ones added to
+// it directly. The matches are materialized in the groupRuleMember table
+// by Refresh, so reads of a group's members see them as of the last
+// refresh.
+type GroupRule struct {
+	Group      string
+	Expression string
+}
+
+type RuleAccessor struct {
+	DB *sql.DB // Database connection
+}
+
+// Returns a new group rule accessor.
+func NewRuleAccessor(db *sql.DB) *RuleAccessor {
+	return &RuleAccessor{db}
+}
+
+// Gets a group's rule.
+func (ra *RuleAccessor) Get(group string) (GroupRule, error) {
+	r := GroupRule{}
+	stmt, err := ra.DB.Prepare("SELECT groupGuid, expression FROM groupRule WHERE groupGuid=?")
+	if err != nil {
+		return r, err
+	}
+
+	err = stmt.QueryRow(group).Scan(&r.Group, &r.Expression)
+	return r, err
+}
+
+// Gets every group rule.
+func (ra *RuleAccessor) GetAll() ([]GroupRule, error) {
+	rules := make([]GroupRule, 0)
+	stmt, err := ra.DB.Prepare("SELECT groupGuid, expression FROM groupRule")
+	if err != nil {
+		return rules, err
+	}
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return rules, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := GroupRule{}
+		err = rows.Scan(&r.Group, &r.Expression)
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// Gets the users a group's rule matched when it was last refreshed.
+func (ra *RuleAccessor) Members(group string) ([]string, error) {
+	members := make([]string, 0)
+	stmt, err := ra.DB.Prepare("SELECT netId FROM groupRuleMember WHERE groupGuid=? ORDER BY netId")
+	if err != nil {
+		return members, err
+	}
+
+	rows, err := stmt.Query(group)
+	if err != nil {
+		return members, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var netId string
+		rows.Scan(&netId)
+		members = append(members, netId)
+	}
+
+	return members, nil
+}
+
+// Create or replace a group's rule. Call Refresh afterwards to update the
+// group's members.
+func (ra *RuleAccessor) Set(rule GroupRule) error {
+	stmt, err := ra.DB.Prepare("INSERT INTO groupRule (groupGuid, expression) VALUES (?,?) ON DUPLICATE KEY UPDATE expression=VALUES(expression)")
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec(rule.Group, rule.Expression)
+	return err
+}
+
+// Remove a group's rule along with the members it matched.
+func (ra *RuleAccessor) Delete(group string) error {
+	tx, err := ra.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	current, err := ruleMembersTx(tx, group)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, netId := range current {
+		if _, err := execPublishing(tx, EventMemberRemove, EventData{Group: group, NetId: netId}, "DELETE FROM groupRuleMember WHERE groupGuid=? AND netId=?", group, netId); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM groupRule WHERE groupGuid=?", group); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Gets the members a group's rule matched when it was last refreshed.
+func ruleMembersTx(tx *sql.Tx, group string) ([]string, error) {
+	members := make([]string, 0)
+	rows, err := tx.Query("SELECT netId FROM groupRuleMember WHERE groupGuid=?", group)
+	if err != nil {
+		return members, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var netId string
+		rows.Scan(&netId)
+		members = append(members, netId)
+	}
+	return members, rows.Err()
+}
+
+// Gets the users rule matches if it were group's rule. Groups the rule
+// names are read as they are now, following their own rules rather than
+// their materialized members, and a rule that leads back to group is an
+// ErrRuleCycle.
+func (ra *RuleAccessor) Evaluate(group string, rule *membership.Rule) ([]string, error) {
+	return rule.Members(&ruleSource{ra, map[string]bool{group: true}})
+}
+
+// Re-evaluate a group's rule and store the users it matches.
+func (ra *RuleAccessor) Refresh(group string) ([]string, error) {
+	r, err := ra.Get(group)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := membership.Parse(r.Expression)
+	if err != nil {
+		return nil, err
+	}
+	members, err := ra.Evaluate(group, rule)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := ra.DB.Begin()
+	if err != nil {
+		return members, err
+	}
+
+	// Only the differences are written, so that refreshes that change
+	// nothing publish nothing
+	current, err := ruleMembersTx(tx, group)
+	if err != nil {
+		tx.Rollback()
+		return members, err
+	}
+	matched := make(map[string]bool, len(current))
+	for _, netId := range current {
+		matched[netId] = true
+	}
+
+	for _, netId := range members {
+		if matched[netId] {
+			delete(matched, netId)
+			continue
+		}
+		if _, err := execPublishing(tx, EventMemberAdd, EventData{Group: group, NetId: netId}, "INSERT INTO groupRuleMember (groupGuid, netId) VALUES (?,?)", group, netId); err != nil {
+			tx.Rollback()
+			return members, err
+		}
+	}
+	for _, netId := range current {
+		if !matched[netId] {
+			continue
+		}
+		if _, err := execPublishing(tx, EventMemberRemove, EventData{Group: group, NetId: netId}, "DELETE FROM groupRuleMember WHERE groupGuid=? AND netId=?", group, netId); err != nil {
+			tx.Rollback()
+			return members, err
+		}
+	}
+
+	return members, tx.Commit()
+}
+
+// Re-evaluate every group rule. Keeps going past rules that fail and
+// returns the first error.
+func (ra *RuleAccessor) RefreshAll() error {
+	rules, err := ra.GetAll()
+	if err != nil {
+		return err
+	}
+
+	var first error
+	for _, r := range rules {
+		if _, err := ra.Refresh(r.Group); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Reads members for rules. Implements membership.Source.
+type ruleSource struct {
+	ra       *RuleAccessor
+	visiting map[string]bool // groups whose rules are being evaluated
+}
+
+// A group's directly added members plus, if it has a rule, the users the
+// rule matches.
+func (s *ruleSource) GroupMembers(guid string) ([]string, error) {
+	if s.visiting[guid] {
+		return nil, ErrRuleCycle
+	}
+
+	members := make([]string, 0)
+	stmt, err := s.ra.DB.Prepare("SELECT netId FROM groupMembers WHERE groupGuid=?")
+	if err != nil {
+		return members, err
+	}
+	rows, err := stmt.Query(guid)
+	if err != nil {
+		return members, err
+	}
+	for rows.Next() {
+		var netId string
+		rows.Scan(&netId)
+		members = append(members, netId)
+	}
+	rows.Close()
+
+	r, err := s.ra.Get(guid)
+	if err == sql.ErrNoRows {
+		return members, nil
+	}
+	if err != nil {
+		return members, err
+	}
+	rule, err := membership.Parse(r.Expression)
+	if err != nil {
+		return members, err
+	}
+
+	s.visiting[guid] = true
+	matched, err := rule.Members(s)
+	delete(s.visiting, guid)
+	if err != nil {
+		return members, err
+	}
+
+	return append(members, matched...), nil
+}
+
+// Everyone added directly to at least one group in the area. Rule matches
+// are left out so a rule can name the area its own group is in.
+func (s *ruleSource) AreaMembers(area string) ([]string, error) {
+	members := make([]string, 0)
+	stmt, err := s.ra.DB.Prepare("SELECT DISTINCT groupMembers.netId FROM groupMembers JOIN groups ON groupMembers.groupGuid = groups.guid WHERE groups.area=?")
+	if err != nil {
+		return members, err
+	}
+
+	rows, err := stmt.Query(area)
+	if err != nil {
+		return members, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var netId string
+		rows.Scan(&netId)
+		members = append(members, netId)
+	}
+
+	return members, nil
+}
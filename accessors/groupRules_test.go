@@ -0,0 +1,61 @@
+package accessors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestRefreshGroupRule(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a rule accessor %v", err)
+		return
+	}
+
+	ra := NewRuleAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT groupGuid, expression FROM groupRule WHERE groupGuid=.").
+		WithArgs("dyn").
+		WillReturnRows(sqlmock.NewRows([]string{"groupGuid", "expression"}).FromCSVString("dyn,group:lab-a"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT netId FROM groupMembers WHERE groupGuid=.").
+		WithArgs("lab-a").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("bob\nalice"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT groupGuid, expression FROM groupRule WHERE groupGuid=.").
+		WithArgs("lab-a").
+		WillReturnRows(sqlmock.NewRows([]string{"groupGuid", "expression"}).FromCSVString(""))
+	sqlmock.ExpectBegin()
+
+	// bob was already matched and carol no longer is
+	sqlmock.ExpectQuery("SELECT netId FROM groupRuleMember WHERE groupGuid=.").
+		WithArgs("dyn").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("bob\ncarol"))
+	sqlmock.ExpectExec("INSERT INTO groupRuleMember").
+		WithArgs("dyn", "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("DELETE FROM groupRuleMember WHERE groupGuid=. AND netId=.").
+		WithArgs("dyn", "carol").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	sqlmock.ExpectCommit()
+
+	members, err := ra.Refresh("dyn")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while refreshing a group rule %v", err)
+	}
+	if !reflect.DeepEqual(members, []string{"alice", "bob"}) {
+		t.Errorf("Expected members alice and bob but got %v", members)
+	}
+
+	if err := ra.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
@@ -2,14 +2,38 @@ package accessors
 
 import (
 	"database/sql"
-	_ "github.com/go-sql-driver/mysql"
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
 )
 
-// Group struct that reflects the groups table.
+var ErrDuplicateGroup = errors.New("a group with that name already exists in the area")
+
+// MySQL's error number for a row that would repeat a unique key.
+const mysqlDuplicateKey = 1062
+
+// Returns ErrDuplicateGroup in place of MySQL refusing a group that would
+// repeat a name in its area, which the UNIQUE (area, name) key catches
+// when two requests race past the lookup.
+func groupError(err error) error {
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == mysqlDuplicateKey {
+		return ErrDuplicateGroup
+	}
+	return err
+}
+
+// Group struct that reflects the groups table. Tags are stored comma
+// separated, Created is the unix time the group was created and CreatedBy
+// the netId of whoever created it.
 type Group struct {
-	Guid string
-	Area string
-	Name string
+	Guid        string
+	Area        string
+	Name        string
+	Description string
+	Tags        []string
+	Created     int64
+	CreatedBy   string
 }
 
 type GroupAccessor struct {
@@ -21,72 +45,300 @@ func NewGroupAccessor(db *sql.DB) *GroupAccessor {
 	return &GroupAccessor{db}
 }
 
-// Create a new group.
-func (ga *GroupAccessor) Insert(group Group) error {
-	stmt, err := ga.DB.Prepare("INSERT INTO groups (guid, area, name) VALUES (?,?,?)")
-	if err != nil {
-		return err
-	}
+const groupColumns = "groups.guid, groups.area, groups.name, groups.description, groups.tags, groups.created, groups.createdBy"
 
-	_, err = stmt.Exec(NewGuid(), group.Area, group.Name)
-	return err
+func scanGroup(scanner interface {
+	Scan(dest ...interface{}) error
+}) (Group, error) {
+	g := Group{}
+	var tags string
+	err := scanner.Scan(&g.Guid, &g.Area, &g.Name, &g.Description, &tags, &g.Created, &g.CreatedBy)
+	g.Tags = splitTags(tags)
+	return g, err
 }
 
-// Gets the group with the given id.
-func (ga *GroupAccessor) Get(guid string) (Group, error) {
-	g := Group{}
-	stmt, err := ga.DB.Prepare("SELECT * FROM groups WHERE guid=?")
-	if err != nil {
-		return g, err
+func splitTags(tags string) []string {
+	if tags == "" {
+		return []string{}
 	}
-
-	row := stmt.QueryRow(guid)
-	err = row.Scan(&g.Guid, &g.Area, &g.Name)
-	return g, err
+	return strings.Split(tags, ",")
 }
 
-// Gets all the groups in an area.
-func (ga *GroupAccessor) GetByArea(area string) ([]Group, error) {
+func queryGroups(db *sql.DB, query string, args ...interface{}) ([]Group, error) {
 	groups := make([]Group, 0)
-	stmt, err := ga.DB.Prepare("SELECT * FROM groups WHERE area=?")
+	stmt, err := db.Prepare(query)
 	if err != nil {
 		return groups, err
 	}
 
-	rows, err := stmt.Query(area)
+	rows, err := stmt.Query(args...)
 	if err != nil {
 		return groups, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		g := Group{}
-		err = rows.Scan(&g.Guid, &g.Area, &g.Name)
+		g, err := scanGroup(rows)
+		if err != nil {
+			return groups, err
+		}
 		groups = append(groups, g)
 	}
 
 	return groups, nil
 }
 
-// Renames a group with the given id to have the provided name.
-func (ga *GroupAccessor) Rename(guid, name string) error {
-	stmt, err := ga.DB.Prepare("UPDATE groups SET name=? WHERE guid=?")
+// Create a new group and return it with its guid. Fails with
+// ErrDuplicateGroup if the area already has a group with the same name.
+func (ga *GroupAccessor) Insert(group Group) (Group, error) {
+	group.Guid = NewGuid()
+	n, err := execEvent(ga.DB, EventGroupCreate, EventData{Group: group.Guid, Area: group.Area, Name: group.Name}, "INSERT INTO groups (guid, area, name, description, tags, created, createdBy) SELECT ?,?,?,?,?,?,? FROM DUAL WHERE NOT EXISTS (SELECT guid FROM groups WHERE area=? AND name=?)", group.Guid, group.Area, group.Name, group.Description, strings.Join(group.Tags, ","), group.Created, group.CreatedBy, group.Area, group.Name)
 	if err != nil {
-		return err
+		return group, groupError(err)
+	}
+	if n == 0 {
+		return group, ErrDuplicateGroup
 	}
 
-	_, err = stmt.Exec(name, guid)
-	return err
+	return group, nil
+}
+
+// Gets the group with the given id.
+func (ga *GroupAccessor) Get(guid string) (Group, error) {
+	stmt, err := ga.DB.Prepare("SELECT " + groupColumns + " FROM groups WHERE guid=?")
+	if err != nil {
+		return Group{}, err
+	}
+
+	return scanGroup(stmt.QueryRow(guid))
+}
+
+// Gets the group in an area with the given name.
+func (ga *GroupAccessor) GetByName(area, name string) (Group, error) {
+	stmt, err := ga.DB.Prepare("SELECT " + groupColumns + " FROM groups WHERE area=? AND name=?")
+	if err != nil {
+		return Group{}, err
+	}
+
+	return scanGroup(stmt.QueryRow(area, name))
+}
+
+// Gets all the groups in an area.
+func (ga *GroupAccessor) GetByArea(area string) ([]Group, error) {
+	return queryGroups(ga.DB, "SELECT "+groupColumns+" FROM groups WHERE area=?", area)
+}
+
+// Gets the groups matching every filter given. Filters left empty match
+// all groups; query matches part of a group's name or description.
+func (ga *GroupAccessor) Search(area, tag, query string) ([]Group, error) {
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	if area != "" {
+		where = append(where, "area=?")
+		args = append(args, area)
+	}
+	if tag != "" {
+		where = append(where, "FIND_IN_SET(?, tags) > 0")
+		args = append(args, tag)
+	}
+	if query != "" {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+		where = append(where, "(name LIKE ? OR description LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+
+	q := "SELECT " + groupColumns + " FROM groups"
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	return queryGroups(ga.DB, q+" ORDER BY name", args...)
+}
+
+// Updates a group's name, description and tags.
+func (ga *GroupAccessor) Update(group Group) error {
+	_, err := execEvent(ga.DB, EventGroupUpdate, EventData{Group: group.Guid, Name: group.Name}, "UPDATE groups SET name=?, description=?, tags=? WHERE guid=?", group.Name, group.Description, strings.Join(group.Tags, ","), group.Guid)
+	return groupError(err)
 }
 
 // Delete a group.
 func (ga *GroupAccessor) Delete(guid string) error {
-	stmt, err := ga.DB.Prepare("DELETE FROM groups WHERE guid=?")
+	_, err := execEvent(ga.DB, EventGroupDelete, EventData{Group: guid}, "DELETE FROM groups WHERE guid=?", guid)
+	return err
+}
+
+// Move a group to another area. Its members, owners and grants go with it
+// since they refer to the group by guid.
+func (ga *GroupAccessor) Move(guid, area string) error {
+	tx, err := ga.DB.Begin()
 	if err != nil {
 		return err
 	}
 
-	_, err = stmt.Exec(guid)
-	return err
+	if _, err := execPublishing(tx, EventGroupMove, EventData{Group: guid, Area: area}, "UPDATE groups SET area=? WHERE guid=?", area, guid); err != nil {
+		tx.Rollback()
+		return groupError(err)
+	}
+
+	return tx.Commit()
+}
+
+// Copy a group under a new guid, which is returned. The copy takes its
+// area, name, Created and CreatedBy from clone and its description and tags
+// from the group. With members the copy gets the same members; with
+// policies it gets the same policy rows, conditions and the bindings of
+// roles in the copy's area. Grants on sensitive resources and bindings of
+// roles covering one aren't copied; each is held as a pending approval
+// requested by CreatedBy instead, and their ids are returned. Owners
+// aren't copied. Each copied row is published as an event of its own.
+func (ga *GroupAccessor) Clone(guid string, clone Group, members, policies bool) (string, []string, error) {
+	cloneGuid := NewGuid()
+	held := make([]string, 0)
+
+	queries := []string{}
+	if members {
+		queries = append(queries, "INSERT INTO groupMembers (netId, groupGuid) SELECT netId, ? FROM groupMembers WHERE groupGuid=?")
+	}
+	if policies {
+		queries = append(queries,
+			"INSERT INTO policy (actor, verb, resource) SELECT ?, verb, resource FROM policy WHERE actor=? AND resource NOT IN (SELECT resource FROM sensitiveResource)",
+			"INSERT INTO policyCondition (actor, verb, resource, expression) SELECT ?, verb, resource, expression FROM policyCondition WHERE actor=? AND resource NOT IN (SELECT resource FROM sensitiveResource)",
+		)
+	}
+
+	tx, err := ga.DB.Begin()
+	if err != nil {
+		return cloneGuid, held, err
+	}
+
+	if _, err := execPublishing(tx, EventGroupCreate, EventData{Group: cloneGuid, Area: clone.Area, Name: clone.Name}, "INSERT INTO groups (guid, area, name, description, tags, created, createdBy) SELECT ?, ?, ?, description, tags, ?, ? FROM groups WHERE guid=?", cloneGuid, clone.Area, clone.Name, clone.Created, clone.CreatedBy, guid); err != nil {
+		tx.Rollback()
+		return cloneGuid, held, groupError(err)
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, cloneGuid, guid); err != nil {
+			tx.Rollback()
+			return cloneGuid, held, err
+		}
+	}
+	if policies {
+		if held, err = cloneHeldTx(tx, guid, cloneGuid, clone); err != nil {
+			tx.Rollback()
+			return cloneGuid, held, err
+		}
+	}
+
+	if members {
+		member := func(row []string) EventData { return EventData{Group: cloneGuid, NetId: row[0]} }
+		if err := publishRows(tx, EventMemberAdd, member, "SELECT netId FROM groupMembers WHERE groupGuid=?", cloneGuid); err != nil {
+			tx.Rollback()
+			return cloneGuid, held, err
+		}
+	}
+	if policies {
+		grant := func(row []string) EventData {
+			return EventData{Actor: cloneGuid, Verb: row[0], Resource: row[1], Condition: row[2]}
+		}
+		if err := publishRows(tx, EventGrant, grant, "SELECT policy.verb, policy.resource, COALESCE(policyCondition.expression, '') FROM policy LEFT JOIN policyCondition ON policyCondition.actor = policy.actor AND policyCondition.verb = policy.verb AND policyCondition.resource = policy.resource WHERE policy.actor=?", cloneGuid); err != nil {
+			tx.Rollback()
+			return cloneGuid, held, err
+		}
+		bind := func(row []string) EventData { return EventData{Role: row[0], Actor: cloneGuid} }
+		if err := publishRows(tx, EventRoleBind, bind, "SELECT roleGuid FROM roleBinding WHERE actor=?", cloneGuid); err != nil {
+			tx.Rollback()
+			return cloneGuid, held, err
+		}
+	}
+
+	return cloneGuid, held, tx.Commit()
+}
+
+// Holds the group's grants on sensitive resources for the copy as pending
+// approvals, binds the copy to the group's roles in the copy's area and
+// holds the bindings of those covering a sensitive resource. Returns the
+// ids of the approvals.
+func cloneHeldTx(tx *sql.Tx, guid, cloneGuid string, clone Group) ([]string, error) {
+	held := make([]string, 0)
+
+	approvals := make([]Approval, 0)
+	rows, err := tx.Query("SELECT policy.verb, policy.resource, COALESCE(policyCondition.expression, '') FROM policy LEFT JOIN policyCondition ON policyCondition.actor = policy.actor AND policyCondition.verb = policy.verb AND policyCondition.resource = policy.resource WHERE policy.actor=? AND policy.resource IN (SELECT resource FROM sensitiveResource)", guid)
+	if err != nil {
+		return held, err
+	}
+	for rows.Next() {
+		a := Approval{Type: ApprovalPermission, Requester: clone.CreatedBy, Area: clone.Area, Actor: cloneGuid}
+		if err := rows.Scan(&a.Verb, &a.Resource, &a.Condition); err != nil {
+			rows.Close()
+			return held, err
+		}
+		approvals = append(approvals, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return held, err
+	}
+
+	// Roles only apply in their own area, so bindings of roles elsewhere
+	// are left behind
+	roles := make([]string, 0)
+	rows, err = tx.Query("SELECT roleBinding.roleGuid FROM roleBinding JOIN role ON role.guid = roleBinding.roleGuid WHERE roleBinding.actor=? AND role.area=?", guid, clone.Area)
+	if err != nil {
+		return held, err
+	}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			rows.Close()
+			return held, err
+		}
+		roles = append(roles, role)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return held, err
+	}
+
+	for _, role := range roles {
+		sensitive, err := roleCoversSensitiveTx(tx, role)
+		if err != nil {
+			return held, err
+		}
+		if sensitive {
+			approvals = append(approvals, Approval{Type: ApprovalRoleBinding, Requester: clone.CreatedBy, Area: clone.Area, Role: role, Actor: cloneGuid})
+			continue
+		}
+		if _, err := tx.Exec("INSERT INTO roleBinding (roleGuid, actor) VALUES (?,?)", role, cloneGuid); err != nil {
+			return held, err
+		}
+	}
+
+	for _, a := range approvals {
+		approval, err := createApprovalTx(tx, a)
+		if err != nil {
+			return held, err
+		}
+		held = append(held, approval)
+	}
+	return held, nil
+}
+
+// Tells whether any of a role's resource patterns covers a sensitive
+// resource.
+func roleCoversSensitiveTx(tx *sql.Tx, role string) (bool, error) {
+	rows, err := tx.Query("SELECT rolePermission.resource, sensitiveResource.resource FROM rolePermission, sensitiveResource WHERE rolePermission.roleGuid=?", role)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pattern, resource string
+		if err := rows.Scan(&pattern, &resource); err != nil {
+			return false, err
+		}
+		if MatchResource(pattern, resource) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
 }
 
 func (ga *GroupAccessor) GetImpliedGroups(netId, area string) ([]Group, error) {
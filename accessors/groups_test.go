@@ -1,6 +1,7 @@
 package accessors
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -16,18 +17,18 @@ func TestGetGroup(t *testing.T) {
 
 	ga := NewGroupAccessor(db)
 
-	expected := Group{"1", "1", "testGroup"}
-	columns := []string{"guid", "area", "name"}
+	expected := Group{Guid: "1", Area: "1", Name: "testGroup", Tags: []string{}}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT (.) FROM groups WHERE guid=(.)").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
 		WithArgs("1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1,1,testGroup"))
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1,1,testGroup,,,0,"))
 	group, err := ga.Get("1")
 	if err != nil {
 		t.Error("An unexpected error occurred while getting a group %v", err)
 	}
 
-	if group != expected {
+	if !reflect.DeepEqual(group, expected) {
 		t.Errorf("Expected %v but got %v", expected, group)
 	}
 
@@ -45,12 +46,12 @@ func TestGroupsGetByArea(t *testing.T) {
 
 	ga := NewGroupAccessor(db)
 
-	expected := []Group{Group{"1", "1", "testGroup"}, Group{"2", "1", "group2"}}
-	columns := []string{"guid", "area", "name"}
+	expected := []Group{Group{Guid: "1", Area: "1", Name: "testGroup", Tags: []string{}}, Group{Guid: "2", Area: "1", Name: "group2", Tags: []string{}}}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT (.) FROM groups WHERE area=(.)").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE area=(.)").
 		WithArgs().
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1,1,testGroup\n2,1,group2"))
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1,1,testGroup,,,0,\n2,1,group2,,,0,"))
 
 	groups, err := ga.GetByArea("1")
 	if err != nil {
@@ -58,7 +59,7 @@ func TestGroupsGetByArea(t *testing.T) {
 	}
 
 	for i := 0; i < len(groups); i++ {
-		if groups[i] != expected[i] {
+		if !reflect.DeepEqual(groups[i], expected[i]) {
 			t.Errorf("Expected %v but got %v", expected, groups)
 		}
 	}
@@ -81,22 +82,32 @@ func TestInsertGroup(t *testing.T) {
 
 	ga := NewGroupAccessor(db)
 
-	sqlmock.ExpectPrepare()
-	sqlmock.ExpectExec("INSERT INTO groups .+ VALUES .+").
-		WithArgs("123def", "1", "testGroup").
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO groups .+ SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("123def", "1", "testGroup", "", "lab,nights", int64(1500000000), "creator", "1", "testGroup").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
-	err = ga.Insert(Group{"", "1", "testGroup"})
+	group, err := ga.Insert(Group{Area: "1", Name: "testGroup", Tags: []string{"lab", "nights"}, Created: 1500000000, CreatedBy: "creator"})
 	if err != nil {
 		t.Error("An unexpected error occurred while getting a group:\n %s", err.Error())
 	}
+	if group.Guid != "123def" {
+		t.Errorf("Expected guid 123def but got %v", group.Guid)
+	}
 
 	if err := ga.DB.Close(); err != nil {
 		t.Errorf("An error occurred: %v", err)
 	}
 }
 
-func TestRenameGroup(t *testing.T) {
+func TestInsertDuplicateGroup(t *testing.T) {
+	NewGuid = func() string {
+		return "123def"
+	}
+
 	db, err := testhelpers.GetMockDB()
 	if err != nil {
 		t.Error("An unexpected error occurred when creating a group accessor %v", err)
@@ -105,12 +116,40 @@ func TestRenameGroup(t *testing.T) {
 
 	ga := NewGroupAccessor(db)
 
-	sqlmock.ExpectPrepare()
-	sqlmock.ExpectExec("UPDATE groups SET name=(.) WHERE guid=(.)").
-		WithArgs("changed", "1").
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO groups .+ SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("123def", "1", "testGroup", "", "", int64(0), "", "1", "testGroup").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectCommit()
+
+	_, err = ga.Insert(Group{Area: "1", Name: "testGroup"})
+	if err != ErrDuplicateGroup {
+		t.Errorf("Expected ErrDuplicateGroup but got %v", err)
+	}
+
+	if err := ga.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestUpdateGroup(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred when creating a group accessor %v", err)
+		return
+	}
+
+	ga := NewGroupAccessor(db)
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("UPDATE groups SET name=(.), description=(.), tags=(.) WHERE guid=(.)").
+		WithArgs("changed", "Night shift staff", "nights", "1").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
-	err = ga.Rename("1", "changed")
+	err = ga.Update(Group{Guid: "1", Name: "changed", Description: "Night shift staff", Tags: []string{"nights"}})
 	if err != nil {
 		t.Error("An unexpected error occurred while getting a group:\n %s", err.Error())
 	}
@@ -129,10 +168,13 @@ func TestDeleteGroup(t *testing.T) {
 
 	ga := NewGroupAccessor(db)
 
-	sqlmock.ExpectPrepare()
+	sqlmock.ExpectBegin()
 	sqlmock.ExpectExec("DELETE FROM groups WHERE guid=(.)").
 		WithArgs("1").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
 	err = ga.Delete("1")
 	if err != nil {
@@ -154,33 +196,41 @@ func TestGetImpliedGroups(t *testing.T) {
 	ga := NewGroupAccessor(db)
 
 	// query expectations
-	columns := []string{"guid", "area", "name"}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups WHERE area=.").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE area=.").
 		WithArgs("1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1\nguid2,1,group2\nguid3,1,group3"))
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1,,,0,\nguid2,1,group2,,,0,\nguid3,1,group3,,,0,"))
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT groups.* FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("netId", "1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1\nguid3,1,group3"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("netId", "1", "netId", "1").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1,,,0,\nguid3,1,group3,,,0,"))
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT groups.* FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("netId", "1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1\nguid3,1,group3"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("netId", "1", "netId", "1").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1,,,0,\nguid3,1,group3,,,0,"))
 	columns = []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE actor IN .+").
-		WithArgs("1", "guid1", "guid3").
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("1,Area,,,0"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource FROM policy LEFT JOIN policyArea .+ WHERE .policyArea.area IS NULL OR policyArea.area=.. AND policy.actor IN .+").
+		WithArgs("1", "1", "netId", "guid1", "guid3").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("group1,edit,res1\ngroup1,view,res1\ngroup3,update,res2"))
 	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("1", "1", "netId", "guid1", "guid3").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+	sqlmock.ExpectPrepare()
 	sqlmock.ExpectQuery("SELECT . FROM policy WHERE actor=.").
 		WithArgs("guid2").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("group2,edit,res1\ngroup2,view,res1"))
-	columns = []string{"guid", "area", "name"}
+	columns = []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups WHERE guid=.").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=.").
 		WithArgs("guid2").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid2,1,group2"))
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid2,1,group2,,,0,"))
 
 	implied, err := ga.GetImpliedGroups("netId", "1")
 	if err != nil {
@@ -191,8 +241,8 @@ func TestGetImpliedGroups(t *testing.T) {
 		t.Errorf("An error occurred: %v", err)
 	}
 
-	expected := Group{"guid2", "1", "group2"}
-	if len(implied) != 1 && implied[0] != expected {
+	expected := Group{Guid: "guid2", Area: "1", Name: "group2", Tags: []string{}}
+	if len(implied) != 1 && !reflect.DeepEqual(implied[0], expected) {
 		t.Errorf("Expected group2 but got %v", implied)
 	}
 }
@@ -207,24 +257,32 @@ func TestGetImpliedGroupsNone(t *testing.T) {
 	ga := NewGroupAccessor(db)
 
 	// query expectations
-	columns := []string{"guid", "area", "name"}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups WHERE area=.").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE area=.").
 		WithArgs("1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1\nguid2,1,group2\nguid3,1,group3"))
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1,,,0,\nguid2,1,group2,,,0,\nguid3,1,group3,,,0,"))
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT groups.* FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("netId", "1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1\nguid3,1,group3"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("netId", "1", "netId", "1").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1,,,0,\nguid3,1,group3,,,0,"))
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT groups.* FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("netId", "1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1\nguid3,1,group3"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("netId", "1", "netId", "1").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1,,,0,\nguid3,1,group3,,,0,"))
 	columns = []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE actor IN .+").
-		WithArgs("1", "guid1", "guid3").
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("1,Area,,,0"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource FROM policy LEFT JOIN policyArea .+ WHERE .policyArea.area IS NULL OR policyArea.area=.. AND policy.actor IN .+").
+		WithArgs("1", "1", "netId", "guid1", "guid3").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("group1,edit,res1\ngroup1,view,res1\ngroup3,update,res2"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("1", "1", "netId", "guid1", "guid3").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
 	columns = []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
 	sqlmock.ExpectQuery("SELECT . FROM policy WHERE actor=.").
@@ -244,3 +302,122 @@ func TestGetImpliedGroupsNone(t *testing.T) {
 		t.Errorf("Expected an empty array but got %v", implied)
 	}
 }
+
+func TestCloneGroup(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a group accessor %v", err)
+		return
+	}
+	NewGuid = func() string {
+		return "copy"
+	}
+
+	ga := NewGroupAccessor(db)
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO groups .+ SELECT .+ FROM groups WHERE guid=.").
+		WithArgs("copy", "area2", "testGroup", int64(1500000000), "creator", "1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO groupMembers .+ SELECT netId, . FROM groupMembers WHERE groupGuid=.").
+		WithArgs("copy", "1").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	sqlmock.ExpectExec("INSERT INTO policy .+ SELECT .+ FROM policy WHERE actor=. AND resource NOT IN .SELECT resource FROM sensitiveResource.").
+		WithArgs("copy", "1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlmock.ExpectExec("INSERT INTO policyCondition .+ SELECT .+ FROM policyCondition WHERE actor=. AND resource NOT IN .SELECT resource FROM sensitiveResource.").
+		WithArgs("copy", "1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// The grant on payroll is sensitive, so it waits for approval
+	sqlmock.ExpectQuery("SELECT policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.actor=. AND policy.resource IN .SELECT resource FROM sensitiveResource.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"verb", "resource", "expression"}).FromCSVString("view,payroll,"))
+
+	// Only r1 is in area2; r2 covers a sensitive resource
+	sqlmock.ExpectQuery("SELECT roleBinding.roleGuid FROM roleBinding JOIN role .+ WHERE roleBinding.actor=. AND role.area=.").
+		WithArgs("1", "area2").
+		WillReturnRows(sqlmock.NewRows([]string{"roleGuid"}).FromCSVString("r1\nr2"))
+	sqlmock.ExpectQuery("SELECT rolePermission.resource, sensitiveResource.resource FROM rolePermission, sensitiveResource WHERE rolePermission.roleGuid=.").
+		WithArgs("r1").
+		WillReturnRows(sqlmock.NewRows([]string{"pattern", "resource"}).FromCSVString("shifts/*,payroll"))
+	sqlmock.ExpectExec("INSERT INTO roleBinding .+ VALUES .+").
+		WithArgs("r1", "copy").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectQuery("SELECT rolePermission.resource, sensitiveResource.resource FROM rolePermission, sensitiveResource WHERE rolePermission.roleGuid=.").
+		WithArgs("r2").
+		WillReturnRows(sqlmock.NewRows([]string{"pattern", "resource"}).FromCSVString("pay*,payroll"))
+	sqlmock.ExpectExec("INSERT INTO approval .+ VALUES .+").
+		WithArgs("copy", "permission", "creator", "area2", "", "", "copy", "view", "payroll", "", "pending", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO approval .+ VALUES .+").
+		WithArgs("copy", "role.binding", "creator", "area2", "", "r2", "copy", "", "", "", "pending", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Each copied row is published
+	sqlmock.ExpectQuery("SELECT netId FROM groupMembers WHERE groupGuid=.").
+		WithArgs("copy").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("alice\nbob\ncarol"))
+	for i := 0; i < 3; i++ {
+		sqlmock.ExpectExec("INSERT INTO event").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	sqlmock.ExpectQuery("SELECT policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.actor=.").
+		WithArgs("copy").
+		WillReturnRows(sqlmock.NewRows([]string{"verb", "resource", "expression"}).FromCSVString("edit,shifts,\nview,reports,"))
+	for i := 0; i < 2; i++ {
+		sqlmock.ExpectExec("INSERT INTO event").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	sqlmock.ExpectQuery("SELECT roleGuid FROM roleBinding WHERE actor=.").
+		WithArgs("copy").
+		WillReturnRows(sqlmock.NewRows([]string{"roleGuid"}).FromCSVString("r1"))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	guid, held, err := ga.Clone("1", Group{Area: "area2", Name: "testGroup", Created: 1500000000, CreatedBy: "creator"}, true, true)
+	if err != nil {
+		t.Errorf("An unexpected error occurred while copying a group %v", err)
+	}
+	if guid != "copy" {
+		t.Errorf("Expected copy but got %v", guid)
+	}
+	if len(held) != 2 {
+		t.Errorf("Expected 2 held grants but got %v", held)
+	}
+
+	if err := ga.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestSearchGroups(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a group accessor %v", err)
+		return
+	}
+
+	ga := NewGroupAccessor(db)
+
+	expected := []Group{Group{Guid: "1", Area: "1", Name: "Lab 50%", Description: "Lab staff", Tags: []string{"lab", "nights"}, Created: 1500000000, CreatedBy: "creator"}}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE FIND_IN_SET.+ AND .name LIKE . OR description LIKE ..").
+		WithArgs("lab", `%50\%%`, `%50\%%`).
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("1,1,Lab 50%,Lab staff,\"lab,nights\",1500000000,creator"))
+
+	groups, err := ga.Search("", "lab", "50%")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while searching groups %v", err)
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected %v but got %v", expected, groups)
+	}
+
+	if err := ga.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
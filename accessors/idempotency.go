@@ -0,0 +1,56 @@
+package accessors
+
+import (
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+type IdempotencyAccessor struct {
+	DB *sql.DB // Database connection
+}
+
+// Returns a new idempotency key accessor. Keys are sent by clients in the
+// Idempotency-Key header and remember the guid of whatever the first
+// request with the key created, so retries get that back instead of
+// creating it again, along with a hash of that request so a key reused for
+// a different one can be refused.
+func NewIdempotencyAccessor(db *sql.DB) *IdempotencyAccessor {
+	return &IdempotencyAccessor{db}
+}
+
+// Gets the guid a user's earlier request with the key created and the hash
+// of that request.
+func (ia *IdempotencyAccessor) Get(netId, key string) (string, string, error) {
+	stmt, err := ia.DB.Prepare("SELECT resource, requestHash FROM idempotencyKey WHERE netId=? AND idempotencyKey=?")
+	if err != nil {
+		return "", "", err
+	}
+
+	var resource, hash string
+	err = stmt.QueryRow(netId, key).Scan(&resource, &hash)
+	return resource, hash, err
+}
+
+// Remember the guid a user's request with the key created and the request's
+// hash. created is the unix time of the request.
+func (ia *IdempotencyAccessor) Put(netId, key, resource, hash string, created int64) error {
+	stmt, err := ia.DB.Prepare("INSERT INTO idempotencyKey (netId, idempotencyKey, resource, requestHash, created) VALUES (?,?,?,?,?)")
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec(netId, key, resource, hash, created)
+	return err
+}
+
+// Forget keys used at or before the given unix time.
+func (ia *IdempotencyAccessor) DeleteBefore(created int64) error {
+	stmt, err := ia.DB.Prepare("DELETE FROM idempotencyKey WHERE created<=?")
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec(created)
+	return err
+}
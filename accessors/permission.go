@@ -2,14 +2,17 @@ package accessors
 
 import (
 	"database/sql"
+	"time"
 
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
 	_ "github.com/go-sql-driver/mysql"
 )
 
 type Permission struct {
-	Actor    string // Actor Guid
-	Verb     string
-	Resource string // Resource Guid
+	Actor     string // Actor Guid
+	Verb      string
+	Resource  string // Resource Guid
+	Condition string // Expression the grant depends on, empty if it always applies
 }
 
 type PermissionAccessor struct {
@@ -21,93 +24,205 @@ func NewPermissionAccessor(db *sql.DB) *PermissionAccessor {
 	return &PermissionAccessor{db}
 }
 
-// Tells whether or not a user has permission to access a resource.
-func (pa *PermissionAccessor) CheckPermission(permissions []string, obj, verb string) (bool, error) {
+// Tells whether one of the actors holds a permission in an area, through a
+// policy row or a role bound to it. Conditions are evaluated against the
+// current time only.
+func (pa *PermissionAccessor) CheckPermission(area string, actors []string, obj, verb string) (bool, error) {
+	d, err := pa.decide(false, false, area, actors, obj, verb, conditions.NewContext(time.Now(), ""))
+	return d.Allowed, err
+}
 
-	// build query and params
-	query := "SELECT * FROM policy WHERE verb=? AND resource=? AND actor IN (?"
-	var params []interface{}
-	params = append(params, verb)
-	params = append(params, obj)
-	params = append(params, permissions[0])
-	for i := 1; i < len(permissions); i++ {
-		query += ",?"
-		params = append(params, permissions[i])
-	}
-	query += ")"
+// Inserts into the policy table which grants permission to a user/group/area
+//   to access a certain resource. Returns the number of rows inserted, 0 if
+//   the permission was already granted.
+func (pa *PermissionAccessor) Add(actor, verb, obj string) (int64, error) {
+	return execEvent(pa.DB, EventGrant, EventData{Actor: actor, Verb: verb, Resource: obj}, "INSERT INTO policy (actor, verb, resource) SELECT ?,?,? FROM DUAL WHERE NOT EXISTS (SELECT actor FROM policy WHERE actor=? AND verb=? AND resource=?)", actor, verb, obj, actor, verb, obj)
+}
 
-	// execute query
-	stmt, err := pa.DB.Prepare(query)
+// Tells whether a permission is granted to the actor itself, not counting
+//   groups, roles or conditions.
+func (pa *PermissionAccessor) Granted(actor, verb, obj string) (bool, error) {
+	stmt, err := pa.DB.Prepare("SELECT actor FROM policy WHERE actor=? AND verb=? AND resource=?")
 	if err != nil {
 		return false, err
 	}
 
-	rows, err := stmt.Query(params...)
+	var found string
+	err = stmt.QueryRow(actor, verb, obj).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Grants a permission that only applies while the condition expression
+//   holds. An empty expression grants it unconditionally. A permission
+//   already granted keeps its condition and 0 rows are reported.
+func (pa *PermissionAccessor) AddConditional(actor, verb, obj, expression string) (int64, error) {
+	if expression == "" {
+		return pa.Add(actor, verb, obj)
+	}
+
+	tx, err := pa.DB.Begin()
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-	defer rows.Close()
 
-	// If one or more rows was returned then the user has permission.
-	if rows.Next() {
-		return true, nil
+	n, err := grantTx(tx, actor, verb, obj, expression, "")
+	if err != nil {
+		tx.Rollback()
+		return 0, err
 	}
 
-	// No rows were returned, return false.
-	return false, nil
+	return n, tx.Commit()
 }
 
-// Inserts into the policy table which grants permission to a user/group/area
-//   to access a certain resource.
-func (pa *PermissionAccessor) Add(actor, verb, obj string) error {
-	stmt, err := pa.DB.Prepare("INSERT INTO policy (actor, verb, resource) VALUES (?,?,?)")
+// Deletes an entry from the policy table, which revokes permission
+//   for a user/group/area to access a resource. Its condition goes too.
+//   Returns the number of policy rows deleted.
+func (pa *PermissionAccessor) Delete(actor, verb, resource string) (int64, error) {
+	tx, err := pa.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := revokeTx(tx, actor, verb, resource)
 	if err != nil {
-		return err
+		tx.Rollback()
+		return 0, err
 	}
 
-	_, err = stmt.Exec(actor, verb, obj)
-	return err
+	return n, tx.Commit()
 }
 
-// Deletes an entry from the policy table, which revokes permission
-//   for a user/group/area to access a resource.
-func (pa *PermissionAccessor) Delete(actor, verb, resource string) error {
-	stmt, err := pa.DB.Prepare("DELETE FROM policy WHERE actor=? AND verb=? AND resource=?")
+// A grant or revoke to be applied by ApplyChanges. A grant with Hold set
+//   isn't applied; the approval request is created instead and its Guid
+//   filled in.
+type PolicyChange struct {
+	Revoke    bool
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string
+	Hold      *Approval
+}
+
+// Applies the changes in a single transaction and returns the number of
+//   policy rows each one inserted or deleted. When atomic, the first
+//   failure rolls every change back and is returned. Otherwise each change
+//   runs under its own savepoint so a failure only undoes that change; its
+//   error is reported at its index and the others are committed.
+func (pa *PermissionAccessor) ApplyChanges(changes []PolicyChange, atomic bool) ([]int64, []error, error) {
+	counts := make([]int64, len(changes))
+	errs := make([]error, len(changes))
+
+	tx, err := pa.DB.Begin()
 	if err != nil {
-		return err
+		return counts, errs, err
 	}
 
-	_, err = stmt.Exec(actor, verb, resource)
-	return err
+	for i, change := range changes {
+		if !atomic {
+			if _, err := tx.Exec("SAVEPOINT policyChange"); err != nil {
+				tx.Rollback()
+				return counts, errs, err
+			}
+		}
+
+		switch {
+		case change.Hold != nil:
+			change.Hold.Guid, err = createApprovalTx(tx, *change.Hold)
+		case change.Revoke:
+			counts[i], err = revokeTx(tx, change.Actor, change.Verb, change.Resource)
+		default:
+			counts[i], err = grantTx(tx, change.Actor, change.Verb, change.Resource, change.Condition, "")
+		}
+		if err == nil {
+			continue
+		}
+
+		if atomic {
+			tx.Rollback()
+			return make([]int64, len(changes)), errs, err
+		}
+		counts[i], errs[i] = 0, err
+		if _, err := tx.Exec("ROLLBACK TO SAVEPOINT policyChange"); err != nil {
+			tx.Rollback()
+			return make([]int64, len(changes)), errs, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return make([]int64, len(changes)), errs, err
+	}
+	return counts, errs, nil
 }
 
-//Gets all the groups a user is in, but restricted by area. Puts the area in the array so that
-//the api can then act on without having to change anything
-func (pa *PermissionAccessor) Get(area, netId string) ([]Group, error) {
-	groups := make([]Group, 0)
-	stmt, err := pa.DB.Prepare("SELECT groups.* FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=? AND groups.area=?")
+// Inserts a policy row and its condition and area, if any, unless the
+//   permission is already granted, and publishes the grant. A row with an
+//   area only applies in that area. Returns the number of policy rows
+//   inserted.
+func grantTx(tx *sql.Tx, actor, verb, obj, expression, area string) (int64, error) {
+	result, err := tx.Exec("INSERT INTO policy (actor, verb, resource) SELECT ?,?,? FROM DUAL WHERE NOT EXISTS (SELECT actor FROM policy WHERE actor=? AND verb=? AND resource=?)", actor, verb, obj, actor, verb, obj)
 	if err != nil {
-		return groups, err
+		return 0, err
 	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if expression != "" {
+		if _, err := tx.Exec("INSERT INTO policyCondition (actor, verb, resource, expression) VALUES (?,?,?,?)", actor, verb, obj, expression); err != nil {
+			return 0, err
+		}
+	}
+	if area != "" {
+		if _, err := tx.Exec("INSERT INTO policyArea (actor, verb, resource, area) VALUES (?,?,?,?)", actor, verb, obj, area); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, publish(tx, EventGrant, EventData{Actor: actor, Verb: verb, Resource: obj, Condition: expression, Area: area})
+}
 
-	rows, err := stmt.Query(netId, area)
+// Deletes a policy row with its condition and area and publishes the revoke.
+//   Returns the number of policy rows deleted.
+func revokeTx(tx *sql.Tx, actor, verb, resource string) (int64, error) {
+	result, err := tx.Exec("DELETE FROM policy WHERE actor=? AND verb=? AND resource=?", actor, verb, resource)
 	if err != nil {
-		return groups, err
+		return 0, err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var g Group
-		err = rows.Scan(&g.Guid, &g.Area, &g.Name)
-		groups = append(groups, g)
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM policyCondition WHERE actor=? AND verb=? AND resource=?", actor, verb, resource); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM policyArea WHERE actor=? AND verb=? AND resource=?", actor, verb, resource); err != nil {
+		return 0, err
 	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	return n, publish(tx, EventRevoke, EventData{Actor: actor, Verb: verb, Resource: resource})
+}
 
-	return groups, nil
+//Gets all the groups a user is in, directly or through a group rule, but restricted by area.
+//Puts the area in the array so that the api can then act on without having to change anything
+func (pa *PermissionAccessor) Get(area, netId string) ([]Group, error) {
+	return queryGroups(pa.DB, "SELECT "+groupColumns+" FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=? AND groups.area=? UNION SELECT "+groupColumns+" FROM groups JOIN groupRuleMember ON groups.guid = groupRuleMember.groupGuid WHERE groupRuleMember.netId=? AND groups.area=?", netId, area, netId, area)
 }
 
-// Return a list of permissions that a group has.
+// Return a list of permissions that a group has, including those granted
+// by roles bound to the group. Conditional grants carry their condition.
 func (pa *PermissionAccessor) GetGroupPermissions(groupGuid string) ([]Permission, error) {
 	perms := make([]Permission, 0)
-	stmt, err := pa.DB.Prepare("SELECT * FROM policy WHERE actor=?")
+	stmt, err := pa.DB.Prepare("SELECT policy.actor, policy.verb, policy.resource, COALESCE(policyCondition.expression, '') FROM policy LEFT JOIN policyCondition ON policyCondition.actor = policy.actor AND policyCondition.verb = policy.verb AND policyCondition.resource = policy.resource WHERE policy.actor=?")
 	if err != nil {
 		return perms, err
 	}
@@ -119,14 +234,20 @@ func (pa *PermissionAccessor) GetGroupPermissions(groupGuid string) ([]Permissio
 	defer rows.Close()
 	for rows.Next() {
 		var p Permission
-		err = rows.Scan(&p.Actor, &p.Verb, &p.Resource)
+		err = rows.Scan(&p.Actor, &p.Verb, &p.Resource, &p.Condition)
 		perms = append(perms, p)
 	}
 
-	return perms, nil
+	bound, err := NewRoleAccessor(pa.DB).GetGroupBoundPermissions(groupGuid)
+	if err != nil {
+		return perms, err
+	}
+
+	return append(perms, bound...), nil
 }
 
-// Return a list of permissions that a user has.
+// Return a list of permissions that a user has. Conditional grants carry
+// their condition.
 func (pa *PermissionAccessor) GetUserPermissions(netId, area string) ([]Permission, error) {
 	perms := make([]Permission, 0)
 
@@ -136,13 +257,23 @@ func (pa *PermissionAccessor) GetUserPermissions(netId, area string) ([]Permissi
 		return nil, err
 	}
 
-	// build query and params
-	query := "SELECT * FROM policy WHERE actor IN (?"
-	var params []interface{}
-	params = append(params, area)
+	// Area level rows of ancestors apply when the area inherits them
+	lineage, err := NewAreaAccessor(pa.DB).Lineage(area)
+	if err != nil {
+		return nil, err
+	}
+
+	// Permissions can be granted to the area, the user or the user's
+	// groups; rows granted to the user for another area don't apply
+	actors := append(PolicyAreas(area, lineage), netId)
 	for i := 0; i < len(groups); i++ {
+		actors = append(actors, groups[i].Guid)
+	}
+	query := "SELECT policy.actor, policy.verb, policy.resource, COALESCE(policyCondition.expression, '') FROM policy LEFT JOIN policyCondition ON policyCondition.actor = policy.actor AND policyCondition.verb = policy.verb AND policyCondition.resource = policy.resource LEFT JOIN policyArea ON policyArea.actor = policy.actor AND policyArea.verb = policy.verb AND policyArea.resource = policy.resource WHERE (policyArea.area IS NULL OR policyArea.area=?) AND policy.actor IN (?"
+	params := []interface{}{area, actors[0]}
+	for i := 1; i < len(actors); i++ {
 		query += ",?"
-		params = append(params, groups[i].Guid)
+		params = append(params, actors[i])
 	}
 	query += ")"
 	stmt, err := pa.DB.Prepare(query)
@@ -158,11 +289,21 @@ func (pa *PermissionAccessor) GetUserPermissions(netId, area string) ([]Permissi
 	defer rows.Close()
 	for rows.Next() {
 		var p Permission
-		err = rows.Scan(&p.Actor, &p.Verb, &p.Resource)
+		err = rows.Scan(&p.Actor, &p.Verb, &p.Resource, &p.Condition)
 		p.Actor = "" // actor is irrelevant
 		perms = append(perms, p)
 	}
 
+	// Add the permissions of roles bound to the same actors
+	bound, err := NewRoleAccessor(pa.DB).GetBoundPermissions(area, actors)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range bound {
+		p.Actor = ""
+		perms = append(perms, p)
+	}
+
 	// Eliminate duplicate entries (i.e. user is in two groups with access to the same permission)
 	uniquePerms := make(map[Permission]bool) // Use this map like a set to get unique permissions, the values don't matter, just the keys
 	for i := 0; i < len(perms); i++ {
@@ -1,9 +1,11 @@
 package accessors
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
 	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
 )
 
@@ -16,12 +18,16 @@ func TestCheckPermission(t *testing.T) {
 
 	pa := NewPermissionAccessor(db)
 
-	columns := []string{"guid"}
+	columns := []string{"actor", "expression", "area"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE verb=. AND resource=. AND actor IN (.+)").
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
 		WithArgs("edit", "11111111-2222-3333-2222-111111111111", "11111111-2222-3333-4444-555555555555", "11111111-1111-2222-2222-333333333333").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("44444444-3333-2222-1111-000000000000\n55555555-6666-7777-8888-999999999999"))
-	perm, err := pa.CheckPermission([]string{"11111111-2222-3333-4444-555555555555", "11111111-1111-2222-2222-333333333333"}, "11111111-2222-3333-2222-111111111111", "edit")
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("11111111-2222-3333-4444-555555555555,,"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "11111111-2222-3333-4444-555555555555", "11111111-1111-2222-2222-333333333333").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+	perm, err := pa.CheckPermission("area", []string{"11111111-2222-3333-4444-555555555555", "11111111-1111-2222-2222-333333333333"}, "11111111-2222-3333-2222-111111111111", "edit")
 	if err != nil {
 		t.Error("An unexpected error occurred while getting a group %v", err)
 	}
@@ -44,12 +50,16 @@ func TestCheckNoPermission(t *testing.T) {
 
 	pa := NewPermissionAccessor(db)
 
-	columns := []string{"guid"}
+	columns := []string{"actor", "expression", "area"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE verb=. AND resource=. AND actor IN (.+)").
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
 		WithArgs("edit", "11111111-2222-3333-2222-111111111111", "11111111-2222-3333-4444-555555555555", "11111111-1111-2222-2222-333333333333").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString(""))
-	perm, err := pa.CheckPermission([]string{"11111111-2222-3333-4444-555555555555", "11111111-1111-2222-2222-333333333333"}, "11111111-2222-3333-2222-111111111111", "edit")
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "11111111-2222-3333-4444-555555555555", "11111111-1111-2222-2222-333333333333").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+	perm, err := pa.CheckPermission("area", []string{"11111111-2222-3333-4444-555555555555", "11111111-1111-2222-2222-333333333333"}, "11111111-2222-3333-2222-111111111111", "edit")
 	if err != nil {
 		t.Error("An unexpected error occurred while getting a group %v", err)
 	}
@@ -63,7 +73,40 @@ func TestCheckNoPermission(t *testing.T) {
 	}
 }
 
-func TestAddPermission(t *testing.T) {
+func TestCheckConditionalPermission(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred when creating a group accessor %v", err)
+		return
+	}
+
+	pa := NewPermissionAccessor(db)
+	ctx := conditions.Context{"time.hour": float64(20)}
+
+	// The only matching row is limited to office hours
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
+		WithArgs("edit", "shifts", "netId", "g1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString("g1,time.hour >= 8 && time.hour < 17,"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "netId", "g1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+	d, err := pa.decide(false, false, "area", []string{"netId", "g1"}, "shifts", "edit", ctx)
+	if err != nil {
+		t.Error("An unexpected error occurred while checking a permission %v", err)
+	}
+
+	if d.Allowed {
+		t.Error("Expected false but got true")
+	}
+
+	if err := pa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestCheckPermissionThroughRole(t *testing.T) {
 	db, err := testhelpers.GetMockDB()
 	if err != nil {
 		t.Error("An unexpected error occurred when creating a group accessor %v", err)
@@ -73,14 +116,51 @@ func TestAddPermission(t *testing.T) {
 	pa := NewPermissionAccessor(db)
 
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectExec("INSERT INTO policy (.+) VALUES (.+)").
-		WithArgs("11111111-2222-3333-2222-111111111111", "edit", "11111111-2222-3333-4444-555555555555").
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
+		WithArgs("edit", "report-2016", "netId", "g1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString(""))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "netId", "g1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString("g1,view,*\ng1,edit,report-*"))
+	perm, err := pa.CheckPermission("area", []string{"netId", "g1"}, "report-2016", "edit")
+	if err != nil {
+		t.Error("An unexpected error occurred while checking a permission %v", err)
+	}
+
+	if !perm {
+		t.Error("Expected true but got false")
+	}
+
+	if err := pa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestAddPermission(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred when creating a group accessor %v", err)
+		return
+	}
+
+	pa := NewPermissionAccessor(db)
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO policy (.+) SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("11111111-2222-3333-2222-111111111111", "edit", "11111111-2222-3333-4444-555555555555", "11111111-2222-3333-2222-111111111111", "edit", "11111111-2222-3333-4444-555555555555").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
-	err = pa.Add("11111111-2222-3333-2222-111111111111", "edit", "11111111-2222-3333-4444-555555555555")
+	n, err := pa.Add("11111111-2222-3333-2222-111111111111", "edit", "11111111-2222-3333-4444-555555555555")
 	if err != nil {
 		t.Error("An unexpected error occurred while getting a resource:\n %s", err.Error())
 	}
+	if n != 1 {
+		t.Errorf("Expected 1 row added but got %d", n)
+	}
 
 	if err := pa.DB.Close(); err != nil {
 		t.Errorf("An error occurred: %v", err)
@@ -96,15 +176,123 @@ func TestDeletePermission(t *testing.T) {
 
 	pa := NewPermissionAccessor(db)
 
-	sqlmock.ExpectPrepare()
+	sqlmock.ExpectBegin()
 	sqlmock.ExpectExec("DELETE FROM policy WHERE actor=. AND verb=. AND resource=.").
 		WithArgs("11111111-2222-3333-2222-111111111111", "edit", "88888888-8888-8888-8888-888888888888").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("DELETE FROM policyCondition WHERE actor=. AND verb=. AND resource=.").
+		WithArgs("11111111-2222-3333-2222-111111111111", "edit", "88888888-8888-8888-8888-888888888888").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("DELETE FROM policyArea WHERE actor=. AND verb=. AND resource=.").
+		WithArgs("11111111-2222-3333-2222-111111111111", "edit", "88888888-8888-8888-8888-888888888888").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
-	err = pa.Delete("11111111-2222-3333-2222-111111111111", "edit", "88888888-8888-8888-8888-888888888888")
+	n, err := pa.Delete("11111111-2222-3333-2222-111111111111", "edit", "88888888-8888-8888-8888-888888888888")
 	if err != nil {
 		t.Error("An unexpected error occurred while getting a resource:\n %s", err.Error())
 	}
+	if n != 1 {
+		t.Errorf("Expected 1 row deleted but got %d", n)
+	}
+
+	if err := pa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestApplyPolicyChanges(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred when creating a group accessor %v", err)
+		return
+	}
+
+	pa := NewPermissionAccessor(db)
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("SAVEPOINT policyChange").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("INSERT INTO policy (.+) SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("11111111-2222-3333-2222-111111111111", "edit", "11111111-2222-3333-4444-555555555555", "11111111-2222-3333-2222-111111111111", "edit", "11111111-2222-3333-4444-555555555555").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("SAVEPOINT policyChange").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("DELETE FROM policy WHERE actor=. AND verb=. AND resource=.").
+		WithArgs("11111111-2222-3333-2222-111111111111", "view", "88888888-8888-8888-8888-888888888888").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("DELETE FROM policyCondition WHERE actor=. AND verb=. AND resource=.").
+		WithArgs("11111111-2222-3333-2222-111111111111", "view", "88888888-8888-8888-8888-888888888888").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("DELETE FROM policyArea WHERE actor=. AND verb=. AND resource=.").
+		WithArgs("11111111-2222-3333-2222-111111111111", "view", "88888888-8888-8888-8888-888888888888").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectCommit()
+
+	changes := []PolicyChange{
+		{Actor: "11111111-2222-3333-2222-111111111111", Verb: "edit", Resource: "11111111-2222-3333-4444-555555555555"},
+		{Revoke: true, Actor: "11111111-2222-3333-2222-111111111111", Verb: "view", Resource: "88888888-8888-8888-8888-888888888888"},
+	}
+	counts, errs, err := pa.ApplyChanges(changes, false)
+	if err != nil {
+		t.Error("An unexpected error occurred while applying changes:\n %s", err.Error())
+	}
+	if !reflect.DeepEqual(counts, []int64{1, 0}) {
+		t.Errorf("Expected counts [1 0] but got %v", counts)
+	}
+	for i, e := range errs {
+		if e != nil {
+			t.Errorf("Unexpected error on change %d: %v", i, e)
+		}
+	}
+
+	if err := pa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestApplyPolicyChangesHold(t *testing.T) {
+	NewGuid = func() string {
+		return "req1"
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred when creating a group accessor %v", err)
+		return
+	}
+
+	pa := NewPermissionAccessor(db)
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO policy (.+) SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("actor", "edit", "report", "actor", "edit", "report").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO approval .+ VALUES .+").
+		WithArgs("req1", "permission", "requester", "area", "", "", "actor", "edit", "payroll", "", "pending", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	hold := &Approval{Type: ApprovalPermission, Requester: "requester", Area: "area", Actor: "actor", Verb: "edit", Resource: "payroll"}
+	changes := []PolicyChange{
+		{Actor: "actor", Verb: "edit", Resource: "report"},
+		{Actor: "actor", Verb: "edit", Resource: "payroll", Hold: hold},
+	}
+	counts, _, err := pa.ApplyChanges(changes, true)
+	if err != nil {
+		t.Error("An unexpected error occurred while applying changes:\n %s", err.Error())
+	}
+	if !reflect.DeepEqual(counts, []int64{1, 0}) {
+		t.Errorf("Expected counts [1 0] but got %v", counts)
+	}
+	if hold.Guid != "req1" {
+		t.Errorf("Expected the held grant to get approval req1 but got %q", hold.Guid)
+	}
 
 	if err := pa.DB.Close(); err != nil {
 		t.Errorf("An error occurred: %v", err)
@@ -121,12 +309,12 @@ func TestGetAllGroupsInArea(t *testing.T) {
 	pa := NewPermissionAccessor(db)
 
 	//expected := []string{"11111111-1111-1111-1111-111111111111", "11111111-1111-1111-1111-111111111112"}
-	expected := []Group{Group{"guid1", "area1", "name1"}, Group{"guid2", "area2", "name2"}}
-	columns := []string{"guid", "area", "name"}
+	expected := []Group{Group{Guid: "guid1", Area: "area1", Name: "name1", Tags: []string{}}, Group{Guid: "guid2", Area: "area2", Name: "name2", Tags: []string{}}}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT groups.* FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("00000000-0000-0000-0000-000000000000", "1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,area1,name1\nguid2,area2,name2"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("00000000-0000-0000-0000-000000000000", "1", "00000000-0000-0000-0000-000000000000", "1").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,area1,name1,,,0,\nguid2,area2,name2,,,0,"))
 
 	actors, err := pa.Get("1", "00000000-0000-0000-0000-000000000000")
 	if err != nil {
@@ -134,7 +322,7 @@ func TestGetAllGroupsInArea(t *testing.T) {
 	}
 
 	for i := 0; i < len(actors); i++ {
-		if actors[i] != expected[i] {
+		if !reflect.DeepEqual(actors[i], expected[i]) {
 			t.Errorf("Expected %v but got %v", expected, actors)
 		}
 	}
@@ -153,12 +341,15 @@ func TestGetPermissionsByGroup(t *testing.T) {
 
 	pa := NewPermissionAccessor(db)
 
-	expected := []Permission{Permission{"actor", "verb", "resource"}, Permission{"actor", "verb1", "resource1"}}
-	columns := []string{"actor", "verb", "resource"}
+	expected := []Permission{Permission{"actor", "verb", "resource", ""}, Permission{"actor", "verb1", "resource1", "time.hour < 17"}}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE actor=.").
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.actor=.").
 		WithArgs("actor").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("actor,verb,resource\nactor,verb1,resource1"))
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource", "condition"}).FromCSVString("actor,verb,resource,\nactor,verb1,resource1,time.hour < 17"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ JOIN groups .+ WHERE roleBinding.actor=. AND role.area = groups.area").
+		WithArgs("actor").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
 
 	permissions, err := pa.GetGroupPermissions("actor")
 	if err != nil {
@@ -189,21 +380,29 @@ func TestGetUserPermissions(t *testing.T) {
 	columns := []string{"guid"}
 	sqlmock.ExpectPrepare()
 	sqlmock.ExpectQuery("SELECT guid FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("netId", "area").
+		WithArgs("netId", "area", "netId", "area").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("g1\ng2"))
 
 	// Get permissions
-	columns = []string{"actor", "verb", "resource"}
+	columns = []string{"actor", "verb", "resource", "condition"}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("area,Area,,,0"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ LEFT JOIN policyArea .+ WHERE .policyArea.area IS NULL OR policyArea.area=.. AND policy.actor IN .+").
+		WithArgs("area", "area", "netId", "g1", "g2").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("g1,edit,resource,\ng1,read,resource,\ng2,edit,resource,\ng2,read,resource2,"))
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE actor IN .+").
-		WithArgs("area", "g1", "g2").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("g1,edit,resource\ng1,read,resource\ng2,edit,resource\ng2,read,resource2"))
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "area", "netId", "g1", "g2").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
 
 	perms, err := pa.GetUserPermissions("netId", "area")
 	expected := []Permission{
-		Permission{"", "edit", "resource"},
-		Permission{"", "read", "resource"},
-		Permission{"", "read", "resource2"},
+		Permission{"", "edit", "resource", ""},
+		Permission{"", "read", "resource", ""},
+		Permission{"", "read", "resource2", ""},
 	}
 	for i := 0; i < len(perms); i++ {
 		if perms[i] != expected[i] {
@@ -211,3 +410,63 @@ func TestGetUserPermissions(t *testing.T) {
 		}
 	}
 }
+
+func TestExplain(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a group accessor %v", err)
+		return
+	}
+
+	pa := NewPermissionAccessor(db)
+	ctx := conditions.Context{"time.hour": float64(20)}
+	areaColumns := []string{"guid", "name", "description", "parent", "inherit"}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows(areaColumns).FromCSVString("area,Area,,,0"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("netId").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString(""))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows(areaColumns).FromCSVString("area,Area,,,0"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT . FROM admin WHERE netId=. AND area IN .+").
+		WithArgs("netId", "area").
+		WillReturnRows(sqlmock.NewRows([]string{"netId", "area"}).FromCSVString(""))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers .+").
+		WithArgs("netId", "area", "netId", "area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("g1,area,desk,,,0,"))
+
+	// The group's row is limited to office hours
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
+		WithArgs("edit", "shifts", "g1", "area", "netId").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString("g1,time.hour >= 8 && time.hour < 17,"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "g1", "area", "netId").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString("g1,view,shifts"))
+
+	e, err := pa.Explain("netId", "area", "shifts", "edit", ctx)
+	if err != nil {
+		t.Errorf("An unexpected error occurred while explaining a permission %v", err)
+	}
+
+	expected := Explanation{
+		Actors: []string{"g1", "area", "netId"},
+		Grants: []Grant{{Actor: "g1", Source: "policy", Resource: "shifts", Condition: "time.hour >= 8 && time.hour < 17"}},
+	}
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("Expected %v but got %v", expected, e)
+	}
+
+	if err := pa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
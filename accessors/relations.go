@@ -0,0 +1,289 @@
+package accessors
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/relations"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Namespaces backed by the existing tables rather than the relationTuple
+// table, so that the relationship model and the policy model answer the
+// same questions the same way:
+//
+//	group:<guid>#member@<netId>               groupMembers
+//	area:<area>#member@group:<guid>#member    the area's groups
+//	resource:<guid>#<verb>@<actor>            unconditional policy rows and
+//	                                          roles bound to the actor,
+//	                                          with group and area actors as
+//	                                          their #member usersets
+//
+// Superusers and admins are not part of either model's CheckPermission.
+var virtualNamespaces = map[string]relations.Namespace{
+	"group":    relations.Namespace{Name: "group", Relations: map[string]relations.Rewrite{"member": relations.Rewrite{}}},
+	"area":     relations.Namespace{Name: "area", Relations: map[string]relations.Rewrite{"member": relations.Rewrite{}}},
+	"resource": relations.Namespace{Name: "resource", AnyRelation: true},
+}
+
+var ErrVirtualNamespace = errors.New("tuples in this namespace are managed through groups and permissions")
+
+// Tells whether tuples in the namespace come from the existing tables.
+func IsVirtualNamespace(name string) bool {
+	_, ok := virtualNamespaces[name]
+	return ok
+}
+
+// Reads and writes relationship tuples. Implements relations.Store.
+type TupleAccessor struct {
+	DB *sql.DB // Database connection
+}
+
+// Returns a new tuple accessor.
+func NewTupleAccessor(db *sql.DB) *TupleAccessor {
+	return &TupleAccessor{db}
+}
+
+// Store a tuple.
+func (ta *TupleAccessor) Add(t relations.Tuple) error {
+	if ns, _ := relations.SplitObject(t.Object); IsVirtualNamespace(ns) {
+		return ErrVirtualNamespace
+	}
+
+	subject := t.Subject.String()
+	_, err := execEvent(ta.DB, EventTupleAdd, EventData{Object: t.Object, Relation: t.Relation, Subject: subject}, "INSERT INTO relationTuple (object, relation, subject) VALUES (?,?,?)", t.Object, t.Relation, subject)
+	return err
+}
+
+// Remove a tuple.
+func (ta *TupleAccessor) Delete(t relations.Tuple) error {
+	if ns, _ := relations.SplitObject(t.Object); IsVirtualNamespace(ns) {
+		return ErrVirtualNamespace
+	}
+
+	subject := t.Subject.String()
+	_, err := execEvent(ta.DB, EventTupleRemove, EventData{Object: t.Object, Relation: t.Relation, Subject: subject}, "DELETE FROM relationTuple WHERE object=? AND relation=? AND subject=?", t.Object, t.Relation, subject)
+	return err
+}
+
+// Gets a namespace's configuration.
+func (ta *TupleAccessor) Namespace(name string) (relations.Namespace, error) {
+	if n, ok := virtualNamespaces[name]; ok {
+		return n, nil
+	}
+
+	n := relations.Namespace{}
+	stmt, err := ta.DB.Prepare("SELECT config FROM relationNamespace WHERE name=?")
+	if err != nil {
+		return n, err
+	}
+
+	var config string
+	err = stmt.QueryRow(name).Scan(&config)
+	if err == sql.ErrNoRows {
+		return n, relations.ErrUnknownNamespace
+	}
+	if err != nil {
+		return n, err
+	}
+
+	if err := json.Unmarshal([]byte(config), &n); err != nil {
+		return n, fmt.Errorf("namespace %s has a malformed config: %v", name, err)
+	}
+	n.Name = name
+	return n, nil
+}
+
+// Create or replace a namespace's configuration.
+func (ta *TupleAccessor) SetNamespace(n relations.Namespace) error {
+	if IsVirtualNamespace(n.Name) {
+		return ErrVirtualNamespace
+	}
+
+	config, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	_, err = execEvent(ta.DB, EventNamespaceSet, EventData{Name: n.Name}, "INSERT INTO relationNamespace (name, config) VALUES (?,?) ON DUPLICATE KEY UPDATE config=VALUES(config)", n.Name, string(config))
+	return err
+}
+
+// Gets the subjects directly related to object by relation.
+func (ta *TupleAccessor) Read(object, relation string) ([]relations.Subject, error) {
+	ns, id := relations.SplitObject(object)
+	switch ns {
+	case "group":
+		return ta.readGroup(id, relation)
+	case "area":
+		return ta.readArea(id, relation)
+	case "resource":
+		return ta.readResource(id, relation)
+	}
+
+	subjects := make([]relations.Subject, 0)
+	stmt, err := ta.DB.Prepare("SELECT subject FROM relationTuple WHERE object=? AND relation=?")
+	if err != nil {
+		return subjects, err
+	}
+
+	rows, err := stmt.Query(object, relation)
+	if err != nil {
+		return subjects, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var subject string
+		rows.Scan(&subject)
+		s, err := relations.ParseSubject(subject)
+		if err != nil {
+			return subjects, err
+		}
+		subjects = append(subjects, s)
+	}
+
+	return subjects, nil
+}
+
+// group:<guid>#member is the group's members.
+func (ta *TupleAccessor) readGroup(guid, relation string) ([]relations.Subject, error) {
+	subjects := make([]relations.Subject, 0)
+	if relation != "member" {
+		return subjects, nil
+	}
+
+	members, err := NewMembersAccessor(ta.DB).GetGroupMembers(guid)
+	if err != nil {
+		return subjects, err
+	}
+	for _, netId := range members {
+		subjects = append(subjects, relations.Subject{User: netId})
+	}
+	return subjects, nil
+}
+
+// area:<area>#member is the members of every group in the area, which is
+// who area level policy rows apply to, along with the members of child
+// areas that inherit the area's rows.
+func (ta *TupleAccessor) readArea(area, relation string) ([]relations.Subject, error) {
+	subjects := make([]relations.Subject, 0)
+	if relation != "member" {
+		return subjects, nil
+	}
+
+	groups, err := NewGroupAccessor(ta.DB).GetByArea(area)
+	if err != nil {
+		return subjects, err
+	}
+	for _, g := range groups {
+		subjects = append(subjects, relations.Subject{Object: "group:" + g.Guid, Relation: "member"})
+	}
+
+	children, err := NewAreaAccessor(ta.DB).Children(area)
+	if err != nil {
+		return subjects, err
+	}
+	for _, child := range children {
+		if child.Inherit {
+			subjects = append(subjects, relations.Subject{Object: "area:" + child.Guid, Relation: "member"})
+		}
+	}
+	return subjects, nil
+}
+
+// resource:<guid>#<verb> is everyone granted verb on the resource. Rows
+// with conditions or limited to one area are left out since tuples can't
+// carry them.
+func (ta *TupleAccessor) readResource(resource, verb string) ([]relations.Subject, error) {
+	subjects := make([]relations.Subject, 0)
+
+	stmt, err := ta.DB.Prepare("SELECT policy.actor FROM policy LEFT JOIN policyCondition ON policyCondition.actor = policy.actor AND policyCondition.verb = policy.verb AND policyCondition.resource = policy.resource LEFT JOIN policyArea ON policyArea.actor = policy.actor AND policyArea.verb = policy.verb AND policyArea.resource = policy.resource WHERE policy.verb=? AND policy.resource=? AND policyCondition.actor IS NULL AND policyArea.actor IS NULL")
+	if err != nil {
+		return subjects, err
+	}
+	rows, err := stmt.Query(verb, resource)
+	if err != nil {
+		return subjects, err
+	}
+	actors := make([]string, 0)
+	for rows.Next() {
+		var actor string
+		rows.Scan(&actor)
+		actors = append(actors, actor)
+	}
+	rows.Close()
+
+	// Actors bound to a role covering the resource
+	stmt, err = ta.DB.Prepare("SELECT roleBinding.actor, rolePermission.resource FROM roleBinding JOIN rolePermission ON roleBinding.roleGuid = rolePermission.roleGuid WHERE rolePermission.verb=?")
+	if err != nil {
+		return subjects, err
+	}
+	rows, err = stmt.Query(verb)
+	if err != nil {
+		return subjects, err
+	}
+	for rows.Next() {
+		var actor, pattern string
+		rows.Scan(&actor, &pattern)
+		if MatchResource(pattern, resource) {
+			actors = append(actors, actor)
+		}
+	}
+	rows.Close()
+
+	if len(actors) == 0 {
+		return subjects, nil
+	}
+
+	// Find out which actors are groups and which are areas
+	query := "SELECT guid, area FROM groups WHERE guid IN (?"
+	params := []interface{}{actors[0]}
+	for i := 1; i < len(actors); i++ {
+		query += ",?"
+		params = append(params, actors[i])
+	}
+	query += ") OR area IN (?"
+	params = append(params, actors[0])
+	for i := 1; i < len(actors); i++ {
+		query += ",?"
+		params = append(params, actors[i])
+	}
+	query += ")"
+	stmt, err = ta.DB.Prepare(query)
+	if err != nil {
+		return subjects, err
+	}
+	rows, err = stmt.Query(params...)
+	if err != nil {
+		return subjects, err
+	}
+	groups := make(map[string]bool)
+	areas := make(map[string]bool)
+	for rows.Next() {
+		var guid, area string
+		rows.Scan(&guid, &area)
+		groups[guid] = true
+		areas[area] = true
+	}
+	rows.Close()
+
+	seen := make(map[string]bool)
+	for _, actor := range actors {
+		if seen[actor] {
+			continue
+		}
+		seen[actor] = true
+
+		switch {
+		case groups[actor]:
+			subjects = append(subjects, relations.Subject{Object: "group:" + actor, Relation: "member"})
+		case areas[actor]:
+			subjects = append(subjects, relations.Subject{Object: "area:" + actor, Relation: "member"})
+		default:
+			subjects = append(subjects, relations.Subject{User: actor})
+		}
+	}
+	return subjects, nil
+}
@@ -0,0 +1,99 @@
+package accessors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/byu-oit-ssengineering/tmt-permissions/relations"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestReadResourceTuples(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a tuple accessor %v", err)
+		return
+	}
+
+	ta := NewTupleAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policyCondition.actor IS NULL AND policyArea.actor IS NULL").
+		WithArgs("edit", "res").
+		WillReturnRows(sqlmock.NewRows([]string{"actor"}).FromCSVString("g1\ncarol\narea"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE rolePermission.verb=.").
+		WithArgs("edit").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "resource"}).FromCSVString("g2,*\ng3,other"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, area FROM groups WHERE guid IN .+ OR area IN .+").
+		WithArgs("g1", "carol", "area", "g2", "g1", "carol", "area", "g2").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area"}).FromCSVString("g1,area\ng2,area"))
+
+	subjects, err := ta.Read("resource:res", "edit")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while reading tuples %v", err)
+	}
+
+	expected := []relations.Subject{
+		relations.Subject{Object: "group:g1", Relation: "member"},
+		relations.Subject{User: "carol"},
+		relations.Subject{Object: "area:area", Relation: "member"},
+		relations.Subject{Object: "group:g2", Relation: "member"},
+	}
+	if !reflect.DeepEqual(subjects, expected) {
+		t.Errorf("Expected %v but got %v", expected, subjects)
+	}
+
+	if err := ta.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestReadStoredTuples(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a tuple accessor %v", err)
+		return
+	}
+
+	ta := NewTupleAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT subject FROM relationTuple WHERE object=. AND relation=.").
+		WithArgs("doc:readme", "editor").
+		WillReturnRows(sqlmock.NewRows([]string{"subject"}).FromCSVString("alice\ngroup:g1#member"))
+
+	subjects, err := ta.Read("doc:readme", "editor")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while reading tuples %v", err)
+	}
+
+	expected := []relations.Subject{
+		relations.Subject{User: "alice"},
+		relations.Subject{Object: "group:g1", Relation: "member"},
+	}
+	if !reflect.DeepEqual(subjects, expected) {
+		t.Errorf("Expected %v but got %v", expected, subjects)
+	}
+
+	if err := ta.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestAddVirtualTuple(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a tuple accessor %v", err)
+		return
+	}
+
+	ta := NewTupleAccessor(db)
+
+	tuple, _ := relations.ParseTuple("group:g1#member@alice")
+	if err := ta.Add(tuple); err != ErrVirtualNamespace {
+		t.Errorf("Expected ErrVirtualNamespace but got %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package accessors
+
+import (
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// States of a review campaign.
+const (
+	ReviewOpen   = "open"
+	ReviewClosed = "closed"
+)
+
+// Kinds of access snapshotted into a campaign.
+const (
+	ReviewMembership = "membership"
+	ReviewPolicy     = "policy"
+)
+
+// Decisions a reviewer can make on an item. Items left undecided when the
+// campaign closes are treated as revoked.
+const (
+	ReviewKeep   = "keep"
+	ReviewRevoke = "revoke"
+)
+
+// ReviewCampaign struct that reflects the reviewCampaign table. Deadline
+// is a unix time.
+type ReviewCampaign struct {
+	Guid     string
+	Area     string
+	Creator  string
+	Deadline int64
+	Status   string
+}
+
+// ReviewItem struct that reflects the reviewItem table. Membership items
+// use NetId/Group, policy items use Group (the actor) and Verb/Resource.
+// Reviewer is the group owner assigned to the item, or empty if it falls
+// to the area's admins.
+type ReviewItem struct {
+	Guid      string
+	Campaign  string
+	Type      string
+	NetId     string
+	Group     string
+	Verb      string
+	Resource  string
+	Reviewer  string
+	Decision  string
+	DecidedBy string
+}
+
+type ReviewAccessor struct {
+	DB *sql.DB // Database connection
+}
+
+// Returns a new review accessor.
+func NewReviewAccessor(db *sql.DB) *ReviewAccessor {
+	return &ReviewAccessor{db}
+}
+
+const reviewCampaignColumns = "guid, area, creator, deadline, status"
+const reviewItemColumns = "guid, campaign, type, netId, groupGuid, verb, resource, reviewer, decision, decidedBy"
+
+// Start a campaign for an area, snapshotting every membership of and
+// every policy row granted to the area's groups. Each item is assigned to
+// one of its group's owners, but never to the member it is about; items
+// with nobody to assign them to fall to the area's admins.
+func (ra *ReviewAccessor) Start(area, creator string, deadline int64) (ReviewCampaign, error) {
+	campaign := ReviewCampaign{NewGuid(), area, creator, deadline, ReviewOpen}
+
+	owners := make(map[string][]string)
+	rows, err := ra.DB.Query("SELECT groupOwners.groupGuid, groupOwners.netId FROM groupOwners JOIN groups ON groupOwners.groupGuid = groups.guid WHERE groups.area=? ORDER BY groupOwners.netId", area)
+	if err != nil {
+		return campaign, err
+	}
+	for rows.Next() {
+		var group, owner string
+		if err := rows.Scan(&group, &owner); err != nil {
+			rows.Close()
+			return campaign, err
+		}
+		owners[group] = append(owners[group], owner)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return campaign, err
+	}
+
+	items := make([]ReviewItem, 0)
+	rows, err = ra.DB.Query("SELECT groupMembers.netId, groupMembers.groupGuid FROM groupMembers JOIN groups ON groupMembers.groupGuid = groups.guid WHERE groups.area=?", area)
+	if err != nil {
+		return campaign, err
+	}
+	for rows.Next() {
+		i := ReviewItem{Type: ReviewMembership}
+		if err := rows.Scan(&i.NetId, &i.Group); err != nil {
+			rows.Close()
+			return campaign, err
+		}
+		items = append(items, i)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return campaign, err
+	}
+
+	rows, err = ra.DB.Query("SELECT policy.actor, policy.verb, policy.resource FROM policy JOIN groups ON policy.actor = groups.guid WHERE groups.area=?", area)
+	if err != nil {
+		return campaign, err
+	}
+	for rows.Next() {
+		i := ReviewItem{Type: ReviewPolicy}
+		if err := rows.Scan(&i.Group, &i.Verb, &i.Resource); err != nil {
+			rows.Close()
+			return campaign, err
+		}
+		items = append(items, i)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return campaign, err
+	}
+
+	for n := range items {
+		for _, owner := range owners[items[n].Group] {
+			if owner != items[n].NetId {
+				items[n].Reviewer = owner
+				break
+			}
+		}
+	}
+
+	// Store the campaign and its items together
+	tx, err := ra.DB.Begin()
+	if err != nil {
+		return campaign, err
+	}
+
+	_, err = tx.Exec("INSERT INTO reviewCampaign ("+reviewCampaignColumns+") VALUES (?,?,?,?,?)", campaign.Guid, campaign.Area, campaign.Creator, campaign.Deadline, campaign.Status)
+	if err != nil {
+		tx.Rollback()
+		return campaign, err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO reviewItem (" + reviewItemColumns + ") VALUES (?,?,?,?,?,?,?,?,?,?)")
+	if err != nil {
+		tx.Rollback()
+		return campaign, err
+	}
+	defer stmt.Close()
+	for _, i := range items {
+		_, err = stmt.Exec(NewGuid(), campaign.Guid, i.Type, i.NetId, i.Group, i.Verb, i.Resource, i.Reviewer, "", "")
+		if err != nil {
+			tx.Rollback()
+			return campaign, err
+		}
+	}
+
+	return campaign, tx.Commit()
+}
+
+func (ra *ReviewAccessor) queryCampaigns(query string, args ...interface{}) ([]ReviewCampaign, error) {
+	campaigns := make([]ReviewCampaign, 0)
+	stmt, err := ra.DB.Prepare(query)
+	if err != nil {
+		return campaigns, err
+	}
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return campaigns, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c := ReviewCampaign{}
+		err = rows.Scan(&c.Guid, &c.Area, &c.Creator, &c.Deadline, &c.Status)
+		campaigns = append(campaigns, c)
+	}
+
+	return campaigns, nil
+}
+
+// Gets the campaign with the given id.
+func (ra *ReviewAccessor) GetCampaign(guid string) (ReviewCampaign, error) {
+	c := ReviewCampaign{}
+	stmt, err := ra.DB.Prepare("SELECT " + reviewCampaignColumns + " FROM reviewCampaign WHERE guid=?")
+	if err != nil {
+		return c, err
+	}
+
+	err = stmt.QueryRow(guid).Scan(&c.Guid, &c.Area, &c.Creator, &c.Deadline, &c.Status)
+	return c, err
+}
+
+// Gets all campaigns for an area.
+func (ra *ReviewAccessor) GetCampaigns(area string) ([]ReviewCampaign, error) {
+	return ra.queryCampaigns("SELECT "+reviewCampaignColumns+" FROM reviewCampaign WHERE area=?", area)
+}
+
+// Gets open campaigns whose deadline is at or before the given unix time.
+func (ra *ReviewAccessor) GetDue(now int64) ([]ReviewCampaign, error) {
+	return ra.queryCampaigns("SELECT "+reviewCampaignColumns+" FROM reviewCampaign WHERE status=? AND deadline<=?", ReviewOpen, now)
+}
+
+// Gets every item in a campaign.
+func (ra *ReviewAccessor) GetItems(campaign string) ([]ReviewItem, error) {
+	items := make([]ReviewItem, 0)
+	stmt, err := ra.DB.Prepare("SELECT " + reviewItemColumns + " FROM reviewItem WHERE campaign=?")
+	if err != nil {
+		return items, err
+	}
+
+	rows, err := stmt.Query(campaign)
+	if err != nil {
+		return items, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		i := ReviewItem{}
+		err = rows.Scan(&i.Guid, &i.Campaign, &i.Type, &i.NetId, &i.Group, &i.Verb, &i.Resource, &i.Reviewer, &i.Decision, &i.DecidedBy)
+		items = append(items, i)
+	}
+
+	return items, nil
+}
+
+// Gets the item with the given id.
+func (ra *ReviewAccessor) GetItem(guid string) (ReviewItem, error) {
+	i := ReviewItem{}
+	stmt, err := ra.DB.Prepare("SELECT " + reviewItemColumns + " FROM reviewItem WHERE guid=?")
+	if err != nil {
+		return i, err
+	}
+
+	err = stmt.QueryRow(guid).Scan(&i.Guid, &i.Campaign, &i.Type, &i.NetId, &i.Group, &i.Verb, &i.Resource, &i.Reviewer, &i.Decision, &i.DecidedBy)
+	return i, err
+}
+
+// Record a reviewer's decision on an item.
+func (ra *ReviewAccessor) Decide(guid, decision, decidedBy string) error {
+	stmt, err := ra.DB.Prepare("UPDATE reviewItem SET decision=?, decidedBy=? WHERE guid=?")
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec(decision, decidedBy, guid)
+	return err
+}
+
+// Close an open campaign, revoking the given items, in one transaction so
+// that a failure leaves the campaign open with nothing revoked. Returns
+// false if the campaign had already been closed.
+func (ra *ReviewAccessor) Close(guid string, revoke []ReviewItem) (bool, error) {
+	tx, err := ra.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := tx.Exec("UPDATE reviewCampaign SET status=? WHERE guid=? AND status=?", ReviewClosed, guid, ReviewOpen)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		tx.Rollback()
+		return false, err
+	}
+
+	for _, i := range revoke {
+		if i.Type == ReviewMembership {
+			_, err = execPublishing(tx, EventMemberRemove, EventData{Group: i.Group, NetId: i.NetId}, removeMemberQuery, i.NetId, i.Group)
+		} else {
+			_, err = revokeTx(tx, i.Group, i.Verb, i.Resource)
+		}
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
@@ -0,0 +1,96 @@
+package accessors
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestStartReview(t *testing.T) {
+	NewGuid = func() string {
+		return "guid"
+	}
+
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a review accessor %v", err)
+		return
+	}
+
+	ra := NewReviewAccessor(db)
+
+	sqlmock.ExpectQuery("SELECT groupOwners.groupGuid, groupOwners.netId FROM groupOwners JOIN groups .+ WHERE groups.area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"groupGuid", "netId"}).FromCSVString("g1,owner\ng2,user2"))
+	sqlmock.ExpectQuery("SELECT groupMembers.netId, groupMembers.groupGuid FROM groupMembers JOIN groups .+ WHERE groups.area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"netId", "groupGuid"}).FromCSVString("user1,g1\nuser2,g2"))
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource FROM policy JOIN groups .+ WHERE groups.area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString("g1,edit,res"))
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO reviewCampaign .+ VALUES .+").
+		WithArgs("guid", "area", "admin", 1000, "open").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectExec("INSERT INTO reviewItem .+ VALUES .+").
+		WithArgs("guid", "guid", "membership", "user1", "g1", "", "", "owner", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO reviewItem .+ VALUES .+").
+		WithArgs("guid", "guid", "membership", "user2", "g2", "", "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO reviewItem .+ VALUES .+").
+		WithArgs("guid", "guid", "policy", "", "g1", "edit", "res", "owner", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	campaign, err := ra.Start("area", "admin", 1000)
+	if err != nil {
+		t.Errorf("An unexpected error occurred while starting a review: %v", err)
+	}
+
+	expected := ReviewCampaign{"guid", "area", "admin", 1000, "open"}
+	if campaign != expected {
+		t.Errorf("Expected %v but got %v", expected, campaign)
+	}
+
+	if err := ra.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestGetReviewItems(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a review accessor %v", err)
+		return
+	}
+
+	ra := NewReviewAccessor(db)
+
+	expected := []ReviewItem{
+		ReviewItem{"i1", "c1", "membership", "user1", "g1", "", "", "owner", "keep", "owner"},
+		ReviewItem{"i2", "c1", "policy", "", "g1", "edit", "res", "owner", "", ""},
+	}
+	columns := []string{"guid", "campaign", "type", "netId", "groupGuid", "verb", "resource", "reviewer", "decision", "decidedBy"}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM reviewItem WHERE campaign=.").
+		WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("i1,c1,membership,user1,g1,,,owner,keep,owner\ni2,c1,policy,,g1,edit,res,owner,,"))
+
+	items, err := ra.GetItems("c1")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while getting review items %v", err)
+	}
+
+	for i := 0; i < len(items); i++ {
+		if items[i] != expected[i] {
+			t.Errorf("Expected %v but got %v", expected, items)
+		}
+	}
+
+	if err := ra.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
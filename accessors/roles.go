@@ -0,0 +1,225 @@
+package accessors
+
+import (
+	"database/sql"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/pdp"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Role struct that reflects the role table. A role is a named set of
+// verb/resource-pattern pairs (the rolePermission table) that can be bound
+// to groups, users or areas (the roleBinding table) in place of copying
+// the same policy rows to each of them.
+type Role struct {
+	Guid string
+	Area string
+	Name string
+}
+
+// Adding a permission to a role and binding a role to an actor.
+const (
+	addRolePermissionQuery = "INSERT INTO rolePermission (roleGuid, verb, resource) VALUES (?,?,?)"
+	bindRoleQuery          = "INSERT INTO roleBinding (roleGuid, actor) VALUES (?,?)"
+)
+
+type RoleAccessor struct {
+	DB *sql.DB // Database connection
+}
+
+// Returns a new role accessor.
+func NewRoleAccessor(db *sql.DB) *RoleAccessor {
+	return &RoleAccessor{db}
+}
+
+// Tells whether a role's resource pattern covers a resource. Patterns use
+// path.Match syntax, so "*" matches any resource.
+func MatchResource(pattern, resource string) bool {
+	return pdp.MatchResource(pattern, resource)
+}
+
+// Create a new role and return its guid.
+func (ra *RoleAccessor) Create(role Role) (string, error) {
+	stmt, err := ra.DB.Prepare("INSERT INTO role (guid, area, name) VALUES (?,?,?)")
+	if err != nil {
+		return "", err
+	}
+
+	guid := NewGuid()
+	_, err = stmt.Exec(guid, role.Area, role.Name)
+	return guid, err
+}
+
+// Gets the role with the given id.
+func (ra *RoleAccessor) Get(guid string) (Role, error) {
+	r := Role{}
+	stmt, err := ra.DB.Prepare("SELECT guid, area, name FROM role WHERE guid=?")
+	if err != nil {
+		return r, err
+	}
+
+	err = stmt.QueryRow(guid).Scan(&r.Guid, &r.Area, &r.Name)
+	return r, err
+}
+
+// Gets all the roles in an area.
+func (ra *RoleAccessor) GetByArea(area string) ([]Role, error) {
+	roles := make([]Role, 0)
+	stmt, err := ra.DB.Prepare("SELECT guid, area, name FROM role WHERE area=?")
+	if err != nil {
+		return roles, err
+	}
+
+	rows, err := stmt.Query(area)
+	if err != nil {
+		return roles, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := Role{}
+		err = rows.Scan(&r.Guid, &r.Area, &r.Name)
+		roles = append(roles, r)
+	}
+
+	return roles, nil
+}
+
+// Delete a role along with its permissions and bindings.
+func (ra *RoleAccessor) Delete(guid string) error {
+	tx, err := ra.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		"DELETE FROM roleBinding WHERE roleGuid=?",
+		"DELETE FROM rolePermission WHERE roleGuid=?",
+	} {
+		if _, err := tx.Exec(query, guid); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := execPublishing(tx, EventRoleDelete, EventData{Role: guid}, "DELETE FROM role WHERE guid=?", guid); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Gets the verb/resource-pattern pairs of a role. The role is the Actor.
+func (ra *RoleAccessor) GetPermissions(guid string) ([]Permission, error) {
+	perms := make([]Permission, 0)
+	stmt, err := ra.DB.Prepare("SELECT roleGuid, verb, resource FROM rolePermission WHERE roleGuid=?")
+	if err != nil {
+		return perms, err
+	}
+
+	rows, err := stmt.Query(guid)
+	if err != nil {
+		return perms, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Permission
+		err = rows.Scan(&p.Actor, &p.Verb, &p.Resource)
+		perms = append(perms, p)
+	}
+
+	return perms, nil
+}
+
+// Add a verb/resource-pattern pair to a role.
+func (ra *RoleAccessor) AddPermission(guid, verb, resource string) error {
+	_, err := execEvent(ra.DB, EventRolePermissionAdd, EventData{Role: guid, Verb: verb, Resource: resource}, addRolePermissionQuery, guid, verb, resource)
+	return err
+}
+
+// Remove a verb/resource-pattern pair from a role.
+func (ra *RoleAccessor) RemovePermission(guid, verb, resource string) error {
+	_, err := execEvent(ra.DB, EventRolePermissionRemove, EventData{Role: guid, Verb: verb, Resource: resource}, "DELETE FROM rolePermission WHERE roleGuid=? AND verb=? AND resource=?", guid, verb, resource)
+	return err
+}
+
+// Gets the actors a role is bound to.
+func (ra *RoleAccessor) GetBindings(guid string) ([]string, error) {
+	actors := make([]string, 0)
+	stmt, err := ra.DB.Prepare("SELECT actor FROM roleBinding WHERE roleGuid=?")
+	if err != nil {
+		return actors, err
+	}
+
+	rows, err := stmt.Query(guid)
+	if err != nil {
+		return actors, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var actor string
+		rows.Scan(&actor)
+		actors = append(actors, actor)
+	}
+
+	return actors, nil
+}
+
+// Bind a role to a group, user or area.
+func (ra *RoleAccessor) Bind(guid, actor string) error {
+	_, err := execEvent(ra.DB, EventRoleBind, EventData{Role: guid, Actor: actor}, bindRoleQuery, guid, actor)
+	return err
+}
+
+// Remove a role from a group, user or area.
+func (ra *RoleAccessor) Unbind(guid, actor string) error {
+	_, err := execEvent(ra.DB, EventRoleUnbind, EventData{Role: guid, Actor: actor}, "DELETE FROM roleBinding WHERE roleGuid=? AND actor=?", guid, actor)
+	return err
+}
+
+// Expand the roles of an area bound to any of the actors into the
+// permissions they grant. Roles of other areas don't apply there, even
+// when bound to one of the actors. Each permission's Actor is the bound
+// actor and its Resource may be a pattern.
+func (ra *RoleAccessor) GetBoundPermissions(area string, actors []string) ([]Permission, error) {
+	if len(actors) == 0 {
+		return make([]Permission, 0), nil
+	}
+
+	// build query and params
+	query := "SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission ON roleBinding.roleGuid = rolePermission.roleGuid JOIN role ON role.guid = roleBinding.roleGuid WHERE role.area=? AND roleBinding.actor IN (?"
+	params := []interface{}{area, actors[0]}
+	for i := 1; i < len(actors); i++ {
+		query += ",?"
+		params = append(params, actors[i])
+	}
+	query += ")"
+
+	return ra.queryBound(query, params...)
+}
+
+// Expand the roles bound to a group into the permissions they grant. Only
+// roles of the group's own area apply.
+func (ra *RoleAccessor) GetGroupBoundPermissions(group string) ([]Permission, error) {
+	return ra.queryBound("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission ON roleBinding.roleGuid = rolePermission.roleGuid JOIN role ON role.guid = roleBinding.roleGuid JOIN groups ON groups.guid = roleBinding.actor WHERE roleBinding.actor=? AND role.area = groups.area", group)
+}
+
+func (ra *RoleAccessor) queryBound(query string, args ...interface{}) ([]Permission, error) {
+	perms := make([]Permission, 0)
+	stmt, err := ra.DB.Prepare(query)
+	if err != nil {
+		return perms, err
+	}
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return perms, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Permission
+		err = rows.Scan(&p.Actor, &p.Verb, &p.Resource)
+		perms = append(perms, p)
+	}
+
+	return perms, nil
+}
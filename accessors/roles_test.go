@@ -0,0 +1,97 @@
+package accessors
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestMatchResource(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		resource string
+		match    bool
+	}{
+		{"res", "res", true},
+		{"res", "other", false},
+		{"*", "anything", true},
+		{"report-*", "report-2016", true},
+		{"report-*", "invoice-2016", false},
+		{"[", "[", true},
+		{"[", "x", false},
+	}
+
+	for _, c := range cases {
+		if MatchResource(c.pattern, c.resource) != c.match {
+			t.Errorf("Expected MatchResource(%q, %q) to be %v", c.pattern, c.resource, c.match)
+		}
+	}
+}
+
+func TestGetBoundPermissions(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a role accessor %v", err)
+		return
+	}
+
+	ra := NewRoleAccessor(db)
+
+	expected := []Permission{Permission{"g1", "edit", "report-*", ""}, Permission{"netId", "view", "*", ""}}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "area", "netId", "g1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString("g1,edit,report-*\nnetId,view,*"))
+
+	perms, err := ra.GetBoundPermissions("area", []string{"area", "netId", "g1"})
+	if err != nil {
+		t.Errorf("An unexpected error occurred while expanding roles %v", err)
+	}
+
+	if len(perms) != len(expected) {
+		t.Errorf("Expected %v but got %v", expected, perms)
+		return
+	}
+	for i := 0; i < len(perms); i++ {
+		if perms[i] != expected[i] {
+			t.Errorf("Expected %v but got %v", expected, perms)
+		}
+	}
+
+	if err := ra.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
+
+func TestDeleteRole(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a role accessor %v", err)
+		return
+	}
+
+	ra := NewRoleAccessor(db)
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("DELETE FROM roleBinding WHERE roleGuid=.").
+		WithArgs("r1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlmock.ExpectExec("DELETE FROM rolePermission WHERE roleGuid=.").
+		WithArgs("r1").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	sqlmock.ExpectExec("DELETE FROM role WHERE guid=.").
+		WithArgs("r1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	if err := ra.Delete("r1"); err != nil {
+		t.Errorf("An unexpected error occurred while deleting a role %v", err)
+	}
+
+	if err := ra.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
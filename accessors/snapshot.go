@@ -0,0 +1,123 @@
+package accessors
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/pdp"
+)
+
+// The queries a snapshot is read with, in order.
+const (
+	snapshotSeq        = "SELECT COALESCE(MAX(seq), 0) FROM event"
+	snapshotAreas      = "SELECT guid, parent, inherit FROM areas"
+	snapshotSuperusers = "SELECT netId FROM superuser WHERE active=1"
+	snapshotAdmins     = "SELECT netId, area FROM admin"
+	snapshotMembers    = "SELECT groups.guid, groups.area, groupMembers.netId FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid UNION SELECT groups.guid, groups.area, groupRuleMember.netId FROM groups JOIN groupRuleMember ON groups.guid = groupRuleMember.groupGuid"
+	snapshotPolicies   = "SELECT policy.actor, policy.verb, policy.resource, COALESCE(policyCondition.expression, ''), COALESCE(policyArea.area, '') FROM policy LEFT JOIN policyCondition ON policyCondition.actor = policy.actor AND policyCondition.verb = policy.verb AND policyCondition.resource = policy.resource LEFT JOIN policyArea ON policyArea.actor = policy.actor AND policyArea.verb = policy.verb AND policyArea.resource = policy.resource"
+	snapshotBindings   = "SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource, role.area FROM roleBinding JOIN rolePermission ON roleBinding.roleGuid = rolePermission.roleGuid JOIN role ON role.guid = roleBinding.roleGuid"
+)
+
+// Reads everything permission decisions are made from, for services that
+// make them in-process with the pdp package. The reads share a transaction
+// so the snapshot is consistent, and it notes the latest event in the change
+// feed so readers can follow the feed from there.
+func (pa *PermissionAccessor) Snapshot() (pdp.Snapshot, error) {
+	s := pdp.Snapshot{
+		Taken:      time.Now().UTC(),
+		Areas:      make([]pdp.Area, 0),
+		Superusers: make([]string, 0),
+		Admins:     make([]pdp.Admin, 0),
+		Members:    make([]pdp.Member, 0),
+		Policies:   make([]pdp.Policy, 0),
+		Bindings:   make([]pdp.Binding, 0),
+	}
+
+	tx, err := pa.DB.Begin()
+	if err != nil {
+		return s, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow(snapshotSeq).Scan(&s.Seq); err != nil {
+		return s, err
+	}
+
+	err = snapshotQuery(tx, snapshotAreas, func(rows *sql.Rows) error {
+		var a pdp.Area
+		err := rows.Scan(&a.Guid, &a.Parent, &a.Inherit)
+		s.Areas = append(s.Areas, a)
+		return err
+	})
+	if err != nil {
+		return s, err
+	}
+
+	err = snapshotQuery(tx, snapshotSuperusers, func(rows *sql.Rows) error {
+		var netId string
+		err := rows.Scan(&netId)
+		s.Superusers = append(s.Superusers, netId)
+		return err
+	})
+	if err != nil {
+		return s, err
+	}
+
+	err = snapshotQuery(tx, snapshotAdmins, func(rows *sql.Rows) error {
+		var a pdp.Admin
+		err := rows.Scan(&a.NetId, &a.Area)
+		s.Admins = append(s.Admins, a)
+		return err
+	})
+	if err != nil {
+		return s, err
+	}
+
+	err = snapshotQuery(tx, snapshotMembers, func(rows *sql.Rows) error {
+		var m pdp.Member
+		err := rows.Scan(&m.Group, &m.Area, &m.NetId)
+		s.Members = append(s.Members, m)
+		return err
+	})
+	if err != nil {
+		return s, err
+	}
+
+	err = snapshotQuery(tx, snapshotPolicies, func(rows *sql.Rows) error {
+		var p pdp.Policy
+		err := rows.Scan(&p.Actor, &p.Verb, &p.Resource, &p.Condition, &p.Area)
+		s.Policies = append(s.Policies, p)
+		return err
+	})
+	if err != nil {
+		return s, err
+	}
+
+	err = snapshotQuery(tx, snapshotBindings, func(rows *sql.Rows) error {
+		var b pdp.Binding
+		err := rows.Scan(&b.Actor, &b.Verb, &b.Resource, &b.Area)
+		s.Bindings = append(s.Bindings, b)
+		return err
+	})
+	if err != nil {
+		return s, err
+	}
+
+	return s, tx.Commit()
+}
+
+// Runs a query in tx and passes each row to scan.
+func snapshotQuery(tx *sql.Tx, query string, scan func(*sql.Rows) error) error {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,62 @@
+package accessors
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/byu-oit-ssengineering/tmt-permissions/pdp"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestSnapshot(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a permission accessor %v", err)
+		return
+	}
+
+	pa := NewPermissionAccessor(db)
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectQuery(regexp.QuoteMeta(snapshotSeq)).
+		WillReturnRows(sqlmock.NewRows([]string{"seq"}).FromCSVString("42"))
+	sqlmock.ExpectQuery(regexp.QuoteMeta(snapshotAreas)).
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "parent", "inherit"}).FromCSVString("campus,,0\nlab,campus,1"))
+	sqlmock.ExpectQuery(regexp.QuoteMeta(snapshotSuperusers)).
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("root"))
+	sqlmock.ExpectQuery(regexp.QuoteMeta(snapshotAdmins)).
+		WillReturnRows(sqlmock.NewRows([]string{"netId", "area"}).FromCSVString("dana,campus"))
+	sqlmock.ExpectQuery(regexp.QuoteMeta(snapshotMembers)).
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "netId"}).FromCSVString("desk,lab,alice"))
+	sqlmock.ExpectQuery(regexp.QuoteMeta(snapshotPolicies)).
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource", "expression", "area"}).FromCSVString("desk,edit,shifts,time.hour >= 8,\ncampus,view,keys,,\nerin,edit,shifts,,lab"))
+	sqlmock.ExpectQuery(regexp.QuoteMeta(snapshotBindings)).
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource", "area"}).FromCSVString("desk,view,reports/*,lab"))
+	sqlmock.ExpectCommit()
+
+	s, err := pa.Snapshot()
+	if err != nil {
+		t.Errorf("An unexpected error occurred while reading a snapshot %v", err)
+	}
+
+	s.Taken = s.Taken.Truncate(0)
+	expected := pdp.Snapshot{
+		Taken:      s.Taken,
+		Seq:        42,
+		Areas:      []pdp.Area{{Guid: "campus"}, {Guid: "lab", Parent: "campus", Inherit: true}},
+		Superusers: []string{"root"},
+		Admins:     []pdp.Admin{{NetId: "dana", Area: "campus"}},
+		Members:    []pdp.Member{{Group: "desk", Area: "lab", NetId: "alice"}},
+		Policies:   []pdp.Policy{{Actor: "desk", Verb: "edit", Resource: "shifts", Condition: "time.hour >= 8"}, {Actor: "campus", Verb: "view", Resource: "keys"}, {Actor: "erin", Verb: "edit", Resource: "shifts", Area: "lab"}},
+		Bindings:   []pdp.Binding{{Actor: "desk", Verb: "view", Resource: "reports/*", Area: "lab"}},
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Expected %v but got %v", expected, s)
+	}
+
+	if err := pa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package accessors
+
+import (
+	"database/sql"
+	"sort"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// What happens when a grant would break a separation of duties rule.
+const (
+	SodBlock = "block"
+	SodWarn  = "warn"
+)
+
+// SodRule struct that reflects the sodRule table. Nobody in the area may
+// hold both VerbA and VerbB on the same resource.
+type SodRule struct {
+	Guid  string
+	Area  string
+	VerbA string
+	VerbB string
+	Mode  string
+}
+
+// A user holding both verbs of a rule on a resource.
+type SodViolation struct {
+	Rule     SodRule
+	NetId    string
+	Resource string
+}
+
+type SodAccessor struct {
+	DB *sql.DB // Database connection
+}
+
+// Returns a new separation of duties accessor.
+func NewSodAccessor(db *sql.DB) *SodAccessor {
+	return &SodAccessor{db}
+}
+
+// Create a new rule and return its guid.
+func (sa *SodAccessor) Add(rule SodRule) (string, error) {
+	stmt, err := sa.DB.Prepare("INSERT INTO sodRule (guid, area, verbA, verbB, mode) VALUES (?,?,?,?,?)")
+	if err != nil {
+		return "", err
+	}
+
+	guid := NewGuid()
+	_, err = stmt.Exec(guid, rule.Area, rule.VerbA, rule.VerbB, rule.Mode)
+	return guid, err
+}
+
+// Delete a rule.
+func (sa *SodAccessor) Delete(guid string) error {
+	stmt, err := sa.DB.Prepare("DELETE FROM sodRule WHERE guid=?")
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec(guid)
+	return err
+}
+
+// Gets the rule with the given id.
+func (sa *SodAccessor) Get(guid string) (SodRule, error) {
+	r := SodRule{}
+	stmt, err := sa.DB.Prepare("SELECT guid, area, verbA, verbB, mode FROM sodRule WHERE guid=?")
+	if err != nil {
+		return r, err
+	}
+
+	err = stmt.QueryRow(guid).Scan(&r.Guid, &r.Area, &r.VerbA, &r.VerbB, &r.Mode)
+	return r, err
+}
+
+// Gets all rules for an area.
+func (sa *SodAccessor) GetByArea(area string) ([]SodRule, error) {
+	rules := make([]SodRule, 0)
+	stmt, err := sa.DB.Prepare("SELECT guid, area, verbA, verbB, mode FROM sodRule WHERE area=?")
+	if err != nil {
+		return rules, err
+	}
+
+	rows, err := stmt.Query(area)
+	if err != nil {
+		return rules, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := SodRule{}
+		err = rows.Scan(&r.Guid, &r.Area, &r.VerbA, &r.VerbB, &r.Mode)
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// Returns the users a policy row for actor applies to: every member of the
+// area for area level rows, the group's members for groups, and otherwise
+// the user the actor names.
+func (sa *SodAccessor) Grantees(actor, area string) ([]string, error) {
+	ma := NewMembersAccessor(sa.DB)
+
+	if actor == area {
+		return ma.GetAreaMembers(area)
+	}
+
+	_, err := NewGroupAccessor(sa.DB).Get(actor)
+	if err == sql.ErrNoRows {
+		return []string{actor}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ma.GetGroupMembers(actor)
+}
+
+// The areas whose separation of duties rules a grant to actor falls under:
+// a group's area, the area itself for area level grants, and for a user
+// the areas of the groups they're in along with area, the area the grant
+// is made from, when it is set.
+func (sa *SodAccessor) GrantAreas(actor, area string) ([]string, error) {
+	ga := NewGroupAccessor(sa.DB)
+
+	group, err := ga.Get(actor)
+	if err == nil {
+		return []string{group.Area}, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	isArea, err := NewAreaAccessor(sa.DB).Exists(actor)
+	if err != nil {
+		return nil, err
+	}
+	if isArea {
+		return []string{actor}, nil
+	}
+
+	groups, err := NewMembersAccessor(sa.DB).GetUserGroups(actor)
+	if err != nil {
+		return nil, err
+	}
+	areas := make([]string, 0)
+	seen := make(map[string]bool)
+	if area != "" {
+		seen[area] = true
+		areas = append(areas, area)
+	}
+	for _, guid := range groups {
+		group, err := ga.Get(guid)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[group.Area] {
+			seen[group.Area] = true
+			areas = append(areas, group.Area)
+		}
+	}
+	return areas, nil
+}
+
+// Returns the violations of an area's rules that granting extra to the
+// users would introduce. Violations the users already had are left out
+// so that existing problems don't block unrelated grants.
+func (sa *SodAccessor) Violations(area string, users func() ([]string, error), extra []Permission) ([]SodViolation, error) {
+	pa := NewPermissionAccessor(sa.DB)
+
+	violations := make([]SodViolation, 0)
+	rules, err := sa.GetByArea(area)
+	if err != nil || len(rules) == 0 {
+		return violations, err
+	}
+
+	netIds, err := users()
+	if err != nil {
+		return violations, err
+	}
+
+	for _, netId := range netIds {
+		perms, err := pa.GetUserPermissions(netId, area)
+		if err != nil {
+			return violations, err
+		}
+
+		for _, v := range CheckSod(rules, netId, append(perms, extra...)) {
+			for _, e := range extra {
+				if MatchResource(e.Resource, v.Resource) && (e.Verb == v.Rule.VerbA || e.Verb == v.Rule.VerbB) {
+					violations = append(violations, v)
+					break
+				}
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// Returns the rules a user's permissions break, one violation per rule
+// and resource. Role permissions are resource patterns and count on every
+// resource they cover, so "approve" on "*" and "submit" on "invoice" break
+// a rule on "invoice".
+func CheckSod(rules []SodRule, netId string, perms []Permission) []SodViolation {
+	granted := make(map[string][]string) // resource or pattern -> verbs granted on it
+	for _, p := range perms {
+		granted[p.Resource] = append(granted[p.Resource], p.Verb)
+	}
+
+	resources := make([]string, 0, len(granted))
+	verbs := make(map[string]map[string]bool) // resource -> verbs held on it
+	for resource := range granted {
+		resources = append(resources, resource)
+		verbs[resource] = make(map[string]bool)
+		for pattern, vs := range granted {
+			if MatchResource(pattern, resource) {
+				for _, v := range vs {
+					verbs[resource][v] = true
+				}
+			}
+		}
+	}
+	sort.Strings(resources)
+
+	violations := make([]SodViolation, 0)
+	for _, rule := range rules {
+		for _, resource := range resources {
+			if verbs[resource][rule.VerbA] && verbs[resource][rule.VerbB] {
+				violations = append(violations, SodViolation{rule, netId, resource})
+			}
+		}
+	}
+
+	return violations
+}
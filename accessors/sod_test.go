@@ -0,0 +1,80 @@
+package accessors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestCheckSod(t *testing.T) {
+	rules := []SodRule{SodRule{"r1", "area", "approve-timesheet", "submit-timesheet", SodBlock}}
+	perms := []Permission{
+		Permission{"", "approve-timesheet", "ts1", ""},
+		Permission{"", "submit-timesheet", "ts1", ""},
+		Permission{"", "approve-timesheet", "ts2", ""},
+		Permission{"", "submit-timesheet", "ts3", ""},
+	}
+
+	violations := CheckSod(rules, "netId", perms)
+
+	if len(violations) != 1 {
+		t.Errorf("Expected one violation but got %v", violations)
+		return
+	}
+	if violations[0].Resource != "ts1" || violations[0].NetId != "netId" || violations[0].Rule != rules[0] {
+		t.Errorf("Expected a violation on ts1 but got %v", violations[0])
+	}
+}
+
+func TestCheckSodPatterns(t *testing.T) {
+	rules := []SodRule{SodRule{"r1", "area", "approve", "submit", SodBlock}}
+	perms := []Permission{
+		Permission{"", "approve", "*", ""},
+		Permission{"", "submit", "invoice", ""},
+		Permission{"", "submit", "report-*", ""},
+		Permission{"", "approve", "timesheet", ""},
+	}
+
+	violations := CheckSod(rules, "netId", perms)
+
+	resources := make([]string, 0)
+	for _, v := range violations {
+		resources = append(resources, v.Resource)
+	}
+	if !reflect.DeepEqual(resources, []string{"invoice", "report-*"}) {
+		t.Errorf("Expected violations on invoice and report-* but got %v", violations)
+	}
+}
+
+func TestGetSodRulesByArea(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a separation of duties accessor %v", err)
+		return
+	}
+
+	sa := NewSodAccessor(db)
+
+	expected := []SodRule{SodRule{"r1", "area", "approve", "submit", "warn"}}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, area, verbA, verbB, mode FROM sodRule WHERE area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "verbA", "verbB", "mode"}).FromCSVString("r1,area,approve,submit,warn"))
+
+	rules, err := sa.GetByArea("area")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while getting rules %v", err)
+	}
+
+	for i := 0; i < len(rules); i++ {
+		if rules[i] != expected[i] {
+			t.Errorf("Expected %v but got %v", expected, rules)
+		}
+	}
+
+	if err := sa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
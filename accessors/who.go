@@ -0,0 +1,208 @@
+package accessors
+
+import (
+	"sort"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// A user who can perform an action and every way they are able to.
+// Paths are "superuser", "admin:<area>" (the area or an ancestor),
+// "area:<area>" (an area level policy row of the area or an ancestor it
+// inherits from, reported for members of the area's groups),
+// "group:<guid>" or "user" (a policy row for the user themselves).
+type Access struct {
+	NetId string
+	Paths []string
+}
+
+// Returns every user who can perform verb on resource in an area along
+// with how they come to have that access.
+func (pa *PermissionAccessor) WhoCan(verb, resource, area string) ([]Access, error) {
+	paths := make(map[string][]string)
+	add := func(netId, path string) {
+		paths[netId] = append(paths[netId], path)
+	}
+
+	// Who is in which of the area's groups
+	members := make(map[string][]string)
+	stmt, err := pa.DB.Prepare("SELECT groupMembers.netId, groupMembers.groupGuid FROM groupMembers JOIN groups ON groupMembers.groupGuid = groups.guid WHERE groups.area=? UNION SELECT groupRuleMember.netId, groupRuleMember.groupGuid FROM groupRuleMember JOIN groups ON groupRuleMember.groupGuid = groups.guid WHERE groups.area=?")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.Query(area, area)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var netId, group string
+		if err := rows.Scan(&netId, &group); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		members[group] = append(members[group], netId)
+	}
+	rows.Close()
+
+	// Resolve each policy row's actor to users
+	stmt, err = pa.DB.Prepare("SELECT actor FROM policy WHERE verb=? AND resource=?")
+	if err != nil {
+		return nil, err
+	}
+	rows, err = stmt.Query(verb, resource)
+	if err != nil {
+		return nil, err
+	}
+	actors := make([]string, 0)
+	for rows.Next() {
+		var actor string
+		if err := rows.Scan(&actor); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		actors = append(actors, actor)
+	}
+	rows.Close()
+
+	// Actors bound to a role covering the resource count the same way
+	stmt, err = pa.DB.Prepare("SELECT roleBinding.actor, rolePermission.resource FROM roleBinding JOIN rolePermission ON roleBinding.roleGuid = rolePermission.roleGuid WHERE rolePermission.verb=?")
+	if err != nil {
+		return nil, err
+	}
+	rows, err = stmt.Query(verb)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var actor, pattern string
+		if err := rows.Scan(&actor, &pattern); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if MatchResource(pattern, resource) {
+			actors = append(actors, actor)
+		}
+	}
+	rows.Close()
+
+	// Find out which actors are groups, group rows only count in their own
+	// area, and which are areas, so that neither is taken for a user
+	groupAreas := make(map[string]string)
+	areas := make(map[string]bool)
+	if len(actors) > 0 {
+		in := " IN (?"
+		params := []interface{}{actors[0]}
+		for i := 1; i < len(actors); i++ {
+			in += ",?"
+			params = append(params, actors[i])
+		}
+		in += ")"
+
+		stmt, err = pa.DB.Prepare("SELECT guid, area FROM groups WHERE guid" + in)
+		if err != nil {
+			return nil, err
+		}
+		rows, err = stmt.Query(params...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var guid, groupArea string
+			if err := rows.Scan(&guid, &groupArea); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			groupAreas[guid] = groupArea
+		}
+		rows.Close()
+
+		stmt, err = pa.DB.Prepare("SELECT guid FROM areas WHERE guid" + in)
+		if err != nil {
+			return nil, err
+		}
+		rows, err = stmt.Query(params...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var guid string
+			if err := rows.Scan(&guid); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			areas[guid] = true
+		}
+		rows.Close()
+	}
+
+	// Area level rows of ancestors count when the area inherits them
+	lineage, err := NewAreaAccessor(pa.DB).Lineage(area)
+	if err != nil {
+		return nil, err
+	}
+	policyAreas := make(map[string]bool)
+	for _, a := range PolicyAreas(area, lineage) {
+		policyAreas[a] = true
+	}
+
+	for _, actor := range actors {
+		if policyAreas[actor] {
+			seen := make(map[string]bool)
+			for _, netIds := range members {
+				for _, netId := range netIds {
+					if !seen[netId] {
+						seen[netId] = true
+						add(netId, "area:"+actor)
+					}
+				}
+			}
+		} else if groupArea, ok := groupAreas[actor]; ok {
+			if groupArea == area {
+				for _, netId := range members[actor] {
+					add(netId, "group:"+actor)
+				}
+			}
+		} else if !areas[actor] {
+			// Rows for areas that don't apply here grant nobody anything
+			add(actor, "user")
+		}
+	}
+
+	// Admins, including admins of ancestor areas, and superusers can do everything
+	for _, a := range AdminAreas(area, lineage) {
+		admins, err := pa.GetAdmins(a)
+		if err != nil {
+			return nil, err
+		}
+		for _, netId := range admins {
+			add(netId, "admin:"+a)
+		}
+	}
+
+	stmt, err = pa.DB.Prepare("SELECT netId FROM superuser WHERE active=1")
+	if err != nil {
+		return nil, err
+	}
+	rows, err = stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var netId string
+		if err := rows.Scan(&netId); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		add(netId, "superuser")
+	}
+	rows.Close()
+
+	result := make([]Access, 0, len(paths))
+	for netId, p := range paths {
+		sort.Strings(p)
+		result = append(result, Access{netId, p})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NetId < result[j].NetId })
+
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package accessors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestWhoCan(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Errorf("An unexpected error occurred when creating a permission accessor %v", err)
+		return
+	}
+
+	pa := NewPermissionAccessor(db)
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT groupMembers.netId, groupMembers.groupGuid FROM groupMembers JOIN groups .+ WHERE groups.area=.").
+		WithArgs("area", "area").
+		WillReturnRows(sqlmock.NewRows([]string{"netId", "groupGuid"}).FromCSVString("alice,g1\nbob,g2"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT actor FROM policy WHERE verb=. AND resource=.").
+		WithArgs("edit", "res").
+		WillReturnRows(sqlmock.NewRows([]string{"actor"}).FromCSVString("g1\nother\ncarol\narea\ndept\nlab"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE rolePermission.verb=.").
+		WithArgs("edit").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "resource"}).FromCSVString("g2,r*\nerin,other-*"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, area FROM groups WHERE guid IN .+").
+		WithArgs("g1", "other", "carol", "area", "dept", "lab", "g2").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area"}).FromCSVString("g1,area\nother,area2\ng2,area"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid FROM areas WHERE guid IN .+").
+		WithArgs("g1", "other", "carol", "area", "dept", "lab", "g2").
+		WillReturnRows(sqlmock.NewRows([]string{"guid"}).FromCSVString("area\ndept\nlab"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("area,Area,,dept,0"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("dept").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("dept,Department,,,0"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT netId FROM admin WHERE area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("dave"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT netId FROM admin WHERE area=.").
+		WithArgs("dept").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("frank"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT netId FROM superuser WHERE active=1").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("root"))
+
+	access, err := pa.WhoCan("edit", "res", "area")
+	if err != nil {
+		t.Errorf("An unexpected error occurred while resolving access %v", err)
+	}
+
+	expected := []Access{
+		Access{"alice", []string{"area:area", "group:g1"}},
+		Access{"bob", []string{"area:area", "group:g2"}},
+		Access{"carol", []string{"user"}},
+		Access{"dave", []string{"admin:area"}},
+		Access{"frank", []string{"admin:dept"}},
+		Access{"root", []string{"superuser"}},
+	}
+	if !reflect.DeepEqual(access, expected) {
+		t.Errorf("Expected %v but got %v", expected, access)
+	}
+
+	if err := pa.DB.Close(); err != nil {
+		t.Errorf("An error occurred: %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package apis
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// Tells whether a user may approve or deny an access request. Area admins
+// and superusers can decide any request, group owners can decide requests
+// to join their groups, and nobody can decide their own request.
+func canReviewAccessRequest(db *sql.DB, netId string, r accessors.AccessRequest) (bool, error) {
+	if netId == r.NetId {
+		return false, nil
+	}
+
+	allowed, err := isSUOrAdmin(accessors.NewPermissionAccessor(db), netId, r.Area)
+	if err != nil || allowed || r.Type != accessors.AccessGroup {
+		return allowed, err
+	}
+
+	return accessors.NewMembersAccessor(db).IsOwner(netId, r.Group)
+}
+
+// Ask to join a group or to be granted a permission.
+// POST /accessRequests area=:areaGuid, group=:groupGuid | verb=:verb, resource=:resource, reason=:reason, duration=:hours
+func (a *Api) CreateAccessRequest(c *eden.Context) {
+	ar := accessors.NewAccessRequestAccessor(a.DB)
+	ga := accessors.NewGroupAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called CreateAccessRequest (POST /accessRequests)", true, ar.DB)
+
+	c.Request.ParseForm()
+	r := accessors.AccessRequest{
+		NetId:    c.User.NetId,
+		Area:     c.Request.Form.Get("area"),
+		Group:    c.Request.Form.Get("group"),
+		Verb:     c.Request.Form.Get("verb"),
+		Resource: c.Request.Form.Get("resource"),
+		Reason:   c.Request.Form.Get("reason"),
+	}
+
+	if r.Area == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+		return
+	}
+
+	if duration := c.Request.Form.Get("duration"); duration != "" {
+		hours, err := strconv.ParseInt(duration, 10, 64)
+		if err != nil || hours < 0 {
+			c.Respond(400, eden.Response{"ERROR", "Invalid duration"})
+			return
+		}
+		r.Duration = hours
+	}
+
+	switch {
+	case r.Group != "" && r.Verb == "" && r.Resource == "":
+		r.Type = accessors.AccessGroup
+
+		group, err := ga.Get(r.Group)
+		if err == sql.ErrNoRows || (err == nil && group.Area != r.Area) {
+			c.Respond(400, eden.Response{"ERROR", "Invalid group"})
+			return
+		}
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in CreateAccessRequest (POST /accessRequests): %v", err), true, ar.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+	case r.Group == "" && r.Verb != "" && r.Resource != "":
+		r.Type = accessors.AccessPermission
+	default:
+		c.Respond(400, eden.Response{"ERROR", "Specify either a group or a verb and resource"})
+		return
+	}
+
+	guid, err := ar.Create(r)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Create in CreateAccessRequest (POST /accessRequests): %v", err), true, ar.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Requested access %s (%s %s%s %s)", guid, r.Type, r.Group, r.Verb, r.Resource), true, ar.DB)
+	c.Respond(200, eden.Response{"OK", guid})
+}
+
+// Get the access requests the user can decide on in an area. Admins see
+// every pending request, group owners see requests to join their groups.
+// GET /accessRequests?area=:areaGuid
+func (a *Api) GetAccessRequests(c *eden.Context) {
+	ar := accessors.NewAccessRequestAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	c.Request.ParseForm()
+	area, areaOk := c.Request.Form["area"]
+	if !areaOk || area[0] == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+		return
+	}
+
+	admin, err := isSUOrAdmin(pa, c.User.NetId, area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in GetAccessRequests (GET /accessRequests?area=:areaGuid): %v", err), true, ar.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	var requests []accessors.AccessRequest
+	if admin {
+		requests, err = ar.GetPendingByArea(area[0])
+	} else {
+		requests, err = ar.GetPendingForOwner(c.User.NetId, area[0])
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error in GetAccessRequests (GET /accessRequests?area=:areaGuid): %v", err), true, ar.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error occurred while retrieving access requests"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", requests})
+}
+
+// Approve an access request and grant the access.
+// POST /accessRequests/:guid/approve
+func (a *Api) ApproveAccessRequest(c *eden.Context) {
+	a.reviewAccessRequest(c, accessors.AccessApproved)
+}
+
+// Deny an access request.
+// POST /accessRequests/:guid/deny
+func (a *Api) DenyAccessRequest(c *eden.Context) {
+	a.reviewAccessRequest(c, accessors.AccessDenied)
+}
+
+func (a *Api) reviewAccessRequest(c *eden.Context, status string) {
+	ar := accessors.NewAccessRequestAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	r, err := ar.Get(guid)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such request"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in reviewAccessRequest (POST /accessRequests/:guid/%s): %v", status, err), true, ar.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	if r.Status != accessors.AccessPending {
+		c.Respond(409, eden.Response{"ERROR", "This request has already been " + r.Status})
+		return
+	}
+
+	allowed, err := canReviewAccessRequest(a.DB, c.User.NetId, r)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on canReviewAccessRequest in reviewAccessRequest (POST /accessRequests/:guid/%s): %v", status, err), true, ar.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You are not eligible to decide on this request"})
+		return
+	}
+
+	// Approving grants access like any other grant, so it is held to the
+	// area's separation of duties rules
+	if status == accessors.AccessApproved {
+		extra := []accessors.Permission{{r.NetId, r.Verb, r.Resource, ""}}
+		if r.Type == accessors.AccessGroup {
+			extra, err = accessors.NewPermissionAccessor(a.DB).GetGroupPermissions(r.Group)
+			if err != nil {
+				accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetGroupPermissions in reviewAccessRequest (POST /accessRequests/:guid/%s): %v", status, err), true, ar.DB)
+				c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+				return
+			}
+		}
+		users := func() ([]string, error) { return []string{r.NetId}, nil }
+		if !a.enforceSod(c, r.Area, users, extra) {
+			return
+		}
+	}
+
+	// Permissions on sensitive resources wait for a second person to
+	// approve, as a grant would; the request stays pending until then
+	if status == accessors.AccessApproved && r.Type == accessors.AccessPermission {
+		sensitive, err := accessors.NewApprovalAccessor(a.DB).IsSensitive(r.Resource)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSensitive in reviewAccessRequest (POST /accessRequests/:guid/%s): %v", status, err), true, ar.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		if sensitive {
+			a.requestApproval(c, accessors.Approval{Type: accessors.ApprovalAccessRequest, Area: r.Area, NetId: r.NetId, Actor: r.Guid, Verb: r.Verb, Resource: r.Resource})
+			return
+		}
+	}
+
+	var expires int64
+	if status == accessors.AccessApproved && r.Duration > 0 {
+		expires = timeNow().Add(time.Duration(r.Duration) * time.Hour).Unix()
+	}
+
+	// An approval grants the access in the same transaction
+	var claimed bool
+	decide := "Resolve"
+	if status == accessors.AccessApproved {
+		decide = "Approve"
+		claimed, err = ar.Approve(r, c.User.NetId, expires)
+	} else {
+		claimed, err = ar.Resolve(guid, accessors.AccessPending, status, c.User.NetId, expires)
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on %s in reviewAccessRequest (POST /accessRequests/:guid/%s): %v", decide, status, err), true, ar.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !claimed {
+		c.Respond(409, eden.Response{"ERROR", "This request has already been decided"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Access request %s for %s %s", guid, r.NetId, status), true, ar.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Revoke access granted by approved requests that have passed their
+// expiry. A request that fails to expire is logged and tried again next
+// time without holding up the others.
+func (a *Api) expireAccessRequests() error {
+	ar := accessors.NewAccessRequestAccessor(a.DB)
+
+	expired, err := ar.GetExpired(timeNow().Unix())
+	if err != nil {
+		return err
+	}
+
+	for _, r := range expired {
+		claimed, err := ar.Expire(r)
+		if err != nil {
+			accessors.Log("error", "system", fmt.Sprintf("Error on Expire in expireAccessRequests for %s: %v", r.Guid, err), true, ar.DB)
+			continue
+		}
+		if claimed {
+			accessors.Log("audit", "system", fmt.Sprintf("Access request %s for %s expired", r.Guid, r.NetId), true, ar.DB)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package apis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+	"github.com/julienschmidt/httprouter"
+)
+
+var accessRequestColumns = []string{"guid", "netId", "area", "type", "groupGuid", "verb", "resource", "reason", "status", "reviewer", "duration", "expires", "granted"}
+
+func TestCreateAccessRequest(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	accessors.NewGuid = func() string {
+		return "ar1"
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
+		WithArgs("g1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("g1,area,Night Shift,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectExec("INSERT INTO accessRequest .+ VALUES .+").
+		WithArgs("ar1", "netId", "area", "group", "g1", "", "", "", "pending", "", 8, 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("area=area&group=g1&duration=8", nil, api.CreateAccessRequest)
+	c.User = eden.User{"netId", "area"}
+	testhelpers.CallAPI(api.CreateAccessRequest, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Data != "ar1" {
+		t.Errorf("Expected: %v, but got %v", "ar1", output)
+	}
+}
+
+func TestCreateAccessRequestAmbiguous(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("area=area&group=g1&verb=edit", nil, api.CreateAccessRequest)
+	c.User = eden.User{"netId", "area"}
+	testhelpers.CallAPI(api.CreateAccessRequest, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
+
+func TestApproveAccessRequestAsOwner(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	timeNow = func() time.Time {
+		return time.Unix(1000, 0)
+	}
+	defer func() { timeNow = time.Now }()
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM accessRequest WHERE guid=.").
+		WithArgs("ar1").
+		WillReturnRows(sqlmock.NewRows(accessRequestColumns).FromCSVString("ar1,netId,area,group,g1,,,,pending,,1,0,0"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("owner").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("area,Area,,,0"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT . FROM admin WHERE netId=. AND area IN .+").
+		WithArgs("owner", "area").
+		WillReturnRows(sqlmock.NewRows([]string{"netId", "area"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT netId FROM groupOwners WHERE netId=. AND groupGuid=.").
+		WithArgs("owner", "g1").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("owner"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.actor=.").
+		WithArgs("g1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource", "condition"}).FromCSVString("g1,edit,res,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ JOIN groups .+ WHERE roleBinding.actor=. AND role.area = groups.area").
+		WithArgs("g1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM sodRule WHERE area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "verbA", "verbB", "mode"}).FromCSVString(""))
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("UPDATE accessRequest SET status=., reviewer=., expires=. WHERE guid=. AND status=.").
+		WithArgs("approved", "owner", 4600, "ar1", "pending").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO groupMembers (.+) SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("netId", "g1", "netId", "g1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("UPDATE accessRequest SET granted=. WHERE guid=.").
+		WithArgs(true, "ar1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectCommit()
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", httprouter.Params{httprouter.Param{Key: "guid", Value: "ar1"}}, api.ApproveAccessRequest)
+	c.User = eden.User{"owner", "area"}
+	testhelpers.CallAPI(api.ApproveAccessRequest, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Data != "success" {
+		t.Errorf("Expected: %v, but got %v", "success", output)
+	}
+}
+
+func TestApproveOwnAccessRequest(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM accessRequest WHERE guid=.").
+		WithArgs("ar1").
+		WillReturnRows(sqlmock.NewRows(accessRequestColumns).FromCSVString("ar1,netId,area,permission,,edit,res,,pending,,0,0,0"))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", httprouter.Params{httprouter.Param{Key: "guid", Value: "ar1"}}, api.ApproveAccessRequest)
+	c.User = eden.User{"netId", "area"}
+	testhelpers.CallAPI(api.ApproveAccessRequest, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
@@ -25,8 +25,10 @@ func (a *Api) IsAdmin(c *eden.Context) {
 	c.Respond(200, eden.Response{"OK", admin})
 }
 
-// Check whether the user is an admin in the given area
-// GET /admin?area=:areaGuid
+// Check whether the user is an admin in the given area. With inherited=true
+// admins of the area's ancestors are included along with where each one
+// was made admin.
+// GET /admin?area=:areaGuid&inherited=true
 func (a *Api) GetAdmins(c *eden.Context) {
 	pa := accessors.NewPermissionAccessor(a.DB)
 
@@ -38,6 +40,18 @@ func (a *Api) GetAdmins(c *eden.Context) {
 		return
 	}
 
+	if c.Request.Form.Get("inherited") == "true" {
+		admins, err := pa.GetInheritedAdmins(area[0])
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetInheritedAdmins in GetAdmins (GET /admin?area=:areaGuid&inherited=true): %v", err), true, pa.DB)
+			c.Respond(500, eden.Response{"ERROR", admins})
+			return
+		}
+
+		c.Respond(200, eden.Response{"OK", admins})
+		return
+	}
+
 	admins, err := pa.GetAdmins(area[0])
 	if err != nil {
 		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error in GetAdmins (GET /admin?area=:areaGuid): v", err), true, pa.DB)
@@ -63,27 +77,29 @@ func (a *Api) GetAllSU(c *eden.Context) {
 	c.Respond(200, eden.Response{"OK", sus})
 }
 
-// Grant a user admin access in an area
+// Request admin access in an area for a user. The grant is applied once
+// another admin approves it. Accepts a form or a JSON AddAdminRequest.
 // POST /admin?area=:areaGuid&netId=:netId
 func (a *Api) AddAdmin(c *eden.Context) {
 	pa := accessors.NewPermissionAccessor(a.DB)
 
 	// Parse input
-	c.Request.ParseForm()
-	area, areaOk := c.Request.Form["area"]
-	netId, netIdOk := c.Request.Form["netId"]
+	var req AddAdminRequest
+	if !decodeRequest(c, &req) {
+		return
+	}
+	area, netId := req.Area, req.NetId
 
-	accessors.Log("notice", c.User.NetId, fmt.Sprintf("%s called AddAdmin (POST /admin?area=:areaGuid&netId=:netId)", netId[0]), true, pa.DB)
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("%s called AddAdmin (POST /admin?area=:areaGuid&netId=:netId)", netId), true, pa.DB)
 
-	if !areaOk || !netIdOk || netId[0] == "" || area[0] == "" {
-		c.Respond(400, eden.Response{"ERROR", "Invalid input"})
+	if !a.knownArea(c, area, "AddAdmin (POST /admin?area=:areaGuid&netId=:netId)") {
 		return
 	}
 
 	// Check that the user is an admin first
 	isAdmin, err := pa.IsAdmin(c.User.NetId, c.User.Area)
 	if err != nil {
-		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsAdmin in AddAdmin by %s (POST /admin?area=:areaGuid&netId=:netId): %v", netId[0], err), true, pa.DB)
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsAdmin in AddAdmin by %s (POST /admin?area=:areaGuid&netId=:netId): %v", netId, err), true, pa.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
@@ -91,7 +107,7 @@ func (a *Api) AddAdmin(c *eden.Context) {
 	if !isAdmin {
 		isSU, err := pa.IsSuperuser(c.User.NetId)
 		if err != nil {
-			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in AddAdmin by %s (POST /admin?area=:areaGuid&netId=:netId): %v", netId[0], err), true, pa.DB)
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in AddAdmin by %s (POST /admin?area=:areaGuid&netId=:netId): %v", netId, err), true, pa.DB)
 			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 			return
 		}
@@ -102,14 +118,21 @@ func (a *Api) AddAdmin(c *eden.Context) {
 		}
 	}
 
-	err = pa.AddAdmin(netId[0], area[0])
+	admins, err := pa.GetAdmins(area)
 	if err != nil {
-		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on AddAdmin by %s (POST /admin?area=:areaGuid&netId=:netId): ", netId[0], err), true, pa.DB)
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetAdmins in AddAdmin by %s (POST /admin?area=:areaGuid&netId=:netId): %v", netId, err), true, pa.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
+	for _, admin := range admins {
+		if admin == netId {
+			c.Respond(200, eden.Response{"OK", WriteResult{false}})
+			return
+		}
+	}
 
-	c.Respond(200, eden.Response{"OK", "success"})
+	// Admin rights only take effect once a second person approves them
+	a.requestApproval(c, accessors.Approval{Type: accessors.ApprovalAdmin, Area: area, NetId: netId})
 }
 
 // Revoke admin access
@@ -143,12 +166,16 @@ func (a *Api) DeleteAdmin(c *eden.Context) {
 		}
 	}
 
-	err = pa.DeleteAdmin(netId, areaGuid)
+	n, err := pa.DeleteAdmin(netId, areaGuid)
 	if err != nil {
 		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error in DeleteAdmin by %s (DELETE /admin/:netId/:areaGuid): %v", netId, err), true, pa.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
+	if n == 0 {
+		c.Respond(404, eden.Response{"ERROR", "No such admin"})
+		return
+	}
 
 	c.Respond(200, eden.Response{"OK", "success"})
 }
@@ -187,26 +214,25 @@ func (a *Api) CanSuperuser(c *eden.Context) {
 	c.Respond(200, eden.Response{"OK", su})
 }
 
-// Grant a user superuser access
+// Request superuser access for a user. The grant is applied once another
+// superuser approves it. Accepts a form or a JSON AddSURequest.
 // POST /superuser?netId=:netId
 func (a *Api) AddSU(c *eden.Context) {
 	pa := accessors.NewPermissionAccessor(a.DB)
 
 	// Parse input
-	c.Request.ParseForm()
-	netId, netIdOk := c.Request.Form["netId"]
-
-	accessors.Log("notice", c.User.NetId, fmt.Sprintf("%s called AddSU (POST /superuser?netId=:netId)", netId[0]), true, pa.DB)
-
-	if !netIdOk || netId[0] == "" {
-		c.Respond(400, eden.Response{"ERROR", "Invalid input"})
+	var req AddSURequest
+	if !decodeRequest(c, &req) {
 		return
 	}
+	netId := req.NetId
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("%s called AddSU (POST /superuser?netId=:netId)", netId), true, pa.DB)
 
 	// Check that the user is superuser
 	isSU, err := pa.IsSuperuser(c.User.NetId)
 	if err != nil {
-		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in AddSU by %s (POST /superuser?netId=:netId): %v", netId[0], err), true, pa.DB)
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in AddSU by %s (POST /superuser?netId=:netId): %v", netId, err), true, pa.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
@@ -215,14 +241,21 @@ func (a *Api) AddSU(c *eden.Context) {
 		return
 	}
 
-	err = pa.AddSU(netId[0])
+	superusers, err := pa.GetAllSU()
 	if err != nil {
-		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error in AddSU by %s (POST /superuser?netId=:netId): %v", netId[0], err), true, pa.DB)
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetAllSU in AddSU by %s (POST /superuser?netId=:netId): %v", netId, err), true, pa.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
+	for _, su := range superusers {
+		if su == netId {
+			c.Respond(200, eden.Response{"OK", WriteResult{false}})
+			return
+		}
+	}
 
-	c.Respond(200, eden.Response{"OK", "success"})
+	// Superuser rights only take effect once a second superuser approves them
+	a.requestApproval(c, accessors.Approval{Type: accessors.ApprovalSuperuser, Area: c.User.Area, NetId: netId})
 }
 
 // Elevate to or stop superuser access.
@@ -289,12 +322,16 @@ func (a *Api) DeleteSU(c *eden.Context) {
 		return
 	}
 
-	err = pa.DeleteSU(netId)
+	n, err := pa.DeleteSU(netId)
 	if err != nil {
 		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error in DeleteSU by %s (DELETE /superuser/:netId): %v", netId, err), true, pa.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
+	if n == 0 {
+		c.Respond(404, eden.Response{"ERROR", "No such superuser"})
+		return
+	}
 
 	c.Respond(200, eden.Response{"OK", "success"})
 }
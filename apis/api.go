@@ -31,3 +31,19 @@ func New() (*Api, error) {
 
 	return &Api{db}, nil
 }
+
+// Response data for writes that may find what they would add already in
+// place. Created is false when nothing had to be added.
+type WriteResult struct {
+	Created bool
+}
+
+// Tells whether the user is a superuser or an admin in the given area.
+func isSUOrAdmin(pa *accessors.PermissionAccessor, netId, area string) (bool, error) {
+	su, err := pa.IsSuperuser(netId)
+	if err != nil || su {
+		return su, err
+	}
+
+	return pa.IsAdmin(netId, area)
+}
@@ -0,0 +1,238 @@
+package apis
+
+import (
+	"database/sql"
+	"fmt"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// Store a sensitive grant as a pending request instead of executing it
+// and respond with the request's guid.
+func (a *Api) requestApproval(c *eden.Context, approval accessors.Approval) {
+	aa := accessors.NewApprovalAccessor(a.DB)
+
+	approval.Requester = c.User.NetId
+	guid, err := aa.Create(approval)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Create in requestApproval for %s: %v", approval.Type, err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Requested approval %s: %s", guid, describeApproval(approval)), true, aa.DB)
+	c.Respond(202, eden.Response{"PENDING", guid})
+}
+
+// Tells whether a user may decide on a pending request. Nobody may
+// approve their own request or access they asked for.
+func canApprove(pa *accessors.PermissionAccessor, netId string, approval accessors.Approval) (bool, error) {
+	if netId == approval.Requester || (approval.Type == accessors.ApprovalAccessRequest && netId == approval.NetId) {
+		return false, nil
+	}
+
+	if approval.Type == accessors.ApprovalSuperuser {
+		return pa.IsSuperuser(netId)
+	}
+
+	return isSUOrAdmin(pa, netId, approval.Area)
+}
+
+// Human readable summary of a request for the audit log.
+func describeApproval(approval accessors.Approval) string {
+	switch approval.Type {
+	case accessors.ApprovalAdmin:
+		return fmt.Sprintf("grant admin on %s to %s", approval.Area, approval.NetId)
+	case accessors.ApprovalSuperuser:
+		return fmt.Sprintf("grant superuser to %s", approval.NetId)
+	case accessors.ApprovalRolePermission:
+		return fmt.Sprintf("add %s on %s to role %s", approval.Verb, approval.Resource, approval.Role)
+	case accessors.ApprovalRoleBinding:
+		return fmt.Sprintf("bind role %s to %s", approval.Role, approval.Actor)
+	case accessors.ApprovalAccessRequest:
+		return fmt.Sprintf("approve access request %s for %s on %s in %s to %s", approval.Actor, approval.Verb, approval.Resource, approval.Area, approval.NetId)
+	}
+
+	if approval.Condition != "" {
+		return fmt.Sprintf("grant %s on %s to %s when %s", approval.Verb, approval.Resource, approval.Actor, approval.Condition)
+	}
+	return fmt.Sprintf("grant %s on %s to %s", approval.Verb, approval.Resource, approval.Actor)
+}
+
+// Get the requests awaiting a second approver in an area.
+// GET /approvals?area=:areaGuid
+func (a *Api) GetPendingApprovals(c *eden.Context) {
+	aa := accessors.NewApprovalAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	c.Request.ParseForm()
+	area, areaOk := c.Request.Form["area"]
+	if !areaOk || area[0] == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in GetPendingApprovals (GET /approvals?area=:areaGuid): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to view pending approvals"})
+		return
+	}
+
+	approvals, err := aa.GetPending(area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetPending in GetPendingApprovals (GET /approvals?area=:areaGuid): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", approvals})
+}
+
+// Approve a pending request and perform the grant.
+// POST /approvals/:guid/approve
+func (a *Api) ApproveRequest(c *eden.Context) {
+	a.resolveApproval(c, accessors.ApprovalApproved)
+}
+
+// Reject a pending request.
+// POST /approvals/:guid/reject
+func (a *Api) RejectRequest(c *eden.Context) {
+	a.resolveApproval(c, accessors.ApprovalRejected)
+}
+
+func (a *Api) resolveApproval(c *eden.Context, status string) {
+	aa := accessors.NewApprovalAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	approval, err := aa.Get(guid)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such request"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in resolveApproval (POST /approvals/:guid/%s): %v", status, err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	if approval.Status != accessors.ApprovalPending {
+		c.Respond(409, eden.Response{"ERROR", "This request has already been " + approval.Status})
+		return
+	}
+
+	allowed, err := canApprove(pa, c.User.NetId, approval)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on canApprove in resolveApproval (POST /approvals/:guid/%s): %v", status, err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You are not eligible to decide on this request"})
+		return
+	}
+
+	// Claim the request so a concurrent decision can't apply it twice. An
+	// approval performs the grant in the same transaction.
+	var claimed bool
+	decide := "Resolve"
+	if status == accessors.ApprovalApproved {
+		decide = "Approve"
+		claimed, err = aa.Approve(approval, c.User.NetId)
+	} else {
+		claimed, err = aa.Resolve(guid, status, c.User.NetId)
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on %s in resolveApproval (POST /approvals/:guid/%s): %v", decide, status, err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !claimed {
+		c.Respond(409, eden.Response{"ERROR", "This request has already been decided"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Approval %s %s: %s (requested by %s)", guid, status, describeApproval(approval), approval.Requester), true, aa.DB)
+
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Flag a resource so that grants on it need a second approver.
+// POST /sensitive resource=:resourceGuid
+func (a *Api) MarkSensitive(c *eden.Context) {
+	aa := accessors.NewApprovalAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called MarkSensitive (POST /sensitive resource=:resourceGuid)", true, aa.DB)
+
+	c.Request.ParseForm()
+	resource, resourceOk := c.Request.Form["resource"]
+	if !resourceOk || resource[0] == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid input"})
+		return
+	}
+
+	isSU, err := pa.IsSuperuser(c.User.NetId)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in MarkSensitive (POST /sensitive resource=:resourceGuid): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !isSU {
+		c.Respond(403, eden.Response{"ERROR", "You need to be superuser to flag sensitive resources"})
+		return
+	}
+
+	if err := aa.MarkSensitive(resource[0]); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on MarkSensitive (POST /sensitive resource=:resourceGuid): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Marked %s sensitive", resource[0]), true, aa.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Remove the sensitive flag from a resource.
+// DELETE /sensitive?resource=:resourceGuid
+func (a *Api) UnmarkSensitive(c *eden.Context) {
+	aa := accessors.NewApprovalAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	c.Request.ParseForm()
+	resource := c.Request.Form.Get("resource")
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called UnmarkSensitive on %s (DELETE /sensitive?resource=:resourceGuid)", resource), true, aa.DB)
+
+	if resource == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid resource"})
+		return
+	}
+
+	isSU, err := pa.IsSuperuser(c.User.NetId)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in UnmarkSensitive (DELETE /sensitive?resource=:resourceGuid): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !isSU {
+		c.Respond(403, eden.Response{"ERROR", "You need to be superuser to flag sensitive resources"})
+		return
+	}
+
+	if err := aa.UnmarkSensitive(resource); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on UnmarkSensitive (DELETE /sensitive?resource=:resourceGuid): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Removed sensitive flag from %s", resource), true, aa.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
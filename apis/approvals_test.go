@@ -0,0 +1,154 @@
+package apis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+	"github.com/julienschmidt/httprouter"
+)
+
+var approvalColumns = []string{"guid", "type", "requester", "area", "netId", "role", "actor", "verb", "resource", "expression", "status", "reviewer"}
+
+func TestApproveRequest(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM approval WHERE guid=.").
+		WithArgs("req1").
+		WillReturnRows(sqlmock.NewRows(approvalColumns).FromCSVString("req1,permission,requester,area,,,group,edit,res,,pending,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("reviewer").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString("1"))
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("UPDATE approval SET status=., reviewer=. WHERE guid=. AND status=.").
+		WithArgs("approved", "reviewer", "req1", "pending").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO policy (.+) SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("group", "edit", "res", "group", "edit", "res").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", httprouter.Params{httprouter.Param{Key: "guid", Value: "req1"}}, api.ApproveRequest)
+	c.User = eden.User{"reviewer", "area"}
+	testhelpers.CallAPI(api.ApproveRequest, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Data != "success" {
+		t.Errorf("Expected: %v, but got %v", "success", output)
+	}
+}
+
+func TestApproveOwnRequest(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM approval WHERE guid=.").
+		WithArgs("req1").
+		WillReturnRows(sqlmock.NewRows(approvalColumns).FromCSVString("req1,superuser,requester,area,netId,,,,,,pending,"))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", httprouter.Params{httprouter.Param{Key: "guid", Value: "req1"}}, api.ApproveRequest)
+	c.User = eden.User{"requester", "area"}
+	testhelpers.CallAPI(api.ApproveRequest, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
+
+func TestAddSensitivePermission(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	accessors.NewGuid = func() string {
+		return "req1"
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("guid").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString("1"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM sodRule WHERE area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "verbA", "verbB", "mode"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT resource FROM sensitiveResource WHERE resource=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"resource"}).FromCSVString("1"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectExec("INSERT INTO approval .+ VALUES .+").
+		WithArgs("req1", "permission", "guid", "area", "", "", "2", "edit", "1", "", "pending", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("actor=2&verb=edit&resource=1", nil, api.AddPermission)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.AddPermission, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "PENDING" || output.Data != "req1" {
+		t.Errorf("Expected: %v, but got %v", "PENDING", output)
+	}
+}
@@ -0,0 +1,424 @@
+package apis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Media types an area's configuration can be written in.
+var yamlMediaTypes = map[string]bool{
+	"application/x-yaml": true,
+	"application/yaml":   true,
+	"text/yaml":          true,
+	"text/x-yaml":        true,
+}
+
+// Response data for POST /areas/:area/import.
+type ImportResult struct {
+	Changes accessors.ConfigChanges
+	DryRun  bool
+	Pending []string // approval requests for the admins added
+}
+
+// Export an area's groups with their direct members, its admins and the
+// policy rows held by the area or its groups. The document is written as
+// is rather than in the usual response envelope so it can be kept under
+// version control and given to POST /areas/:area/import. Only superusers
+// and admins of the area can export it.
+// GET /areas/:area/export?format=json|yaml
+func (a *Api) ExportArea(c *eden.Context) {
+	aa := accessors.NewAreaAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	query, err := url.ParseQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		c.Respond(400, eden.Response{"ERROR", "Unable to process request"})
+		return
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "yaml" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid format"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in ExportArea (GET /areas/:area/export): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to export an area"})
+		return
+	}
+
+	config, err := aa.Export(guid)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such area"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Export in ExportArea (GET /areas/:area/export): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	var document []byte
+	contentType := "application/json"
+	if format == "yaml" {
+		document, err = yaml.Marshal(config)
+		contentType = "application/x-yaml"
+	} else {
+		document, err = json.MarshalIndent(config, "", "  ")
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Marshal in ExportArea (GET /areas/:area/export): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Response.Header().Set("Content-Type", contentType)
+	c.Response.WriteHeader(200)
+	c.Response.Write(document)
+}
+
+// Apply a document from GET /areas/:area/export to an area, as JSON or
+// YAML according to the Content-Type. Groups are matched by guid, then by
+// name. mode=merge, the default, only adds and updates; mode=replace also
+// removes the groups, members, admins and policy rows the document doesn't
+// have. Each remap=:from:to uses the guid to in place of from, for groups
+// and resources whose guids differ between environments. With dryRun=true
+// the changes are worked out and returned without being made. Everything
+// is applied in a single transaction. Grants on sensitive resources can't
+// be imported since they need approval; admins to add are requested for
+// approval instead, and the requests listed in Pending.
+// POST /areas/:area/import?mode=merge|replace&dryRun=true&remap=:from:to
+func (a *Api) ImportArea(c *eden.Context) {
+	aa := accessors.NewAreaAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called ImportArea on %s (POST /areas/:area/import)", guid), true, aa.DB)
+
+	// Parse options
+	opts, query, ok := importOptions(c, false)
+	if !ok {
+		return
+	}
+	dryRun := false
+	if value := query.Get("dryRun"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.Respond(400, eden.Response{"ERROR", []FieldError{{"dryRun", "must be true or false"}}})
+			return
+		}
+	}
+
+	if !a.canConfigureArea(c, guid, "ImportArea (POST /areas/:area/import)") {
+		return
+	}
+
+	// Read the document and work out the changes
+	config, ok := decodeConfig(c)
+	if !ok {
+		return
+	}
+	_, changes, ok := a.planAreaImport(c, guid, config, opts, "ImportArea (POST /areas/:area/import)")
+	if !ok {
+		return
+	}
+
+	if dryRun {
+		c.Respond(200, eden.Response{"OK", ImportResult{changes, true, []string{}}})
+		return
+	}
+
+	pending, err := aa.ApplyConfig(guid, changes, timeNow().Unix(), c.User.NetId)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on ApplyConfig in ImportArea (POST /areas/:area/import): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error occurred while importing the configuration"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Imported configuration into area %s: %d groups created, %d updated, %d deleted, %d members added, %d removed, %d admins added, %d removed, %d policy rows added, %d removed",
+		guid, len(changes.CreateGroups), len(changes.UpdateGroups), len(changes.DeleteGroups), len(changes.AddMembers), len(changes.RemoveMembers),
+		len(changes.AddAdmins), len(changes.RemoveAdmins), len(changes.AddPolicy), len(changes.RemovePolicy)), true, aa.DB)
+	for i, approval := range pending {
+		accessors.Log("audit", c.User.NetId, fmt.Sprintf("Requested approval %s to make %s an admin of area %s", approval, changes.AddAdmins[i], guid), true, aa.DB)
+	}
+	c.Respond(200, eden.Response{"OK", ImportResult{changes, false, pending}})
+}
+
+// Read the mode and remap query parameters of an import, and return them
+// along with the rest of the query. replace is the default mode. Responds
+// with a 400 and returns false if they aren't valid.
+func importOptions(c *eden.Context, replace bool) (accessors.ImportOptions, url.Values, bool) {
+	opts := accessors.ImportOptions{Replace: replace, Remap: make(map[string]string)}
+
+	query, err := url.ParseQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		c.Respond(400, eden.Response{"ERROR", "Unable to process request"})
+		return opts, query, false
+	}
+
+	errs := make([]FieldError, 0)
+	switch query.Get("mode") {
+	case "":
+	case "merge":
+		opts.Replace = false
+	case "replace":
+		opts.Replace = true
+	default:
+		errs = append(errs, FieldError{"mode", "must be merge or replace"})
+	}
+	for _, remap := range query["remap"] {
+		parts := strings.SplitN(remap, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			errs = append(errs, FieldError{"remap", fmt.Sprintf("%q isn't from:to", remap)})
+			continue
+		}
+		opts.Remap[parts[0]] = parts[1]
+	}
+	if len(errs) > 0 {
+		c.Respond(400, eden.Response{"ERROR", errs})
+		return opts, query, false
+	}
+
+	return opts, query, true
+}
+
+// Make sure the user is a superuser or an admin of the area whose
+// configuration they are changing. Responds and returns false if not.
+func (a *Api) canConfigureArea(c *eden.Context, area, handler string) bool {
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in %s: %v", handler, err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return false
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to change an area's configuration"})
+		return false
+	}
+
+	return true
+}
+
+// Work out the changes importing config makes to an area and check them
+// against sensitive resources and separation of duties rules. Returns the
+// area's configuration before the import along with the changes. Responds
+// and returns false if the import can't go ahead.
+func (a *Api) planAreaImport(c *eden.Context, area string, config accessors.AreaConfig, opts accessors.ImportOptions, handler string) (accessors.AreaConfig, accessors.ConfigChanges, bool) {
+	aa := accessors.NewAreaAccessor(a.DB)
+	approvals := accessors.NewApprovalAccessor(a.DB)
+
+	current, err := aa.Export(area)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such area"})
+		return current, accessors.ConfigChanges{}, false
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Export in %s: %v", handler, err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return current, accessors.ConfigChanges{}, false
+	}
+
+	changes, err := accessors.PlanImport(current, config, opts)
+	if err != nil {
+		c.Respond(400, eden.Response{"ERROR", err.Error()})
+		return current, changes, false
+	}
+
+	// Sensitive grants go through approval one at a time
+	sensitive := make([]accessors.PolicyConfig, 0)
+	for _, p := range changes.AddPolicy {
+		isSensitive, err := approvals.IsSensitive(p.Resource)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSensitive in %s: %v", handler, err), true, aa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return current, changes, false
+		}
+		if isSensitive {
+			sensitive = append(sensitive, p)
+		}
+	}
+	if len(sensitive) > 0 {
+		c.Respond(409, eden.Response{"FAILURE", sensitive})
+		return current, changes, false
+	}
+
+	if !a.enforceImportSod(c, area, current, changes) {
+		return current, changes, false
+	}
+
+	return current, changes, true
+}
+
+// Read an area's configuration from the request body and validate it.
+// Responds with a 400 listing the problems and returns false if it can't
+// be read or isn't valid.
+func decodeConfig(c *eden.Context) (accessors.AreaConfig, bool) {
+	var config accessors.AreaConfig
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Respond(400, eden.Response{"ERROR", []FieldError{{"body", "Unable to read the request body"}}})
+		return config, false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/json":
+		err = json.Unmarshal(body, &config)
+	case yamlMediaTypes[mediaType]:
+		err = yaml.Unmarshal(body, &config)
+	default:
+		c.Respond(400, eden.Response{"ERROR", []FieldError{{"body", "Content-Type must be application/json or application/x-yaml"}}})
+		return config, false
+	}
+	if err != nil {
+		c.Respond(400, eden.Response{"ERROR", []FieldError{{"body", fmt.Sprintf("Invalid document: %v", err)}}})
+		return config, false
+	}
+
+	if errs := validateConfig(config); len(errs) > 0 {
+		c.Respond(400, eden.Response{"ERROR", errs})
+		return config, false
+	}
+	return config, true
+}
+
+// Check that an area's configuration is complete and consistent.
+func validateConfig(config accessors.AreaConfig) []FieldError {
+	errs := required(nil, "Area", config.Area)
+
+	guids := make(map[string]bool)
+	names := make(map[string]bool)
+	for i, g := range config.Groups {
+		field := fmt.Sprintf("Groups[%d].", i)
+		errs = required(errs, field+"Name", g.Name)
+		if g.Guid != "" && guids[g.Guid] {
+			errs = append(errs, FieldError{field + "Guid", "is used by another group"})
+		}
+		if g.Name != "" && names[g.Name] {
+			errs = append(errs, FieldError{field + "Name", "is used by another group"})
+		}
+		guids[g.Guid], names[g.Name] = true, true
+
+		for _, tag := range g.Tags {
+			if tag == "" || strings.Contains(tag, ",") {
+				errs = append(errs, FieldError{field + "Tags", fmt.Sprintf("%q isn't a valid tag", tag)})
+			}
+		}
+		for j, netId := range g.Members {
+			errs = required(errs, fmt.Sprintf("%sMembers[%d]", field, j), netId)
+		}
+	}
+
+	for i, netId := range config.Admins {
+		errs = required(errs, fmt.Sprintf("Admins[%d]", i), netId)
+	}
+
+	for i, p := range config.Policy {
+		field := fmt.Sprintf("Policy[%d].", i)
+		errs = required(errs, field+"Actor", p.Actor, field+"Verb", p.Verb, field+"Resource", p.Resource)
+		if p.Condition != "" {
+			if _, err := conditions.Parse(p.Condition); err != nil {
+				errs = append(errs, FieldError{field + "Condition", fmt.Sprintf("Invalid condition: %v", err)})
+			}
+		}
+	}
+	return errs
+}
+
+// Check an import against the area's separation of duties rules: the
+// area's members against the rows added for the area, users joining a
+// group against everything the group will hold and a group's members
+// against the rows added for it. current is the area's configuration
+// before the import. Responds and returns false if the import must not go
+// ahead.
+func (a *Api) enforceImportSod(c *eden.Context, area string, current accessors.AreaConfig, changes accessors.ConfigChanges) bool {
+	added := make(map[string][]accessors.Permission)
+	for _, p := range changes.AddPolicy {
+		added[p.Actor] = append(added[p.Actor], accessors.Permission{p.Actor, p.Verb, p.Resource, p.Condition})
+	}
+	if perms := added[area]; len(perms) > 0 {
+		users := func() ([]string, error) { return accessors.NewSodAccessor(a.DB).Grantees(area, area) }
+		if !a.enforceSod(c, area, users, perms) {
+			return false
+		}
+	}
+
+	// Each group's policy rows and members once the import is applied
+	removed := make(map[accessors.Permission]bool)
+	for _, p := range changes.RemovePolicy {
+		removed[accessors.Permission{p.Actor, p.Verb, p.Resource, p.Condition}] = true
+	}
+	held := make(map[string][]accessors.Permission)
+	for _, p := range current.Policy {
+		if perm := (accessors.Permission{p.Actor, p.Verb, p.Resource, p.Condition}); !removed[perm] {
+			held[p.Actor] = append(held[p.Actor], perm)
+		}
+	}
+	for actor, perms := range added {
+		held[actor] = append(held[actor], perms...)
+	}
+
+	members := make(map[string][]string)
+	leaving := make(map[accessors.Membership]bool)
+	for _, m := range changes.RemoveMembers {
+		leaving[m] = true
+	}
+	for _, g := range current.Groups {
+		for _, netId := range g.Members {
+			if !leaving[accessors.Membership{g.Guid, netId}] {
+				members[g.Guid] = append(members[g.Guid], netId)
+			}
+		}
+	}
+	joining := make(map[string][]string)
+	for _, m := range changes.AddMembers {
+		members[m.Group] = append(members[m.Group], m.NetId)
+		joining[m.Group] = append(joining[m.Group], m.NetId)
+	}
+
+	groups := make([]string, 0)
+	for group := range members {
+		if group != area && (len(added[group]) > 0 || len(joining[group]) > 0) {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		users, extra := joining[group], held[group]
+		if len(added[group]) > 0 {
+			users = members[group]
+			if len(joining[group]) == 0 {
+				extra = added[group]
+			}
+		}
+
+		if !a.enforceSod(c, area, func() ([]string, error) { return users, nil }, extra) {
+			return false
+		}
+	}
+
+	return true
+}
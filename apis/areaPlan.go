@@ -0,0 +1,196 @@
+package apis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// What applying a configuration file to an area would do. Fingerprint
+// identifies the area's configuration, the file and the options the plan
+// was made from, so that an apply can make sure nothing changed since.
+// Summary describes each change in words.
+type Plan struct {
+	Fingerprint string
+	Changes     accessors.ConfigChanges
+	Summary     []string
+}
+
+// Identifies a plan by what it was made from. Groups a plan creates get
+// new guids every time, so the changes themselves can't be used.
+func planFingerprint(current, config accessors.AreaConfig, opts accessors.ImportOptions) (string, error) {
+	b, err := json.Marshal([]interface{}{current, config, opts})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Describes each change in words, naming groups rather than giving their
+// guids.
+func describeChanges(current accessors.AreaConfig, changes accessors.ConfigChanges) []string {
+	names := map[string]string{current.Area: "the area"}
+	for _, groups := range [][]accessors.GroupConfig{current.Groups, changes.UpdateGroups, changes.CreateGroups} {
+		for _, g := range groups {
+			names[g.Guid] = "group " + g.Name
+		}
+	}
+	name := func(guid string) string {
+		if n, ok := names[guid]; ok {
+			return n
+		}
+		return guid
+	}
+	grant := func(p accessors.PolicyConfig) string {
+		s := fmt.Sprintf("%s on %s", p.Verb, p.Resource)
+		if p.Condition != "" {
+			s += fmt.Sprintf(" when %s", p.Condition)
+		}
+		return s
+	}
+
+	summary := make([]string, 0)
+	for _, guid := range changes.DeleteGroups {
+		summary = append(summary, fmt.Sprintf("delete %s", name(guid)))
+	}
+	for _, g := range changes.UpdateGroups {
+		summary = append(summary, fmt.Sprintf("update group %s", g.Name))
+	}
+	for _, g := range changes.CreateGroups {
+		summary = append(summary, fmt.Sprintf("create group %s", g.Name))
+	}
+	for _, m := range changes.RemoveMembers {
+		summary = append(summary, fmt.Sprintf("remove %s from %s", m.NetId, name(m.Group)))
+	}
+	for _, m := range changes.AddMembers {
+		summary = append(summary, fmt.Sprintf("add %s to %s", m.NetId, name(m.Group)))
+	}
+	for _, p := range changes.RemovePolicy {
+		summary = append(summary, fmt.Sprintf("revoke %s from %s", grant(p), name(p.Actor)))
+	}
+	for _, p := range changes.AddPolicy {
+		summary = append(summary, fmt.Sprintf("grant %s to %s", grant(p), name(p.Actor)))
+	}
+	for _, netId := range changes.RemoveAdmins {
+		summary = append(summary, fmt.Sprintf("remove admin %s", netId))
+	}
+	for _, netId := range changes.AddAdmins {
+		summary = append(summary, fmt.Sprintf("request approval to add admin %s", netId))
+	}
+
+	return summary
+}
+
+// Read a configuration file and work out its plan. Responds and returns
+// false if the file can't be planned.
+func (a *Api) makePlan(c *eden.Context, area string, opts accessors.ImportOptions, handler string) (Plan, bool) {
+	config, ok := decodeConfig(c)
+	if !ok {
+		return Plan{}, false
+	}
+
+	current, changes, ok := a.planAreaImport(c, area, config, opts, handler)
+	if !ok {
+		return Plan{}, false
+	}
+
+	fingerprint, err := planFingerprint(current, config, opts)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on planFingerprint in %s: %v", handler, err), true, a.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return Plan{}, false
+	}
+
+	return Plan{fingerprint, changes, describeChanges(current, changes)}, true
+}
+
+// Plan applying a configuration file, in the format GET /areas/:area/export
+// writes, to an area. The file describes the whole area, so groups,
+// members, admins and policy rows it doesn't have are removed unless
+// mode=merge. Admins are left alone if the file has no Admins. Policy rows
+// may name their group by name. Nothing is changed; apply the file with
+// POST /areas/:area/apply.
+// POST /areas/:area/plan?mode=replace|merge&remap=:from:to
+func (a *Api) PlanArea(c *eden.Context) {
+	guid := c.Params[0].Value
+
+	opts, _, ok := importOptions(c, true)
+	if !ok {
+		return
+	}
+
+	if !a.canConfigureArea(c, guid, "PlanArea (POST /areas/:area/plan)") {
+		return
+	}
+
+	plan, ok := a.makePlan(c, guid, opts, "PlanArea (POST /areas/:area/plan)")
+	if !ok {
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", plan})
+}
+
+// Apply a configuration file to an area in a single transaction, as
+// POST /areas/:area/plan would plan it. revision names the version of the
+// file, such as a git commit, and is recorded in an audit entry for each
+// change. With plan=:fingerprint the file is only applied if the area and
+// the file are still what that plan was made from. Admins to add are
+// requested for approval rather than added.
+// POST /areas/:area/apply?revision=:revision&plan=:fingerprint&mode=replace|merge&remap=:from:to
+func (a *Api) ApplyArea(c *eden.Context) {
+	aa := accessors.NewAreaAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called ApplyArea on %s (POST /areas/:area/apply)", guid), true, aa.DB)
+
+	opts, query, ok := importOptions(c, true)
+	if !ok {
+		return
+	}
+	revision := query.Get("revision")
+	if revision == "" {
+		c.Respond(400, eden.Response{"ERROR", []FieldError{{"revision", "is required"}}})
+		return
+	}
+
+	if !a.canConfigureArea(c, guid, "ApplyArea (POST /areas/:area/apply)") {
+		return
+	}
+
+	plan, ok := a.makePlan(c, guid, opts, "ApplyArea (POST /areas/:area/apply)")
+	if !ok {
+		return
+	}
+	if expected := query.Get("plan"); expected != "" && expected != plan.Fingerprint {
+		c.Respond(409, eden.Response{"FAILURE", "The area or the file changed since the plan was made; plan again"})
+		return
+	}
+
+	if plan.Changes.Empty() {
+		c.Respond(200, eden.Response{"OK", plan})
+		return
+	}
+
+	pending, err := aa.ApplyConfig(guid, plan.Changes, timeNow().Unix(), c.User.NetId)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on ApplyConfig in ApplyArea (POST /areas/:area/apply): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error occurred while applying the plan"})
+		return
+	}
+
+	for _, change := range plan.Summary {
+		accessors.Log("audit", c.User.NetId, fmt.Sprintf("Applied revision %s to area %s: %s", revision, guid, change), true, aa.DB)
+	}
+	for i, approval := range pending {
+		accessors.Log("audit", c.User.NetId, fmt.Sprintf("Requested approval %s to make %s an admin of area %s", approval, plan.Changes.AddAdmins[i], guid), true, aa.DB)
+	}
+	c.Respond(200, eden.Response{"OK", plan})
+}
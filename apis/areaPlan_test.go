@@ -0,0 +1,33 @@
+package apis
+
+import (
+	"reflect"
+	"testing"
+
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+func TestDescribeChanges(t *testing.T) {
+	current := accessors.AreaConfig{
+		Area:   "area",
+		Groups: []accessors.GroupConfig{{Guid: "g1", Name: "desk"}},
+	}
+	changes := accessors.ConfigChanges{
+		CreateGroups:  []accessors.GroupConfig{{Guid: "g2", Name: "night"}},
+		AddMembers:    []accessors.Membership{{"g2", "carol"}},
+		RemoveMembers: []accessors.Membership{{"g1", "bob"}},
+		AddPolicy:     []accessors.PolicyConfig{{Actor: "g2", Verb: "edit", Resource: "res", Condition: "time.hour >= 9"}},
+		RemovePolicy:  []accessors.PolicyConfig{{Actor: "area", Verb: "view", Resource: "res"}},
+	}
+
+	expected := []string{
+		"create group night",
+		"remove bob from group desk",
+		"add carol to group night",
+		"revoke view on res from the area",
+		"grant edit on res when time.hour >= 9 to group night",
+	}
+	if summary := describeChanges(current, changes); !reflect.DeepEqual(summary, expected) {
+		t.Errorf("Expected %q but got %q", expected, summary)
+	}
+}
@@ -0,0 +1,294 @@
+package apis
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// Make sure an area referenced by a request exists. Responds and returns
+// false if it doesn't or can't be looked up.
+func (a *Api) knownArea(c *eden.Context, area, handler string) bool {
+	aa := accessors.NewAreaAccessor(a.DB)
+
+	exists, err := aa.Exists(area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Exists in %s: %v", handler, err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return false
+	}
+	if !exists {
+		c.Respond(400, eden.Response{"ERROR", "No such area"})
+		return false
+	}
+
+	return true
+}
+
+// Get every area.
+// GET /areas
+func (a *Api) GetAreas(c *eden.Context) {
+	aa := accessors.NewAreaAccessor(a.DB)
+
+	areas, err := aa.GetAll()
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetAll in GetAreas (GET /areas): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", areas})
+}
+
+// Get an area.
+// GET /areas/:area
+func (a *Api) GetArea(c *eden.Context) {
+	aa := accessors.NewAreaAccessor(a.DB)
+
+	area, err := aa.Get(c.Params[0].Value)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such area"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in GetArea (GET /areas/:area): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", area})
+}
+
+// Create an area. Superusers can create any area, admins can create areas
+// below one they administer. With inherit=true the new area inherits its
+// parent's area level policy rows.
+// POST /areas name=:name, description=:description, parent=:areaGuid, inherit=:bool
+func (a *Api) CreateArea(c *eden.Context) {
+	aa := accessors.NewAreaAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called CreateArea (POST /areas name=:name, description=:description, parent=:areaGuid, inherit=:bool)", true, aa.DB)
+
+	c.Request.ParseForm()
+	area := accessors.Area{
+		Name:        c.Request.Form.Get("name"),
+		Description: c.Request.Form.Get("description"),
+		Parent:      c.Request.Form.Get("parent"),
+	}
+	if area.Name == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid name"})
+		return
+	}
+	inherit, ok := parseInherit(c)
+	if !ok {
+		return
+	}
+	area.Inherit = inherit
+
+	var allowed bool
+	var err error
+	if area.Parent == "" {
+		allowed, err = pa.IsSuperuser(c.User.NetId)
+	} else {
+		if !a.knownArea(c, area.Parent, "CreateArea (POST /areas)") {
+			return
+		}
+		allowed, err = isSUOrAdmin(pa, c.User.NetId, area.Parent)
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error checking rights in CreateArea (POST /areas): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be a superuser or an admin of the parent area to create areas"})
+		return
+	}
+
+	guid, err := aa.Create(area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Create in CreateArea (POST /areas): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Created area %s (%s) under %q", guid, area.Name, area.Parent), true, aa.DB)
+	c.Respond(200, eden.Response{"OK", guid})
+}
+
+// Read the inherit form value. Responds and returns false if it isn't a
+// boolean.
+func parseInherit(c *eden.Context) (bool, bool) {
+	inherit := c.Request.Form.Get("inherit")
+	if inherit == "" {
+		return false, true
+	}
+
+	b, err := strconv.ParseBool(inherit)
+	if err != nil {
+		c.Respond(400, eden.Response{"ERROR", "Invalid inherit"})
+		return false, false
+	}
+	return b, true
+}
+
+// Update an area. Fields left out are unchanged. Admins of the area can
+// change its name, description and inheritance; moving it to another
+// parent (or to the top level with parent=) takes a superuser.
+// PUT /areas/:area name=:name, description=:description, parent=:areaGuid, inherit=:bool
+func (a *Api) UpdateArea(c *eden.Context) {
+	aa := accessors.NewAreaAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called UpdateArea on %s (PUT /areas/:area)", guid), true, aa.DB)
+
+	area, err := aa.Get(guid)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such area"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in UpdateArea (PUT /areas/:area): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in UpdateArea (PUT /areas/:area): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to update an area"})
+		return
+	}
+
+	c.Request.ParseForm()
+	if name, ok := c.Request.Form["name"]; ok {
+		if name[0] == "" {
+			c.Respond(400, eden.Response{"ERROR", "Invalid name"})
+			return
+		}
+		area.Name = name[0]
+	}
+	if description, ok := c.Request.Form["description"]; ok {
+		area.Description = description[0]
+	}
+	if _, ok := c.Request.Form["inherit"]; ok {
+		inherit, ok := parseInherit(c)
+		if !ok {
+			return
+		}
+		area.Inherit = inherit
+	}
+	if parent, ok := c.Request.Form["parent"]; ok && parent[0] != area.Parent {
+		if !a.moveArea(c, &area, parent[0]) {
+			return
+		}
+	}
+
+	if err := aa.Update(area); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Update in UpdateArea (PUT /areas/:area): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Updated area %s (%s) under %q, inherit %v", guid, area.Name, area.Parent, area.Inherit), true, aa.DB)
+	c.Respond(200, eden.Response{"OK", area})
+}
+
+// Set an area's parent, making sure the user is a superuser and the move
+// doesn't put the area below itself. Responds and returns false if the
+// move isn't allowed.
+func (a *Api) moveArea(c *eden.Context, area *accessors.Area, parent string) bool {
+	aa := accessors.NewAreaAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	su, err := pa.IsSuperuser(c.User.NetId)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in UpdateArea (PUT /areas/:area): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return false
+	}
+	if !su {
+		c.Respond(403, eden.Response{"ERROR", "You need to be a superuser to move an area"})
+		return false
+	}
+
+	if parent != "" {
+		lineage, err := aa.Lineage(parent)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Lineage in UpdateArea (PUT /areas/:area): %v", err), true, aa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return false
+		}
+		if len(lineage) == 0 {
+			c.Respond(400, eden.Response{"ERROR", "No such area"})
+			return false
+		}
+		for _, ancestor := range lineage {
+			if ancestor.Guid == area.Guid {
+				c.Respond(400, eden.Response{"ERROR", "An area can't be moved below itself"})
+				return false
+			}
+		}
+	}
+
+	area.Parent = parent
+	return true
+}
+
+// Decommission an area, removing its groups, admins, roles and grants.
+// Only superusers can decommission areas. Responds with the number of rows
+// affected in each table.
+// DELETE /areas/:area
+func (a *Api) DecommissionArea(c *eden.Context) {
+	aa := accessors.NewAreaAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called DecommissionArea on %s (DELETE /areas/:area)", guid), true, aa.DB)
+
+	su, err := pa.IsSuperuser(c.User.NetId)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in DecommissionArea (DELETE /areas/:area): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !su {
+		c.Respond(403, eden.Response{"ERROR", "You need to be a superuser to decommission areas"})
+		return
+	}
+
+	if !a.knownArea(c, guid, "DecommissionArea (DELETE /areas/:area)") {
+		return
+	}
+
+	children, err := aa.Children(guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Children in DecommissionArea (DELETE /areas/:area): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if len(children) > 0 {
+		c.Respond(409, eden.Response{"ERROR", "The area has areas below it; move or decommission them first"})
+		return
+	}
+
+	affected, err := aa.Decommission(guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Decommission in DecommissionArea (DELETE /areas/:area): %v", err), true, aa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Decommissioned area %s: %v", guid, affected), true, aa.DB)
+	c.Respond(200, eden.Response{"OK", affected})
+}
@@ -0,0 +1,115 @@
+package apis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestCreateGroupInUnknownArea(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid FROM areas WHERE guid=.").
+		WithArgs("typo").
+		WillReturnRows(sqlmock.NewRows([]string{"guid"}).FromCSVString(""))
+
+	// Create context and call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("area=typo&name=testGroup", nil, api.CreateGroup)
+	testhelpers.CallAPI(api.CreateGroup, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Ensure correct output
+	if output.Data != "No such area" {
+		t.Errorf("expected to get 'No such area' but got %v instead", output)
+	}
+}
+
+func TestCreateAreaWithoutSuperuser(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("guid").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString("0"))
+
+	// Create context and call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("name=Facilities", nil, api.CreateArea)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.CreateArea, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Ensure correct output
+	if output.Status != "ERROR" {
+		t.Errorf("expected an error but got %v instead", output)
+	}
+}
+
+func TestImportAreaInvalidOptions(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// Create context and call API
+	var result []byte
+	var output struct {
+		Status string
+		Data   []FieldError
+	}
+	c := testhelpers.NewTestingContext("mode=overwrite&remap=s-res", httprouter.Params{httprouter.Param{Key: "area", Value: "area"}}, api.ImportArea)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.ImportArea, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Ensure correct output
+	if output.Status != "ERROR" || len(output.Data) != 2 {
+		t.Errorf("expected errors for mode and remap but got %v instead", output)
+	}
+}
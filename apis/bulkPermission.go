@@ -0,0 +1,279 @@
+package apis
+
+import (
+	"fmt"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// What became of an operation of a bulk request.
+const (
+	bulkGranted   = "granted"
+	bulkRevoked   = "revoked"
+	bulkUnchanged = "unchanged"
+	bulkNotFound  = "notFound"
+	bulkPending   = "pending"
+	bulkForbidden = "forbidden"
+	bulkBlocked   = "blocked"
+	bulkFailed    = "failed"
+	bulkSkipped   = "skipped"
+)
+
+// The result of one operation of a bulk request. Detail holds the
+// approval guid of pending grants, the violations blocking a grant, or
+// why an operation was refused or failed.
+type BulkResult struct {
+	Index  int
+	Status string
+	Detail interface{}
+}
+
+// Returns a function telling whether the requestor may grant or revoke a
+// verb on a resource. Superusers and the area's admins may grant and
+// revoke anything; others need the permission themselves. The
+// requestor's groups are only looked up once.
+func (a *Api) bulkAuthorizer(c *eden.Context) (func(resource, verb string) (bool, error), error) {
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	suOrAdmin, err := isSUOrAdmin(pa, c.User.NetId, c.User.Area)
+	if err != nil {
+		return nil, err
+	}
+	if suOrAdmin {
+		return func(resource, verb string) (bool, error) { return true, nil }, nil
+	}
+
+	actors, err := pa.Get(c.User.Area, c.User.NetId)
+	if err != nil {
+		return nil, err
+	}
+
+	actorArray := make([]string, 0)
+	for i := 0; i < len(actors); i++ {
+		actorArray = append(actorArray, actors[i].Guid)
+	}
+	actorArray = append(actorArray, c.User.Area, c.User.NetId)
+
+	checked := make(map[[2]string]bool)
+	return func(resource, verb string) (bool, error) {
+		key := [2]string{resource, verb}
+		if permission, ok := checked[key]; ok {
+			return permission, nil
+		}
+
+		permission, err := pa.CheckPermission(c.User.Area, actorArray, resource, verb)
+		if err != nil {
+			return false, err
+		}
+		checked[key] = permission
+		return permission, nil
+	}, nil
+}
+
+// Grant and revoke permissions in one request. Each operation is authorized
+// and checked against separation of duties rules as POST and DELETE
+// /permission would, then all of them run in a single transaction. In
+// atomic mode nothing is applied unless every operation can be; in
+// bestEffort mode the rest go ahead. Grants on sensitive resources are
+// held for approval, their requests created in the same transaction.
+// POST /permission/bulk {"Mode": "atomic|bestEffort", "Operations": [{"Op": "grant|revoke", "Actor", "Verb", "Resource", "Condition"}]}
+func (a *Api) BulkPermissions(c *eden.Context) {
+	pa := accessors.NewPermissionAccessor(a.DB)
+	aa := accessors.NewApprovalAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called BulkPermissions (POST /permission/bulk)", true, pa.DB)
+
+	// Parse input
+	var req BulkPermissionRequest
+	if !decodeRequest(c, &req) {
+		return
+	}
+	atomic := req.Mode == bulkAtomic
+
+	allowed, err := a.bulkAuthorizer(c)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on bulkAuthorizer in BulkPermissions (POST /permission/bulk): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	// Decide what to do with each operation. Those left without a status
+	// are applied, or held for approval if they're in held.
+	results := make([]BulkResult, len(req.Operations))
+	sensitive := make(map[string]bool)
+	held := make(map[int]bool)
+	grants := make(map[string][]int)
+	for i, op := range req.Operations {
+		results[i].Index = i
+
+		permission, err := allowed(op.Resource, op.Verb)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on CheckPermission in BulkPermissions (POST /permission/bulk): %v", err), true, pa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		if !permission {
+			results[i].Status = bulkForbidden
+			results[i].Detail = "You need to have this permission in order to grant it"
+			continue
+		}
+		if op.Op == "revoke" {
+			continue
+		}
+
+		granted, err := pa.Granted(op.Actor, op.Verb, op.Resource)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Granted in BulkPermissions (POST /permission/bulk): %v", err), true, pa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		if granted {
+			results[i].Status = bulkUnchanged
+			continue
+		}
+
+		isSensitive, ok := sensitive[op.Resource]
+		if !ok {
+			isSensitive, err = aa.IsSensitive(op.Resource)
+			if err != nil {
+				accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSensitive in BulkPermissions (POST /permission/bulk): %v", err), true, pa.DB)
+				c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+				return
+			}
+			sensitive[op.Resource] = isSensitive
+		}
+		held[i] = isSensitive
+
+		grants[op.Actor] = append(grants[op.Actor], i)
+	}
+
+	// Check each actor's new grants, held ones included, against
+	// separation of duties together, so that a conflicting pair in the same
+	// request is caught.
+	blocked := make(map[int][]accessors.SodViolation)
+	warnings := make(map[int][]accessors.SodViolation)
+	for actor, indexes := range grants {
+		extra := make([]accessors.Permission, 0, len(indexes))
+		for _, i := range indexes {
+			extra = append(extra, accessors.Permission{actor, req.Operations[i].Verb, req.Operations[i].Resource, req.Operations[i].Condition})
+		}
+
+		sa := accessors.NewSodAccessor(a.DB)
+		areas, err := sa.GrantAreas(actor, c.User.Area)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GrantAreas in BulkPermissions (POST /permission/bulk): %v", err), true, pa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+
+		violations := make([]accessors.SodViolation, 0)
+		for _, area := range areas {
+			grantee := actor
+			users := func() ([]string, error) { return sa.Grantees(grantee, area) }
+			found, err := sa.Violations(area, users, extra)
+			if err != nil {
+				accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Violations in BulkPermissions (POST /permission/bulk): %v", err), true, pa.DB)
+				c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+				return
+			}
+			violations = append(violations, found...)
+		}
+
+		for _, v := range violations {
+			for _, i := range indexes {
+				op := req.Operations[i]
+				if v.Resource != op.Resource || (v.Rule.VerbA != op.Verb && v.Rule.VerbB != op.Verb) {
+					continue
+				}
+				if v.Rule.Mode == accessors.SodWarn {
+					warnings[i] = append(warnings[i], v)
+				} else {
+					blocked[i] = append(blocked[i], v)
+				}
+			}
+		}
+	}
+	for i, violations := range blocked {
+		results[i].Status = bulkBlocked
+		results[i].Detail = violations
+	}
+
+	if atomic {
+		refused := false
+		for _, result := range results {
+			if result.Status == bulkForbidden || result.Status == bulkBlocked {
+				refused = true
+				break
+			}
+		}
+		if refused {
+			for i := range results {
+				if results[i].Status != bulkForbidden && results[i].Status != bulkBlocked {
+					results[i].Status = bulkSkipped
+					results[i].Detail = nil
+				}
+			}
+			c.Respond(409, eden.Response{"FAILURE", results})
+			return
+		}
+	}
+
+	// Apply everything left in one transaction
+	changes := make([]accessors.PolicyChange, 0)
+	indexes := make([]int, 0)
+	for i, op := range req.Operations {
+		if results[i].Status != "" {
+			continue
+		}
+		change := accessors.PolicyChange{Revoke: op.Op == "revoke", Actor: op.Actor, Verb: op.Verb, Resource: op.Resource, Condition: op.Condition}
+		if held[i] {
+			change.Hold = &accessors.Approval{Type: accessors.ApprovalPermission, Area: c.User.Area, Requester: c.User.NetId, Actor: op.Actor, Verb: op.Verb, Resource: op.Resource, Condition: op.Condition}
+		}
+		changes = append(changes, change)
+		indexes = append(indexes, i)
+	}
+
+	if len(changes) > 0 {
+		counts, errs, err := pa.ApplyChanges(changes, atomic)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on ApplyChanges in BulkPermissions (POST /permission/bulk): %v", err), true, pa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error occurred while applying the operations"})
+			return
+		}
+
+		for j, i := range indexes {
+			switch {
+			case errs[j] != nil:
+				accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on ApplyChanges in BulkPermissions (POST /permission/bulk) for operation %d: %v", i, errs[j]), true, pa.DB)
+				results[i].Status = bulkFailed
+				results[i].Detail = "An error has occurred"
+			case changes[j].Hold != nil:
+				accessors.Log("audit", c.User.NetId, fmt.Sprintf("Requested approval %s: %s", changes[j].Hold.Guid, describeApproval(*changes[j].Hold)), true, pa.DB)
+				results[i].Status = bulkPending
+				results[i].Detail = changes[j].Hold.Guid
+			case changes[j].Revoke && counts[j] > 0:
+				results[i].Status = bulkRevoked
+			case changes[j].Revoke:
+				results[i].Status = bulkNotFound
+			case counts[j] > 0:
+				results[i].Status = bulkGranted
+			default:
+				results[i].Status = bulkUnchanged
+			}
+		}
+	}
+
+	// Report separation of duties warnings for grants that went ahead
+	for i, violations := range warnings {
+		if results[i].Status != bulkGranted {
+			continue
+		}
+		for _, v := range violations {
+			accessors.Log("audit", c.User.NetId, fmt.Sprintf("Separation of duties warning: %s would hold %s and %s on %s", v.NetId, v.Rule.VerbA, v.Rule.VerbB, v.Resource), true, pa.DB)
+			c.Response.Header().Add("Warning", fmt.Sprintf("299 tmt-permissions \"separation of duties: %s would hold %s and %s on %s\"", v.NetId, v.Rule.VerbA, v.Rule.VerbB, v.Resource))
+		}
+	}
+
+	c.Respond(200, eden.Response{"OK", results})
+}
@@ -0,0 +1,193 @@
+package apis
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+const (
+	eventLifetime     = 7 * 24 * time.Hour // how long events are kept
+	eventMaxWait      = 60 * time.Second   // the longest a long poll is held
+	eventPollInterval = 500 * time.Millisecond
+	eventKeepAlive    = 15 * time.Second // how often an idle stream is written to
+	eventLimit        = 100
+	eventMaxLimit     = 1000
+)
+
+// A page of the change feed. Next is the sequence number to ask for events
+// after to get the following page.
+type EventPage struct {
+	Events []accessors.Event
+	Next   int64
+}
+
+// Read the change feed: every change that can affect permission decisions,
+// in order. Pass the Next of the previous page as since to resume. With
+// wait, the request is held for up to that many seconds until there are
+// events. Clients that send "Accept: text/event-stream" get the events as
+// server-sent events instead, each with its sequence number as its id,
+// resuming after Last-Event-ID if it's set. The feed covers every area, so
+// only superusers can read it.
+// GET /events?since=:seq&limit=:limit&wait=:seconds
+func (a *Api) GetEvents(c *eden.Context) {
+	pa := accessors.NewPermissionAccessor(a.DB)
+	ea := accessors.NewEventAccessor(a.DB)
+
+	isSU, err := pa.IsSuperuser(c.User.NetId)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in GetEvents (GET /events): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !isSU {
+		c.Respond(403, eden.Response{"ERROR", "You need to be superuser to read the change feed"})
+		return
+	}
+
+	query := c.Request.URL.Query()
+	if id := c.Request.Header.Get("Last-Event-ID"); id != "" {
+		query.Set("since", id)
+	}
+	since, limit, wait, errs := eventOptions(query)
+	if len(errs) > 0 {
+		c.Respond(400, eden.Response{"ERROR", errs})
+		return
+	}
+
+	if strings.Contains(c.Request.Header.Get("Accept"), "text/event-stream") {
+		a.streamEvents(c, ea, since, limit)
+		return
+	}
+
+	deadline := timeNow().Add(wait)
+	for {
+		events, err := ea.Since(since, limit)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Since in GetEvents (GET /events): %v", err), true, pa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		if len(events) > 0 || !timeNow().Before(deadline) {
+			page := EventPage{events, since}
+			if len(events) > 0 {
+				page.Next = events[len(events)-1].Seq
+			}
+			c.Respond(200, eden.Response{"OK", page})
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-time.After(eventPollInterval):
+		}
+	}
+}
+
+// Parse and check the since, limit and wait query parameters.
+func eventOptions(query map[string][]string) (int64, int, time.Duration, map[string]string) {
+	errs := make(map[string]string)
+	get := func(name string) string {
+		if values := query[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	var since int64
+	if s := get("since"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			errs["since"] = "must be a sequence number"
+		}
+		since = n
+	}
+
+	limit := eventLimit
+	if s := get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > eventMaxLimit {
+			errs["limit"] = fmt.Sprintf("must be between 1 and %d", eventMaxLimit)
+		}
+		limit = n
+	}
+
+	var wait time.Duration
+	if s := get("wait"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || time.Duration(n)*time.Second > eventMaxWait {
+			errs["wait"] = fmt.Sprintf("must be between 0 and %d seconds", int(eventMaxWait/time.Second))
+		}
+		wait = time.Duration(n) * time.Second
+	}
+
+	return since, limit, wait, errs
+}
+
+// Send events as server-sent events until the client goes away.
+func (a *Api) streamEvents(c *eden.Context, ea *accessors.EventAccessor, since int64, limit int) {
+	flusher, ok := c.Response.(http.Flusher)
+	if !ok {
+		c.Respond(500, eden.Response{"ERROR", "Streaming isn't supported"})
+		return
+	}
+
+	w := c.Response
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(200)
+	flusher.Flush()
+
+	idle := timeNow()
+	for {
+		events, err := ea.Since(since, limit)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Since in GetEvents (GET /events): %v", err), true, ea.DB)
+			return
+		}
+
+		for _, e := range events {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data); err != nil {
+				return
+			}
+			since = e.Seq
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+			idle = timeNow()
+		} else if timeNow().Sub(idle) >= eventKeepAlive {
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			idle = timeNow()
+		}
+
+		// A full page means there are probably more waiting
+		if len(events) == limit {
+			continue
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-time.After(eventPollInterval):
+		}
+	}
+}
+
+// Drop events older than eventLifetime.
+func (a *Api) pruneEvents() error {
+	_, err := accessors.NewEventAccessor(a.DB).DeleteBefore(timeNow().Add(-eventLifetime).Unix())
+	return err
+}
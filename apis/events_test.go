@@ -0,0 +1,62 @@
+package apis
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestGetEventsWithoutSuperuser(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("guid").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString(""))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", nil, api.GetEvents)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.GetEvents, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf("An unexpected error occurred parsing the response %v", err)
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
+
+func TestEventOptions(t *testing.T) {
+	query, _ := url.ParseQuery("since=12&limit=50&wait=30")
+	since, limit, wait, errs := eventOptions(query)
+	if since != 12 || limit != 50 || wait != 30*time.Second || len(errs) != 0 {
+		t.Errorf("Expected 12, 50 and 30s but got %d, %d, %v and %v", since, limit, wait, errs)
+	}
+
+	since, limit, wait, errs = eventOptions(url.Values{})
+	if since != 0 || limit != eventLimit || wait != 0 || len(errs) != 0 {
+		t.Errorf("Expected the defaults but got %d, %d, %v and %v", since, limit, wait, errs)
+	}
+
+	query, _ = url.ParseQuery("since=last&limit=5000&wait=120")
+	if _, _, _, errs = eventOptions(query); len(errs) != 3 {
+		t.Errorf("Expected since, limit and wait to be rejected but got %v", errs)
+	}
+}
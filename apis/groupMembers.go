@@ -1,11 +1,14 @@
 package apis
 
 import (
+	"database/sql"
 	"fmt"
 	"net/url"
+	"strings"
 
 	eden "github.com/byu-oit-ssengineering/tmt-eden"
 	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	"github.com/byu-oit-ssengineering/tmt-permissions/membership"
 )
 
 // Get a list of all the groups a user is a member of.
@@ -60,7 +63,7 @@ func (a *Api) GetGroupMembers(c *eden.Context) {
 	c.Respond(200, eden.Response{"OK", members})
 }
 
-// Add a user to a group.
+// Add a user to a group. Accepts a form or a JSON AddGroupMemberRequest.
 // POST /groupMembers netId=:netId, group=:groupId
 func (a *Api) AddGroupMember(c *eden.Context) {
 	// Create new group accessor
@@ -69,23 +72,127 @@ func (a *Api) AddGroupMember(c *eden.Context) {
 	accessors.Log("notice", c.User.NetId, "Called AddGroupMember (POST /groupMembers netId=:netId, group=:groupId)", true, ma.DB)
 
 	// Parse group id and netId from POST data.
-	c.Request.ParseForm()
-	netId, netIdOk := c.Request.Form["netId"]
-	group, groupOk := c.Request.Form["group"]
-	if !netIdOk || !groupOk {
-		c.Respond(400, eden.Response{"ERROR", "Invalid netId or groupId"})
+	var req AddGroupMemberRequest
+	if !decodeRequest(c, &req) {
+		return
+	}
+	netId, group := req.NetId, req.Group
+
+	// Check the group's permissions against the area's separation of duties rules
+	ga := accessors.NewGroupAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+	g, err := ga.Get(group)
+	if err == sql.ErrNoRows {
+		c.Respond(400, eden.Response{"ERROR", []FieldError{{"group", "No such group"}}})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in AddGroupMember by %s (POST /groupMembers netId=:netId, group=:groupId): %v", netId, err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	groupPerms, err := pa.GetGroupPermissions(g.Guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetGroupPermissions in AddGroupMember by %s (POST /groupMembers netId=:netId, group=:groupId): %v", netId, err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	users := func() ([]string, error) { return []string{netId}, nil }
+	if !a.enforceSod(c, g.Area, users, groupPerms) {
 		return
 	}
 
 	// Insert the group and test for errors
-	if err := ma.AddToGroup(netId[0], group[0]); err != nil {
-		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on AddToGroup in AddGroupMember by %s (POST /groupMembers netId=:netId, group=:groupId): %v", netId[0], err), true, ma.DB)
+	n, err := ma.AddToGroup(netId, group)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on AddToGroup in AddGroupMember by %s (POST /groupMembers netId=:netId, group=:groupId): %v", netId, err), true, ma.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
 
 	// Respond
-	c.Respond(200, eden.Response{"OK", "success"})
+	c.Respond(200, eden.Response{"OK", WriteResult{n > 0}})
+}
+
+// Response data for PUT /groupMembers/:groupGuid.
+type MembershipChanges struct {
+	Added   []string
+	Removed []string
+	DryRun  bool
+}
+
+// Make the users added to a group directly exactly the given list, adding
+// and removing members in a single transaction. Users the group's rule
+// matches are left alone. With dryRun the changes are worked out and
+// returned without being made. Accepts a form or a JSON
+// SyncGroupMembersRequest.
+// PUT /groupMembers/:groupGuid members=:netId,:netId... [dryRun=true]
+func (a *Api) SyncGroupMembers(c *eden.Context) {
+	ma := accessors.NewMembersAccessor(a.DB)
+	ga := accessors.NewGroupAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called SyncGroupMembers (PUT /groupMembers/:groupGuid)", true, ma.DB)
+
+	// Parse input
+	group := c.Params[0].Value
+	var req SyncGroupMembersRequest
+	if !decodeRequest(c, &req) {
+		return
+	}
+
+	g, err := ga.Get(group)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such group"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in SyncGroupMembers (PUT /groupMembers/:groupGuid): %v", err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	// Work out the changes
+	current, err := ma.GetDirectMembers(group)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetDirectMembers in SyncGroupMembers (PUT /groupMembers/:groupGuid): %v", err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	for i := range req.Members {
+		req.Members[i] = strings.TrimSpace(req.Members[i])
+	}
+	add, remove := membership.Diff(current, req.Members)
+
+	// Check the group's permissions against the area's separation of duties
+	// rules for the users joining it
+	if len(add) > 0 {
+		groupPerms, err := pa.GetGroupPermissions(g.Guid)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetGroupPermissions in SyncGroupMembers (PUT /groupMembers/:groupGuid): %v", err), true, ma.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		users := func() ([]string, error) { return add, nil }
+		if !a.enforceSod(c, g.Area, users, groupPerms) {
+			return
+		}
+	}
+
+	if req.DryRun {
+		c.Respond(200, eden.Response{"OK", MembershipChanges{add, remove, true}})
+		return
+	}
+
+	added, removed, err := ma.UpdateMembers(group, add, remove)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on UpdateMembers in SyncGroupMembers (PUT /groupMembers/:groupGuid): %v", err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error occurred while updating the group's members"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Synced members of group %s: added %d, removed %d", group, len(added), len(removed)), true, ma.DB)
+	c.Respond(200, eden.Response{"OK", MembershipChanges{added, removed, false}})
 }
 
 // Remove a user from a group.
@@ -101,11 +208,16 @@ func (a *Api) RemoveGroupMember(c *eden.Context) {
 	accessors.Log("notice", c.User.NetId, fmt.Sprintf("%s called RemoveGroupMember (DELETE /groupMembers/:netId/:groupGuid)", netId), true, ma.DB)
 
 	// Delete the group
-	if err := ma.RemoveFromGroup(netId, groupId); err != nil {
+	n, err := ma.RemoveFromGroup(netId, groupId)
+	if err != nil {
 		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on RemoveFromGroup in RemoveGroupMember by %s (DELETE /groupMembers/:netId/:groupGuid): %v", netId, err), true, ma.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
+	if n == 0 {
+		c.Respond(404, eden.Response{"ERROR", "No such membership"})
+		return
+	}
 
 	// Respond
 	c.Respond(200, eden.Response{"OK", "success"})
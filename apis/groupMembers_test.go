@@ -9,6 +9,9 @@ import (
 	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/julienschmidt/httprouter"
+	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -29,7 +32,7 @@ func TestGetGroupsByNetId(t *testing.T) {
 	columns := []string{"groupGuid"}
 	sqlmock.ExpectPrepare()
 	sqlmock.ExpectQuery("SELECT groupGuid FROM groupMembers WHERE netId=(.)").
-		WithArgs("someone").
+		WithArgs("someone", "someone").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1\n2"))
 
 	// Create context, call API
@@ -65,7 +68,7 @@ func TestGetGroupMembers(t *testing.T) {
 	columns := []string{"netId"}
 	sqlmock.ExpectPrepare()
 	sqlmock.ExpectQuery("SELECT netId FROM groupMembers WHERE groupGuid=(.)").
-		WithArgs("1").
+		WithArgs("1", "1").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("netId\nsomeone"))
 
 	// Create context, call API
@@ -101,9 +104,32 @@ func TestAddGroupMember(t *testing.T) {
 	api := &Api{db}
 
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectExec("INSERT INTO groupMembers .+ VALUES .+").
-		WithArgs("netId", "1").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("1,area,group1,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.actor=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource", "condition"}).FromCSVString("1,edit,res,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ JOIN groups .+ WHERE roleBinding.actor=. AND role.area = groups.area").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM sodRule WHERE area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "verbA", "verbB", "mode"}).FromCSVString(""))
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO groupMembers .+ SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("netId", "1", "netId", "1").
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
 	// Create context and call API
 	var result []byte
@@ -118,12 +144,12 @@ func TestAddGroupMember(t *testing.T) {
 	}
 
 	// Ensure correct output
-	if output.Data != "success" {
-		t.Errorf("expected to get 'success' but got %v instead", output.Data)
+	if data, ok := output.Data.(map[string]interface{}); !ok || data["Created"] != true {
+		t.Errorf("expected to get %v but got %v instead", WriteResult{true}, output.Data)
 	}
 }
 
-func TestRemoveGroupMember(t *testing.T) {
+func TestAddGroupMemberJSON(t *testing.T) {
 	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
 		return true, nil
 	}
@@ -135,9 +161,106 @@ func TestRemoveGroupMember(t *testing.T) {
 	api := &Api{db}
 
 	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("1,area,group1,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.actor=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource", "condition"}).FromCSVString("1,edit,res,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ JOIN groups .+ WHERE roleBinding.actor=. AND role.area = groups.area").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM sodRule WHERE area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "verbA", "verbB", "mode"}).FromCSVString(""))
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO groupMembers .+ SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("netId", "1", "netId", "1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	// Create context and call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", nil, api.AddGroupMember)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Body = ioutil.NopCloser(strings.NewReader(`{"netId": "netId", "group": "1"}`))
+	testhelpers.CallAPI(api.AddGroupMember, c, &result)
+
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+		t.FailNow()
+	}
+
+	// Ensure correct output
+	if data, ok := output.Data.(map[string]interface{}); !ok || data["Created"] != true {
+		t.Errorf("expected to get %v but got %v instead", WriteResult{true}, output.Data)
+	}
+}
+
+func TestAddGroupMemberMissingFields(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// Create context and call API
+	var result []byte
+	var output struct {
+		Status string
+		Data   []FieldError
+	}
+	c := testhelpers.NewTestingContext("", nil, api.AddGroupMember)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Body = ioutil.NopCloser(strings.NewReader(`{"group": ""}`))
+	testhelpers.CallAPI(api.AddGroupMember, c, &result)
+
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+		t.FailNow()
+	}
+
+	// Ensure correct output
+	expected := []FieldError{{"netId", "is required"}, {"group", "is required"}}
+	if !reflect.DeepEqual(output.Data, expected) {
+		t.Errorf("expected to get %v but got %v instead", expected, output.Data)
+	}
+}
+
+func TestRemoveGroupMember(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectBegin()
 	sqlmock.ExpectExec("DELETE FROM groupMembers WHERE netId=(.) AND groupGuid=(.)").
 		WithArgs("netId", "1").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
 	// Create context and call API
 	var result []byte
@@ -156,3 +279,166 @@ func TestRemoveGroupMember(t *testing.T) {
 		t.Errorf("expected to get 'success' but got %v instead", output.Data)
 	}
 }
+
+func TestRemoveMissingGroupMember(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("DELETE FROM groupMembers WHERE netId=(.) AND groupGuid=(.)").
+		WithArgs("netId", "1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectCommit()
+
+	// Create context and call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", httprouter.Params{httprouter.Param{Key: "netId", Value: "netId"}, httprouter.Param{Key: "groupGuid", Value: "1"}}, api.RemoveGroupMember)
+	testhelpers.CallAPI(api.RemoveGroupMember, c, &result)
+
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+		t.FailNow()
+	}
+
+	// Ensure correct output
+	if output.Data != "No such membership" {
+		t.Errorf("expected to get 'No such membership' but got %v instead", output.Data)
+	}
+}
+
+func TestAddGroupMemberViolatingSod(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("1,area,approvers,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.actor=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource", "condition"}).FromCSVString("1,approve-timesheet,ts,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ JOIN groups .+ WHERE roleBinding.actor=. AND role.area = groups.area").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM sodRule WHERE area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "verbA", "verbB", "mode"}).FromCSVString("r1,area,approve-timesheet,submit-timesheet,block"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("netId", "area", "netId", "area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("2,area,submitters,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("area,Area,,,0"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ LEFT JOIN policyArea .+ WHERE .policyArea.area IS NULL OR policyArea.area=.. AND policy.actor IN .+").
+		WithArgs("area", "area", "netId", "2").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource", "condition"}).FromCSVString("2,submit-timesheet,ts,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "area", "netId", "2").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+
+	// Create context and call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("netId=netId&group=1", nil, api.AddGroupMember)
+	testhelpers.CallAPI(api.AddGroupMember, c, &result)
+
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+		t.FailNow()
+	}
+
+	// Ensure the membership was refused
+	if output.Status != "FAILURE" {
+		t.Errorf("expected to get 'FAILURE' but got %v instead", output.Status)
+	}
+}
+
+func TestSyncGroupMembersDryRun(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("1,area,group1,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT netId FROM groupMembers WHERE groupGuid=(.)").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("alice\nbob"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.actor=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource", "condition"}).FromCSVString("1,edit,res,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ JOIN groups .+ WHERE roleBinding.actor=. AND role.area = groups.area").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM sodRule WHERE area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "verbA", "verbB", "mode"}).FromCSVString(""))
+
+	// Create context and call API
+	var result []byte
+	var output struct {
+		Status string
+		Data   MembershipChanges
+	}
+	c := testhelpers.NewTestingContext("", httprouter.Params{httprouter.Param{Key: "groupGuid", Value: "1"}}, api.SyncGroupMembers)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Body = ioutil.NopCloser(strings.NewReader(`{"Members": ["alice", "carol"], "DryRun": true}`))
+	testhelpers.CallAPI(api.SyncGroupMembers, c, &result)
+
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+		t.FailNow()
+	}
+
+	// Ensure correct output
+	expected := MembershipChanges{[]string{"carol"}, []string{"bob"}, true}
+	if !reflect.DeepEqual(output.Data, expected) {
+		t.Errorf("expected to get %v but got %v instead", expected, output.Data)
+	}
+}
@@ -0,0 +1,131 @@
+package apis
+
+import (
+	"database/sql"
+	"fmt"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// Tells whether the user may manage the group: superusers, admins of the
+// group's area and the group's owners can.
+func canManageGroup(db *sql.DB, netId string, group accessors.Group) (bool, error) {
+	pa := accessors.NewPermissionAccessor(db)
+	ma := accessors.NewMembersAccessor(db)
+
+	allowed, err := isSUOrAdmin(pa, netId, group.Area)
+	if err != nil || allowed {
+		return allowed, err
+	}
+
+	return ma.IsOwner(netId, group.Guid)
+}
+
+// Get a list of the owners of a group.
+// GET /groupOwners/:groupGuid
+func (a *Api) GetGroupOwners(c *eden.Context) {
+	ma := accessors.NewMembersAccessor(a.DB)
+
+	group := c.Params[0].Value
+
+	owners, err := ma.GetGroupOwners(group)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error in GetGroupOwners (GET /groupOwners/:groupGuid): %v", err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error occurred while retrieving group owners"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", owners})
+}
+
+// Make a user an owner of a group.
+// POST /groupOwners netId=:netId, group=:groupGuid
+func (a *Api) AddGroupOwner(c *eden.Context) {
+	ma := accessors.NewMembersAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+	ga := accessors.NewGroupAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called AddGroupOwner (POST /groupOwners netId=:netId, group=:groupGuid)", true, ma.DB)
+
+	c.Request.ParseForm()
+	netId, netIdOk := c.Request.Form["netId"]
+	groupGuid, groupOk := c.Request.Form["group"]
+	if !netIdOk || !groupOk || netId[0] == "" || groupGuid[0] == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid netId or groupId"})
+		return
+	}
+
+	group, err := ga.Get(groupGuid[0])
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such group"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in AddGroupOwner by %s (POST /groupOwners netId=:netId, group=:groupGuid): %v", netId[0], err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, group.Area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in AddGroupOwner by %s (POST /groupOwners netId=:netId, group=:groupGuid): %v", netId[0], err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to assign group owners"})
+		return
+	}
+
+	if err := ma.AddOwner(netId[0], group.Guid); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on AddOwner in AddGroupOwner by %s (POST /groupOwners netId=:netId, group=:groupGuid): %v", netId[0], err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Remove a user as owner of a group.
+// DELETE /groupOwners/:netId/:groupGuid
+func (a *Api) RemoveGroupOwner(c *eden.Context) {
+	ma := accessors.NewMembersAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+	ga := accessors.NewGroupAccessor(a.DB)
+
+	netId := c.Params[0].Value
+	groupGuid := c.Params[1].Value
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("%s called RemoveGroupOwner (DELETE /groupOwners/:netId/:groupGuid)", netId), true, ma.DB)
+
+	group, err := ga.Get(groupGuid)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such group"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in RemoveGroupOwner by %s (DELETE /groupOwners/:netId/:groupGuid): %v", netId, err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, group.Area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in RemoveGroupOwner by %s (DELETE /groupOwners/:netId/:groupGuid): %v", netId, err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to remove group owners"})
+		return
+	}
+
+	if err := ma.RemoveOwner(netId, group.Guid); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on RemoveOwner in RemoveGroupOwner by %s (DELETE /groupOwners/:netId/:groupGuid): %v", netId, err), true, ma.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", "success"})
+}
@@ -0,0 +1,200 @@
+package apis
+
+import (
+	"database/sql"
+	"fmt"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	"github.com/byu-oit-ssengineering/tmt-permissions/membership"
+)
+
+// A group's rule along with the users it currently matches.
+type GroupRuleDetail struct {
+	Rule    accessors.GroupRule
+	Members []string
+}
+
+// Get a group's membership rule and the members it matched when it was
+// last refreshed.
+// GET /groupRules/:groupGuid
+func (a *Api) GetGroupRule(c *eden.Context) {
+	ra := accessors.NewRuleAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	rule, err := ra.Get(guid)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "The group has no rule"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in GetGroupRule (GET /groupRules/:groupGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	members, err := ra.Members(guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Members in GetGroupRule (GET /groupRules/:groupGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", GroupRuleDetail{rule, members}})
+}
+
+// Evaluate a rule for a group without saving it. Responds with the users
+// it would match, or a 400 if it doesn't parse or refers back to the group.
+// POST /groupRules/preview group=:groupGuid, expression=:rule
+func (a *Api) PreviewGroupRule(c *eden.Context) {
+	c.Request.ParseForm()
+	members, ok := a.evaluateRule(c, c.Request.Form.Get("group"), c.Request.Form.Get("expression"), "PreviewGroupRule (POST /groupRules/preview)")
+	if !ok {
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", members})
+}
+
+// Parse and evaluate a rule for a group. Responds and returns false if it
+// can't be.
+func (a *Api) evaluateRule(c *eden.Context, group, expression, handler string) ([]string, bool) {
+	ra := accessors.NewRuleAccessor(a.DB)
+
+	rule, err := membership.Parse(expression)
+	if err != nil {
+		c.Respond(400, eden.Response{"ERROR", fmt.Sprintf("Invalid rule: %v", err)})
+		return nil, false
+	}
+
+	members, err := ra.Evaluate(group, rule)
+	if err == accessors.ErrRuleCycle {
+		c.Respond(400, eden.Response{"ERROR", err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Evaluate in %s: %v", handler, err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return nil, false
+	}
+
+	return members, true
+}
+
+// Give a group a membership rule, or replace its rule, and refresh its
+// members. Admins of the group's area and the group's owners can set rules.
+// PUT /groupRules/:groupGuid expression=:rule
+func (a *Api) SetGroupRule(c *eden.Context) {
+	ra := accessors.NewRuleAccessor(a.DB)
+	ma := accessors.NewMembersAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called SetGroupRule (PUT /groupRules/:groupGuid expression=:rule)", true, ra.DB)
+
+	group, ok := a.routeGroup(c, "SetGroupRule (PUT /groupRules/:groupGuid)")
+	if !ok {
+		return
+	}
+
+	allowed, err := canManageGroup(a.DB, c.User.NetId, group)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on canManageGroup in SetGroupRule (PUT /groupRules/:groupGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin or an owner of the group to set its rule"})
+		return
+	}
+
+	c.Request.ParseForm()
+	expression := c.Request.Form.Get("expression")
+	members, ok := a.evaluateRule(c, group.Guid, expression, "SetGroupRule (PUT /groupRules/:groupGuid)")
+	if !ok {
+		return
+	}
+
+	// Users the rule adds pick up the group's permissions
+	current, err := ma.GetGroupMembers(group.Guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetGroupMembers in SetGroupRule (PUT /groupRules/:groupGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	isMember := make(map[string]bool)
+	for _, netId := range current {
+		isMember[netId] = true
+	}
+	added := make([]string, 0)
+	for _, netId := range members {
+		if !isMember[netId] {
+			added = append(added, netId)
+		}
+	}
+	groupPerms, err := pa.GetGroupPermissions(group.Guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetGroupPermissions in SetGroupRule (PUT /groupRules/:groupGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	users := func() ([]string, error) { return added, nil }
+	if !a.enforceSod(c, group.Area, users, groupPerms) {
+		return
+	}
+
+	if err := ra.Set(accessors.GroupRule{Group: group.Guid, Expression: expression}); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Set in SetGroupRule (PUT /groupRules/:groupGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	members, err = ra.Refresh(group.Guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Refresh in SetGroupRule (PUT /groupRules/:groupGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Set the rule of group %s to %q, matching %d users", group.Guid, expression, len(members)), true, ra.DB)
+	c.Respond(200, eden.Response{"OK", members})
+}
+
+// Remove a group's membership rule. Users only the rule matched are no
+// longer members.
+// DELETE /groupRules/:groupGuid
+func (a *Api) DeleteGroupRule(c *eden.Context) {
+	ra := accessors.NewRuleAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called DeleteGroupRule (DELETE /groupRules/:groupGuid)", true, ra.DB)
+
+	group, ok := a.routeGroup(c, "DeleteGroupRule (DELETE /groupRules/:groupGuid)")
+	if !ok {
+		return
+	}
+
+	allowed, err := canManageGroup(a.DB, c.User.NetId, group)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on canManageGroup in DeleteGroupRule (DELETE /groupRules/:groupGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin or an owner of the group to remove its rule"})
+		return
+	}
+
+	if err := ra.Delete(group.Guid); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Delete in DeleteGroupRule (DELETE /groupRules/:groupGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Removed the rule of group %s", group.Guid), true, ra.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Re-evaluate every group rule so materialized members catch up with
+// changes to the groups the rules name.
+func (a *Api) refreshGroupRules() error {
+	return accessors.NewRuleAccessor(a.DB).RefreshAll()
+}
@@ -0,0 +1,41 @@
+package apis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestPreviewInvalidGroupRule(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// Create context and call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("group=dyn&expression=group:lab-a+and", nil, api.PreviewGroupRule)
+	testhelpers.CallAPI(api.PreviewGroupRule, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Ensure correct output
+	if msg, _ := output.Data.(string); !strings.HasPrefix(msg, "Invalid rule") {
+		t.Errorf("expected an invalid rule error but got %v instead", output)
+	}
+}
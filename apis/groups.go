@@ -1,29 +1,33 @@
 package apis
 
 import (
+	"database/sql"
 	"fmt"
 	"strconv"
+	"strings"
 
 	eden "github.com/byu-oit-ssengineering/tmt-eden"
 	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
 )
 
-// Get all the groups for an area.
-// GET /groups?area=:area
-func (a *Api) GetGroupsByArea(c *eden.Context) {
+// Search groups by area, tag and part of their name or description. At
+// least one filter is needed.
+// GET /groups?area=:area&tag=:tag&q=:query
+func (a *Api) GetGroups(c *eden.Context) {
 	ga := accessors.NewGroupAccessor(a.DB)
 
 	c.Request.ParseForm()
-	area, ok := c.Request.Form["area"]
-	if !ok || area[0] == "" {
-		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+	area := c.Request.Form.Get("area")
+	tag := c.Request.Form.Get("tag")
+	query := c.Request.Form.Get("q")
+	if area == "" && tag == "" && query == "" {
+		c.Respond(400, eden.Response{"ERROR", "Specify an area, tag or q"})
 		return
 	}
 
-	// Get the area's groups
-	result, err := ga.GetByArea(area[0])
+	result, err := ga.Search(area, tag, query)
 	if err != nil {
-		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetByArea in GetGroupsByArea (GET /groups?area=:area): %v", err), true, ga.DB)
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Search in GetGroups (GET /groups?area=:area&tag=:tag&q=:query): %v", err), true, ga.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error occurred while retrieving groups"})
 		return
 	}
@@ -32,6 +36,20 @@ func (a *Api) GetGroupsByArea(c *eden.Context) {
 	c.Respond(200, eden.Response{"OK", result})
 }
 
+// Split a comma separated list of tags, dropping blanks and repeats.
+func parseTags(value string) []string {
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 // Gets a group by guid.
 // GET /groups/:guid
 func (a *Api) GetGroup(c *eden.Context) {
@@ -53,68 +71,142 @@ func (a *Api) GetGroup(c *eden.Context) {
 	c.Respond(200, eden.Response{"OK", group})
 }
 
-// Creates a new group
-// POST /groups name=:newGroupName, area=:areaGuid
+// Creates a new group and responds with it. If the area already has a
+// group with the name, responds with a 409 and that group. Requests sent
+// with an Idempotency-Key header the user has used before respond with the
+// group the first one created, or a 422 if the request differs from the
+// first. Accepts a form or a JSON CreateGroupRequest.
+// POST /groups name=:newGroupName, area=:areaGuid, description=:description, tags=:tag,:tag
 func (a *Api) CreateGroup(c *eden.Context) {
 	// Create new group accessor
 	ga := accessors.NewGroupAccessor(a.DB)
+	ia := accessors.NewIdempotencyAccessor(a.DB)
 
 	accessors.Log("notice", c.User.NetId, "Called CreateGroup (POST /groups name=:newGroupName, area=:areaGuid)", true, ga.DB)
 
+	// Replay retries
+	key := c.Request.Header.Get(idempotencyKeyHeader)
+	var hash string
+	if key != "" {
+		var err error
+		if hash, err = requestHash(c); err != nil {
+			c.Respond(400, eden.Response{"ERROR", "Unable to read request"})
+			return
+		}
+		guid, firstHash, err := ia.Get(c.User.NetId, key)
+		if err != nil && err != sql.ErrNoRows {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in CreateGroup (POST /groups name=:newGroupName, area=:areaGuid): %v", err), true, ga.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		if err == nil && firstHash != hash {
+			c.Respond(422, eden.Response{"ERROR", "This Idempotency-Key was used for a different request"})
+			return
+		}
+		if err == nil {
+			group, err := ga.Get(guid)
+			if err == sql.ErrNoRows {
+				c.Respond(404, eden.Response{"ERROR", "The group created with this key has since been deleted"})
+				return
+			}
+			if err != nil {
+				accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in CreateGroup (POST /groups name=:newGroupName, area=:areaGuid): %v", err), true, ga.DB)
+				c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+				return
+			}
+			c.Respond(200, eden.Response{"OK", group})
+			return
+		}
+	}
+
 	// Parse area and group name from POST data.
-	c.Request.ParseForm()
-	if area, ok := c.Request.Form["area"]; !ok || area[0] == "" {
-		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+	var req CreateGroupRequest
+	if !decodeRequest(c, &req) {
 		return
 	}
 
-	if name, ok := c.Request.Form["name"]; !ok || name[0] == "" {
-		c.Respond(400, eden.Response{"ERROR", "Invalid group name"})
-		return
+	name := req.Name
+	area := req.Area
+	group := accessors.Group{
+		Area:        area,
+		Name:        name,
+		Description: req.Description,
+		Tags:        parseTags(strings.Join(req.Tags, ",")),
+		Created:     timeNow().Unix(),
+		CreatedBy:   c.User.NetId,
 	}
 
-	name := c.Request.Form["name"][0]
-	area := c.Request.Form["area"][0]
-	group := accessors.Group{Area: area, Name: name}
+	if !a.knownArea(c, area, "CreateGroup (POST /groups name=:newGroupName, area=:areaGuid)") {
+		return
+	}
 
 	// Insert the group and test for errors
-	if err := ga.Insert(group); err != nil {
+	group, err := ga.Insert(group)
+	if err == accessors.ErrDuplicateGroup {
+		a.groupNameFree(c, area, name, "", "CreateGroup (POST /groups name=:newGroupName, area=:areaGuid)")
+		return
+	}
+	if err != nil {
 		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Insert in CreateGroup (POST /groups name=:newGroupName, area=:areaGuid): %v", err), true, ga.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
 
-	// Respond
-	c.Respond(200, eden.Response{"OK", "success"})
+	if key != "" {
+		if err := ia.Put(c.User.NetId, key, group.Guid, hash, group.Created); err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Put in CreateGroup (POST /groups name=:newGroupName, area=:areaGuid): %v", err), true, ga.DB)
+		}
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Created group %s (%s) in area %s", group.Guid, group.Name, group.Area), true, ga.DB)
+	c.Respond(200, eden.Response{"OK", group})
 }
 
-// Update a group's name.
-// PUT /groups/:guid name=:newName
-func (a *Api) RenameGroup(c *eden.Context) {
+// Update a group's name, description and tags. Fields left out are
+// unchanged; tags replaces the group's tags.
+// PUT /groups/:guid name=:newName, description=:description, tags=:tag,:tag
+func (a *Api) UpdateGroup(c *eden.Context) {
 	// Create new group accessor
 	ga := accessors.NewGroupAccessor(a.DB)
 
-	accessors.Log("notice", c.User.NetId, "Called RenameGroup (PUT /groups/:guid name=:newName)", true, ga.DB)
+	accessors.Log("notice", c.User.NetId, "Called UpdateGroup (PUT /groups/:guid name=:newName, description=:description, tags=:tag,:tag)", true, ga.DB)
 
-	// Parse group guid
-	guid := c.Params[0].Value
+	group, ok := a.routeGroup(c, "UpdateGroup (PUT /groups/:guid)")
+	if !ok {
+		return
+	}
 
-	// Parse new group name
 	c.Request.ParseForm()
-	if group, ok := c.Request.Form["name"]; !ok || group[0] == "" {
-		c.Respond(400, eden.Response{"ERROR", "No group name specified"})
-		return
+	if name, ok := c.Request.Form["name"]; ok {
+		if name[0] == "" {
+			c.Respond(400, eden.Response{"ERROR", "No group name specified"})
+			return
+		}
+		if name[0] != group.Name && !a.groupNameFree(c, group.Area, name[0], group.Guid, "UpdateGroup (PUT /groups/:guid)") {
+			return
+		}
+		group.Name = name[0]
+	}
+	if description, ok := c.Request.Form["description"]; ok {
+		group.Description = description[0]
+	}
+	if tags, ok := c.Request.Form["tags"]; ok {
+		group.Tags = parseTags(tags[0])
 	}
 
-	name := c.Request.Form["name"][0]
-	if err := ga.Rename(guid, name); err != nil {
-		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Rename in RenameGroup (PUT /groups/:guid name=:newName): %v", err), true, ga.DB)
+	err := ga.Update(group)
+	if err == accessors.ErrDuplicateGroup {
+		a.groupNameFree(c, group.Area, group.Name, group.Guid, "UpdateGroup (PUT /groups/:guid)")
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Update in UpdateGroup (PUT /groups/:guid): %v", err), true, ga.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
 
 	// Respond
-	c.Respond(200, eden.Response{"OK", "success"})
+	c.Respond(200, eden.Response{"OK", group})
 }
 
 // Delete a group.
@@ -180,3 +272,190 @@ func (a *Api) GetUserGroups(c *eden.Context) {
 
 	c.Respond(200, eden.Response{"OK", groups})
 }
+
+// Load the group named by the first route parameter. Responds and returns
+// false if it can't be found.
+func (a *Api) routeGroup(c *eden.Context, handler string) (accessors.Group, bool) {
+	ga := accessors.NewGroupAccessor(a.DB)
+
+	group, err := ga.Get(c.Params[0].Value)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such group"})
+		return group, false
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in %s: %v", handler, err), true, ga.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return group, false
+	}
+
+	return group, true
+}
+
+// Make sure no group other than guid in area is called name. Responds
+// with a 409 and the group that is, and returns false, if one is.
+func (a *Api) groupNameFree(c *eden.Context, area, name, guid, handler string) bool {
+	ga := accessors.NewGroupAccessor(a.DB)
+
+	existing, err := ga.GetByName(area, name)
+	if err == sql.ErrNoRows {
+		return true
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetByName in %s: %v", handler, err), true, ga.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return false
+	}
+	if existing.Guid == guid {
+		return true
+	}
+
+	c.Respond(409, eden.Response{"ERROR", existing})
+	return false
+}
+
+// Make sure a group can be moved or copied into area: the area exists, the
+// user is an admin of both areas and, when the group's members come along,
+// they wouldn't break the destination area's separation of duties rules.
+// Responds and returns false if the handler should stop.
+func (a *Api) canTransferGroup(c *eden.Context, group accessors.Group, area string, withMembers bool, handler string) bool {
+	pa := accessors.NewPermissionAccessor(a.DB)
+	ma := accessors.NewMembersAccessor(a.DB)
+
+	if !a.knownArea(c, area, handler) {
+		return false
+	}
+
+	for _, ar := range []string{group.Area, area} {
+		allowed, err := isSUOrAdmin(pa, c.User.NetId, ar)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in %s: %v", handler, err), true, pa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return false
+		}
+		if !allowed {
+			c.Respond(403, eden.Response{"ERROR", "You need to be an admin of both areas to move or copy a group"})
+			return false
+		}
+	}
+
+	if withMembers {
+		perms, err := pa.GetGroupPermissions(group.Guid)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetGroupPermissions in %s: %v", handler, err), true, pa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return false
+		}
+		users := func() ([]string, error) { return ma.GetGroupMembers(group.Guid) }
+		if !a.enforceSod(c, area, users, perms) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Move a group, with its members and grants, to another area.
+// POST /groups/:guid/move area=:areaGuid
+func (a *Api) MoveGroup(c *eden.Context) {
+	ga := accessors.NewGroupAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called MoveGroup (POST /groups/:guid/move area=:areaGuid)", true, ga.DB)
+
+	c.Request.ParseForm()
+	area := c.Request.Form.Get("area")
+	if area == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+		return
+	}
+
+	group, ok := a.routeGroup(c, "MoveGroup (POST /groups/:guid/move)")
+	if !ok || !a.canTransferGroup(c, group, area, true, "MoveGroup (POST /groups/:guid/move)") {
+		return
+	}
+	if group.Area == area {
+		c.Respond(200, eden.Response{"OK", "success"})
+		return
+	}
+	if !a.groupNameFree(c, area, group.Name, group.Guid, "MoveGroup (POST /groups/:guid/move)") {
+		return
+	}
+
+	err := ga.Move(group.Guid, area)
+	if err == accessors.ErrDuplicateGroup {
+		a.groupNameFree(c, area, group.Name, group.Guid, "MoveGroup (POST /groups/:guid/move)")
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Move in MoveGroup (POST /groups/:guid/move): %v", err), true, ga.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Moved group %s (%s) from area %s to %s", group.Guid, group.Name, group.Area, area), true, ga.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Copy a group into an area, by default its own, under a new guid. The
+// copy keeps the group's name unless one is given. members=true copies
+// the members and policies=true the policy rows and the bindings of roles
+// in the copy's area. Grants on sensitive resources wait for a second
+// approver, as they would if granted directly. Responds with the new
+// group's guid.
+// POST /groups/:guid/clone area=:areaGuid, name=:name, members=:bool, policies=:bool
+func (a *Api) CloneGroup(c *eden.Context) {
+	ga := accessors.NewGroupAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called CloneGroup (POST /groups/:guid/clone area=:areaGuid, name=:name, members=:bool, policies=:bool)", true, ga.DB)
+
+	c.Request.ParseForm()
+	members, err := strconv.ParseBool(c.Request.Form.Get("members"))
+	if err != nil && c.Request.Form.Get("members") != "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid members"})
+		return
+	}
+	policies, err := strconv.ParseBool(c.Request.Form.Get("policies"))
+	if err != nil && c.Request.Form.Get("policies") != "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid policies"})
+		return
+	}
+
+	group, ok := a.routeGroup(c, "CloneGroup (POST /groups/:guid/clone)")
+	if !ok {
+		return
+	}
+	area := c.Request.Form.Get("area")
+	if area == "" {
+		area = group.Area
+	}
+	name := c.Request.Form.Get("name")
+	if name == "" {
+		name = group.Name
+	}
+
+	// Members only pick up the copy's grants when both are copied
+	if !a.canTransferGroup(c, group, area, members && policies, "CloneGroup (POST /groups/:guid/clone)") {
+		return
+	}
+	if !a.groupNameFree(c, area, name, "", "CloneGroup (POST /groups/:guid/clone)") {
+		return
+	}
+
+	clone := accessors.Group{Area: area, Name: name, Created: timeNow().Unix(), CreatedBy: c.User.NetId}
+	guid, held, err := ga.Clone(group.Guid, clone, members, policies)
+	if err == accessors.ErrDuplicateGroup {
+		a.groupNameFree(c, area, name, "", "CloneGroup (POST /groups/:guid/clone)")
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Clone in CloneGroup (POST /groups/:guid/clone): %v", err), true, ga.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Copied group %s (%s) in area %s to %s (%s) in area %s, members %v, policies %v", group.Guid, group.Name, group.Area, guid, name, area, members, policies), true, ga.DB)
+	for _, approval := range held {
+		accessors.Log("audit", c.User.NetId, fmt.Sprintf("Requested approval %s for a sensitive grant copied to %s", approval, guid), true, ga.DB)
+	}
+	c.Respond(200, eden.Response{"OK", guid})
+}
@@ -9,7 +9,9 @@ import (
 	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/julienschmidt/httprouter"
+	"reflect"
 	"testing"
+	"time"
 )
 
 type testGroupResponse struct {
@@ -30,12 +32,12 @@ func TestGetGroup(t *testing.T) {
 	}
 	api := &Api{db}
 
-	expected := accessors.Group{"1", "1", "testGroup"}
-	columns := []string{"id", "area", "name"}
+	expected := accessors.Group{Guid: "1", Area: "1", Name: "testGroup", Tags: []string{}}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT (.) FROM groups WHERE guid=(.)").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
 		WithArgs("1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1,1,testGroup"))
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1,1,testGroup,,,0,"))
 
 	// Create context, call API
 	var result []byte
@@ -50,7 +52,7 @@ func TestGetGroup(t *testing.T) {
 	}
 
 	// Compare output to expected output
-	if output.Data != expected {
+	if !reflect.DeepEqual(output.Data, expected) {
 		t.Errorf("Expected: %v, but got %v", expected, output.Data)
 	}
 }
@@ -63,18 +65,18 @@ func TestGetGroupsByArea(t *testing.T) {
 	}
 	api := &Api{db}
 
-	expected := []accessors.Group{accessors.Group{"1", "1", "testGroup"}, accessors.Group{"2", "1", "group2"}}
-	columns := []string{"guid", "area", "name"}
+	expected := []accessors.Group{accessors.Group{Guid: "1", Area: "1", Name: "testGroup", Tags: []string{}}, accessors.Group{Guid: "2", Area: "1", Name: "group2", Tags: []string{}}}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT (.) FROM groups WHERE area=(.)").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE area=(.)").
 		WithArgs("1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1,1,testGroup\n2,1,group2"))
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1,1,testGroup,,,0,\n2,1,group2,,,0,"))
 
 	// Create context, call API
 	var result []byte
 	var output testGroupArrayResponse
-	c := testhelpers.NewTestingContext("area=1", nil, api.GetGroup)
-	testhelpers.CallAPI(api.GetGroupsByArea, c, &result)
+	c := testhelpers.NewTestingContext("area=1", nil, api.GetGroups)
+	testhelpers.CallAPI(api.GetGroups, c, &result)
 
 	// Parse output
 	err = json.Unmarshal(result, &output)
@@ -84,7 +86,7 @@ func TestGetGroupsByArea(t *testing.T) {
 
 	// Compare output to expected output
 	for i := 0; i < len(expected); i++ {
-		if output.Data[i] != expected[i] {
+		if !reflect.DeepEqual(output.Data[i], expected[i]) {
 			t.Errorf("Expected: %v, but got %v instead", expected, output)
 		}
 	}
@@ -102,17 +104,160 @@ func TestInsertGroup(t *testing.T) {
 	accessors.NewGuid = func() string {
 		return "123def"
 	}
+	timeNow = func() time.Time {
+		return time.Unix(1500000000, 0)
+	}
+	defer func() { timeNow = time.Now }()
 	api := &Api{db}
 
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectExec("INSERT INTO groups .+ VALUES .+").
-		WithArgs("123def", "1", "testGroup").
+	sqlmock.ExpectQuery("SELECT guid FROM areas WHERE guid=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid"}).FromCSVString("1"))
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO groups .+ SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("123def", "1", "testGroup", "Night shift staff", "lab,nights", int64(1500000000), "", "1", "testGroup").
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	// Create context and call API
+	var result []byte
+	var output testGroupResponse
+	c := testhelpers.NewTestingContext("area=1&name=testGroup&description=Night+shift+staff&tags=lab,+nights,lab", nil, api.CreateGroup)
+	testhelpers.CallAPI(api.CreateGroup, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Ensure correct output
+	expected := accessors.Group{Guid: "123def", Area: "1", Name: "testGroup", Description: "Night shift staff", Tags: []string{"lab", "nights"}, Created: 1500000000}
+	if !reflect.DeepEqual(output.Data, expected) {
+		t.Errorf("Expected: %v, but got %v instead", expected, output.Data)
+	}
+}
+
+func TestInsertDuplicateGroup(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	accessors.NewGuid = func() string {
+		return "123def"
+	}
+	timeNow = func() time.Time {
+		return time.Unix(1500000000, 0)
+	}
+	defer func() { timeNow = time.Now }()
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid FROM areas WHERE guid=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid"}).FromCSVString("1"))
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO groups .+ SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("123def", "1", "testGroup", "", "", int64(1500000000), "", "1", "testGroup").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectCommit()
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE area=. AND name=.").
+		WithArgs("1", "testGroup").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("existing,1,testGroup,,,0,"))
+
+	// Create context and call API
+	var result []byte
+	var output testGroupResponse
+	c := testhelpers.NewTestingContext("area=1&name=testGroup", nil, api.CreateGroup)
+	testhelpers.CallAPI(api.CreateGroup, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Ensure correct output
+	if output.Status != "ERROR" || output.Data.Guid != "existing" {
+		t.Errorf("expected the existing group but got %v instead", output)
+	}
+}
+
+func TestCreateGroupRetry(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	c := testhelpers.NewTestingContext("area=1&name=testGroup", nil, api.CreateGroup)
+	c.Request.Header.Set("Idempotency-Key", "key1")
+	hash, err := requestHash(c)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT resource, requestHash FROM idempotencyKey WHERE netId=. AND idempotencyKey=.").
+		WithArgs("", "key1").
+		WillReturnRows(sqlmock.NewRows([]string{"resource", "requestHash"}).FromCSVString("created," + hash))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=.").
+		WithArgs("created").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("created,1,testGroup,,,0,"))
+
+	// Call API
+	var result []byte
+	var output testGroupResponse
+	testhelpers.CallAPI(api.CreateGroup, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Ensure correct output
+	if output.Status != "OK" || output.Data.Guid != "created" {
+		t.Errorf("expected the group created earlier but got %v instead", output)
+	}
+}
+
+func TestCreateGroupReusedKey(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// The key was first sent with a different request
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT resource, requestHash FROM idempotencyKey WHERE netId=. AND idempotencyKey=.").
+		WithArgs("", "key1").
+		WillReturnRows(sqlmock.NewRows([]string{"resource", "requestHash"}).FromCSVString("created,otherhash"))
 
 	// Create context and call API
 	var result []byte
 	var output eden.Response
 	c := testhelpers.NewTestingContext("area=1&name=testGroup", nil, api.CreateGroup)
+	c.Request.Header.Set("Idempotency-Key", "key1")
 	testhelpers.CallAPI(api.CreateGroup, c, &result)
 
 	// Parse output
@@ -122,12 +267,12 @@ func TestInsertGroup(t *testing.T) {
 	}
 
 	// Ensure correct output
-	if output.Data != "success" {
-		t.Errorf("expected to get 'success' but got %v instead", output)
+	if output.Status != "ERROR" {
+		t.Errorf("expected the reused key to be refused but got %v instead", output)
 	}
 }
 
-func TestRenameGroup(t *testing.T) {
+func TestUpdateGroup(t *testing.T) {
 	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
 		return true, nil
 	}
@@ -138,16 +283,24 @@ func TestRenameGroup(t *testing.T) {
 	}
 	api := &Api{db}
 
+	expected := accessors.Group{Guid: "1", Area: "1", Name: "testGroup", Description: "Night shift staff", Tags: []string{"nights"}, Created: 1500000000, CreatedBy: "creator"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectExec("UPDATE groups SET name=(.) WHERE guid=(.)").
-		WithArgs("changed", "1").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("1,1,testGroup,,\"lab,old\",1500000000,creator"))
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("UPDATE groups SET name=(.), description=(.), tags=(.) WHERE guid=(.)").
+		WithArgs("testGroup", "Night shift staff", "nights", "1").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
 	// Create context and call API
 	var result []byte
-	var output eden.Response
-	c := testhelpers.NewTestingContext("name=changed", httprouter.Params{httprouter.Param{Key: "id", Value: "1"}}, api.RenameGroup)
-	testhelpers.CallAPI(api.RenameGroup, c, &result)
+	var output testGroupResponse
+	c := testhelpers.NewTestingContext("description=Night+shift+staff&tags=nights", httprouter.Params{httprouter.Param{Key: "guid", Value: "1"}}, api.UpdateGroup)
+	testhelpers.CallAPI(api.UpdateGroup, c, &result)
 
 	err = json.Unmarshal(result, &output)
 	if err != nil {
@@ -155,8 +308,8 @@ func TestRenameGroup(t *testing.T) {
 	}
 
 	// Ensure correct output
-	if output.Data != "success" {
-		t.Errorf("expected to get 'success' but got %v instead", output)
+	if !reflect.DeepEqual(output.Data, expected) {
+		t.Errorf("Expected: %v, but got %v instead", expected, output.Data)
 	}
 }
 
@@ -171,10 +324,13 @@ func TestDeleteGroup(t *testing.T) {
 	}
 	api := &Api{db}
 
-	sqlmock.ExpectPrepare()
+	sqlmock.ExpectBegin()
 	sqlmock.ExpectExec("DELETE FROM groups WHERE guid=(.)").
 		WithArgs("1").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
 	// Create context and call API
 	var result []byte
@@ -201,12 +357,12 @@ func TestGetUserGroups(t *testing.T) {
 	}
 	api := &Api{db}
 
-	expected := accessors.Group{"g1", "area", "n1"}
-	columns := []string{"guid", "area", "name"}
+	expected := accessors.Group{Guid: "g1", Area: "area", Name: "n1", Tags: []string{}}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT groups.. FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("netId", "area").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("g1,area,n1"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("netId", "area", "netId", "area").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("g1,area,n1,,,0,"))
 
 	// Create context, call API
 	var result []byte
@@ -221,7 +377,7 @@ func TestGetUserGroups(t *testing.T) {
 	}
 
 	// Compare output to expected output
-	if output.Data[0] != expected {
+	if !reflect.DeepEqual(output.Data[0], expected) {
 		t.Errorf("Expected: %v, but got %v", expected, output.Data)
 	}
 }
@@ -234,42 +390,51 @@ func TestGetUserGroupsImplied(t *testing.T) {
 	}
 	api := &Api{db}
 
-	expected := []accessors.Group{accessors.Group{"guid1", "area", "group1"}, accessors.Group{"guid3", "area", "group3"}, accessors.Group{"guid2", "area", "group2"}}
+	expected := []accessors.Group{accessors.Group{Guid: "guid1", Area: "area", Name: "group1", Tags: []string{}}, accessors.Group{Guid: "guid3", Area: "area", Name: "group3", Tags: []string{}}, accessors.Group{Guid: "guid2", Area: "area", Name: "group2", Tags: []string{}}}
 	// query expectations
-	columns := []string{"guid", "area", "name"}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT groups.. FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("netId", "1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,area,group1\nguid3,area,group3"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("netId", "1", "netId", "1").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,area,group1,,,0,\nguid3,area,group3,,,0,"))
 
 	// for implied
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups WHERE area=.").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE area=.").
 		WithArgs("1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1\nguid2,1,group2\nguid3,1,group3"))
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1,,,0,\nguid2,1,group2,,,0,\nguid3,1,group3,,,0,"))
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT groups.. FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("netId", "1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1\nguid3,1,group3"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("netId", "1", "netId", "1").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1,,,0,\nguid3,1,group3,,,0,"))
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT groups.. FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("netId", "1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1\nguid3,1,group3"))
-	columns = []string{"actor", "verb", "resource"}
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("netId", "1", "netId", "1").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid1,1,group1,,,0,\nguid3,1,group3,,,0,"))
+	columns = []string{"actor", "verb", "resource", "condition"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE actor IN .+").
-		WithArgs("1", "guid1", "guid3").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("group1,edit,res1\ngroup1,view,res1\ngroup3,update,res2"))
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("1,Area,,,0"))
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ LEFT JOIN policyArea .+ WHERE .policyArea.area IS NULL OR policyArea.area=.. AND policy.actor IN .+").
+		WithArgs("1", "1", "netId", "guid1", "guid3").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("group1,edit,res1,\ngroup1,view,res1,\ngroup3,update,res2,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("1", "1", "netId", "guid1", "guid3").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
 	columns = []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
 	sqlmock.ExpectQuery("SELECT . FROM policy WHERE actor=.").
 		WithArgs("guid2").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("group2,edit,res1\ngroup2,view,res1"))
-	columns = []string{"guid", "area", "name"}
+	columns = []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups WHERE guid=.").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=.").
 		WithArgs("guid2").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid2,area,group2"))
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("guid2,area,group2,,,0,"))
 
 	// Create context, call API
 	var result []byte
@@ -285,8 +450,47 @@ func TestGetUserGroupsImplied(t *testing.T) {
 
 	// Compare output to expected output
 	for i := 0; i < len(expected); i++ {
-		if output.Data[i] != expected[i] {
+		if !reflect.DeepEqual(output.Data[i], expected[i]) {
 			t.Errorf("Expected: %v, but got %v", expected, output.Data)
 		}
 	}
 }
+
+func TestMoveGroupToUnknownArea(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("1,1,testGroup,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid FROM areas WHERE guid=.").
+		WithArgs("typo").
+		WillReturnRows(sqlmock.NewRows([]string{"guid"}).FromCSVString(""))
+
+	// Create context and call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("area=typo", httprouter.Params{httprouter.Param{Key: "guid", Value: "1"}}, api.MoveGroup)
+	testhelpers.CallAPI(api.MoveGroup, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Ensure correct output
+	if output.Data != "No such area" {
+		t.Errorf("expected to get 'No such area' but got %v instead", output)
+	}
+}
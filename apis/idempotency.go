@@ -0,0 +1,40 @@
+package apis
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"time"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// Header clients set so retries of a create don't create twice.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// How long an idempotency key is remembered.
+const idempotencyKeyLifetime = 24 * time.Hour
+
+// Forget idempotency keys older than idempotencyKeyLifetime.
+func (a *Api) expireIdempotencyKeys() error {
+	return accessors.NewIdempotencyAccessor(a.DB).DeleteBefore(timeNow().Add(-idempotencyKeyLifetime).Unix())
+}
+
+// Hashes a request's query and body, so that a retry can be told from a
+// different request sent with the same key. The body is put back for the
+// handler to read.
+func requestHash(c *eden.Context) (string, error) {
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(c.Request.Body); err != nil {
+			return "", err
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	sum := sha256.Sum256(append([]byte(c.Request.URL.RawQuery+"\n"), body...))
+	return hex.EncodeToString(sum[:]), nil
+}
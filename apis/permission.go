@@ -1,11 +1,14 @@
 package apis
 
 import (
+	"database/sql"
 	"fmt"
+	"net"
 	"net/url"
 
 	eden "github.com/byu-oit-ssengineering/tmt-eden"
 	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
 )
 
 // Check whether the user has permission to access a resource.
@@ -13,8 +16,12 @@ import (
 // The GET data passed in will be used to evaluate whether or
 //   not the user has access to something. The verb is the
 //   action the user is trying to perform, object is the
-//   resource being accessed, and actors is a list of the
-//   guids of the user, the user's groups, and the current area.
+//   resource being accessed, and the user's groups, the
+//   current area (and the ancestors it inherits from) and the
+//   user are the actors checked. Any other
+//   parameters are attributes that conditions on policy rows can
+//   read as attr.<name>, alongside the time and the user's address
+//   from ip=:address.
 func (a *Api) CheckPermission(c *eden.Context) {
 	pa := accessors.NewPermissionAccessor(a.DB)
 
@@ -36,42 +43,118 @@ func (a *Api) CheckPermission(c *eden.Context) {
 		return
 	}
 
-	// Check superuser
-	su, err := pa.IsSuperuser(employeeGuid[0])
-	if su {
-		c.Respond(200, eden.Response{"OK", true})
+	// Decide as pdp does, from the user's superuser and admin status, their
+	// actors' policy rows and the roles bound to them
+	e, err := pa.Explain(employeeGuid[0], areaGuid[0], resource[0], verb[0], conditionContext(query))
+	if err == sql.ErrNoRows {
+		c.Respond(400, eden.Response{"ERROR", false})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Explain in CheckPermission (GET /permission?object=:objectGUID&verb=:verb&actors[]=:actors): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", false})
 		return
 	}
 
-	// Check admin
-	admin, err := pa.IsAdmin(employeeGuid[0], areaGuid[0])
-	if admin {
-		c.Respond(200, eden.Response{"OK", true})
+	c.Respond(200, eden.Response{"OK", e.Allowed})
+}
+
+// Explain whether a user has permission to access a resource: whether they
+//   are a superuser or admin, the actors checked and each policy row or
+//   role binding that grants it, with its condition evaluated as GET
+//   /permission would. Users can explain their own permissions; explaining
+//   someone else's takes an admin of the area.
+// GET /permission/explain?areaGuid=:areaGuid&employeeGuid=:netId&verb=:verb&resource=:resource
+func (a *Api) ExplainPermission(c *eden.Context) {
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	query, err := url.ParseQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		c.Respond(400, eden.Response{"ERROR", "Unable to process request"})
 		return
 	}
+	area, netId, verb, resource := query.Get("areaGuid"), query.Get("employeeGuid"), query.Get("verb"), query.Get("resource")
+	if errs := required(nil, "areaGuid", area, "employeeGuid", netId, "verb", verb, "resource", resource); len(errs) > 0 {
+		c.Respond(400, eden.Response{"ERROR", errs})
+		return
+	}
+
+	if netId != c.User.NetId {
+		allowed, err := isSUOrAdmin(pa, c.User.NetId, area)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in ExplainPermission (GET /permission/explain): %v", err), true, pa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		if !allowed {
+			c.Respond(403, eden.Response{"ERROR", "You need to be an admin to explain someone else's permissions"})
+			return
+		}
+	}
 
-	// Check permission
-	actors, err := pa.Get(areaGuid[0], employeeGuid[0])
+	explanation, err := pa.Explain(netId, area, resource, verb, conditionContext(query))
+	if err == sql.ErrNoRows {
+		c.Respond(400, eden.Response{"ERROR", "No such area"})
+		return
+	}
 	if err != nil {
-		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in CheckPermission (GET /permission?object=:objectGUID&verb=:verb&actors[]=:actors): %v", err), true, pa.DB)
-		c.Respond(500, eden.Response{"ERROR", false})
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Explain in ExplainPermission (GET /permission/explain): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
 
-	actorArray := make([]string, 0)
-	for i := 0; i < len(actors); i++ {
-		actorArray = append(actorArray, actors[i].Guid)
+	c.Respond(200, eden.Response{"OK", explanation})
+}
+
+// Get everything permission decisions are made from, for services that
+//   make them in-process with the pdp package. The snapshot holds every
+//   area's policy, so only superusers can read it.
+// GET /permission/snapshot
+func (a *Api) GetSnapshot(c *eden.Context) {
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	isSU, err := pa.IsSuperuser(c.User.NetId)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in GetSnapshot (GET /permission/snapshot): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !isSU {
+		c.Respond(403, eden.Response{"ERROR", "You need to be superuser to read the policy snapshot"})
+		return
 	}
 
-	actorArray = append(actorArray, areaGuid[0])
-	permission, err := pa.CheckPermission(actorArray, resource[0], verb[0])
+	snapshot, err := pa.Snapshot()
 	if err != nil {
-		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error in CheckPermission (GET /permission?object=:objectGUID&verb=:verb&actors[]=:actors): %v", err), true, pa.DB)
-		c.Respond(500, eden.Response{"ERROR", false})
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Snapshot in GetSnapshot (GET /permission/snapshot): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
 		return
 	}
 
-	c.Respond(200, eden.Response{"OK", permission})
+	c.Respond(200, eden.Response{"OK", snapshot})
+}
+
+// Build the context conditions are evaluated in from the request. The
+//   subject's address comes from the ip parameter, since the caller is
+//   usually a service asking on the user's behalf; query parameters other
+//   than the ones CheckPermission itself reads become attributes.
+func conditionContext(query url.Values) conditions.Context {
+	ip := query.Get("ip")
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	ctx := conditions.NewContext(timeNow(), ip)
+	for name, values := range query {
+		switch name {
+		case "areaGuid", "employeeGuid", "verb", "resource", "ip":
+			continue
+		}
+		if len(values) > 0 {
+			ctx.SetAttr(name, values[0])
+		}
+	}
+	return ctx
 }
 
 // Get all permission groups that have access to a specified verb
@@ -94,7 +177,7 @@ func (a *Api) GetGroupsByVerb(c *eden.Context) {
 
 	for i := 0; i < len(rawGroups); i++ {
 		// Check permission
-		permission, err := pa.CheckPermission([]string{rawGroups[i].Guid}, resourceGuid, verb)
+		permission, err := pa.CheckPermission(c.User.Area, []string{rawGroups[i].Guid}, resourceGuid, verb)
 		if err != nil {
 			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on CheckPermission in GetGroupsByVerb (GET /permission/:resourceGUID/:verb): %v", err), true, pa.DB)
 			c.Respond(500, eden.Response{"ERROR", false})
@@ -109,19 +192,51 @@ func (a *Api) GetGroupsByVerb(c *eden.Context) {
 	c.Respond(200, eden.Response{"OK", groups})
 }
 
-// POST /permission actor=:actor verb=:verb resource=:resource
-func (a *Api) AddPermission(c *eden.Context) {
+// List every user who can perform an action and how they can.
+// GET /permission/who?verb=:verb&resource=:resourceGUID&area=:areaGuid
+func (a *Api) WhoCan(c *eden.Context) {
 	pa := accessors.NewPermissionAccessor(a.DB)
 
-	accessors.Log("notice", c.User.NetId, "Called AddPermission (POST /permission actor=:actor verb=:verb resource=:resource)", true, pa.DB)
-
-	// Parse input
 	c.Request.ParseForm()
-	actor, actorOk := c.Request.Form["actor"]
 	verb, verbOk := c.Request.Form["verb"]
 	resource, resourceOk := c.Request.Form["resource"]
-	if !actorOk || !verbOk || !resourceOk {
-		c.Respond(400, eden.Response{"ERROR", "Not enough information given"})
+	area, areaOk := c.Request.Form["area"]
+	if !verbOk || !resourceOk || !areaOk || verb[0] == "" || resource[0] == "" || area[0] == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid input"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in WhoCan (GET /permission/who?verb=:verb&resource=:resourceGUID&area=:areaGuid): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to see who has access"})
+		return
+	}
+
+	access, err := pa.WhoCan(verb[0], resource[0], area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on WhoCan in WhoCan (GET /permission/who?verb=:verb&resource=:resourceGUID&area=:areaGuid): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred while getting the permissions"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", access})
+}
+
+// Grant a permission. Accepts a form or a JSON AddPermissionRequest.
+// POST /permission actor=:actor verb=:verb resource=:resource [condition=:expression]
+func (a *Api) AddPermission(c *eden.Context) {
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called AddPermission (POST /permission actor=:actor verb=:verb resource=:resource)", true, pa.DB)
+
+	// Parse input. The condition, if any, is the one the grant only applies under
+	var req AddPermissionRequest
+	if !decodeRequest(c, &req) {
 		return
 	}
 
@@ -134,15 +249,7 @@ func (a *Api) AddPermission(c *eden.Context) {
 	}
 
 	if su {
-		// Insert permission
-		err = pa.Add(actor[0], verb[0], resource[0])
-		if err != nil {
-			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Add in AddPermission (POST /permission actor=:actor verb=:verb resource=:resource): %v", err), true, pa.DB)
-			c.Respond(500, eden.Response{"ERROR", "An error occurred while granting permission"})
-			return
-		}
-
-		c.Respond(200, eden.Response{"OK", "success"})
+		a.grantPermission(c, req.Actor, req.Verb, req.Resource, req.Condition)
 		return
 	}
 
@@ -155,13 +262,7 @@ func (a *Api) AddPermission(c *eden.Context) {
 	}
 
 	if admin {
-		// Insert permission
-		err = pa.Add(actor[0], verb[0], resource[0])
-		if err != nil {
-			c.Respond(500, eden.Response{"ERROR", "An error occurred while granting permission"})
-			return
-		}
-		c.Respond(200, eden.Response{"OK", "success"})
+		a.grantPermission(c, req.Actor, req.Verb, req.Resource, req.Condition)
 		return
 	}
 
@@ -177,8 +278,8 @@ func (a *Api) AddPermission(c *eden.Context) {
 	for i := 0; i < len(actors); i++ {
 		actorArray = append(actorArray, actors[i].Guid)
 	}
-	actorArray = append(actorArray, c.User.Area)
-	permission, err := pa.CheckPermission(actorArray, resource[0], verb[0])
+	actorArray = append(actorArray, c.User.Area, c.User.NetId)
+	permission, err := pa.CheckPermission(c.User.Area, actorArray, req.Resource, req.Verb)
 	if err != nil {
 		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on CheckPermission in AddPermission (POST /permission actor=:actor verb=:verb resource=:resource): %v", err), true, pa.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
@@ -190,15 +291,64 @@ func (a *Api) AddPermission(c *eden.Context) {
 		return
 	}
 
+	a.grantPermission(c, req.Actor, req.Verb, req.Resource, req.Condition)
+}
+
+// Insert a permission the requestor has been authorized to grant. Grants
+//   breaking separation of duties rules are refused and grants on
+//   sensitive resources are held until a second person approves them.
+func (a *Api) grantPermission(c *eden.Context, actor, verb, resource, condition string) {
+	pa := accessors.NewPermissionAccessor(a.DB)
+	aa := accessors.NewApprovalAccessor(a.DB)
+
+	granted, err := pa.Granted(actor, verb, resource)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Granted in AddPermission (POST /permission actor=:actor verb=:verb resource=:resource): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if granted {
+		c.Respond(200, eden.Response{"OK", WriteResult{false}})
+		return
+	}
+
+	// The grant falls under the rules of the actor's area, not the
+	// requestor's
+	sa := accessors.NewSodAccessor(a.DB)
+	areas, err := sa.GrantAreas(actor, c.User.Area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GrantAreas in AddPermission (POST /permission actor=:actor verb=:verb resource=:resource): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	for _, area := range areas {
+		users := func() ([]string, error) { return sa.Grantees(actor, area) }
+		if !a.enforceSod(c, area, users, []accessors.Permission{{actor, verb, resource, condition}}) {
+			return
+		}
+	}
+
+	sensitive, err := aa.IsSensitive(resource)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSensitive in AddPermission (POST /permission actor=:actor verb=:verb resource=:resource): %v", err), true, pa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	if sensitive {
+		a.requestApproval(c, accessors.Approval{Type: accessors.ApprovalPermission, Area: c.User.Area, Actor: actor, Verb: verb, Resource: resource, Condition: condition})
+		return
+	}
+
 	// Insert permission
-	err = pa.Add(actor[0], verb[0], resource[0])
+	n, err := pa.AddConditional(actor, verb, resource, condition)
 	if err != nil {
-		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Add in AddPermission (POST /permission actor=:actor verb=:verb resource=:resource): %v", err), true, pa.DB)
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on AddConditional in AddPermission (POST /permission actor=:actor verb=:verb resource=:resource): %v", err), true, pa.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error occurred while granting permission"})
 		return
 	}
 
-	c.Respond(200, eden.Response{"OK", "success"})
+	c.Respond(200, eden.Response{"OK", WriteResult{n > 0}})
 }
 
 // DELETE /permission/:actor/:verb/:resource
@@ -221,13 +371,7 @@ func (a *Api) DeletePermission(c *eden.Context) {
 	}
 
 	if su {
-		// Insert permission
-		err = pa.Delete(actor, verb, object)
-		if err != nil {
-			c.Respond(500, eden.Response{"ERROR", "An error occurred while granting permission"})
-			return
-		}
-		c.Respond(200, eden.Response{"OK", "success"})
+		a.revokePermission(c, actor, verb, object)
 		return
 	}
 
@@ -239,15 +383,7 @@ func (a *Api) DeletePermission(c *eden.Context) {
 		return
 	}
 	if admin {
-		// Insert permission
-		err = pa.Delete(actor, verb, object)
-		if err != nil {
-			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Delete in DeletePermission (DELETE /permission/:actor/:verb/:resource): %v", err), true, pa.DB)
-			c.Respond(500, eden.Response{"ERROR", "An error occurred while granting permission"})
-			return
-		}
-
-		c.Respond(200, eden.Response{"OK", "success"})
+		a.revokePermission(c, actor, verb, object)
 		return
 	}
 
@@ -264,8 +400,8 @@ func (a *Api) DeletePermission(c *eden.Context) {
 		actorArray = append(actorArray, actors[i].Guid)
 	}
 
-	actorArray = append(actorArray, c.User.Area)
-	permission, err := pa.CheckPermission(actorArray, object, verb)
+	actorArray = append(actorArray, c.User.Area, c.User.NetId)
+	permission, err := pa.CheckPermission(c.User.Area, actorArray, object, verb)
 	if err != nil {
 		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on CheckPermission in DeletePermission (DELETE /permission/:actor/:verb/:resource): %v", err), true, pa.DB)
 		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
@@ -277,14 +413,24 @@ func (a *Api) DeletePermission(c *eden.Context) {
 		return
 	}
 
-	// Delete permission
-	err = pa.Delete(actor, verb, object)
+	a.revokePermission(c, actor, verb, object)
+}
+
+// Delete a permission the requestor has been authorized to revoke.
+//   Responds with a 404 if it wasn't granted.
+func (a *Api) revokePermission(c *eden.Context, actor, verb, resource string) {
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	n, err := pa.Delete(actor, verb, resource)
 	if err != nil {
 		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Delete in DeletePermission (DELETE /permission/:actor/:verb/:resource): %v", err), true, pa.DB)
-
 		c.Respond(500, eden.Response{"ERROR", "An error occurred while revoking permission"})
 		return
 	}
+	if n == 0 {
+		c.Respond(404, eden.Response{"ERROR", "No such permission"})
+		return
+	}
 
 	c.Respond(200, eden.Response{"OK", "success"})
 }
@@ -3,6 +3,10 @@ package apis
 import (
 	"database/sql"
 	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -33,17 +37,41 @@ func TestCheckPermission(t *testing.T) {
 
 	expected := true
 
-	columns := []string{"guid", "area", "name"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("A").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("A,Area,,,0"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("E").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("A").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("A,Area,,,0"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT . FROM admin WHERE netId=. AND area IN .+").
 		WithArgs("E", "A").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1\ny,1,n2\nz,1,n3"))
+		WillReturnRows(sqlmock.NewRows([]string{"netId", "area"}).FromCSVString(""))
+
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
+		WithArgs("E", "A", "E", "A").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1,,,0,\ny,1,n2,,,0,\nz,1,n3,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
+		WithArgs("edit", "1", "x", "y", "z", "A", "E").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString("x,,\ny,,\nz,,"))
 
-	columns = []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE verb=(.) AND resource=(.) AND actor IN (.+)").
-		WithArgs("edit", "1", "x", "y", "z", "A").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,edit,1\ny,edit,1\nz,edit,1"))
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("A", "x", "y", "z", "A", "E").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
 
 	// Create context, call API
 	var result []byte
@@ -71,23 +99,32 @@ func TestGetGroupsByVerb(t *testing.T) {
 	}
 	api := &Api{db}
 
-	expected := []accessors.Group{accessors.Group{"1", "1", "testGroup"}}
-	columns := []string{"guid", "area", "name"}
+	expected := []accessors.Group{accessors.Group{Guid: "1", Area: "1", Name: "testGroup", Tags: []string{}}}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
-		WithArgs("E", "A").
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
+		WithArgs("E", "A", "E", "A").
 		WillReturnRows(sqlmock.NewRows(columns).FromCSVString(""))
 
-	columns = []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE verb=. AND resource=. AND actor IN (.+)").
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
 		WithArgs("poot", "3", "1").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("1,poot,3"))
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString("1,,"))
 
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE verb=. AND resource=. AND actor IN (.+)").
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
 		WithArgs("poot", "3", "2").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString(""))
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("", "2").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
 
 	// Create context, call API
 	var result []byte
@@ -103,7 +140,7 @@ func TestGetGroupsByVerb(t *testing.T) {
 
 	// Compare output to expected output
 	for i := 0; i < len(output.Data); i++ {
-		if output.Data[i] != expected[i] {
+		if !reflect.DeepEqual(output.Data[i], expected[i]) {
 			t.Errorf("Expected: %v, but got %v", expected, output.Data)
 		}
 	}
@@ -120,22 +157,50 @@ func TestAddPermission(t *testing.T) {
 	}
 	api := &Api{db}
 
-	columns := []string{"guid", "area", "name"}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
-		WithArgs("guid", "area").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1\ny,1,n2\nz,1,n3"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
+		WithArgs("guid", "area", "guid", "area").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1,,,0,\ny,1,n2,,,0,\nz,1,n3,,,0,"))
 
-	columns = []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE verb=(.) AND resource=(.) AND actor IN (.+)").
-		WithArgs("edit", "1", "x", "y", "z", "area").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,edit,1\ny,edit,1\nz,edit,1"))
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
+		WithArgs("edit", "1", "x", "y", "z", "area", "guid").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString("x,,\ny,,\nz,,"))
 
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectExec("INSERT INTO policy (.+) VALUES (.+)").
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "x", "y", "z", "area", "guid").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT actor FROM policy WHERE actor=. AND verb=. AND resource=.").
 		WithArgs("2", "edit", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"actor"}).FromCSVString(""))
+
+	// The actor is a group in another area, whose rules apply
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=.").
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("2,other,n2,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM sodRule WHERE area=.").
+		WithArgs("other").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "verbA", "verbB", "mode"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT resource FROM sensitiveResource WHERE resource=.").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"resource"}).FromCSVString(""))
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO policy (.+) SELECT .+ WHERE NOT EXISTS .+").
+		WithArgs("2", "edit", "1", "2", "edit", "1").
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
 	// Create context, call API
 	var result []byte
@@ -151,8 +216,8 @@ func TestAddPermission(t *testing.T) {
 	}
 
 	// Compare output to expected output
-	if output.Data != "success" {
-		t.Errorf("Expected: %v, but got %v", "success", output)
+	if data, ok := output.Data.(map[string]interface{}); !ok || data["Created"] != true {
+		t.Errorf("Expected: %v, but got %v", WriteResult{true}, output)
 	}
 }
 
@@ -167,17 +232,21 @@ func TestAddWithoutPermission(t *testing.T) {
 	}
 	api := &Api{db}
 
-	columns := []string{"guid", "area", "name"}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
-		WithArgs("guid", "area").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1\ny,1,n2\nz,1,n3"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
+		WithArgs("guid", "area", "guid", "area").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1,,,0,\ny,1,n2,,,0,\nz,1,n3,,,0,"))
 
-	columns = []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE verb=(.) AND resource=(.) AND actor IN (.+)").
-		WithArgs("edit", "1", "x", "y", "z", "area").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString(""))
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
+		WithArgs("edit", "1", "x", "y", "z", "area", "guid").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "x", "y", "z", "area", "guid").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
 
 	// Create context, call API
 	var result []byte
@@ -209,22 +278,35 @@ func TestDeletePermission(t *testing.T) {
 	}
 	api := &Api{db}
 
-	columns := []string{"guid", "area", "name"}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
-		WithArgs("guid", "area").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1\ny,1,n2\nz,1,n3"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
+		WithArgs("guid", "area", "guid", "area").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1,,,0,\ny,1,n2,,,0,\nz,1,n3,,,0,"))
 
-	columns = []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE verb=(.) AND resource=(.) AND actor IN (.+)").
-		WithArgs("edit", "1", "x", "y", "z", "area").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,edit,1\ny,edit,1\nz,edit,1"))
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
+		WithArgs("edit", "1", "x", "y", "z", "area", "guid").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString("x,,\ny,,\nz,,"))
 
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectExec("DELETE FROM policy WHERE actor=(.) AND verb=(.) AND resource=(.)").
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "x", "y", "z", "area", "guid").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("DELETE FROM policy WHERE actor=. AND verb=. AND resource=.").
 		WithArgs("2", "edit", "1").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("DELETE FROM policyCondition WHERE actor=. AND verb=. AND resource=.").
+		WithArgs("2", "edit", "1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("DELETE FROM policyArea WHERE actor=. AND verb=. AND resource=.").
+		WithArgs("2", "edit", "1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
 
 	// Create context, call API
 	var result []byte
@@ -262,17 +344,21 @@ func TestDeleteWithoutPermission(t *testing.T) {
 	}
 	api := &Api{db}
 
-	columns := []string{"guid", "area", "name"}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
-		WithArgs("guid", "area").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1\ny,1,n2\nz,1,n3"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
+		WithArgs("guid", "area", "guid", "area").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1,,,0,\ny,1,n2,,,0,\nz,1,n3,,,0,"))
 
-	columns = []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE verb=(.) AND resource=(.) AND actor IN (.+)").
-		WithArgs("edit", "1", "x", "y", "z", "area").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString(""))
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
+		WithArgs("edit", "1", "x", "y", "z", "area", "guid").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "x", "y", "z", "area", "guid").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
 
 	// Create context, call API
 	var result []byte
@@ -308,11 +394,15 @@ func TestGetPermissionsByGroup(t *testing.T) {
 	api := &Api{db}
 
 	expected := []testPermission{testPermission{"actor", "verb", "resource"}, testPermission{"actor", "verb1", "resource1"}}
-	columns := []string{"actor", "verb", "resource"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE actor=.").
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.actor=.").
 		WithArgs("actor").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("actor,verb,resource\nactor,verb1,resource1"))
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource", "condition"}).FromCSVString("actor,verb,resource,\nactor,verb1,resource1,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ JOIN groups .+ WHERE roleBinding.actor=. AND role.area = groups.area").
+		WithArgs("actor").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
 
 	// Create context, call API
 	var result []byte
@@ -345,18 +435,27 @@ func TestGetUserPermissions(t *testing.T) {
 	api := &Api{db}
 
 	// Get groups
-	columns := []string{"guid", "area", "name"}
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
-		WithArgs("netId", "area").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("g1,1,n1\ng2,1,n2"))
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=(.) AND groups.area=(.)").
+		WithArgs("netId", "area", "netId", "area").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("g1,1,n1,,,0,\ng2,1,n2,,,0,"))
 
 	// Get permissions
-	columns = []string{"actor", "verb", "resource"}
+	columns = []string{"actor", "verb", "resource", "condition"}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("area,Area,,,0"))
 	sqlmock.ExpectPrepare()
-	sqlmock.ExpectQuery("SELECT . FROM policy WHERE actor IN .+").
-		WithArgs("area", "g1", "g2").
-		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("g1,edit,resource\ng1,read,resource\ng2,edit,resource\ng2,read,resource2"))
+	sqlmock.ExpectQuery("SELECT policy.actor, policy.verb, policy.resource, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ LEFT JOIN policyArea .+ WHERE .policyArea.area IS NULL OR policyArea.area=.. AND policy.actor IN .+").
+		WithArgs("area", "area", "netId", "g1", "g2").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("g1,edit,resource,\ng1,read,resource,\ng2,edit,resource,\ng2,read,resource2,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "area", "netId", "g1", "g2").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
 
 	expected := []testPermission{
 		testPermission{"", "edit", "resource"},
@@ -385,3 +484,241 @@ func TestGetUserPermissions(t *testing.T) {
 		}
 	}
 }
+
+func TestWhoCanWithoutAdmin(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("guid").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("area,Area,,,0"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT . FROM admin WHERE netId=. AND area IN .+").
+		WithArgs("guid", "area").
+		WillReturnRows(sqlmock.NewRows([]string{"netId", "area"}).FromCSVString(""))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("verb=edit&resource=1&area=area", nil, api.WhoCan)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.WhoCan, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
+
+func TestGetSnapshotWithoutSuperuser(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("guid").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString(""))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", nil, api.GetSnapshot)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.GetSnapshot, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf("An unexpected error occurred parsing the response %v", err)
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
+
+func TestAddPermissionInvalidCondition(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("actor=2&verb=edit&resource=1&condition=time.hours+%3E+9", nil, api.AddPermission)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.AddPermission, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
+
+func TestBulkPermissionsWithoutPermission(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	columns := []string{"guid", "area", "name", "description", "tags", "created", "createdBy"}
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups JOIN groupMembers ON groups.guid = groupMembers.groupGuid WHERE groupMembers.netId=. AND groups.area=.").
+		WithArgs("guid", "area", "guid", "area").
+		WillReturnRows(sqlmock.NewRows(columns).FromCSVString("x,1,n1,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor, COALESCE.+ FROM policy LEFT JOIN policyCondition .+ WHERE policy.verb=. AND policy.resource=. AND policy.actor IN .+").
+		WithArgs("edit", "1", "x", "area", "guid").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "expression", "area"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.verb, rolePermission.resource FROM roleBinding JOIN rolePermission .+ WHERE role.area=. AND roleBinding.actor IN .+").
+		WithArgs("area", "x", "area", "guid").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "verb", "resource"}).FromCSVString(""))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", nil, api.BulkPermissions)
+	c.User = eden.User{"guid", "area"}
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Body = ioutil.NopCloser(strings.NewReader(`{"Operations": [{"Op": "grant", "Actor": "2", "Verb": "edit", "Resource": "1"}]}`))
+	testhelpers.CallAPI(api.BulkPermissions, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	results, ok := output.Data.([]interface{})
+	if output.Status != "FAILURE" || !ok || len(results) != 1 {
+		t.Errorf("Expected: %v, but got %v", "FAILURE", output)
+		return
+	}
+	if item, ok := results[0].(map[string]interface{}); !ok || item["Status"] != bulkForbidden {
+		t.Errorf("Expected status %v, but got %v", bulkForbidden, results[0])
+	}
+}
+
+func TestBulkPermissionsInvalid(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", nil, api.BulkPermissions)
+	c.User = eden.User{"guid", "area"}
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Body = ioutil.NopCloser(strings.NewReader(`{"Mode": "sometimes", "Operations": [{"Op": "revoke", "Actor": "2", "Verb": "edit"}]}`))
+	testhelpers.CallAPI(api.BulkPermissions, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	errs, ok := output.Data.([]interface{})
+	if output.Status != "ERROR" || !ok || len(errs) != 2 {
+		t.Errorf("Expected errors for mode and operations[0].resource, but got %v", output)
+	}
+}
+
+func TestBulkPermissionsSamePermissionTwice(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("", nil, api.BulkPermissions)
+	c.User = eden.User{"guid", "area"}
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Body = ioutil.NopCloser(strings.NewReader(`{"Operations": [{"Op": "revoke", "Actor": "2", "Verb": "edit", "Resource": "1"}, {"Op": "grant", "Actor": "2", "Verb": "edit", "Resource": "1"}]}`))
+	testhelpers.CallAPI(api.BulkPermissions, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	errs, ok := output.Data.([]interface{})
+	if output.Status != "ERROR" || !ok || len(errs) != 1 {
+		t.Errorf("Expected an error for operations[1], but got %v", output)
+	}
+}
+
+func TestConditionContext(t *testing.T) {
+	query := url.Values{"areaGuid": {"area"}, "ip": {"10.1.2.3:443"}, "shift": {"night"}}
+
+	ctx := conditionContext(query)
+	if ctx["request.ip"] != "10.1.2.3" {
+		t.Errorf("Expected: %v, but got %v", "10.1.2.3", ctx["request.ip"])
+	}
+	if _, ok := ctx["attr.ip"]; ok {
+		t.Errorf("Expected ip not to be an attribute, but got %v", ctx)
+	}
+	if ctx["attr.shift"] != "night" {
+		t.Errorf("Expected: %v, but got %v", "night", ctx["attr.shift"])
+	}
+}
@@ -0,0 +1,236 @@
+package apis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	"github.com/byu-oit-ssengineering/tmt-permissions/relations"
+)
+
+// Check whether a user has a relation to an object.
+// GET /relations/check?object=:namespace:id&relation=:relation&user=:netId
+func (a *Api) CheckRelation(c *eden.Context) {
+	ta := accessors.NewTupleAccessor(a.DB)
+
+	c.Request.ParseForm()
+	object := c.Request.Form.Get("object")
+	relation := c.Request.Form.Get("relation")
+	user := c.Request.Form.Get("user")
+	if object == "" || relation == "" || user == "" {
+		c.Respond(400, eden.Response{"ERROR", false})
+		return
+	}
+
+	ok, err := relations.NewChecker(ta).Check(object, relation, user)
+	if err == relations.ErrUnknownNamespace {
+		c.Respond(400, eden.Response{"ERROR", "Unknown namespace"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Check in CheckRelation (GET /relations/check): %v", err), true, ta.DB)
+		c.Respond(500, eden.Response{"ERROR", false})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", ok})
+}
+
+// Expand a relation on an object into the tree of who has it.
+// GET /relations/expand?object=:namespace:id&relation=:relation
+func (a *Api) ExpandRelation(c *eden.Context) {
+	ta := accessors.NewTupleAccessor(a.DB)
+
+	c.Request.ParseForm()
+	object := c.Request.Form.Get("object")
+	relation := c.Request.Form.Get("relation")
+	if object == "" || relation == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid object or relation"})
+		return
+	}
+
+	tree, err := relations.NewChecker(ta).Expand(object, relation)
+	if err == relations.ErrUnknownNamespace {
+		c.Respond(400, eden.Response{"ERROR", "Unknown namespace"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Expand in ExpandRelation (GET /relations/expand): %v", err), true, ta.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", tree})
+}
+
+// Tells whether the user may write tuples for object#relation: superusers
+// and admins can, as can anyone who has the relation themselves.
+func (a *Api) canWriteTuple(c *eden.Context, t relations.Tuple) (bool, error) {
+	pa := accessors.NewPermissionAccessor(a.DB)
+	ta := accessors.NewTupleAccessor(a.DB)
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, c.User.Area)
+	if err != nil || allowed {
+		return allowed, err
+	}
+
+	return relations.NewChecker(ta).Check(t.Object, t.Relation, c.User.NetId)
+}
+
+// Parse a tuple and check it against its namespace's configuration.
+// Responds and returns false if it can't be written.
+func (a *Api) writableTuple(c *eden.Context, s, handler string) (relations.Tuple, bool) {
+	ta := accessors.NewTupleAccessor(a.DB)
+
+	t, err := relations.ParseTuple(s)
+	if err != nil {
+		c.Respond(400, eden.Response{"ERROR", err.Error()})
+		return t, false
+	}
+
+	ns, _ := relations.SplitObject(t.Object)
+	if accessors.IsVirtualNamespace(ns) {
+		c.Respond(400, eden.Response{"ERROR", accessors.ErrVirtualNamespace.Error()})
+		return t, false
+	}
+	namespace, err := ta.Namespace(ns)
+	if err == relations.ErrUnknownNamespace {
+		c.Respond(400, eden.Response{"ERROR", "Unknown namespace"})
+		return t, false
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Namespace in %s: %v", handler, err), true, ta.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return t, false
+	}
+	if _, ok := namespace.Relations[t.Relation]; !ok && !namespace.AnyRelation {
+		c.Respond(400, eden.Response{"ERROR", fmt.Sprintf("Namespace %s has no relation %s", ns, t.Relation)})
+		return t, false
+	}
+
+	allowed, err := a.canWriteTuple(c, t)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on canWriteTuple in %s: %v", handler, err), true, ta.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return t, false
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"FAILURE", "You need to have this relation in order to grant it"})
+		return t, false
+	}
+
+	return t, true
+}
+
+// Store a relationship tuple.
+// POST /relations tuple=:object#relation@subject
+func (a *Api) AddRelation(c *eden.Context) {
+	ta := accessors.NewTupleAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called AddRelation (POST /relations tuple=:object#relation@subject)", true, ta.DB)
+
+	c.Request.ParseForm()
+	t, ok := a.writableTuple(c, c.Request.Form.Get("tuple"), "AddRelation (POST /relations)")
+	if !ok {
+		return
+	}
+
+	if err := ta.Add(t); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Add in AddRelation (POST /relations): %v", err), true, ta.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Added relation %s", t), true, ta.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Remove a relationship tuple. The # in the tuple must be escaped as %23.
+// DELETE /relations/:tuple
+func (a *Api) DeleteRelation(c *eden.Context) {
+	ta := accessors.NewTupleAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called DeleteRelation (DELETE /relations/:tuple)", true, ta.DB)
+
+	t, ok := a.writableTuple(c, c.Params[0].Value, "DeleteRelation (DELETE /relations/:tuple)")
+	if !ok {
+		return
+	}
+
+	if err := ta.Delete(t); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Delete in DeleteRelation (DELETE /relations/:tuple): %v", err), true, ta.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Deleted relation %s", t), true, ta.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Get a namespace's configuration.
+// GET /relations/namespaces/:name
+func (a *Api) GetNamespace(c *eden.Context) {
+	ta := accessors.NewTupleAccessor(a.DB)
+
+	namespace, err := ta.Namespace(c.Params[0].Value)
+	if err == relations.ErrUnknownNamespace {
+		c.Respond(404, eden.Response{"ERROR", "Unknown namespace"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Namespace in GetNamespace (GET /relations/namespaces/:name): %v", err), true, ta.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", namespace})
+}
+
+// Create or replace a namespace's configuration. The body is the JSON
+// encoded relations.Namespace.
+// PUT /relations/namespaces/:name
+func (a *Api) SetNamespace(c *eden.Context) {
+	ta := accessors.NewTupleAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	name := c.Params[0].Value
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called SetNamespace on %s (PUT /relations/namespaces/:name)", name), true, ta.DB)
+
+	su, err := pa.IsSuperuser(c.User.NetId)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on IsSuperuser in SetNamespace (PUT /relations/namespaces/:name): %v", err), true, ta.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !su {
+		c.Respond(403, eden.Response{"ERROR", "You need to be a superuser to configure namespaces"})
+		return
+	}
+
+	if accessors.IsVirtualNamespace(name) {
+		c.Respond(400, eden.Response{"ERROR", accessors.ErrVirtualNamespace.Error()})
+		return
+	}
+
+	var namespace relations.Namespace
+	if err := json.NewDecoder(c.Request.Body).Decode(&namespace); err != nil {
+		c.Respond(400, eden.Response{"ERROR", fmt.Sprintf("Invalid namespace: %v", err)})
+		return
+	}
+	namespace.Name = name
+	if err := namespace.Validate(); err != nil {
+		c.Respond(400, eden.Response{"ERROR", fmt.Sprintf("Invalid namespace: %v", err)})
+		return
+	}
+
+	if err := ta.SetNamespace(namespace); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on SetNamespace in SetNamespace (PUT /relations/namespaces/:name): %v", err), true, ta.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Configured relation namespace %s", name), true, ta.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
@@ -0,0 +1,90 @@
+package apis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+)
+
+func TestCheckRelationThroughGroup(t *testing.T) {
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// resource:res#edit comes from the policy table
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT policy.actor FROM policy LEFT JOIN policyCondition .+").
+		WithArgs("edit", "res").
+		WillReturnRows(sqlmock.NewRows([]string{"actor"}).FromCSVString("g1"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleBinding.actor, rolePermission.resource FROM roleBinding .+").
+		WithArgs("edit").
+		WillReturnRows(sqlmock.NewRows([]string{"actor", "resource"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, area FROM groups WHERE guid IN .+ OR area IN .+").
+		WithArgs("g1", "g1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area"}).FromCSVString("g1,area"))
+
+	// group:g1#member comes from groupMembers
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT netId FROM groupMembers WHERE groupGuid=.").
+		WithArgs("g1", "g1").
+		WillReturnRows(sqlmock.NewRows([]string{"netId"}).FromCSVString("bob\nalice"))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("object=resource:res&relation=edit&user=alice", nil, api.CheckRelation)
+	testhelpers.CallAPI(api.CheckRelation, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Data != true {
+		t.Errorf("Expected: %v, but got %v", true, output)
+	}
+}
+
+func TestAddRelationToVirtualNamespace(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("tuple=group:g1%23member@alice", nil, api.AddRelation)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.AddRelation, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
@@ -0,0 +1,264 @@
+package apis
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
+)
+
+// A problem with one field of a request body.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// A request body that can be sent either as JSON or form encoded.
+type request interface {
+	fromForm(form url.Values)
+	validate() []FieldError
+}
+
+// Fill req from the request body, JSON if the Content-Type says so and
+// form values otherwise, and validate it. Responds with a 400 listing the
+// problems and returns false if the body can't be read or isn't valid.
+func decodeRequest(c *eden.Context, req request) bool {
+	mediaType, _, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.NewDecoder(c.Request.Body).Decode(req); err != nil {
+			c.Respond(400, eden.Response{"ERROR", []FieldError{{"body", fmt.Sprintf("Invalid JSON: %v", err)}}})
+			return false
+		}
+	} else {
+		c.Request.ParseForm()
+		req.fromForm(c.Request.Form)
+	}
+
+	if errs := req.validate(); len(errs) > 0 {
+		c.Respond(400, eden.Response{"ERROR", errs})
+		return false
+	}
+	return true
+}
+
+// Adds a FieldError to errs for each named field whose value is empty.
+func required(errs []FieldError, fields ...string) []FieldError {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] == "" {
+			errs = append(errs, FieldError{fields[i], "is required"})
+		}
+	}
+	return errs
+}
+
+// POST /permission
+type AddPermissionRequest struct {
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string
+}
+
+func (r *AddPermissionRequest) fromForm(form url.Values) {
+	r.Actor = form.Get("actor")
+	r.Verb = form.Get("verb")
+	r.Resource = form.Get("resource")
+	r.Condition = form.Get("condition")
+}
+
+func (r *AddPermissionRequest) validate() []FieldError {
+	errs := required(nil, "actor", r.Actor, "verb", r.Verb, "resource", r.Resource)
+	if r.Condition != "" {
+		if _, err := conditions.Parse(r.Condition); err != nil {
+			errs = append(errs, FieldError{"condition", fmt.Sprintf("Invalid condition: %v", err)})
+		}
+	}
+	return errs
+}
+
+// POST /admin
+type AddAdminRequest struct {
+	Area  string
+	NetId string
+}
+
+func (r *AddAdminRequest) fromForm(form url.Values) {
+	r.Area = form.Get("area")
+	r.NetId = form.Get("netId")
+}
+
+func (r *AddAdminRequest) validate() []FieldError {
+	return required(nil, "area", r.Area, "netId", r.NetId)
+}
+
+// POST /superuser
+type AddSURequest struct {
+	NetId string
+}
+
+func (r *AddSURequest) fromForm(form url.Values) {
+	r.NetId = form.Get("netId")
+}
+
+func (r *AddSURequest) validate() []FieldError {
+	return required(nil, "netId", r.NetId)
+}
+
+// POST /groups. Form encoded tags are comma separated.
+type CreateGroupRequest struct {
+	Area        string
+	Name        string
+	Description string
+	Tags        []string
+}
+
+func (r *CreateGroupRequest) fromForm(form url.Values) {
+	r.Area = form.Get("area")
+	r.Name = form.Get("name")
+	r.Description = form.Get("description")
+	r.Tags = parseTags(form.Get("tags"))
+}
+
+func (r *CreateGroupRequest) validate() []FieldError {
+	errs := required(nil, "area", r.Area, "name", r.Name)
+	for _, tag := range r.Tags {
+		if strings.Contains(tag, ",") {
+			errs = append(errs, FieldError{"tags", fmt.Sprintf("%q can't contain a comma", tag)})
+		}
+	}
+	return errs
+}
+
+// POST /groupMembers
+type AddGroupMemberRequest struct {
+	NetId string
+	Group string
+}
+
+func (r *AddGroupMemberRequest) fromForm(form url.Values) {
+	r.NetId = form.Get("netId")
+	r.Group = form.Get("group")
+}
+
+func (r *AddGroupMemberRequest) validate() []FieldError {
+	return required(nil, "netId", r.NetId, "group", r.Group)
+}
+
+// PUT /groupMembers/:groupGuid. Form encoded members are comma separated.
+// An empty list is allowed and removes every direct member, but the list
+// has to be given.
+type SyncGroupMembersRequest struct {
+	Members []string
+	DryRun  bool
+}
+
+func (r *SyncGroupMembersRequest) fromForm(form url.Values) {
+	if _, ok := form["members"]; ok {
+		r.Members = make([]string, 0)
+		for _, netId := range strings.Split(form.Get("members"), ",") {
+			if netId = strings.TrimSpace(netId); netId != "" {
+				r.Members = append(r.Members, netId)
+			}
+		}
+	}
+	r.DryRun, _ = strconv.ParseBool(form.Get("dryRun"))
+}
+
+func (r *SyncGroupMembersRequest) validate() []FieldError {
+	if r.Members == nil {
+		return []FieldError{{"members", "is required"}}
+	}
+
+	var errs []FieldError
+	for i, netId := range r.Members {
+		if strings.TrimSpace(netId) == "" {
+			errs = append(errs, FieldError{fmt.Sprintf("members[%d]", i), "can't be empty"})
+		}
+	}
+	return errs
+}
+
+// The most operations a single POST /permission/bulk may carry.
+const maxBulkOperations = 1000
+
+// Bulk modes. Atomic applies every operation or none of them; best effort
+// applies what it can and reports the rest.
+const (
+	bulkAtomic     = "atomic"
+	bulkBestEffort = "bestEffort"
+)
+
+// One grant or revoke in a BulkPermissionRequest.
+type BulkOperation struct {
+	Op        string
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string
+}
+
+// POST /permission/bulk. Form encoded operations are a JSON array.
+type BulkPermissionRequest struct {
+	Mode       string
+	Operations []BulkOperation
+
+	formError error
+}
+
+func (r *BulkPermissionRequest) fromForm(form url.Values) {
+	r.Mode = form.Get("mode")
+	if operations := form.Get("operations"); operations != "" {
+		r.formError = json.Unmarshal([]byte(operations), &r.Operations)
+	}
+}
+
+func (r *BulkPermissionRequest) validate() []FieldError {
+	var errs []FieldError
+	if r.Mode == "" {
+		r.Mode = bulkAtomic
+	}
+	if r.Mode != bulkAtomic && r.Mode != bulkBestEffort {
+		errs = append(errs, FieldError{"mode", fmt.Sprintf("must be %s or %s", bulkAtomic, bulkBestEffort)})
+	}
+
+	if r.formError != nil {
+		return append(errs, FieldError{"operations", fmt.Sprintf("Invalid JSON: %v", r.formError)})
+	}
+	if len(r.Operations) == 0 {
+		return append(errs, FieldError{"operations", "is required"})
+	}
+	if len(r.Operations) > maxBulkOperations {
+		return append(errs, FieldError{"operations", fmt.Sprintf("can't have more than %d entries", maxBulkOperations)})
+	}
+
+	// Operations are decided against the policy as it stands before the
+	// request, so two on the same permission would be decided wrongly.
+	seen := make(map[[3]string]int)
+	for i, op := range r.Operations {
+		field := fmt.Sprintf("operations[%d].", i)
+		if op.Op != "grant" && op.Op != "revoke" {
+			errs = append(errs, FieldError{field + "op", "must be grant or revoke"})
+		}
+		errs = required(errs, field+"actor", op.Actor, field+"verb", op.Verb, field+"resource", op.Resource)
+		key := [3]string{op.Actor, op.Verb, op.Resource}
+		if j, ok := seen[key]; ok {
+			errs = append(errs, FieldError{fmt.Sprintf("operations[%d]", i), fmt.Sprintf("is for the same permission as operations[%d]", j)})
+		} else {
+			seen[key] = i
+		}
+		if op.Condition == "" {
+			continue
+		}
+		if op.Op == "revoke" {
+			errs = append(errs, FieldError{field + "condition", "only applies to grants"})
+		} else if _, err := conditions.Parse(op.Condition); err != nil {
+			errs = append(errs, FieldError{field + "condition", fmt.Sprintf("Invalid condition: %v", err)})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,375 @@
+package apis
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// Outcome of a review campaign. Unreviewed items are revoked as well, so
+// they appear in both Revoked and Unreviewed.
+type ReviewReport struct {
+	Campaign   accessors.ReviewCampaign
+	Kept       []accessors.ReviewItem
+	Revoked    []accessors.ReviewItem
+	Unreviewed []accessors.ReviewItem
+}
+
+// Sort a campaign's items into a report.
+func buildReviewReport(campaign accessors.ReviewCampaign, items []accessors.ReviewItem) ReviewReport {
+	report := ReviewReport{campaign, make([]accessors.ReviewItem, 0), make([]accessors.ReviewItem, 0), make([]accessors.ReviewItem, 0)}
+	for _, i := range items {
+		switch i.Decision {
+		case accessors.ReviewKeep:
+			report.Kept = append(report.Kept, i)
+		case accessors.ReviewRevoke:
+			report.Revoked = append(report.Revoked, i)
+		default:
+			report.Revoked = append(report.Revoked, i)
+			report.Unreviewed = append(report.Unreviewed, i)
+		}
+	}
+
+	return report
+}
+
+// Remove everything not explicitly kept in a campaign and close it, all
+// or nothing. Returns false if the campaign had already been closed.
+func closeReviewCampaign(db *sql.DB, campaign accessors.ReviewCampaign, actor string) (ReviewReport, bool, error) {
+	ra := accessors.NewReviewAccessor(db)
+
+	items, err := ra.GetItems(campaign.Guid)
+	if err != nil {
+		return ReviewReport{}, false, err
+	}
+
+	report := buildReviewReport(campaign, items)
+	closed, err := ra.Close(campaign.Guid, report.Revoked)
+	if err != nil || !closed {
+		return report, false, err
+	}
+	report.Campaign.Status = accessors.ReviewClosed
+
+	for _, i := range report.Revoked {
+		accessors.Log("audit", actor, fmt.Sprintf("Review %s revoked %s %s%s %s %s", campaign.Guid, i.Type, i.NetId, i.Group, i.Verb, i.Resource), true, db)
+	}
+	accessors.Log("audit", actor, fmt.Sprintf("Review %s closed: %d kept, %d revoked, %d unreviewed", campaign.Guid, len(report.Kept), len(report.Revoked), len(report.Unreviewed)), true, db)
+	return report, true, nil
+}
+
+// Tells whether a user may see an area's campaigns and their reports:
+// superusers, the area's admins and the owners of its groups may.
+func canViewReviews(db *sql.DB, netId, area string) (bool, error) {
+	allowed, err := isSUOrAdmin(accessors.NewPermissionAccessor(db), netId, area)
+	if err != nil || allowed {
+		return allowed, err
+	}
+
+	return accessors.NewMembersAccessor(db).OwnsInArea(netId, area)
+}
+
+// Close every open campaign whose deadline has passed. A campaign that
+// fails to close is logged and tried again next time without holding up
+// the others.
+func (a *Api) closeDueReviews() error {
+	ra := accessors.NewReviewAccessor(a.DB)
+
+	due, err := ra.GetDue(timeNow().Unix())
+	if err != nil {
+		return err
+	}
+
+	for _, campaign := range due {
+		if _, _, err := closeReviewCampaign(a.DB, campaign, "system"); err != nil {
+			accessors.Log("error", "system", fmt.Sprintf("Error on closeReviewCampaign in closeDueReviews for %s: %v", campaign.Guid, err), true, a.DB)
+		}
+	}
+
+	return nil
+}
+
+// Start a review campaign for an area.
+// POST /reviews area=:areaGuid, deadline=:RFC3339
+func (a *Api) StartReview(c *eden.Context) {
+	ra := accessors.NewReviewAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called StartReview (POST /reviews area=:areaGuid, deadline=:RFC3339)", true, ra.DB)
+
+	c.Request.ParseForm()
+	area, areaOk := c.Request.Form["area"]
+	deadline, deadlineOk := c.Request.Form["deadline"]
+	if !areaOk || area[0] == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+		return
+	}
+	if !deadlineOk {
+		c.Respond(400, eden.Response{"ERROR", "Invalid deadline"})
+		return
+	}
+	due, err := time.Parse(time.RFC3339, deadline[0])
+	if err != nil || !due.After(timeNow()) {
+		c.Respond(400, eden.Response{"ERROR", "Invalid deadline"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in StartReview (POST /reviews area=:areaGuid, deadline=:RFC3339): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to start a review"})
+		return
+	}
+
+	campaign, err := ra.Start(area[0], c.User.NetId, due.Unix())
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Start in StartReview (POST /reviews area=:areaGuid, deadline=:RFC3339): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Started review %s of area %s due %s", campaign.Guid, campaign.Area, deadline[0]), true, ra.DB)
+	c.Respond(200, eden.Response{"OK", campaign})
+}
+
+// Get the review campaigns for an area.
+// GET /reviews?area=:areaGuid
+func (a *Api) GetReviews(c *eden.Context) {
+	ra := accessors.NewReviewAccessor(a.DB)
+
+	c.Request.ParseForm()
+	area, areaOk := c.Request.Form["area"]
+	if !areaOk || area[0] == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+		return
+	}
+
+	allowed, err := canViewReviews(a.DB, c.User.NetId, area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on canViewReviews in GetReviews (GET /reviews?area=:areaGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin or a group owner to see reviews"})
+		return
+	}
+
+	campaigns, err := ra.GetCampaigns(area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetCampaigns in GetReviews (GET /reviews?area=:areaGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error occurred while retrieving reviews"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", campaigns})
+}
+
+// Get the items of a campaign. Admins see every item, everyone else only
+// the items assigned to them.
+// GET /reviews/:guid
+func (a *Api) GetReviewItems(c *eden.Context) {
+	ra := accessors.NewReviewAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	campaign, err := ra.GetCampaign(guid)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such review"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetCampaign in GetReviewItems (GET /reviews/:guid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	admin, err := isSUOrAdmin(pa, c.User.NetId, campaign.Area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in GetReviewItems (GET /reviews/:guid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	items, err := ra.GetItems(guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetItems in GetReviewItems (GET /reviews/:guid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	if !admin {
+		assigned := make([]accessors.ReviewItem, 0)
+		for _, i := range items {
+			if i.Reviewer == c.User.NetId {
+				assigned = append(assigned, i)
+			}
+		}
+		items = assigned
+	}
+
+	c.Respond(200, eden.Response{"OK", items})
+}
+
+// Record a keep or revoke decision on an item.
+// PUT /reviews/:guid/items/:itemGuid decision=keep|revoke
+func (a *Api) DecideReviewItem(c *eden.Context) {
+	ra := accessors.NewReviewAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	guid := c.Params[0].Value
+	itemGuid := c.Params[1].Value
+
+	c.Request.ParseForm()
+	decision, decisionOk := c.Request.Form["decision"]
+	if !decisionOk || (decision[0] != accessors.ReviewKeep && decision[0] != accessors.ReviewRevoke) {
+		c.Respond(400, eden.Response{"ERROR", "Decision must be keep or revoke"})
+		return
+	}
+
+	item, err := ra.GetItem(itemGuid)
+	if err == sql.ErrNoRows || (err == nil && item.Campaign != guid) {
+		c.Respond(404, eden.Response{"ERROR", "No such review item"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetItem in DecideReviewItem (PUT /reviews/:guid/items/:itemGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	campaign, err := ra.GetCampaign(guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetCampaign in DecideReviewItem (PUT /reviews/:guid/items/:itemGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if campaign.Status != accessors.ReviewOpen {
+		c.Respond(409, eden.Response{"ERROR", "This review has been closed"})
+		return
+	}
+
+	// Nobody certifies their own access; those items fall to the area's
+	// other admins
+	if item.NetId == c.User.NetId {
+		c.Respond(403, eden.Response{"ERROR", "You can't review your own access"})
+		return
+	}
+
+	if item.Reviewer != c.User.NetId {
+		admin, err := isSUOrAdmin(pa, c.User.NetId, campaign.Area)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in DecideReviewItem (PUT /reviews/:guid/items/:itemGuid): %v", err), true, ra.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		if !admin {
+			c.Respond(403, eden.Response{"ERROR", "This item is not assigned to you"})
+			return
+		}
+	}
+
+	if err := ra.Decide(itemGuid, decision[0], c.User.NetId); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Decide in DecideReviewItem (PUT /reviews/:guid/items/:itemGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Close a campaign before its deadline, revoking everything not kept.
+// POST /reviews/:guid/close
+func (a *Api) CloseReview(c *eden.Context) {
+	ra := accessors.NewReviewAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called CloseReview on %s (POST /reviews/:guid/close)", guid), true, ra.DB)
+
+	campaign, err := ra.GetCampaign(guid)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such review"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetCampaign in CloseReview (POST /reviews/:guid/close): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if campaign.Status != accessors.ReviewOpen {
+		c.Respond(409, eden.Response{"ERROR", "This review has already been closed"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, campaign.Area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in CloseReview (POST /reviews/:guid/close): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to close a review"})
+		return
+	}
+
+	report, closed, err := closeReviewCampaign(a.DB, campaign, c.User.NetId)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on closeReviewCampaign in CloseReview (POST /reviews/:guid/close): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !closed {
+		c.Respond(409, eden.Response{"ERROR", "This review has already been closed"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", report})
+}
+
+// Get the report of a campaign.
+// GET /reviews/:guid/report
+func (a *Api) GetReviewReport(c *eden.Context) {
+	ra := accessors.NewReviewAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	campaign, err := ra.GetCampaign(guid)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such review"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetCampaign in GetReviewReport (GET /reviews/:guid/report): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	allowed, err := canViewReviews(a.DB, c.User.NetId, campaign.Area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on canViewReviews in GetReviewReport (GET /reviews/:guid/report): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin or a group owner to see this report"})
+		return
+	}
+
+	items, err := ra.GetItems(guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetItems in GetReviewReport (GET /reviews/:guid/report): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", buildReviewReport(campaign, items)})
+}
@@ -0,0 +1,207 @@
+package apis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+	"github.com/julienschmidt/httprouter"
+)
+
+var reviewCampaignColumns = []string{"guid", "area", "creator", "deadline", "status"}
+var reviewItemColumns = []string{"guid", "campaign", "type", "netId", "groupGuid", "verb", "resource", "reviewer", "decision", "decidedBy"}
+
+func TestBuildReviewReport(t *testing.T) {
+	items := []accessors.ReviewItem{
+		accessors.ReviewItem{Guid: "i1", Decision: accessors.ReviewKeep},
+		accessors.ReviewItem{Guid: "i2", Decision: accessors.ReviewRevoke},
+		accessors.ReviewItem{Guid: "i3"},
+	}
+
+	report := buildReviewReport(accessors.ReviewCampaign{Guid: "c1"}, items)
+
+	if len(report.Kept) != 1 || report.Kept[0].Guid != "i1" {
+		t.Errorf("Expected i1 to be kept but got %v", report.Kept)
+	}
+	if len(report.Revoked) != 2 || report.Revoked[0].Guid != "i2" || report.Revoked[1].Guid != "i3" {
+		t.Errorf("Expected i2 and i3 to be revoked but got %v", report.Revoked)
+	}
+	if len(report.Unreviewed) != 1 || report.Unreviewed[0].Guid != "i3" {
+		t.Errorf("Expected i3 to be unreviewed but got %v", report.Unreviewed)
+	}
+}
+
+func TestDecideUnassignedReviewItem(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM reviewItem WHERE guid=.").
+		WithArgs("i1").
+		WillReturnRows(sqlmock.NewRows(reviewItemColumns).FromCSVString("i1,c1,membership,user1,g1,,,owner,,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM reviewCampaign WHERE guid=.").
+		WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows(reviewCampaignColumns).FromCSVString("c1,area,admin,1000,open"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("someone").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("area,Area,,,0"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT . FROM admin WHERE netId=. AND area IN .+").
+		WithArgs("someone", "area").
+		WillReturnRows(sqlmock.NewRows([]string{"netId", "area"}).FromCSVString(""))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("decision=keep",
+		httprouter.Params{
+			httprouter.Param{Key: "guid", Value: "c1"},
+			httprouter.Param{Key: "itemGuid", Value: "i1"}},
+		api.DecideReviewItem,
+	)
+	c.User = eden.User{"someone", "area"}
+	testhelpers.CallAPI(api.DecideReviewItem, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
+
+func TestDecideOwnReviewItem(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	// user1 owns g1 but can't keep their own membership
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM reviewItem WHERE guid=.").
+		WithArgs("i1").
+		WillReturnRows(sqlmock.NewRows(reviewItemColumns).FromCSVString("i1,c1,membership,user1,g1,,,user1,,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM reviewCampaign WHERE guid=.").
+		WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows(reviewCampaignColumns).FromCSVString("c1,area,admin,1000,open"))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("decision=keep",
+		httprouter.Params{
+			httprouter.Param{Key: "guid", Value: "c1"},
+			httprouter.Param{Key: "itemGuid", Value: "i1"}},
+		api.DecideReviewItem,
+	)
+	c.User = eden.User{"user1", "area"}
+	testhelpers.CallAPI(api.DecideReviewItem, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
+
+func TestCloseReview(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM reviewCampaign WHERE guid=.").
+		WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows(reviewCampaignColumns).FromCSVString("c1,area,admin,1000,open"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString("1"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM reviewItem WHERE campaign=.").
+		WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows(reviewItemColumns).FromCSVString("i1,c1,membership,user1,g1,,,owner,keep,owner\ni2,c1,policy,,g1,edit,res,owner,,"))
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("UPDATE reviewCampaign SET status=. WHERE guid=. AND status=.").
+		WithArgs("closed", "c1", "open").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("DELETE FROM policy WHERE actor=. AND verb=. AND resource=.").
+		WithArgs("g1", "edit", "res").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlmock.ExpectExec("DELETE FROM policyCondition WHERE actor=. AND verb=. AND resource=.").
+		WithArgs("g1", "edit", "res").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("DELETE FROM policyArea WHERE actor=. AND verb=. AND resource=.").
+		WithArgs("g1", "edit", "res").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	// Create context, call API
+	var result []byte
+	var output struct {
+		Status string
+		Data   ReviewReport
+	}
+	c := testhelpers.NewTestingContext("", httprouter.Params{httprouter.Param{Key: "guid", Value: "c1"}}, api.CloseReview)
+	c.User = eden.User{"admin", "area"}
+	testhelpers.CallAPI(api.CloseReview, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if len(output.Data.Kept) != 1 || len(output.Data.Unreviewed) != 1 || output.Data.Campaign.Status != "closed" {
+		t.Errorf("Expected one kept and one unreviewed item, but got %v", output.Data)
+	}
+}
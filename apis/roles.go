@@ -0,0 +1,362 @@
+package apis
+
+import (
+	"database/sql"
+	"fmt"
+	"path"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// A role along with what it grants and who it is bound to.
+type RoleDetail struct {
+	Role        accessors.Role
+	Permissions []accessors.Permission
+	Bindings    []string
+}
+
+// Load the role named by the first route parameter and make sure the user
+// may manage it. Responds and returns false if the handler should stop.
+func (a *Api) managedRole(c *eden.Context, handler string) (accessors.Role, bool) {
+	ra := accessors.NewRoleAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	role, err := ra.Get(c.Params[0].Value)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such role"})
+		return role, false
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in %s: %v", handler, err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return role, false
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, role.Area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in %s: %v", handler, err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return role, false
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to manage roles"})
+		return role, false
+	}
+
+	return role, true
+}
+
+// Get the roles defined in an area.
+// GET /roles?area=:areaGuid
+func (a *Api) GetRoles(c *eden.Context) {
+	ra := accessors.NewRoleAccessor(a.DB)
+
+	c.Request.ParseForm()
+	area, areaOk := c.Request.Form["area"]
+	if !areaOk || area[0] == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+		return
+	}
+
+	roles, err := ra.GetByArea(area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetByArea in GetRoles (GET /roles?area=:areaGuid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", roles})
+}
+
+// Get a role with its permissions and bindings.
+// GET /roles/:guid
+func (a *Api) GetRole(c *eden.Context) {
+	ra := accessors.NewRoleAccessor(a.DB)
+
+	role, err := ra.Get(c.Params[0].Value)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such role"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in GetRole (GET /roles/:guid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	perms, err := ra.GetPermissions(role.Guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetPermissions in GetRole (GET /roles/:guid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	bindings, err := ra.GetBindings(role.Guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetBindings in GetRole (GET /roles/:guid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", RoleDetail{role, perms, bindings}})
+}
+
+// Create a role.
+// POST /roles area=:areaGuid, name=:name
+func (a *Api) CreateRole(c *eden.Context) {
+	ra := accessors.NewRoleAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called CreateRole (POST /roles area=:areaGuid, name=:name)", true, ra.DB)
+
+	c.Request.ParseForm()
+	role := accessors.Role{
+		Area: c.Request.Form.Get("area"),
+		Name: c.Request.Form.Get("name"),
+	}
+	if role.Area == "" || role.Name == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid area or name"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, role.Area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in CreateRole (POST /roles): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to manage roles"})
+		return
+	}
+
+	guid, err := ra.Create(role)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Create in CreateRole (POST /roles): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Created role %s (%s) in %s", guid, role.Name, role.Area), true, ra.DB)
+	c.Respond(200, eden.Response{"OK", guid})
+}
+
+// Delete a role, which revokes it from everything it is bound to.
+// DELETE /roles/:guid
+func (a *Api) DeleteRole(c *eden.Context) {
+	ra := accessors.NewRoleAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called DeleteRole on %s (DELETE /roles/:guid)", c.Params[0].Value), true, ra.DB)
+
+	role, ok := a.managedRole(c, "DeleteRole (DELETE /roles/:guid)")
+	if !ok {
+		return
+	}
+
+	if err := ra.Delete(role.Guid); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Delete in DeleteRole (DELETE /roles/:guid): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Deleted role %s (%s)", role.Guid, role.Name), true, ra.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Add a verb/resource pair to a role. The resource may be a pattern such
+// as "*" or "report-*". Every binding of the role picks the change up.
+// POST /roles/:guid/permissions verb=:verb, resource=:resourcePattern
+func (a *Api) AddRolePermission(c *eden.Context) {
+	ra := accessors.NewRoleAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called AddRolePermission on %s (POST /roles/:guid/permissions verb=:verb, resource=:resourcePattern)", c.Params[0].Value), true, ra.DB)
+
+	c.Request.ParseForm()
+	verb := c.Request.Form.Get("verb")
+	resource := c.Request.Form.Get("resource")
+	if verb == "" || resource == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid verb or resource"})
+		return
+	}
+	if _, err := path.Match(resource, ""); err != nil {
+		c.Respond(400, eden.Response{"ERROR", "Invalid resource pattern"})
+		return
+	}
+
+	role, ok := a.managedRole(c, "AddRolePermission (POST /roles/:guid/permissions)")
+	if !ok {
+		return
+	}
+
+	// Everyone the role is bound to gains the permission
+	extra := []accessors.Permission{accessors.Permission{"", verb, resource, ""}}
+	users := func() ([]string, error) {
+		bindings, err := ra.GetBindings(role.Guid)
+		if err != nil {
+			return nil, err
+		}
+		netIds := make([]string, 0)
+		for _, actor := range bindings {
+			more, err := accessors.NewSodAccessor(a.DB).Grantees(actor, role.Area)
+			if err != nil {
+				return nil, err
+			}
+			netIds = append(netIds, more...)
+		}
+		return netIds, nil
+	}
+	if !a.enforceSod(c, role.Area, users, extra) {
+		return
+	}
+
+	// A pattern covering a sensitive resource grants it to everyone the
+	// role is bound to, so it needs a second approver
+	sensitive, err := accessors.NewApprovalAccessor(a.DB).CoversSensitive(resource)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on CoversSensitive in AddRolePermission (POST /roles/:guid/permissions): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if sensitive {
+		a.requestApproval(c, accessors.Approval{Type: accessors.ApprovalRolePermission, Area: role.Area, Role: role.Guid, Verb: verb, Resource: resource})
+		return
+	}
+
+	if err := ra.AddPermission(role.Guid, verb, resource); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on AddPermission in AddRolePermission (POST /roles/:guid/permissions): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Added %s on %s to role %s", verb, resource, role.Guid), true, ra.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Remove a verb/resource pair from a role.
+// DELETE /roles/:guid/permissions/:verb?resource=:resource
+func (a *Api) RemoveRolePermission(c *eden.Context) {
+	ra := accessors.NewRoleAccessor(a.DB)
+
+	// Patterns hold slashes, so the resource comes from the query
+	verb := c.Params[1].Value
+	c.Request.ParseForm()
+	resource := c.Request.Form.Get("resource")
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called RemoveRolePermission on %s (DELETE /roles/:guid/permissions/:verb?resource=:resource)", c.Params[0].Value), true, ra.DB)
+
+	if resource == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid resource"})
+		return
+	}
+
+	role, ok := a.managedRole(c, "RemoveRolePermission (DELETE /roles/:guid/permissions/:verb?resource=:resource)")
+	if !ok {
+		return
+	}
+
+	if err := ra.RemovePermission(role.Guid, verb, resource); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on RemovePermission in RemoveRolePermission (DELETE /roles/:guid/permissions/:verb?resource=:resource): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Removed %s on %s from role %s", verb, resource, role.Guid), true, ra.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Bind a role to the area itself or one of its groups.
+// POST /roles/:guid/bindings actor=:actor
+func (a *Api) BindRole(c *eden.Context) {
+	ra := accessors.NewRoleAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called BindRole on %s (POST /roles/:guid/bindings actor=:actor)", c.Params[0].Value), true, ra.DB)
+
+	c.Request.ParseForm()
+	actor := c.Request.Form.Get("actor")
+	if actor == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid actor"})
+		return
+	}
+
+	role, ok := a.managedRole(c, "BindRole (POST /roles/:guid/bindings)")
+	if !ok {
+		return
+	}
+
+	// A role only applies in its own area, so it can only be bound there
+	if actor != role.Area {
+		group, err := accessors.NewGroupAccessor(a.DB).Get(actor)
+		if err != nil && err != sql.ErrNoRows {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in BindRole (POST /roles/:guid/bindings): %v", err), true, ra.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		if err == sql.ErrNoRows || group.Area != role.Area {
+			c.Respond(400, eden.Response{"ERROR", "Roles can only be bound to their area or its groups"})
+			return
+		}
+	}
+
+	perms, err := ra.GetPermissions(role.Guid)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetPermissions in BindRole (POST /roles/:guid/bindings): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	users := func() ([]string, error) {
+		return accessors.NewSodAccessor(a.DB).Grantees(actor, role.Area)
+	}
+	if !a.enforceSod(c, role.Area, users, perms) {
+		return
+	}
+
+	// Binding a role that covers a sensitive resource grants it, so it
+	// needs a second approver
+	aa := accessors.NewApprovalAccessor(a.DB)
+	for _, p := range perms {
+		sensitive, err := aa.CoversSensitive(p.Resource)
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on CoversSensitive in BindRole (POST /roles/:guid/bindings): %v", err), true, ra.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		if sensitive {
+			a.requestApproval(c, accessors.Approval{Type: accessors.ApprovalRoleBinding, Area: role.Area, Role: role.Guid, Actor: actor})
+			return
+		}
+	}
+
+	if err := ra.Bind(role.Guid, actor); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Bind in BindRole (POST /roles/:guid/bindings): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Bound role %s to %s", role.Guid, actor), true, ra.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// Remove a role from a group, user or area.
+// DELETE /roles/:guid/bindings/:actor
+func (a *Api) UnbindRole(c *eden.Context) {
+	ra := accessors.NewRoleAccessor(a.DB)
+
+	actor := c.Params[1].Value
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called UnbindRole on %s (DELETE /roles/:guid/bindings/:actor)", c.Params[0].Value), true, ra.DB)
+
+	role, ok := a.managedRole(c, "UnbindRole (DELETE /roles/:guid/bindings/:actor)")
+	if !ok {
+		return
+	}
+
+	if err := ra.Unbind(role.Guid, actor); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Unbind in UnbindRole (DELETE /roles/:guid/bindings/:actor): %v", err), true, ra.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Unbound role %s from %s", role.Guid, actor), true, ra.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
@@ -0,0 +1,239 @@
+package apis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	testhelpers "github.com/byu-oit-ssengineering/tmt-test-helpers"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestBindRole(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, area, name FROM role WHERE guid=.").
+		WithArgs("r1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name"}).FromCSVString("r1,area,editor"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("guid").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString("1"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
+		WithArgs("g1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("g1,area,Night Shift,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleGuid, verb, resource FROM rolePermission WHERE roleGuid=.").
+		WithArgs("r1").
+		WillReturnRows(sqlmock.NewRows([]string{"roleGuid", "verb", "resource"}).FromCSVString("r1,edit,report-*"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM sodRule WHERE area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "verbA", "verbB", "mode"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT resource FROM sensitiveResource").
+		WillReturnRows(sqlmock.NewRows([]string{"resource"}).FromCSVString("payroll"))
+
+	sqlmock.ExpectBegin()
+	sqlmock.ExpectExec("INSERT INTO roleBinding .+ VALUES .+").
+		WithArgs("r1", "g1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectExec("INSERT INTO event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlmock.ExpectCommit()
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("actor=g1", httprouter.Params{httprouter.Param{Key: "guid", Value: "r1"}}, api.BindRole)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.BindRole, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Data != "success" {
+		t.Errorf("Expected: %v, but got %v", "success", output)
+	}
+}
+
+func TestBindSensitiveRole(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	accessors.NewGuid = func() string {
+		return "req1"
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, area, name FROM role WHERE guid=.").
+		WithArgs("r1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name"}).FromCSVString("r1,area,editor"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("guid").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString("1"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
+		WithArgs("g1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("g1,area,Night Shift,,,0,"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT roleGuid, verb, resource FROM rolePermission WHERE roleGuid=.").
+		WithArgs("r1").
+		WillReturnRows(sqlmock.NewRows([]string{"roleGuid", "verb", "resource"}).FromCSVString("r1,edit,report-*"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM sodRule WHERE area=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "verbA", "verbB", "mode"}).FromCSVString(""))
+
+	// report-* covers a sensitive report, so the binding waits for approval
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT resource FROM sensitiveResource").
+		WillReturnRows(sqlmock.NewRows([]string{"resource"}).FromCSVString("report-salaries"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectExec("INSERT INTO approval .+ VALUES .+").
+		WithArgs("req1", "role.binding", "guid", "area", "", "r1", "g1", "", "", "", "pending", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("actor=g1", httprouter.Params{httprouter.Param{Key: "guid", Value: "r1"}}, api.BindRole)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.BindRole, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "PENDING" || output.Data != "req1" {
+		t.Errorf("Expected: %v, but got %v", "PENDING", output)
+	}
+}
+
+func TestBindRoleOtherArea(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, area, name FROM role WHERE guid=.").
+		WithArgs("r1").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name"}).FromCSVString("r1,area,editor"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("guid").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString("1"))
+
+	// g2 belongs to another area, where the role never applies
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT .+ FROM groups WHERE guid=(.)").
+		WithArgs("g2").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "area", "name", "description", "tags", "created", "createdBy"}).FromCSVString("g2,other,Day Shift,,,0,"))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("actor=g2", httprouter.Params{httprouter.Param{Key: "guid", Value: "r1"}}, api.BindRole)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.BindRole, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
+
+func TestCreateRoleWithoutAdmin(t *testing.T) {
+	accessors.Log = func(t, a, d string, logToStdErr bool, db *sql.DB) (bool, error) {
+		return true, nil
+	}
+	db, err := testhelpers.GetMockDB()
+	if err != nil {
+		t.Error("An unexpected error occurred instantiating accessor")
+		return
+	}
+	api := &Api{db}
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT active FROM superuser WHERE netId=.").
+		WithArgs("guid").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).FromCSVString(""))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT guid, name, description, parent, inherit FROM areas WHERE guid=.").
+		WithArgs("area").
+		WillReturnRows(sqlmock.NewRows([]string{"guid", "name", "description", "parent", "inherit"}).FromCSVString("area,Area,,,0"))
+
+	sqlmock.ExpectPrepare()
+	sqlmock.ExpectQuery("SELECT . FROM admin WHERE netId=. AND area IN .+").
+		WithArgs("guid", "area").
+		WillReturnRows(sqlmock.NewRows([]string{"netId", "area"}).FromCSVString(""))
+
+	// Create context, call API
+	var result []byte
+	var output eden.Response
+	c := testhelpers.NewTestingContext("area=area&name=editor", nil, api.CreateRole)
+	c.User = eden.User{"guid", "area"}
+	testhelpers.CallAPI(api.CreateRole, c, &result)
+
+	// Parse output
+	err = json.Unmarshal(result, &output)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Compare output to expected output
+	if output.Status != "ERROR" {
+		t.Errorf("Expected: %v, but got %v", "ERROR", output)
+	}
+}
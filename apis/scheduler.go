@@ -0,0 +1,37 @@
+package apis
+
+import (
+	"fmt"
+	"time"
+
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// Current time, replaceable in tests.
+var timeNow = time.Now
+
+// Run the periodic maintenance tasks every interval. Never returns, so
+// call it in its own goroutine.
+func (a *Api) RunScheduledTasks(interval time.Duration) {
+	for range time.Tick(interval) {
+		a.runScheduledTasks()
+	}
+}
+
+func (a *Api) runScheduledTasks() {
+	if err := a.expireAccessRequests(); err != nil {
+		accessors.Log("error", "system", fmt.Sprintf("Error on expireAccessRequests in runScheduledTasks: %v", err), true, a.DB)
+	}
+	if err := a.closeDueReviews(); err != nil {
+		accessors.Log("error", "system", fmt.Sprintf("Error on closeDueReviews in runScheduledTasks: %v", err), true, a.DB)
+	}
+	if err := a.refreshGroupRules(); err != nil {
+		accessors.Log("error", "system", fmt.Sprintf("Error on refreshGroupRules in runScheduledTasks: %v", err), true, a.DB)
+	}
+	if err := a.expireIdempotencyKeys(); err != nil {
+		accessors.Log("error", "system", fmt.Sprintf("Error on expireIdempotencyKeys in runScheduledTasks: %v", err), true, a.DB)
+	}
+	if err := a.pruneEvents(); err != nil {
+		accessors.Log("error", "system", fmt.Sprintf("Error on pruneEvents in runScheduledTasks: %v", err), true, a.DB)
+	}
+}
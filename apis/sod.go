@@ -0,0 +1,212 @@
+package apis
+
+import (
+	"database/sql"
+	"fmt"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// Check a grant against the area's separation of duties rules. Responds
+// and returns false if the grant must not go ahead; warn-only violations
+// are audited and reported in Warning headers.
+func (a *Api) enforceSod(c *eden.Context, area string, users func() ([]string, error), extra []accessors.Permission) bool {
+	violations, err := accessors.NewSodAccessor(a.DB).Violations(area, users, extra)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Violations in enforceSod: %v", err), true, a.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return false
+	}
+
+	blocking := make([]accessors.SodViolation, 0)
+	for _, v := range violations {
+		if v.Rule.Mode == accessors.SodWarn {
+			accessors.Log("audit", c.User.NetId, fmt.Sprintf("Separation of duties warning: %s would hold %s and %s on %s", v.NetId, v.Rule.VerbA, v.Rule.VerbB, v.Resource), true, a.DB)
+			c.Response.Header().Add("Warning", fmt.Sprintf("299 tmt-permissions \"separation of duties: %s would hold %s and %s on %s\"", v.NetId, v.Rule.VerbA, v.Rule.VerbB, v.Resource))
+		} else {
+			blocking = append(blocking, v)
+		}
+	}
+
+	if len(blocking) > 0 {
+		c.Respond(409, eden.Response{"FAILURE", blocking})
+		return false
+	}
+
+	return true
+}
+
+// Get the separation of duties rules for an area.
+// GET /sod?area=:areaGuid
+func (a *Api) GetSodRules(c *eden.Context) {
+	sa := accessors.NewSodAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	c.Request.ParseForm()
+	area, areaOk := c.Request.Form["area"]
+	if !areaOk || area[0] == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in GetSodRules (GET /sod?area=:areaGuid): %v", err), true, sa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to see separation of duties rules"})
+		return
+	}
+
+	rules, err := sa.GetByArea(area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetByArea in GetSodRules (GET /sod?area=:areaGuid): %v", err), true, sa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	c.Respond(200, eden.Response{"OK", rules})
+}
+
+// Create a separation of duties rule.
+// POST /sod area=:areaGuid, verbA=:verb, verbB=:verb, mode=block|warn
+func (a *Api) AddSodRule(c *eden.Context) {
+	sa := accessors.NewSodAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	accessors.Log("notice", c.User.NetId, "Called AddSodRule (POST /sod area=:areaGuid, verbA=:verb, verbB=:verb, mode=block|warn)", true, sa.DB)
+
+	c.Request.ParseForm()
+	rule := accessors.SodRule{
+		Area:  c.Request.Form.Get("area"),
+		VerbA: c.Request.Form.Get("verbA"),
+		VerbB: c.Request.Form.Get("verbB"),
+		Mode:  c.Request.Form.Get("mode"),
+	}
+	if rule.Mode == "" {
+		rule.Mode = accessors.SodBlock
+	}
+	if rule.Area == "" || rule.VerbA == "" || rule.VerbB == "" || rule.VerbA == rule.VerbB || (rule.Mode != accessors.SodBlock && rule.Mode != accessors.SodWarn) {
+		c.Respond(400, eden.Response{"ERROR", "Invalid input"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, rule.Area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in AddSodRule (POST /sod): %v", err), true, sa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to manage separation of duties rules"})
+		return
+	}
+
+	guid, err := sa.Add(rule)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Add in AddSodRule (POST /sod): %v", err), true, sa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Added separation of duties rule %s in %s: %s/%s (%s)", guid, rule.Area, rule.VerbA, rule.VerbB, rule.Mode), true, sa.DB)
+	c.Respond(200, eden.Response{"OK", guid})
+}
+
+// Delete a separation of duties rule.
+// DELETE /sod/:guid
+func (a *Api) DeleteSodRule(c *eden.Context) {
+	sa := accessors.NewSodAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+
+	guid := c.Params[0].Value
+
+	accessors.Log("notice", c.User.NetId, fmt.Sprintf("Called DeleteSodRule on %s (DELETE /sod/:guid)", guid), true, sa.DB)
+
+	rule, err := sa.Get(guid)
+	if err == sql.ErrNoRows {
+		c.Respond(404, eden.Response{"ERROR", "No such rule"})
+		return
+	}
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Get in DeleteSodRule (DELETE /sod/:guid): %v", err), true, sa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, rule.Area)
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in DeleteSodRule (DELETE /sod/:guid): %v", err), true, sa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to manage separation of duties rules"})
+		return
+	}
+
+	if err := sa.Delete(guid); err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on Delete in DeleteSodRule (DELETE /sod/:guid): %v", err), true, sa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	accessors.Log("audit", c.User.NetId, fmt.Sprintf("Deleted separation of duties rule %s", guid), true, sa.DB)
+	c.Respond(200, eden.Response{"OK", "success"})
+}
+
+// List every existing violation of an area's rules.
+// GET /sod/violations?area=:areaGuid
+func (a *Api) GetSodViolations(c *eden.Context) {
+	sa := accessors.NewSodAccessor(a.DB)
+	pa := accessors.NewPermissionAccessor(a.DB)
+	ma := accessors.NewMembersAccessor(a.DB)
+
+	c.Request.ParseForm()
+	area, areaOk := c.Request.Form["area"]
+	if !areaOk || area[0] == "" {
+		c.Respond(400, eden.Response{"ERROR", "Invalid area"})
+		return
+	}
+
+	allowed, err := isSUOrAdmin(pa, c.User.NetId, area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on isSUOrAdmin in GetSodViolations (GET /sod/violations?area=:areaGuid): %v", err), true, sa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+	if !allowed {
+		c.Respond(403, eden.Response{"ERROR", "You need to be an admin to see separation of duties violations"})
+		return
+	}
+
+	rules, err := sa.GetByArea(area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetByArea in GetSodViolations (GET /sod/violations?area=:areaGuid): %v", err), true, sa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	members, err := ma.GetAreaMembers(area[0])
+	if err != nil {
+		accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetAreaMembers in GetSodViolations (GET /sod/violations?area=:areaGuid): %v", err), true, sa.DB)
+		c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+		return
+	}
+
+	violations := make([]accessors.SodViolation, 0)
+	for _, netId := range members {
+		perms, err := pa.GetUserPermissions(netId, area[0])
+		if err != nil {
+			accessors.Log("error", c.User.NetId, fmt.Sprintf("Error on GetUserPermissions in GetSodViolations (GET /sod/violations?area=:areaGuid): %v", err), true, sa.DB)
+			c.Respond(500, eden.Response{"ERROR", "An error has occurred"})
+			return
+		}
+		violations = append(violations, accessors.CheckSod(rules, netId, perms)...)
+	}
+
+	c.Respond(200, eden.Response{"OK", violations})
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Gets the admins of an area.
+// GET /admin?area=:areaGuid
+func (c *Client) Admins(ctx context.Context, area string) ([]string, error) {
+	admins := make([]string, 0)
+	err := c.get(ctx, "/admin", url.Values{"area": {area}}, &admins)
+	return admins, err
+}
+
+// Gets the admins of an area and of the areas above it.
+// GET /admin?area=:areaGuid&inherited=true
+func (c *Client) InheritedAdmins(ctx context.Context, area string) ([]AreaAdmin, error) {
+	admins := make([]AreaAdmin, 0)
+	err := c.get(ctx, "/admin", url.Values{"area": {area}, "inherited": {"true"}}, &admins)
+	return admins, err
+}
+
+// Tells whether a user is an admin of an area.
+// GET /admin/:netId/:areaGuid
+func (c *Client) IsAdmin(ctx context.Context, netId, area string) (bool, error) {
+	var admin bool
+	err := c.get(ctx, path("/admin", netId, area), nil, &admin)
+	return admin, err
+}
+
+// Requests admin rights in an area for a user. They take effect once
+// another admin approves them.
+// POST /admin area=:areaGuid, netId=:netId
+func (c *Client) AddAdmin(ctx context.Context, netId, area string) (WriteResult, error) {
+	return c.write(ctx, "/admin", url.Values{"area": {area}, "netId": {netId}})
+}
+
+// Revokes a user's admin rights in an area.
+// DELETE /admin/:netId/:areaGuid
+func (c *Client) RemoveAdmin(ctx context.Context, netId, area string) error {
+	return c.delete(ctx, path("/admin", netId, area), nil)
+}
+
+// Gets every superuser.
+// GET /superuser
+func (c *Client) Superusers(ctx context.Context) ([]string, error) {
+	superusers := make([]string, 0)
+	err := c.get(ctx, "/superuser", nil, &superusers)
+	return superusers, err
+}
+
+// Tells whether a user has elevated to superuser rights.
+// GET /superuser/is/:netId
+func (c *Client) IsSuperuser(ctx context.Context, netId string) (bool, error) {
+	var su bool
+	err := c.get(ctx, path("/superuser/is", netId), nil, &su)
+	return su, err
+}
+
+// Tells whether a user may elevate to superuser rights.
+// GET /superuser/can/:netId
+func (c *Client) CanSuperuser(ctx context.Context, netId string) (bool, error) {
+	var su bool
+	err := c.get(ctx, path("/superuser/can", netId), nil, &su)
+	return su, err
+}
+
+// Requests superuser rights for a user. They take effect once another
+// superuser approves them.
+// POST /superuser netId=:netId
+func (c *Client) AddSuperuser(ctx context.Context, netId string) (WriteResult, error) {
+	return c.write(ctx, "/superuser", url.Values{"netId": {netId}})
+}
+
+// Elevates a superuser to their superuser rights, or drops back out of
+// them.
+// PUT /superuser/:netId elevate=:bool
+func (c *Client) Elevate(ctx context.Context, netId string, elevate bool) error {
+	return c.put(ctx, path("/superuser", netId), url.Values{"elevate": {strconv.FormatBool(elevate)}}, nil)
+}
+
+// Revokes a user's superuser rights.
+// DELETE /superuser/:netId
+func (c *Client) RemoveSuperuser(ctx context.Context, netId string) error {
+	return c.delete(ctx, path("/superuser", netId), nil)
+}
+
+// Gets the grants waiting on approval in an area.
+// GET /approvals?area=:areaGuid
+func (c *Client) PendingApprovals(ctx context.Context, area string) ([]Approval, error) {
+	approvals := make([]Approval, 0)
+	err := c.get(ctx, "/approvals", url.Values{"area": {area}}, &approvals)
+	return approvals, err
+}
+
+// Approves a pending grant, which then takes effect.
+// POST /approvals/:guid/approve
+func (c *Client) Approve(ctx context.Context, guid string) error {
+	return c.post(ctx, path("/approvals", guid, "approve"), url.Values{}, nil)
+}
+
+// Rejects a pending grant.
+// POST /approvals/:guid/reject
+func (c *Client) Reject(ctx context.Context, guid string) error {
+	return c.post(ctx, path("/approvals", guid, "reject"), url.Values{}, nil)
+}
+
+// Marks a resource as sensitive, so that grants on it need a second
+// person's approval.
+// POST /sensitive resource=:resourceGuid
+func (c *Client) MarkSensitive(ctx context.Context, resource string) error {
+	return c.post(ctx, "/sensitive", url.Values{"resource": {resource}}, nil)
+}
+
+// DELETE /sensitive?resource=:resourceGuid
+func (c *Client) UnmarkSensitive(ctx context.Context, resource string) error {
+	return c.delete(ctx, "/sensitive", url.Values{"resource": {resource}})
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// GET /areas
+func (c *Client) Areas(ctx context.Context) ([]Area, error) {
+	areas := make([]Area, 0)
+	err := c.get(ctx, "/areas", nil, &areas)
+	return areas, err
+}
+
+// GET /areas/:area
+func (c *Client) Area(ctx context.Context, guid string) (Area, error) {
+	var area Area
+	err := c.get(ctx, path("/areas", guid), nil, &area)
+	return area, err
+}
+
+func areaValues(area Area) url.Values {
+	return url.Values{"name": {area.Name}, "description": {area.Description}, "parent": {area.Parent}, "inherit": {strconv.FormatBool(area.Inherit)}}
+}
+
+// Creates an area from its Name, Description, Parent and Inherit and
+// returns its guid.
+// POST /areas name=:name, description=:description, parent=:areaGuid, inherit=:bool
+func (c *Client) CreateArea(ctx context.Context, area Area) (string, error) {
+	var guid string
+	err := c.post(ctx, "/areas", areaValues(area), &guid)
+	return guid, err
+}
+
+// Sets an area's name, description, parent and inheritance.
+// PUT /areas/:area name=:name, description=:description, parent=:areaGuid, inherit=:bool
+func (c *Client) UpdateArea(ctx context.Context, area Area) (Area, error) {
+	var updated Area
+	err := c.put(ctx, path("/areas", area.Guid), areaValues(area), &updated)
+	return updated, err
+}
+
+// Removes an area and everything that belongs to it. Returns the number of
+// rows affected in each table.
+// DELETE /areas/:area
+func (c *Client) DecommissionArea(ctx context.Context, guid string) (map[string]int64, error) {
+	affected := make(map[string]int64)
+	_, err := c.do(ctx, call{method: "DELETE", path: path("/areas", guid), idempotent: true}, &affected)
+	return affected, err
+}
+
+// Gets an area's configuration.
+// GET /areas/:area/export?format=json
+func (c *Client) ExportArea(ctx context.Context, guid string) (AreaConfig, error) {
+	var config AreaConfig
+	var document []byte
+	_, err := c.do(ctx, call{method: "GET", path: path("/areas", guid, "export"), query: url.Values{"format": {"json"}}, idempotent: true, raw: true}, &document)
+	if err != nil {
+		return config, err
+	}
+
+	err = json.Unmarshal(document, &config)
+	return config, err
+}
+
+// The query import, plan and apply read their options from.
+func configQuery(opts ImportOptions) url.Values {
+	query := url.Values{"mode": {"merge"}}
+	if opts.Replace {
+		query.Set("mode", "replace")
+	}
+	for from, to := range opts.Remap {
+		query.Add("remap", from+":"+to)
+	}
+	return query
+}
+
+// Sends a configuration to one of an area's configuration routes.
+func (c *Client) sendConfig(ctx context.Context, guid, action string, query url.Values, config AreaConfig, out interface{}) error {
+	b, err := jsonBody(config)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(ctx, call{method: "POST", path: path("/areas", guid, action), query: query, body: b}, out)
+	return err
+}
+
+// Imports a configuration into an area. With dryRun the changes are worked
+// out but not made.
+// POST /areas/:area/import?mode=merge|replace&dryRun=:bool&remap=:from:to
+func (c *Client) ImportArea(ctx context.Context, guid string, config AreaConfig, opts ImportOptions, dryRun bool) (ImportResult, error) {
+	query := configQuery(opts)
+	query.Set("dryRun", strconv.FormatBool(dryRun))
+
+	var result ImportResult
+	err := c.sendConfig(ctx, guid, "import", query, config, &result)
+	return result, err
+}
+
+// Plans applying a configuration to an area without changing anything.
+// POST /areas/:area/plan?mode=merge|replace&remap=:from:to
+func (c *Client) PlanArea(ctx context.Context, guid string, config AreaConfig, opts ImportOptions) (Plan, error) {
+	var plan Plan
+	err := c.sendConfig(ctx, guid, "plan", configQuery(opts), config, &plan)
+	return plan, err
+}
+
+// Applies a configuration to an area. revision names the version of the
+// configuration for the audit log. A fingerprint from PlanArea makes sure
+// nothing changed since the plan; leave it empty to apply regardless.
+// POST /areas/:area/apply?revision=:revision&plan=:fingerprint&mode=merge|replace&remap=:from:to
+func (c *Client) ApplyArea(ctx context.Context, guid string, config AreaConfig, opts ImportOptions, revision, fingerprint string) (Plan, error) {
+	query := configQuery(opts)
+	query.Set("revision", revision)
+	if fingerprint != "" {
+		query.Set("plan", fingerprint)
+	}
+
+	var plan Plan
+	err := c.sendConfig(ctx, guid, "apply", query, config, &plan)
+	return plan, err
+}
@@ -0,0 +1,310 @@
+// Package client calls the tmt-permissions service from Go. Each route
+// has a method that sends the request, unwraps the {Status, Data} envelope
+// the service responds with and decodes the data into a typed result.
+//
+// Requests are bounded by the caller's context and by Timeout. Reads,
+// and writes that are safe to repeat, are retried when the service can't
+// be reached or responds that it is unavailable.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Supplies the Authorization header sent with each request, for example a
+// token that is refreshed as it expires.
+type AuthProvider interface {
+	Authorization(ctx context.Context) (string, error)
+}
+
+// Adapts a function to an AuthProvider.
+type AuthFunc func(ctx context.Context) (string, error)
+
+func (f AuthFunc) Authorization(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// An AuthProvider that always sends the same header value.
+func StaticAuth(value string) AuthProvider {
+	return AuthFunc(func(context.Context) (string, error) { return value, nil })
+}
+
+// A tmt-permissions client. The fields may be changed before the client is
+// first used.
+type Client struct {
+	BaseURL string       // such as https://permissions.example.com
+	Auth    AuthProvider // nil sends no Authorization header
+	HTTP    *http.Client
+
+	Timeout time.Duration // for each attempt; 0 leaves it to the context
+	Retries int           // attempts made after the first fails transiently
+	Backoff time.Duration // wait before the first retry, doubled for each one after
+}
+
+// Returns a new client for the service at baseURL.
+func New(baseURL string, auth AuthProvider) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Auth:    auth,
+		HTTP:    &http.Client{},
+		Timeout: 10 * time.Second,
+		Retries: 2,
+		Backoff: 200 * time.Millisecond,
+	}
+}
+
+// A response other than 2xx. Status is the envelope's status, such as
+// ERROR or FAILURE, and Data what the service sent with it: a message,
+// field errors or separation of duties violations.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Status     string
+	Data       json.RawMessage
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, e.Status, e.Message())
+}
+
+// The error's data as text, unquoted if the service sent a string.
+func (e *Error) Message() string {
+	var s string
+	if json.Unmarshal(e.Data, &s) == nil {
+		return s
+	}
+	return string(e.Data)
+}
+
+// Tells whether err is a response with the given status code, such as
+// 404 for something that doesn't exist.
+func IsStatus(err error, code int) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == code
+}
+
+// A request body and its content type.
+type body struct {
+	contentType string
+	data        []byte
+}
+
+func form(values url.Values) *body {
+	return &body{"application/x-www-form-urlencoded", []byte(values.Encode())}
+}
+
+func jsonBody(v interface{}) (*body, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &body{"application/json", b}, nil
+}
+
+// A request to send. Idempotent requests may be retried. Raw responses
+// aren't wrapped in an envelope and are returned as they are. Wait is how
+// long the service may hold the request, on top of Timeout.
+type call struct {
+	method     string
+	path       string
+	query      url.Values
+	body       *body
+	header     http.Header
+	idempotent bool
+	raw        bool
+	wait       time.Duration
+}
+
+// The response envelope.
+type envelope struct {
+	Status string
+	Data   json.RawMessage
+}
+
+// Joins segments into a path, escaping each one after the first.
+func path(segments ...string) string {
+	for i := 1; i < len(segments); i++ {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+// Send a request, retrying transient failures, and decode the response's
+// data into out, or for raw calls store the body in out, a *[]byte.
+// Returns the envelope's status. A retried DELETE that gets a 404 counts
+// as done, since the attempt whose response was lost may have deleted it;
+// out is left as it was.
+func (c *Client) do(ctx context.Context, r call, out interface{}) (string, error) {
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		data, err := c.send(ctx, r)
+		if err == nil {
+			if r.raw {
+				*out.(*[]byte) = data
+				return "", nil
+			}
+
+			var env envelope
+			if err := json.Unmarshal(data, &env); err != nil {
+				return "", fmt.Errorf("%s %s: unexpected response: %v", r.method, r.path, err)
+			}
+			if out != nil {
+				if err := json.Unmarshal(env.Data, out); err != nil {
+					return env.Status, fmt.Errorf("%s %s: unexpected response: %v", r.method, r.path, err)
+				}
+			}
+			return env.Status, nil
+		}
+
+		if attempt > 0 && r.method == "DELETE" && IsStatus(err, http.StatusNotFound) {
+			return "", nil
+		}
+		if attempt >= c.Retries || !r.idempotent || !transient(ctx, err) {
+			return "", err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// Tells whether a failed attempt may succeed if it's tried again: the
+// service couldn't be reached or said it's unavailable.
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch e := err.(type) {
+	case *Error:
+		switch e.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	case *url.Error:
+		return true
+	}
+	return false
+}
+
+// Make one attempt and return the body of a 2xx response.
+func (c *Client) send(ctx context.Context, r call) ([]byte, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout+r.wait)
+		defer cancel()
+	}
+
+	u := c.BaseURL + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	var reader io.Reader
+	if r.body != nil {
+		reader = bytes.NewReader(r.body.data)
+	}
+	req, err := http.NewRequest(r.method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	if r.body != nil {
+		req.Header.Set("Content-Type", r.body.contentType)
+	}
+	if c.Auth != nil {
+		auth, err := c.Auth.Authorization(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return data, nil
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		env.Data, _ = json.Marshal(strings.TrimSpace(string(data)))
+	}
+	return nil, &Error{r.method, r.path, resp.StatusCode, env.Status, env.Data}
+}
+
+// A random Idempotency-Key, so that a create can be retried without
+// creating twice.
+func idempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Shorthands for the requests most routes make.
+
+func (c *Client) get(ctx context.Context, p string, query url.Values, out interface{}) error {
+	_, err := c.do(ctx, call{method: "GET", path: p, query: query, idempotent: true}, out)
+	return err
+}
+
+func (c *Client) post(ctx context.Context, p string, values url.Values, out interface{}) error {
+	_, err := c.do(ctx, call{method: "POST", path: p, body: form(values)}, out)
+	return err
+}
+
+func (c *Client) put(ctx context.Context, p string, values url.Values, out interface{}) error {
+	_, err := c.do(ctx, call{method: "PUT", path: p, body: form(values), idempotent: true}, out)
+	return err
+}
+
+func (c *Client) delete(ctx context.Context, p string, query url.Values) error {
+	_, err := c.do(ctx, call{method: "DELETE", path: p, query: query, idempotent: true}, nil)
+	return err
+}
+
+// Send a grant that may already be in place or be held for approval.
+func (c *Client) write(ctx context.Context, p string, values url.Values) (WriteResult, error) {
+	var data json.RawMessage
+	status, err := c.do(ctx, call{method: "POST", path: p, body: form(values)}, &data)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	return writeResult(status, data), nil
+}
+
+func writeResult(status string, data json.RawMessage) WriteResult {
+	if status == "PENDING" {
+		var guid string
+		json.Unmarshal(data, &guid)
+		return WriteResult{Approval: guid}
+	}
+
+	result := WriteResult{Created: true}
+	json.Unmarshal(data, &result)
+	return result
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A server that answers every request with handler, counting them. The
+// handler is passed the number of the request, starting from 1.
+func testServer(handler func(w http.ResponseWriter, r *http.Request, n int)) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		handler(w, r, calls)
+	}))
+	return server, &calls
+}
+
+func respond(w http.ResponseWriter, code int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	fmt.Fprint(w, body)
+}
+
+func TestCheck(t *testing.T) {
+	server, _ := testServer(func(w http.ResponseWriter, r *http.Request, n int) {
+		query := r.URL.Query()
+		if r.URL.Path != "/permission" || query.Get("areaGuid") != "area" || query.Get("employeeGuid") != "alice" || query.Get("region") != "west" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Expected the Authorization header but got %q", r.Header.Get("Authorization"))
+		}
+		respond(w, 200, `{"Status":"OK","Data":true}`)
+	})
+	defer server.Close()
+
+	c := New(server.URL, StaticAuth("Bearer token"))
+	allowed, err := c.Check(context.Background(), "alice", "area", "view", "res", map[string]string{"region": "west"})
+	if err != nil || !allowed {
+		t.Errorf("Expected the permission to be allowed but got %v, %v", allowed, err)
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	server, _ := testServer(func(w http.ResponseWriter, r *http.Request, n int) {
+		if r.URL.EscapedPath() != "/groupMembers/a%2Fb" {
+			t.Errorf("Expected the guid to be escaped but got %s", r.URL.EscapedPath())
+		}
+		respond(w, 200, `{"Status":"OK","Data":["alice","bob"]}`)
+	})
+	defer server.Close()
+
+	members, err := New(server.URL, nil).GroupMembers(context.Background(), "a/b")
+	if err != nil || !reflect.DeepEqual(members, []string{"alice", "bob"}) {
+		t.Errorf("Expected alice and bob but got %v, %v", members, err)
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+	responses := []string{`{"Status":"OK","Data":{"Created":false}}`, `{"Status":"PENDING","Data":"approval1"}`}
+	server, _ := testServer(func(w http.ResponseWriter, r *http.Request, n int) {
+		if r.Method != "POST" || r.FormValue("actor") != "desk" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		if n == 1 {
+			respond(w, 200, responses[0])
+		} else {
+			respond(w, 202, responses[1])
+		}
+	})
+	defer server.Close()
+
+	c := New(server.URL, nil)
+	if result, err := c.Grant(context.Background(), "desk", "edit", "res", ""); err != nil || result != (WriteResult{}) {
+		t.Errorf("Expected the grant to be unchanged but got %v, %v", result, err)
+	}
+	if result, err := c.Grant(context.Background(), "desk", "edit", "res", ""); err != nil || result != (WriteResult{Approval: "approval1"}) {
+		t.Errorf("Expected the grant to be pending but got %v, %v", result, err)
+	}
+}
+
+func TestError(t *testing.T) {
+	server, _ := testServer(func(w http.ResponseWriter, r *http.Request, n int) {
+		respond(w, 403, `{"Status":"FAILURE","Data":"You need to have this permission in order to grant it"}`)
+	})
+	defer server.Close()
+
+	_, err := New(server.URL, nil).Grant(context.Background(), "desk", "edit", "res", "")
+	if !IsStatus(err, 403) {
+		t.Fatalf("Expected a 403 but got %v", err)
+	}
+	if e := err.(*Error); e.Status != "FAILURE" || e.Message() != "You need to have this permission in order to grant it" {
+		t.Errorf("Expected the service's message but got %v", e)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	server, calls := testServer(func(w http.ResponseWriter, r *http.Request, n int) {
+		if n < 3 {
+			respond(w, 503, `{"Status":"ERROR","Data":"unavailable"}`)
+			return
+		}
+		respond(w, 200, `{"Status":"OK","Data":["alice"]}`)
+	})
+	defer server.Close()
+
+	c := New(server.URL, nil)
+	c.Backoff = time.Millisecond
+	superusers, err := c.Superusers(context.Background())
+	if err != nil || *calls != 3 || !reflect.DeepEqual(superusers, []string{"alice"}) {
+		t.Errorf("Expected to succeed on the third attempt but got %v, %v after %d", superusers, err, *calls)
+	}
+
+	// Grants aren't safe to repeat
+	*calls = 0
+	if _, err := c.Grant(context.Background(), "desk", "edit", "res", ""); !IsStatus(err, 503) || *calls != 1 {
+		t.Errorf("Expected a grant not to be retried but got %v after %d", err, *calls)
+	}
+}
+
+func TestRetriedDelete(t *testing.T) {
+	// The first delete goes through but its response is lost
+	server, calls := testServer(func(w http.ResponseWriter, r *http.Request, n int) {
+		if n == 1 {
+			respond(w, 502, `{"Status":"ERROR","Data":"bad gateway"}`)
+			return
+		}
+		respond(w, 404, `{"Status":"ERROR","Data":"No such permission"}`)
+	})
+	defer server.Close()
+
+	c := New(server.URL, nil)
+	c.Backoff = time.Millisecond
+	if err := c.Revoke(context.Background(), "desk", "edit", "res"); err != nil || *calls != 2 {
+		t.Errorf("Expected the retried delete to succeed but got %v after %d", err, *calls)
+	}
+
+	// A 404 on the first attempt is still an error
+	*calls = 1
+	if err := c.Revoke(context.Background(), "desk", "edit", "res"); !IsStatus(err, 404) || *calls != 2 {
+		t.Errorf("Expected a 404 but got %v after %d", err, *calls)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	server, _ := testServer(func(w http.ResponseWriter, r *http.Request, n int) {
+		time.Sleep(50 * time.Millisecond)
+		respond(w, 200, `{"Status":"OK","Data":[]}`)
+	})
+	defer server.Close()
+
+	c := New(server.URL, nil)
+	c.Retries = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Superusers(ctx); err == nil {
+		t.Errorf("Expected the request to time out")
+	}
+}
+
+func TestEvents(t *testing.T) {
+	server, _ := testServer(func(w http.ResponseWriter, r *http.Request, n int) {
+		query := r.URL.Query()
+		if r.URL.Path != "/events" || query.Get("since") != "4" || query.Get("wait") != "1" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		// Held longer than Timeout, as a long poll is
+		time.Sleep(30 * time.Millisecond)
+		respond(w, 200, `{"Status":"OK","Data":{"Events":[{"Seq":5,"Type":"member.add","Group":"desk","NetId":"alice"}],"Next":5}}`)
+	})
+	defer server.Close()
+
+	c := New(server.URL, nil)
+	c.Timeout = 10 * time.Millisecond
+	page, err := c.Events(context.Background(), 4, 0, time.Second)
+	expected := EventPage{[]Event{{Seq: 5, Type: "member.add", Group: "desk", NetId: "alice"}}, 5}
+	if err != nil || !reflect.DeepEqual(page, expected) {
+		t.Errorf("Expected %v but got %v, %v", expected, page, err)
+	}
+}
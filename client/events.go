@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// How long Changes asks the service to wait for events.
+const changesWait = 30 * time.Second
+
+// Gets up to limit events after since, waiting up to wait for there to be
+// any. Takes a superuser.
+// GET /events?since=:seq&limit=:limit&wait=:seconds
+func (c *Client) Events(ctx context.Context, since int64, limit int, wait time.Duration) (EventPage, error) {
+	query := url.Values{}
+	query.Set("since", strconv.FormatInt(since, 10))
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if wait > 0 {
+		query.Set("wait", strconv.Itoa(int(wait/time.Second)))
+	}
+
+	page := EventPage{Events: make([]Event, 0)}
+	_, err := c.do(ctx, call{method: "GET", path: "/events", query: query, idempotent: true, wait: wait}, &page)
+	return page, err
+}
+
+// Waits a while for events after since and returns the latest one, or
+// since if there were none, so that a Client can be the Feed a pdp.PDP
+// watches.
+func (c *Client) Changes(ctx context.Context, since int64) (int64, error) {
+	page, err := c.Events(ctx, since, 1000, changesWait)
+	if err != nil {
+		return since, err
+	}
+	return page.Next, nil
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Gets a group by guid.
+// GET /groups/:guid
+func (c *Client) Group(ctx context.Context, guid string) (Group, error) {
+	var group Group
+	err := c.get(ctx, path("/groups", guid), nil, &group)
+	return group, err
+}
+
+// Searches groups by area, tag and part of their name or description.
+// Empty filters are left out; at least one is needed.
+// GET /groups?area=:area&tag=:tag&q=:query
+func (c *Client) SearchGroups(ctx context.Context, area, tag, q string) ([]Group, error) {
+	query := url.Values{}
+	for name, value := range map[string]string{"area": area, "tag": tag, "q": q} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	groups := make([]Group, 0)
+	err := c.get(ctx, "/groups", query, &groups)
+	return groups, err
+}
+
+// Gets the groups a user is in within an area, and with implied the
+// groups those imply.
+// GET /permission/groups?area=:areaGuid&netId=:netId&implied=true
+func (c *Client) UserGroups(ctx context.Context, netId, area string, implied bool) ([]Group, error) {
+	query := url.Values{"area": {area}, "netId": {netId}}
+	if implied {
+		query.Set("implied", "true")
+	}
+
+	groups := make([]Group, 0)
+	err := c.get(ctx, "/permission/groups", query, &groups)
+	return groups, err
+}
+
+// Creates a group from its Area, Name, Description and Tags and returns it
+// with its guid. The request carries an Idempotency-Key so it is safe to
+// retry. A 409 error means the area already has a group with the name.
+// POST /groups
+func (c *Client) CreateGroup(ctx context.Context, group Group) (Group, error) {
+	values := url.Values{"area": {group.Area}, "name": {group.Name}, "description": {group.Description}, "tags": {strings.Join(group.Tags, ",")}}
+	header := http.Header{"Idempotency-Key": {idempotencyKey()}}
+
+	var created Group
+	_, err := c.do(ctx, call{method: "POST", path: "/groups", body: form(values), header: header, idempotent: true}, &created)
+	return created, err
+}
+
+// Renames a group and sets its description and tags.
+// PUT /groups/:guid name=:name, description=:description, tags=:tag,:tag
+func (c *Client) UpdateGroup(ctx context.Context, group Group) (Group, error) {
+	values := url.Values{"name": {group.Name}, "description": {group.Description}, "tags": {strings.Join(group.Tags, ",")}}
+
+	var updated Group
+	err := c.put(ctx, path("/groups", group.Guid), values, &updated)
+	return updated, err
+}
+
+// DELETE /groups/:guid
+func (c *Client) DeleteGroup(ctx context.Context, guid string) error {
+	return c.delete(ctx, path("/groups", guid), nil)
+}
+
+// Moves a group to another area, along with its members and grants.
+// POST /groups/:guid/move area=:areaGuid
+func (c *Client) MoveGroup(ctx context.Context, guid, area string) error {
+	return c.post(ctx, path("/groups", guid, "move"), url.Values{"area": {area}}, nil)
+}
+
+// Copies a group into an area under a new name, with its members and
+// policy rows if asked, and returns the copy's guid.
+// POST /groups/:guid/clone area=:areaGuid, name=:name, members=:bool, policies=:bool
+func (c *Client) CloneGroup(ctx context.Context, guid, area, name string, members, policies bool) (string, error) {
+	values := url.Values{"area": {area}, "name": {name}, "members": {strconv.FormatBool(members)}, "policies": {strconv.FormatBool(policies)}}
+
+	var clone string
+	err := c.post(ctx, path("/groups", guid, "clone"), values, &clone)
+	return clone, err
+}
+
+// Gets a group's members, including those its rule matches.
+// GET /groupMembers/:groupGuid
+func (c *Client) GroupMembers(ctx context.Context, group string) ([]string, error) {
+	members := make([]string, 0)
+	err := c.get(ctx, path("/groupMembers", group), nil, &members)
+	return members, err
+}
+
+// Gets the guids of the groups a user is in.
+// GET /groupMembers?netId=:netId
+func (c *Client) MemberGroups(ctx context.Context, netId string) ([]string, error) {
+	groups := make([]string, 0)
+	err := c.get(ctx, "/groupMembers", url.Values{"netId": {netId}}, &groups)
+	return groups, err
+}
+
+// POST /groupMembers netId=:netId, group=:groupGuid
+func (c *Client) AddMember(ctx context.Context, group, netId string) (WriteResult, error) {
+	return c.write(ctx, "/groupMembers", url.Values{"netId": {netId}, "group": {group}})
+}
+
+// Makes the users added to a group directly exactly members. With dryRun
+// the changes are worked out but not made.
+// PUT /groupMembers/:groupGuid
+func (c *Client) SyncMembers(ctx context.Context, group string, members []string, dryRun bool) (MembershipChanges, error) {
+	var changes MembershipChanges
+	b, err := jsonBody(struct {
+		Members []string
+		DryRun  bool
+	}{members, dryRun})
+	if err != nil {
+		return changes, err
+	}
+
+	_, err = c.do(ctx, call{method: "PUT", path: path("/groupMembers", group), body: b, idempotent: true}, &changes)
+	return changes, err
+}
+
+// DELETE /groupMembers/:netId/:groupGuid
+func (c *Client) RemoveMember(ctx context.Context, group, netId string) error {
+	return c.delete(ctx, path("/groupMembers", netId, group), nil)
+}
+
+// Removes a user from every group.
+// DELETE /groupMembers/:netId
+func (c *Client) RemoveFromAllGroups(ctx context.Context, netId string) error {
+	return c.delete(ctx, path("/groupMembers", netId), nil)
+}
+
+// Gets a group's rule and the users it matches.
+// GET /groupRules/:groupGuid
+func (c *Client) GroupRule(ctx context.Context, group string) (GroupRuleDetail, error) {
+	var detail GroupRuleDetail
+	err := c.get(ctx, path("/groupRules", group), nil, &detail)
+	return detail, err
+}
+
+// Gets the users a rule would match without setting it.
+// POST /groupRules/preview group=:groupGuid, expression=:rule
+func (c *Client) PreviewGroupRule(ctx context.Context, group, expression string) ([]string, error) {
+	members := make([]string, 0)
+	err := c.post(ctx, "/groupRules/preview", url.Values{"group": {group}, "expression": {expression}}, &members)
+	return members, err
+}
+
+// Sets a group's rule and returns the users it matches.
+// PUT /groupRules/:groupGuid expression=:rule
+func (c *Client) SetGroupRule(ctx context.Context, group, expression string) ([]string, error) {
+	members := make([]string, 0)
+	err := c.put(ctx, path("/groupRules", group), url.Values{"expression": {expression}}, &members)
+	return members, err
+}
+
+// DELETE /groupRules/:groupGuid
+func (c *Client) DeleteGroupRule(ctx context.Context, group string) error {
+	return c.delete(ctx, path("/groupRules", group), nil)
+}
+
+// GET /groupOwners/:groupGuid
+func (c *Client) GroupOwners(ctx context.Context, group string) ([]string, error) {
+	owners := make([]string, 0)
+	err := c.get(ctx, path("/groupOwners", group), nil, &owners)
+	return owners, err
+}
+
+// POST /groupOwners netId=:netId, group=:groupGuid
+func (c *Client) AddGroupOwner(ctx context.Context, group, netId string) error {
+	return c.post(ctx, "/groupOwners", url.Values{"netId": {netId}, "group": {group}}, nil)
+}
+
+// DELETE /groupOwners/:netId/:groupGuid
+func (c *Client) RemoveGroupOwner(ctx context.Context, group, netId string) error {
+	return c.delete(ctx, path("/groupOwners", netId, group), nil)
+}
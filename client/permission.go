@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/pdp"
+)
+
+// The query GET /permission and GET /permission/explain read. Attributes
+// conditions can read as attr.<name> are passed as extra parameters.
+func checkQuery(netId, area, verb, resource string, attrs map[string]string) url.Values {
+	query := url.Values{}
+	for name, value := range attrs {
+		query.Set(name, value)
+	}
+	query.Set("areaGuid", area)
+	query.Set("employeeGuid", netId)
+	query.Set("verb", verb)
+	query.Set("resource", resource)
+	return query
+}
+
+// Tells whether a user may use verb on a resource in an area. attrs are
+// read by conditions as attr.<name>; set "ip" to the user's address for
+// request.ip.
+// GET /permission?areaGuid=:areaGuid&employeeGuid=:netId&verb=:verb&resource=:resource
+func (c *Client) Check(ctx context.Context, netId, area, verb, resource string, attrs map[string]string) (bool, error) {
+	var allowed bool
+	err := c.get(ctx, "/permission", checkQuery(netId, area, verb, resource, attrs), &allowed)
+	return allowed, err
+}
+
+// Explains why a user does or doesn't have a permission.
+// GET /permission/explain?areaGuid=:areaGuid&employeeGuid=:netId&verb=:verb&resource=:resource
+func (c *Client) Explain(ctx context.Context, netId, area, verb, resource string, attrs map[string]string) (Explanation, error) {
+	var e Explanation
+	err := c.get(ctx, "/permission/explain", checkQuery(netId, area, verb, resource, attrs), &e)
+	return e, err
+}
+
+// Gets everything permission decisions are made from, for deciding them
+// in-process with the pdp package. Takes a superuser.
+// GET /permission/snapshot
+func (c *Client) Snapshot(ctx context.Context) (pdp.Snapshot, error) {
+	var s pdp.Snapshot
+	err := c.get(ctx, "/permission/snapshot", nil, &s)
+	return s, err
+}
+
+// Gets the groups in the caller's area that may use verb on a resource.
+// GET /permission/verbs/:resourceGUID/:verb
+func (c *Client) GroupsWithVerb(ctx context.Context, resource, verb string) ([]Group, error) {
+	groups := make([]Group, 0)
+	err := c.get(ctx, path("/permission/verbs", resource, verb), nil, &groups)
+	return groups, err
+}
+
+// Lists every user in an area who can use verb on a resource and how.
+// GET /permission/who?verb=:verb&resource=:resourceGUID&area=:areaGuid
+func (c *Client) WhoCan(ctx context.Context, verb, resource, area string) ([]Access, error) {
+	access := make([]Access, 0)
+	err := c.get(ctx, "/permission/who", url.Values{"verb": {verb}, "resource": {resource}, "area": {area}}, &access)
+	return access, err
+}
+
+// Gets the permissions a group holds, including through roles.
+// GET /permission/groups/:group
+func (c *Client) GroupPermissions(ctx context.Context, group string) ([]Permission, error) {
+	perms := make([]Permission, 0)
+	err := c.get(ctx, path("/permission/groups", group), nil, &perms)
+	return perms, err
+}
+
+// Gets the permissions a user holds in an area.
+// GET /permission/user/:netId/:areaGuid
+func (c *Client) UserPermissions(ctx context.Context, netId, area string) ([]Permission, error) {
+	perms := make([]Permission, 0)
+	err := c.get(ctx, path("/permission/user", netId, area), nil, &perms)
+	return perms, err
+}
+
+// Grants a permission, only while condition holds if it isn't empty.
+// POST /permission actor=:actor verb=:verb resource=:resource [condition=:expression]
+func (c *Client) Grant(ctx context.Context, actor, verb, resource, condition string) (WriteResult, error) {
+	values := url.Values{"actor": {actor}, "verb": {verb}, "resource": {resource}}
+	if condition != "" {
+		values.Set("condition", condition)
+	}
+	return c.write(ctx, "/permission", values)
+}
+
+// Revokes a permission.
+// DELETE /permission/:actor/:verb/:resource
+func (c *Client) Revoke(ctx context.Context, actor, verb, resource string) error {
+	return c.delete(ctx, path("/permission", actor, verb, resource), nil)
+}
+
+// Grants and revokes permissions in one request. mode is "atomic" or
+// "bestEffort". When an atomic request is refused the results say which
+// operations failed and are returned along with the error.
+// POST /permission/bulk
+func (c *Client) BulkPermissions(ctx context.Context, mode string, ops []BulkOperation) ([]BulkResult, error) {
+	results := make([]BulkResult, 0)
+	b, err := jsonBody(struct {
+		Mode       string
+		Operations []BulkOperation
+	}{mode, ops})
+	if err != nil {
+		return results, err
+	}
+
+	_, err = c.do(ctx, call{method: "POST", path: "/permission/bulk", body: b}, &results)
+	if e, ok := err.(*Error); ok && e.Status == "FAILURE" {
+		json.Unmarshal(e.Data, &results)
+	}
+	return results, err
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/relations"
+)
+
+// Tells whether a user has a relation to an object, such as whether alice
+// is an editor of doc:readme.
+// GET /relations/check?object=:namespace:id&relation=:relation&user=:netId
+func (c *Client) CheckRelation(ctx context.Context, object, relation, user string) (bool, error) {
+	var ok bool
+	err := c.get(ctx, "/relations/check", url.Values{"object": {object}, "relation": {relation}, "user": {user}}, &ok)
+	return ok, err
+}
+
+// Gets the tree of users and usersets that have a relation to an object.
+// GET /relations/expand?object=:namespace:id&relation=:relation
+func (c *Client) ExpandRelation(ctx context.Context, object, relation string) (*relations.Tree, error) {
+	var tree relations.Tree
+	err := c.get(ctx, "/relations/expand", url.Values{"object": {object}, "relation": {relation}}, &tree)
+	return &tree, err
+}
+
+// GET /relations/namespaces/:name
+func (c *Client) Namespace(ctx context.Context, name string) (relations.Namespace, error) {
+	var namespace relations.Namespace
+	err := c.get(ctx, path("/relations/namespaces", name), nil, &namespace)
+	return namespace, err
+}
+
+// Sets how a namespace's relations are computed.
+// PUT /relations/namespaces/:name
+func (c *Client) SetNamespace(ctx context.Context, name string, namespace relations.Namespace) error {
+	b, err := jsonBody(namespace)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(ctx, call{method: "PUT", path: path("/relations/namespaces", name), body: b, idempotent: true}, nil)
+	return err
+}
+
+// Stores a tuple of the form object#relation@subject.
+// POST /relations tuple=:object#relation@subject
+func (c *Client) AddRelation(ctx context.Context, tuple string) error {
+	return c.post(ctx, "/relations", url.Values{"tuple": {tuple}}, nil)
+}
+
+// DELETE /relations/:tuple
+func (c *Client) DeleteRelation(ctx context.Context, tuple string) error {
+	return c.delete(ctx, path("/relations", tuple), nil)
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// GET /accessRequests?area=:areaGuid
+func (c *Client) AccessRequests(ctx context.Context, area string) ([]AccessRequest, error) {
+	requests := make([]AccessRequest, 0)
+	err := c.get(ctx, "/accessRequests", url.Values{"area": {area}}, &requests)
+	return requests, err
+}
+
+// Requests to join a Group or to be granted Verb on Resource in an Area,
+// for Duration hours if it isn't 0, and returns the request's guid.
+// POST /accessRequests area=:areaGuid, group=:groupGuid | verb=:verb, resource=:resource, reason=:reason, duration=:hours
+func (c *Client) CreateAccessRequest(ctx context.Context, request AccessRequest) (string, error) {
+	values := url.Values{"area": {request.Area}, "reason": {request.Reason}}
+	if request.Group != "" {
+		values.Set("group", request.Group)
+	} else {
+		values.Set("verb", request.Verb)
+		values.Set("resource", request.Resource)
+	}
+	if request.Duration != 0 {
+		values.Set("duration", strconv.FormatInt(request.Duration, 10))
+	}
+
+	var guid string
+	err := c.post(ctx, "/accessRequests", values, &guid)
+	return guid, err
+}
+
+// POST /accessRequests/:guid/approve
+func (c *Client) ApproveAccessRequest(ctx context.Context, guid string) error {
+	return c.post(ctx, path("/accessRequests", guid, "approve"), url.Values{}, nil)
+}
+
+// POST /accessRequests/:guid/deny
+func (c *Client) DenyAccessRequest(ctx context.Context, guid string) error {
+	return c.post(ctx, path("/accessRequests", guid, "deny"), url.Values{}, nil)
+}
+
+// GET /reviews?area=:areaGuid
+func (c *Client) Reviews(ctx context.Context, area string) ([]ReviewCampaign, error) {
+	campaigns := make([]ReviewCampaign, 0)
+	err := c.get(ctx, "/reviews", url.Values{"area": {area}}, &campaigns)
+	return campaigns, err
+}
+
+// Starts a campaign reviewing an area's memberships and grants.
+// POST /reviews area=:areaGuid, deadline=:RFC3339
+func (c *Client) StartReview(ctx context.Context, area string, deadline time.Time) (ReviewCampaign, error) {
+	var campaign ReviewCampaign
+	err := c.post(ctx, "/reviews", url.Values{"area": {area}, "deadline": {deadline.Format(time.RFC3339)}}, &campaign)
+	return campaign, err
+}
+
+// GET /reviews/:guid
+func (c *Client) ReviewItems(ctx context.Context, guid string) ([]ReviewItem, error) {
+	items := make([]ReviewItem, 0)
+	err := c.get(ctx, path("/reviews", guid), nil, &items)
+	return items, err
+}
+
+// Decides whether to keep or revoke an item under review.
+// PUT /reviews/:guid/items/:itemGuid decision=keep|revoke
+func (c *Client) DecideReviewItem(ctx context.Context, guid, item, decision string) error {
+	return c.put(ctx, path("/reviews", guid, "items", item), url.Values{"decision": {decision}}, nil)
+}
+
+// Closes a campaign, revoking what reviewers decided to revoke.
+// POST /reviews/:guid/close
+func (c *Client) CloseReview(ctx context.Context, guid string) (ReviewReport, error) {
+	var report ReviewReport
+	err := c.post(ctx, path("/reviews", guid, "close"), url.Values{}, &report)
+	return report, err
+}
+
+// GET /reviews/:guid/report
+func (c *Client) ReviewReport(ctx context.Context, guid string) (ReviewReport, error) {
+	var report ReviewReport
+	err := c.get(ctx, path("/reviews", guid, "report"), nil, &report)
+	return report, err
+}
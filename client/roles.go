@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+// GET /roles?area=:areaGuid
+func (c *Client) Roles(ctx context.Context, area string) ([]Role, error) {
+	roles := make([]Role, 0)
+	err := c.get(ctx, "/roles", url.Values{"area": {area}}, &roles)
+	return roles, err
+}
+
+// Gets a role with its permissions and bindings.
+// GET /roles/:guid
+func (c *Client) Role(ctx context.Context, guid string) (RoleDetail, error) {
+	var detail RoleDetail
+	err := c.get(ctx, path("/roles", guid), nil, &detail)
+	return detail, err
+}
+
+// Creates a role and returns its guid.
+// POST /roles area=:areaGuid, name=:name
+func (c *Client) CreateRole(ctx context.Context, area, name string) (string, error) {
+	var guid string
+	err := c.post(ctx, "/roles", url.Values{"area": {area}, "name": {name}}, &guid)
+	return guid, err
+}
+
+// DELETE /roles/:guid
+func (c *Client) DeleteRole(ctx context.Context, guid string) error {
+	return c.delete(ctx, path("/roles", guid), nil)
+}
+
+// Adds a permission to a role. resource may be a pattern.
+// POST /roles/:guid/permissions verb=:verb, resource=:resourcePattern
+func (c *Client) AddRolePermission(ctx context.Context, role, verb, resource string) error {
+	return c.post(ctx, path("/roles", role, "permissions"), url.Values{"verb": {verb}, "resource": {resource}}, nil)
+}
+
+// Removes a permission from a role. resource may be a pattern.
+// DELETE /roles/:guid/permissions/:verb?resource=:resourcePattern
+func (c *Client) RemoveRolePermission(ctx context.Context, role, verb, resource string) error {
+	return c.delete(ctx, path("/roles", role, "permissions", verb), url.Values{"resource": {resource}})
+}
+
+// Binds a role to an actor, granting it the role's permissions.
+// POST /roles/:guid/bindings actor=:actor
+func (c *Client) BindRole(ctx context.Context, role, actor string) error {
+	return c.post(ctx, path("/roles", role, "bindings"), url.Values{"actor": {actor}}, nil)
+}
+
+// DELETE /roles/:guid/bindings/:actor
+func (c *Client) UnbindRole(ctx context.Context, role, actor string) error {
+	return c.delete(ctx, path("/roles", role, "bindings", actor), nil)
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+// GET /sod?area=:areaGuid
+func (c *Client) SodRules(ctx context.Context, area string) ([]SodRule, error) {
+	rules := make([]SodRule, 0)
+	err := c.get(ctx, "/sod", url.Values{"area": {area}}, &rules)
+	return rules, err
+}
+
+// Gets the users in an area who break its separation of duties rules.
+// GET /sod/violations?area=:areaGuid
+func (c *Client) SodViolations(ctx context.Context, area string) ([]SodViolation, error) {
+	violations := make([]SodViolation, 0)
+	err := c.get(ctx, "/sod/violations", url.Values{"area": {area}}, &violations)
+	return violations, err
+}
+
+// Adds a rule from its Area, VerbA, VerbB and Mode and returns its guid.
+// POST /sod area=:areaGuid, verbA=:verb, verbB=:verb, mode=block|warn
+func (c *Client) AddSodRule(ctx context.Context, rule SodRule) (string, error) {
+	var guid string
+	err := c.post(ctx, "/sod", url.Values{"area": {rule.Area}, "verbA": {rule.VerbA}, "verbB": {rule.VerbB}, "mode": {rule.Mode}}, &guid)
+	return guid, err
+}
+
+// DELETE /sod/:guid
+func (c *Client) DeleteSodRule(ctx context.Context, guid string) error {
+	return c.delete(ctx, path("/sod", guid), nil)
+}
@@ -0,0 +1,292 @@
+package client
+
+// These mirror the data the service responds with, so that callers don't
+// need the service's own packages or its database driver.
+
+// What a grant did. Created is false when it was already in place.
+// Approval is the guid of the pending approval when a second person has to
+// approve it before it takes effect.
+type WriteResult struct {
+	Created  bool
+	Approval string
+}
+
+// A group of users in an area. Created is a unix time.
+type Group struct {
+	Guid        string
+	Area        string
+	Name        string
+	Description string
+	Tags        []string
+	Created     int64
+	CreatedBy   string
+}
+
+// A policy row or role grant: actor may use verb on resource.
+type Permission struct {
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string // empty if the grant always applies
+}
+
+// A policy row or role binding through which an actor holds a permission.
+type Grant struct {
+	Actor     string
+	Source    string // "policy" or "role"
+	Resource  string
+	Condition string
+	Applies   bool
+}
+
+// Why a user does or doesn't have a permission in an area.
+type Explanation struct {
+	Allowed   bool
+	Superuser bool
+	Admin     bool
+	Actors    []string
+	Grants    []Grant
+}
+
+// A user who can perform an action and the ways they can.
+type Access struct {
+	NetId string
+	Paths []string
+}
+
+// An operation in a bulk grant or revoke. Op is "grant" or "revoke".
+type BulkOperation struct {
+	Op        string
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string
+}
+
+// What happened to one operation of a bulk request.
+type BulkResult struct {
+	Index  int
+	Status string
+	Detail interface{}
+}
+
+type Area struct {
+	Guid        string
+	Name        string
+	Description string
+	Parent      string
+	Inherit     bool
+}
+
+// An admin of an area or of one of the areas above it.
+type AreaAdmin struct {
+	NetId string
+	Area  string
+}
+
+// A grant waiting on a second person. Type is "admin", "superuser",
+// "permission", or "role.permission" or "role.binding" for roles whose
+// resource patterns cover a sensitive resource.
+type Approval struct {
+	Guid      string
+	Type      string
+	Requester string
+	Area      string
+	NetId     string
+	Role      string
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string
+	Status    string
+	Reviewer  string
+}
+
+// A separation of duties rule: nobody may hold both verbs on a resource.
+// Mode is "block" or "warn".
+type SodRule struct {
+	Guid  string
+	Area  string
+	VerbA string
+	VerbB string
+	Mode  string
+}
+
+type SodViolation struct {
+	Rule     SodRule
+	NetId    string
+	Resource string
+}
+
+// A user's request to join a group or be granted a permission. Duration is
+// in hours and Expires a unix time; 0 means the access doesn't expire.
+// Granted is whether approval gave the user access they didn't already
+// have, which is all that expiry takes away.
+type AccessRequest struct {
+	Guid     string
+	NetId    string
+	Area     string
+	Type     string
+	Group    string
+	Verb     string
+	Resource string
+	Reason   string
+	Status   string
+	Reviewer string
+	Duration int64
+	Expires  int64
+	Granted  bool
+}
+
+// An access review campaign. Deadline is a unix time.
+type ReviewCampaign struct {
+	Guid     string
+	Area     string
+	Creator  string
+	Deadline int64
+	Status   string
+}
+
+type ReviewItem struct {
+	Guid      string
+	Campaign  string
+	Type      string
+	NetId     string
+	Group     string
+	Verb      string
+	Resource  string
+	Reviewer  string
+	Decision  string
+	DecidedBy string
+}
+
+type ReviewReport struct {
+	Campaign   ReviewCampaign
+	Kept       []ReviewItem
+	Revoked    []ReviewItem
+	Unreviewed []ReviewItem
+}
+
+type Role struct {
+	Guid string
+	Area string
+	Name string
+}
+
+// A role with the permissions it grants and the actors it's bound to.
+type RoleDetail struct {
+	Role        Role
+	Permissions []Permission
+	Bindings    []string
+}
+
+type GroupRule struct {
+	Group      string
+	Expression string
+}
+
+// A group's rule and the users it currently matches.
+type GroupRuleDetail struct {
+	Rule    GroupRule
+	Members []string
+}
+
+// The members a sync added and removed, or would have with DryRun.
+type MembershipChanges struct {
+	Added   []string
+	Removed []string
+	DryRun  bool
+}
+
+// An area's groups, members, admins and policy, as exported and imported.
+type AreaConfig struct {
+	Area   string
+	Name   string
+	Groups []GroupConfig
+	Admins []string
+	Policy []PolicyConfig
+}
+
+type GroupConfig struct {
+	Guid        string
+	Name        string
+	Description string
+	Tags        []string
+	Members     []string
+}
+
+type PolicyConfig struct {
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string
+}
+
+type Membership struct {
+	Group string
+	NetId string
+}
+
+// The changes that make an area match an AreaConfig.
+type ConfigChanges struct {
+	CreateGroups  []GroupConfig
+	UpdateGroups  []GroupConfig
+	DeleteGroups  []string
+	AddMembers    []Membership
+	RemoveMembers []Membership
+	AddAdmins     []string
+	RemoveAdmins  []string
+	AddPolicy     []PolicyConfig
+	RemovePolicy  []PolicyConfig
+}
+
+type ImportResult struct {
+	Changes ConfigChanges
+	DryRun  bool
+	Pending []string // approval requests for the admins added
+}
+
+// What applying a configuration would do, with each change described in
+// words. Fingerprint can be passed to ApplyArea to make sure nothing
+// changed since.
+type Plan struct {
+	Fingerprint string
+	Changes     ConfigChanges
+	Summary     []string
+}
+
+// How a configuration is imported. Replace removes what the configuration
+// doesn't have; Remap gives guids in the configuration and what to use
+// instead.
+type ImportOptions struct {
+	Replace bool
+	Remap   map[string]string
+}
+
+// A change that can affect permission decisions. Seq orders events;
+// Created is a unix time. Type names the change, such as "grant" or
+// "member.add", and which of the other fields it sets.
+type Event struct {
+	Seq       int64
+	Type      string
+	Created   int64
+	Area      string
+	Group     string
+	Role      string
+	NetId     string
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string
+	Name      string
+	Object    string
+	Relation  string
+	Subject   string
+}
+
+// A page of the change feed. Next is what to pass as since for the
+// following page.
+type EventPage struct {
+	Events []Event
+	Next   int64
+}
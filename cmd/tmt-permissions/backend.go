@@ -0,0 +1,36 @@
+package main
+
+import (
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// What the tool can do, against either the HTTP API or the database.
+// Writes describe what happened: "created", "unchanged" when it was
+// already in place, "removed", or "pending approval <guid>" when a second
+// person has to approve it first.
+type backend interface {
+	Groups(area string) ([]accessors.Group, error)
+	Group(guid string) (accessors.Group, error)
+	CreateGroup(group accessors.Group) (accessors.Group, error)
+	DeleteGroup(guid string) (string, error)
+
+	Members(group string) ([]string, error)
+	AddMember(group, netId string) (string, error)
+	RemoveMember(group, netId string) (string, error)
+
+	GroupGrants(group string) ([]accessors.Permission, error)
+	UserGrants(netId, area string) ([]accessors.Permission, error)
+	Grant(actor, verb, resource, condition string) (string, error)
+	Revoke(actor, verb, resource string) (string, error)
+
+	Admins(area string) ([]string, error)
+	AddAdmin(netId, area string) (string, error)
+	RemoveAdmin(netId, area string) (string, error)
+
+	Superusers() ([]string, error)
+	AddSuperuser(netId string) (string, error)
+	RemoveSuperuser(netId string) (string, error)
+
+	Check(netId, area, verb, resource string, attrs map[string]string) (bool, error)
+	Explain(netId, area, verb, resource string, attrs map[string]string) (accessors.Explanation, error)
+}
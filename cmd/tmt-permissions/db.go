@@ -0,0 +1,314 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
+)
+
+// Works on the database directly through the accessors, for operators who
+// can reach it. Writes are audit logged under actor. Admin and superuser
+// grants and grants on sensitive resources are held for a second person's
+// approval, and grants and memberships are checked against separation of
+// duties rules, as the API does.
+type dbBackend struct {
+	db    *sql.DB
+	actor string
+}
+
+func (d *dbBackend) audit(msg string, args ...interface{}) {
+	accessors.Log("audit", d.actor, fmt.Sprintf(msg, args...)+" (tmt-permissions)", true, d.db)
+}
+
+// Hold a grant until a second person approves it.
+func (d *dbBackend) requestApproval(approval accessors.Approval) (string, error) {
+	approval.Requester = d.actor
+	guid, err := accessors.NewApprovalAccessor(d.db).Create(approval)
+	if err != nil {
+		return "", err
+	}
+
+	switch approval.Type {
+	case accessors.ApprovalPermission:
+		d.audit("Requested approval %s: grant %s on %s to %s", guid, approval.Verb, approval.Resource, approval.Actor)
+	case accessors.ApprovalAdmin:
+		d.audit("Requested approval %s: make %s an admin of area %s", guid, approval.NetId, approval.Area)
+	default:
+		d.audit("Requested approval %s: make %s a superuser", guid, approval.NetId)
+	}
+	return "pending approval " + guid, nil
+}
+
+// Check new permissions for users against an area's separation of duties
+// rules. Warn-only violations are audit logged; the rest are returned as
+// an error.
+func (d *dbBackend) enforceSod(area string, users func() ([]string, error), extra []accessors.Permission) error {
+	violations, err := accessors.NewSodAccessor(d.db).Violations(area, users, extra)
+	if err != nil {
+		return err
+	}
+
+	blocking := make([]string, 0)
+	for _, v := range violations {
+		if v.Rule.Mode == accessors.SodWarn {
+			d.audit("Separation of duties warning: %s would hold %s and %s on %s", v.NetId, v.Rule.VerbA, v.Rule.VerbB, v.Resource)
+		} else {
+			blocking = append(blocking, fmt.Sprintf("%s would hold %s and %s on %s", v.NetId, v.Rule.VerbA, v.Rule.VerbB, v.Resource))
+		}
+	}
+
+	if len(blocking) > 0 {
+		return fmt.Errorf("separation of duties: %s", strings.Join(blocking, "; "))
+	}
+	return nil
+}
+
+func created(n int64) string {
+	if n > 0 {
+		return "created"
+	}
+	return "unchanged"
+}
+
+func (d *dbBackend) Groups(area string) ([]accessors.Group, error) {
+	return accessors.NewGroupAccessor(d.db).GetByArea(area)
+}
+
+func (d *dbBackend) Group(guid string) (accessors.Group, error) {
+	group, err := accessors.NewGroupAccessor(d.db).Get(guid)
+	if err == sql.ErrNoRows {
+		return group, fmt.Errorf("no such group %s", guid)
+	}
+	return group, err
+}
+
+func (d *dbBackend) CreateGroup(group accessors.Group) (accessors.Group, error) {
+	lineage, err := accessors.NewAreaAccessor(d.db).Lineage(group.Area)
+	if err != nil {
+		return group, err
+	}
+	if len(lineage) == 0 {
+		return group, fmt.Errorf("no such area %s", group.Area)
+	}
+
+	group.Created = time.Now().Unix()
+	group.CreatedBy = d.actor
+	group, err = accessors.NewGroupAccessor(d.db).Insert(group)
+	if err != nil {
+		return group, err
+	}
+
+	d.audit("Created group %s (%s) in area %s", group.Guid, group.Name, group.Area)
+	return group, nil
+}
+
+func (d *dbBackend) DeleteGroup(guid string) (string, error) {
+	if _, err := d.Group(guid); err != nil {
+		return "", err
+	}
+	if err := accessors.NewGroupAccessor(d.db).Delete(guid); err != nil {
+		return "", err
+	}
+
+	d.audit("Deleted group %s", guid)
+	return "removed", nil
+}
+
+func (d *dbBackend) Members(group string) ([]string, error) {
+	return accessors.NewMembersAccessor(d.db).GetGroupMembers(group)
+}
+
+func (d *dbBackend) AddMember(group, netId string) (string, error) {
+	g, err := d.Group(group)
+	if err != nil {
+		return "", err
+	}
+
+	perms, err := accessors.NewPermissionAccessor(d.db).GetGroupPermissions(group)
+	if err != nil {
+		return "", err
+	}
+	users := func() ([]string, error) { return []string{netId}, nil }
+	if err := d.enforceSod(g.Area, users, perms); err != nil {
+		return "", err
+	}
+
+	n, err := accessors.NewMembersAccessor(d.db).AddToGroup(netId, group)
+	if err != nil {
+		return "", err
+	}
+
+	if n > 0 {
+		d.audit("Added %s to group %s", netId, group)
+	}
+	return created(n), nil
+}
+
+func (d *dbBackend) RemoveMember(group, netId string) (string, error) {
+	n, err := accessors.NewMembersAccessor(d.db).RemoveFromGroup(netId, group)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", errors.New("no such membership")
+	}
+
+	d.audit("Removed %s from group %s", netId, group)
+	return "removed", nil
+}
+
+func (d *dbBackend) GroupGrants(group string) ([]accessors.Permission, error) {
+	return accessors.NewPermissionAccessor(d.db).GetGroupPermissions(group)
+}
+
+func (d *dbBackend) UserGrants(netId, area string) ([]accessors.Permission, error) {
+	return accessors.NewPermissionAccessor(d.db).GetUserPermissions(netId, area)
+}
+
+func (d *dbBackend) Grant(actor, verb, resource, condition string) (string, error) {
+	pa := accessors.NewPermissionAccessor(d.db)
+
+	if condition != "" {
+		if _, err := conditions.Parse(condition); err != nil {
+			return "", fmt.Errorf("invalid condition: %v", err)
+		}
+	}
+
+	granted, err := pa.Granted(actor, verb, resource)
+	if err != nil || granted {
+		return "unchanged", err
+	}
+
+	sa := accessors.NewSodAccessor(d.db)
+	areas, err := sa.GrantAreas(actor, "")
+	if err != nil {
+		return "", err
+	}
+	for _, area := range areas {
+		users := func() ([]string, error) { return sa.Grantees(actor, area) }
+		if err := d.enforceSod(area, users, []accessors.Permission{{actor, verb, resource, condition}}); err != nil {
+			return "", err
+		}
+	}
+
+	sensitive, err := accessors.NewApprovalAccessor(d.db).IsSensitive(resource)
+	if err != nil {
+		return "", err
+	}
+	if sensitive {
+		return d.requestApproval(accessors.Approval{Type: accessors.ApprovalPermission, Actor: actor, Verb: verb, Resource: resource, Condition: condition})
+	}
+
+	n, err := pa.AddConditional(actor, verb, resource, condition)
+	if err != nil {
+		return "", err
+	}
+
+	if n > 0 {
+		d.audit("Granted %s on %s to %s", verb, resource, actor)
+	}
+	return created(n), nil
+}
+
+func (d *dbBackend) Revoke(actor, verb, resource string) (string, error) {
+	n, err := accessors.NewPermissionAccessor(d.db).Delete(actor, verb, resource)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", errors.New("no such permission")
+	}
+
+	d.audit("Revoked %s on %s from %s", verb, resource, actor)
+	return "removed", nil
+}
+
+func (d *dbBackend) Admins(area string) ([]string, error) {
+	return accessors.NewPermissionAccessor(d.db).GetAdmins(area)
+}
+
+func (d *dbBackend) AddAdmin(netId, area string) (string, error) {
+	admins, err := d.Admins(area)
+	if err != nil {
+		return "", err
+	}
+	for _, admin := range admins {
+		if admin == netId {
+			return "unchanged", nil
+		}
+	}
+
+	return d.requestApproval(accessors.Approval{Type: accessors.ApprovalAdmin, Area: area, NetId: netId})
+}
+
+func (d *dbBackend) RemoveAdmin(netId, area string) (string, error) {
+	n, err := accessors.NewPermissionAccessor(d.db).DeleteAdmin(netId, area)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", errors.New("no such admin")
+	}
+
+	d.audit("Removed admin %s from area %s", netId, area)
+	return "removed", nil
+}
+
+func (d *dbBackend) Superusers() ([]string, error) {
+	return accessors.NewPermissionAccessor(d.db).GetAllSU()
+}
+
+func (d *dbBackend) AddSuperuser(netId string) (string, error) {
+	superusers, err := d.Superusers()
+	if err != nil {
+		return "", err
+	}
+	for _, su := range superusers {
+		if su == netId {
+			return "unchanged", nil
+		}
+	}
+
+	return d.requestApproval(accessors.Approval{Type: accessors.ApprovalSuperuser, NetId: netId})
+}
+
+func (d *dbBackend) RemoveSuperuser(netId string) (string, error) {
+	n, err := accessors.NewPermissionAccessor(d.db).DeleteSU(netId)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", errors.New("no such superuser")
+	}
+
+	d.audit("Removed superuser %s", netId)
+	return "removed", nil
+}
+
+// The context conditions are evaluated in, as the API builds it for a
+// caller without an ip.
+func attrContext(attrs map[string]string) conditions.Context {
+	ctx := conditions.NewContext(time.Now(), "")
+	for name, value := range attrs {
+		ctx.SetAttr(name, value)
+	}
+	return ctx
+}
+
+func (d *dbBackend) Check(netId, area, verb, resource string, attrs map[string]string) (bool, error) {
+	e, err := d.Explain(netId, area, verb, resource, attrs)
+	return e.Allowed, err
+}
+
+func (d *dbBackend) Explain(netId, area, verb, resource string, attrs map[string]string) (accessors.Explanation, error) {
+	e, err := accessors.NewPermissionAccessor(d.db).Explain(netId, area, resource, verb, attrContext(attrs))
+	if err == sql.ErrNoRows {
+		return e, fmt.Errorf("no such area %s", area)
+	}
+	return e, err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	"github.com/byu-oit-ssengineering/tmt-permissions/client"
+)
+
+// Talks to a running tmt-permissions service through the client package.
+type httpBackend struct {
+	c *client.Client
+}
+
+func newHTTPBackend(baseURL, auth string) *httpBackend {
+	var provider client.AuthProvider
+	if auth != "" {
+		provider = client.StaticAuth(auth)
+	}
+	return &httpBackend{client.New(baseURL, provider)}
+}
+
+// Describe what a write did.
+func writeStatus(result client.WriteResult, err error) (string, error) {
+	switch {
+	case err != nil:
+		return "", err
+	case result.Approval != "":
+		return "pending approval " + result.Approval, nil
+	case !result.Created:
+		return "unchanged", nil
+	}
+	return "created", nil
+}
+
+func removed(err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return "removed", nil
+}
+
+func groups(gs []client.Group, err error) ([]accessors.Group, error) {
+	result := make([]accessors.Group, 0, len(gs))
+	for _, g := range gs {
+		result = append(result, accessors.Group(g))
+	}
+	return result, err
+}
+
+func permissions(ps []client.Permission, err error) ([]accessors.Permission, error) {
+	result := make([]accessors.Permission, 0, len(ps))
+	for _, p := range ps {
+		result = append(result, accessors.Permission(p))
+	}
+	return result, err
+}
+
+func (h *httpBackend) Groups(area string) ([]accessors.Group, error) {
+	return groups(h.c.SearchGroups(context.Background(), area, "", ""))
+}
+
+func (h *httpBackend) Group(guid string) (accessors.Group, error) {
+	g, err := h.c.Group(context.Background(), guid)
+	return accessors.Group(g), err
+}
+
+func (h *httpBackend) CreateGroup(group accessors.Group) (accessors.Group, error) {
+	g, err := h.c.CreateGroup(context.Background(), client.Group(group))
+	return accessors.Group(g), err
+}
+
+func (h *httpBackend) DeleteGroup(guid string) (string, error) {
+	return removed(h.c.DeleteGroup(context.Background(), guid))
+}
+
+func (h *httpBackend) Members(group string) ([]string, error) {
+	return h.c.GroupMembers(context.Background(), group)
+}
+
+func (h *httpBackend) AddMember(group, netId string) (string, error) {
+	return writeStatus(h.c.AddMember(context.Background(), group, netId))
+}
+
+func (h *httpBackend) RemoveMember(group, netId string) (string, error) {
+	return removed(h.c.RemoveMember(context.Background(), group, netId))
+}
+
+func (h *httpBackend) GroupGrants(group string) ([]accessors.Permission, error) {
+	return permissions(h.c.GroupPermissions(context.Background(), group))
+}
+
+func (h *httpBackend) UserGrants(netId, area string) ([]accessors.Permission, error) {
+	return permissions(h.c.UserPermissions(context.Background(), netId, area))
+}
+
+func (h *httpBackend) Grant(actor, verb, resource, condition string) (string, error) {
+	return writeStatus(h.c.Grant(context.Background(), actor, verb, resource, condition))
+}
+
+func (h *httpBackend) Revoke(actor, verb, resource string) (string, error) {
+	return removed(h.c.Revoke(context.Background(), actor, verb, resource))
+}
+
+func (h *httpBackend) Admins(area string) ([]string, error) {
+	return h.c.Admins(context.Background(), area)
+}
+
+func (h *httpBackend) AddAdmin(netId, area string) (string, error) {
+	return writeStatus(h.c.AddAdmin(context.Background(), netId, area))
+}
+
+func (h *httpBackend) RemoveAdmin(netId, area string) (string, error) {
+	return removed(h.c.RemoveAdmin(context.Background(), netId, area))
+}
+
+func (h *httpBackend) Superusers() ([]string, error) {
+	return h.c.Superusers(context.Background())
+}
+
+func (h *httpBackend) AddSuperuser(netId string) (string, error) {
+	return writeStatus(h.c.AddSuperuser(context.Background(), netId))
+}
+
+func (h *httpBackend) RemoveSuperuser(netId string) (string, error) {
+	return removed(h.c.RemoveSuperuser(context.Background(), netId))
+}
+
+func (h *httpBackend) Check(netId, area, verb, resource string, attrs map[string]string) (bool, error) {
+	return h.c.Check(context.Background(), netId, area, verb, resource, attrs)
+}
+
+func (h *httpBackend) Explain(netId, area, verb, resource string, attrs map[string]string) (accessors.Explanation, error) {
+	e, err := h.c.Explain(context.Background(), netId, area, verb, resource, attrs)
+	explanation := accessors.Explanation{Allowed: e.Allowed, Superuser: e.Superuser, Admin: e.Admin, Actors: e.Actors, Grants: make([]accessors.Grant, 0, len(e.Grants))}
+	for _, g := range e.Grants {
+		explanation.Grants = append(explanation.Grants, accessors.Grant(g))
+	}
+	return explanation, err
+}
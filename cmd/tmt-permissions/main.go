@@ -0,0 +1,260 @@
+// Command tmt-permissions manages groups, members, grants, admins and
+// superusers from the command line, through a running service or directly
+// against its database.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"strings"
+
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	apis "github.com/byu-oit-ssengineering/tmt-permissions/apis"
+)
+
+const usage = `Usage: tmt-permissions [-api URL [-auth VALUE] | -db] [-o table|json] COMMAND
+
+Commands:
+  groups list AREA
+  groups get GUID
+  groups create [-description TEXT] [-tags TAG,TAG] AREA NAME
+  groups delete GUID
+  members list GROUP
+  members add GROUP NETID
+  members remove GROUP NETID
+  grants list GROUP
+  grants user NETID AREA
+  grants add [-condition EXPR] ACTOR VERB RESOURCE
+  grants revoke ACTOR VERB RESOURCE
+  admins list AREA
+  admins add NETID AREA
+  admins remove NETID AREA
+  superusers list
+  superusers add NETID
+  superusers remove NETID
+  check NETID AREA VERB RESOURCE [NAME=VALUE...]
+  explain NETID AREA VERB RESOURCE [NAME=VALUE...]
+
+-api talks to the service at URL (default $TMT_PERMISSIONS_URL), sending
+-auth as the Authorization header (default $TMT_PERMISSIONS_AUTH). -db
+works on the database named by DB_USER, DB_PASS, DB_HOST, DB_PORT and
+DB_NAME instead, logging writes under your login. Separation of duties
+rules are enforced and admin, superuser and sensitive grants wait for a
+second person's approval either way.
+
+check exits with 1 when the permission is denied. NAME=VALUE pairs are
+attributes conditions can read as attr.NAME.
+`
+
+var errUsage = errors.New("usage")
+
+// Fails unless exactly n arguments are given.
+func want(args []string, n int) error {
+	if len(args) != n {
+		return errUsage
+	}
+	return nil
+}
+
+// Parse NAME=VALUE attribute pairs.
+func parseAttrs(args []string) (map[string]string, error) {
+	attrs := make(map[string]string)
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("attribute %q isn't NAME=VALUE", arg)
+		}
+		attrs[arg[:i]] = arg[i+1:]
+	}
+	return attrs, nil
+}
+
+func users(ids []string, err error) (interface{}, error) {
+	return netIds(ids), err
+}
+
+// Run a command and return its result for rendering.
+func run(b backend, args []string) (interface{}, error) {
+	if len(args) > 0 && (args[0] == "check" || args[0] == "explain") {
+		if len(args) < 5 {
+			return nil, errUsage
+		}
+		attrs, err := parseAttrs(args[5:])
+		if err != nil {
+			return nil, err
+		}
+		if args[0] == "check" {
+			return b.Check(args[1], args[2], args[3], args[4], attrs)
+		}
+		return b.Explain(args[1], args[2], args[3], args[4], attrs)
+	}
+
+	if len(args) < 2 {
+		return nil, errUsage
+	}
+	cmd, args := args[0]+" "+args[1], args[2:]
+
+	flags := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	description := flags.String("description", "", "")
+	tags := flags.String("tags", "", "")
+	condition := flags.String("condition", "", "")
+	if err := flags.Parse(args); err != nil {
+		return nil, errUsage
+	}
+	args = flags.Args()
+
+	switch cmd {
+	case "groups list":
+		if err := want(args, 1); err != nil {
+			return nil, err
+		}
+		return b.Groups(args[0])
+	case "groups get":
+		if err := want(args, 1); err != nil {
+			return nil, err
+		}
+		return b.Group(args[0])
+	case "groups create":
+		if err := want(args, 2); err != nil {
+			return nil, err
+		}
+		group := accessors.Group{Area: args[0], Name: args[1], Description: *description, Tags: []string{}}
+		if *tags != "" {
+			group.Tags = strings.Split(*tags, ",")
+		}
+		return b.CreateGroup(group)
+	case "groups delete":
+		if err := want(args, 1); err != nil {
+			return nil, err
+		}
+		return b.DeleteGroup(args[0])
+	case "members list":
+		if err := want(args, 1); err != nil {
+			return nil, err
+		}
+		return users(b.Members(args[0]))
+	case "members add":
+		if err := want(args, 2); err != nil {
+			return nil, err
+		}
+		return b.AddMember(args[0], args[1])
+	case "members remove":
+		if err := want(args, 2); err != nil {
+			return nil, err
+		}
+		return b.RemoveMember(args[0], args[1])
+	case "grants list":
+		if err := want(args, 1); err != nil {
+			return nil, err
+		}
+		return b.GroupGrants(args[0])
+	case "grants user":
+		if err := want(args, 2); err != nil {
+			return nil, err
+		}
+		return b.UserGrants(args[0], args[1])
+	case "grants add":
+		if err := want(args, 3); err != nil {
+			return nil, err
+		}
+		return b.Grant(args[0], args[1], args[2], *condition)
+	case "grants revoke":
+		if err := want(args, 3); err != nil {
+			return nil, err
+		}
+		return b.Revoke(args[0], args[1], args[2])
+	case "admins list":
+		if err := want(args, 1); err != nil {
+			return nil, err
+		}
+		return users(b.Admins(args[0]))
+	case "admins add":
+		if err := want(args, 2); err != nil {
+			return nil, err
+		}
+		return b.AddAdmin(args[0], args[1])
+	case "admins remove":
+		if err := want(args, 2); err != nil {
+			return nil, err
+		}
+		return b.RemoveAdmin(args[0], args[1])
+	case "superusers list":
+		if err := want(args, 0); err != nil {
+			return nil, err
+		}
+		return users(b.Superusers())
+	case "superusers add":
+		if err := want(args, 1); err != nil {
+			return nil, err
+		}
+		return b.AddSuperuser(args[0])
+	case "superusers remove":
+		if err := want(args, 1); err != nil {
+			return nil, err
+		}
+		return b.RemoveSuperuser(args[0])
+	}
+	return nil, errUsage
+}
+
+// The login writes made with -db are logged under.
+func operator() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func main() {
+	flags := flag.NewFlagSet("tmt-permissions", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	api := flags.String("api", os.Getenv("TMT_PERMISSIONS_URL"), "")
+	auth := flags.String("auth", os.Getenv("TMT_PERMISSIONS_AUTH"), "")
+	direct := flags.Bool("db", false, "")
+	format := flags.String("o", "table", "")
+	flags.Parse(os.Args[1:])
+
+	if *format != "table" && *format != "json" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var b backend
+	switch {
+	case *direct:
+		a, err := apis.New()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tmt-permissions:", err)
+			os.Exit(1)
+		}
+		b = &dbBackend{a.DB, operator()}
+	case *api != "":
+		b = newHTTPBackend(*api, *auth)
+	default:
+		fmt.Fprintln(os.Stderr, "tmt-permissions: give -api URL, set TMT_PERMISSIONS_URL or use -db")
+		os.Exit(2)
+	}
+
+	result, err := run(b, flags.Args())
+	if err == errUsage {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tmt-permissions:", err)
+		os.Exit(1)
+	}
+
+	if err := render(os.Stdout, *format, result); err != nil {
+		fmt.Fprintln(os.Stderr, "tmt-permissions:", err)
+		os.Exit(1)
+	}
+	if allowed, ok := result.(bool); ok && !allowed {
+		os.Exit(1)
+	}
+}
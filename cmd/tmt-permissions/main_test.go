@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// Records the calls it gets. Methods the tests don't use panic.
+type fakeBackend struct {
+	backend
+	calls []string
+}
+
+func (f *fakeBackend) Grant(actor, verb, resource, condition string) (string, error) {
+	f.calls = append(f.calls, "grant "+actor+" "+verb+" "+resource+" "+condition)
+	return "created", nil
+}
+
+func (f *fakeBackend) Admins(area string) ([]string, error) {
+	f.calls = append(f.calls, "admins "+area)
+	return []string{"alice", "bob"}, nil
+}
+
+func (f *fakeBackend) Check(netId, area, verb, resource string, attrs map[string]string) (bool, error) {
+	f.calls = append(f.calls, "check "+netId+" "+area+" "+verb+" "+resource+" "+attrs["region"])
+	return false, nil
+}
+
+func TestRun(t *testing.T) {
+	f := &fakeBackend{}
+
+	if result, err := run(f, []string{"grants", "add", "-condition", "time.hour >= 9", "desk", "edit", "res"}); err != nil || result != "created" {
+		t.Errorf("Expected the grant to be created but got %v, %v", result, err)
+	}
+	if result, err := run(f, []string{"admins", "list", "area"}); err != nil || !reflect.DeepEqual(result, netIds{"alice", "bob"}) {
+		t.Errorf("Expected the area's admins but got %v, %v", result, err)
+	}
+	if result, err := run(f, []string{"check", "alice", "area", "view", "res", "region=west"}); err != nil || result != false {
+		t.Errorf("Expected the check to be denied but got %v, %v", result, err)
+	}
+
+	expected := []string{"grant desk edit res time.hour >= 9", "admins area", "check alice area view res west"}
+	if !reflect.DeepEqual(f.calls, expected) {
+		t.Errorf("Expected calls %v but got %v", expected, f.calls)
+	}
+
+	for _, args := range [][]string{{}, {"groups"}, {"groups", "list"}, {"members", "add", "group"}, {"grants", "add", "-bogus", "a", "b", "c"}, {"check", "alice"}} {
+		if _, err := run(f, args); err != errUsage {
+			t.Errorf("Expected a usage error for %v but got %v", args, err)
+		}
+	}
+	if _, err := run(f, []string{"explain", "alice", "area", "view", "res", "region"}); err == nil {
+		t.Errorf("Expected an attribute without a value to be refused")
+	}
+}
+
+func TestRender(t *testing.T) {
+	groups := []accessors.Group{{Guid: "g1", Name: "desk", Area: "area", Tags: []string{"a", "b"}}}
+
+	var table bytes.Buffer
+	if err := render(&table, "table", groups); err != nil {
+		t.Fatalf("An unexpected error occurred while rendering: %v", err)
+	}
+	expected := "GUID  NAME  AREA  TAGS  DESCRIPTION\ng1    desk  area  a,b   \n"
+	if table.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, table.String())
+	}
+
+	var json bytes.Buffer
+	if err := render(&json, "json", netIds{"alice"}); err != nil {
+		t.Fatalf("An unexpected error occurred while rendering: %v", err)
+	}
+	if json.String() != "[\n  \"alice\"\n]\n" {
+		t.Errorf("Expected a JSON array but got %q", json.String())
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+)
+
+// A list of users, written under a NETID heading.
+type netIds []string
+
+// Write a result as an aligned table or, for scripts, as indented JSON.
+func render(w io.Writer, format string, v interface{}) error {
+	if format == "json" {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	switch v := v.(type) {
+	case []accessors.Group:
+		fmt.Fprintln(tw, "GUID\tNAME\tAREA\tTAGS\tDESCRIPTION")
+		for _, g := range v {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", g.Guid, g.Name, g.Area, strings.Join(g.Tags, ","), g.Description)
+		}
+	case accessors.Group:
+		return render(w, format, []accessors.Group{v})
+	case netIds:
+		fmt.Fprintln(tw, "NETID")
+		for _, netId := range v {
+			fmt.Fprintln(tw, netId)
+		}
+	case []accessors.Permission:
+		fmt.Fprintln(tw, "ACTOR\tVERB\tRESOURCE\tCONDITION")
+		for _, p := range v {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Actor, p.Verb, p.Resource, p.Condition)
+		}
+	case accessors.Explanation:
+		fmt.Fprintf(tw, "ALLOWED\t%t\n", v.Allowed)
+		fmt.Fprintf(tw, "SUPERUSER\t%t\n", v.Superuser)
+		fmt.Fprintf(tw, "ADMIN\t%t\n", v.Admin)
+		fmt.Fprintf(tw, "ACTORS\t%s\n", strings.Join(v.Actors, ", "))
+		if len(v.Grants) > 0 {
+			fmt.Fprintln(tw)
+			fmt.Fprintln(tw, "ACTOR\tSOURCE\tRESOURCE\tCONDITION\tAPPLIES")
+			for _, g := range v.Grants {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\n", g.Actor, g.Source, g.Resource, g.Condition, g.Applies)
+			}
+		}
+	case bool:
+		if v {
+			fmt.Fprintln(tw, "allowed")
+		} else {
+			fmt.Fprintln(tw, "denied")
+		}
+	default:
+		fmt.Fprintln(tw, v)
+	}
+	return tw.Flush()
+}
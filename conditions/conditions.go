@@ -0,0 +1,175 @@
+// Package conditions implements the small expression language used to put
+// conditions on policy rows, for example
+//
+//	!(time.weekday in ["Saturday", "Sunday"]) && inCidr(request.ip, "10.0.0.0/8")
+//
+// An expression can only read the attributes it is evaluated against and
+// call the built in functions. There are no loops, assignments or ways to
+// reach outside the evaluator, and parsing is bounded in both length and
+// nesting depth, so conditions are safe to accept from admins.
+//
+// Attributes are namespaced: time.* and request.* are filled in by the
+// server, attr.* come from the caller.
+package conditions
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits on what Parse accepts.
+const (
+	MaxLength = 1024
+	MaxDepth  = 32
+)
+
+// Attributes an expression may read that the server fills in.
+var builtinAttributes = map[string]bool{
+	"time.weekday": true, // "Monday" ... "Sunday"
+	"time.hour":    true, // 0 - 23
+	"time.date":    true, // "2006-01-02", compares correctly as a string
+	"time.unix":    true, // seconds since the epoch
+	"request.ip":   true, // the subject's address
+}
+
+// The values an expression is evaluated against, keyed by attribute name.
+// Values are strings, float64s or bools.
+type Context map[string]interface{}
+
+// Returns a context holding the time attributes for now and the subject's ip.
+func NewContext(now time.Time, ip string) Context {
+	return Context{
+		"time.weekday": now.Weekday().String(),
+		"time.hour":    float64(now.Hour()),
+		"time.date":    now.Format("2006-01-02"),
+		"time.unix":    float64(now.Unix()),
+		"request.ip":   ip,
+	}
+}
+
+// Add a caller supplied attribute, readable as attr.<name>.
+func (c Context) SetAttr(name, value string) {
+	c["attr."+name] = value
+}
+
+// A parsed condition.
+type Expr struct {
+	src  string
+	root node
+}
+
+// The source the expression was parsed from.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Evaluate the expression. Anything that isn't a boolean result, such as a
+// missing attribute or a type mismatch, is an error.
+func (e *Expr) Eval(ctx Context) (bool, error) {
+	v, err := e.root.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition evaluated to %v, not a boolean", v)
+	}
+	return b, nil
+}
+
+// Parse and check a condition.
+func Parse(src string) (*Expr, error) {
+	if len(src) > MaxLength {
+		return nil, fmt.Errorf("condition is longer than %d characters", MaxLength)
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+
+	return &Expr{src, root}, nil
+}
+
+// Parse and evaluate a condition in one step.
+func Evaluate(src string, ctx Context) (bool, error) {
+	e, err := Parse(src)
+	if err != nil {
+		return false, err
+	}
+	return e.Eval(ctx)
+}
+
+// Built in functions, by name.
+type function struct {
+	arity int
+	call  func(args []interface{}) (interface{}, error)
+}
+
+var functions = map[string]function{
+	// inCidr(ip, "10.0.0.0/8") tells whether an address is in a network.
+	"inCidr": {2, func(args []interface{}) (interface{}, error) {
+		ip, err := asString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		cidr, err := asString(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		// Addresses may come with a port attached
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		addr := net.ParseIP(ip)
+		return addr != nil && network.Contains(addr), nil
+	}},
+	// startsWith(s, prefix)
+	"startsWith": {2, func(args []interface{}) (interface{}, error) {
+		s, err := asString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		prefix, err := asString(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return strings.HasPrefix(s, prefix), nil
+	}},
+	// number(s) converts a caller supplied attribute to a number.
+	"number": {1, func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case float64:
+			return v, nil
+		case string:
+			return strconv.ParseFloat(v, 64)
+		}
+		return nil, fmt.Errorf("cannot convert %v to a number", args[0])
+	}},
+}
+
+func asString(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string but got %v", v)
+	}
+	return s, nil
+}
@@ -0,0 +1,92 @@
+package conditions
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testContext() Context {
+	// A Tuesday afternoon
+	ctx := NewContext(time.Date(2016, time.March, 15, 14, 30, 0, 0, time.UTC), "10.1.2.3:5555")
+	ctx.SetAttr("shift", "morning")
+	ctx.SetAttr("hours", "12")
+	return ctx
+}
+
+func TestEvaluate(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected bool
+	}{
+		{`true`, true},
+		{`!true`, false},
+		{`time.weekday == "Tuesday"`, true},
+		{`!(time.weekday in ["Saturday", "Sunday"])`, true},
+		{`time.hour >= 9 && time.hour < 17`, true},
+		{`time.hour < 9 || time.hour >= 17`, false},
+		{`time.date >= "2016-01-01" && time.date <= '2016-12-31'`, true},
+		{`inCidr(request.ip, "10.0.0.0/8")`, true},
+		{`inCidr(request.ip, "192.168.0.0/16")`, false},
+		{`attr.shift != "night"`, true},
+		{`number(attr.hours) <= 40`, true},
+		{`startsWith(attr.shift, "morn")`, true},
+		{`false && attr.missing == "x"`, false},
+		{`"a \"quoted\" string" == 'a "quoted" string'`, true},
+		{`-1 < 0`, true},
+	}
+
+	ctx := testContext()
+	for _, c := range cases {
+		result, err := Evaluate(c.src, ctx)
+		if err != nil {
+			t.Errorf("Unexpected error evaluating %s: %v", c.src, err)
+			continue
+		}
+		if result != c.expected {
+			t.Errorf("Expected %s to be %v", c.src, c.expected)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	cases := []string{
+		`attr.missing == "x"`,         // unset attribute
+		`time.hour == "14" || true`,   // left side is evaluated first
+		`time.hour < "14"`,            // mismatched types
+		`time.hour`,                   // not a boolean
+		`time.weekday in "Tuesday"`,   // in needs a list
+		`inCidr(request.ip, "bogus")`, // bad network
+	}
+
+	ctx := testContext()
+	for _, src := range cases {
+		if _, err := Evaluate(src, ctx); err == nil {
+			t.Errorf("Expected an error evaluating %s", src)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []string{
+		``,
+		`time.hour >`,
+		`time.hours > 9`,          // unknown server attribute
+		`attr. == "x"`,            // empty attribute name
+		`exec("rm -rf /")`,        // unknown function
+		`inCidr(request.ip)`,      // wrong number of arguments
+		`(time.hour > 9`,          // unbalanced parens
+		`"unterminated`,           // unterminated string
+		`time.hour > 9 time.hour`, // trailing tokens
+		`time.hour # 9`,           // unknown character
+		strings.Repeat("(", 40) + "true" + strings.Repeat(")", 40),
+		strings.Repeat("!", 40) + "true",
+		`attr.x == "` + strings.Repeat("x", MaxLength) + `"`,
+	}
+
+	for _, src := range cases {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("Expected an error parsing %.40s", src)
+		}
+	}
+}
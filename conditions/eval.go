@@ -0,0 +1,209 @@
+package conditions
+
+import (
+	"fmt"
+)
+
+type node interface {
+	eval(ctx Context) (interface{}, error)
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (n *literal) eval(ctx Context) (interface{}, error) {
+	return n.value, nil
+}
+
+type attribute struct {
+	name string
+}
+
+func (n *attribute) eval(ctx Context) (interface{}, error) {
+	v, ok := ctx[n.name]
+	if !ok {
+		return nil, fmt.Errorf("attribute %s is not set", n.name)
+	}
+	return v, nil
+}
+
+type list struct {
+	items []node
+}
+
+func (n *list) eval(ctx Context) (interface{}, error) {
+	values := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+type not struct {
+	x node
+}
+
+func (n *not) eval(ctx Context) (interface{}, error) {
+	v, err := evalBool(n.x, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return !v, nil
+}
+
+type call struct {
+	name string
+	args []node
+}
+
+func (n *call) eval(ctx Context) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return functions[n.name].call(args)
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+func (n *binary) eval(ctx Context) (interface{}, error) {
+	// Logical operators short circuit
+	switch n.op {
+	case "&&", "||":
+		l, err := evalBool(n.left, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		return evalBool(n.right, ctx)
+	}
+
+	l, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r)
+	case "!=":
+		eq, err := equal(l, r)
+		if err != nil {
+			return nil, err
+		}
+		return !eq, nil
+	case "in":
+		items, ok := r.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("the right side of in must be a list, not %v", r)
+		}
+		for _, item := range items {
+			eq, err := equal(l, item)
+			if err != nil {
+				return nil, err
+			}
+			if eq {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return order(n.op, l, r)
+}
+
+func evalBool(n node, ctx Context) (bool, error) {
+	v, err := n.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean but got %v", v)
+	}
+	return b, nil
+}
+
+// Values can only be compared with values of the same type, so that
+// time.hour == "14" is caught rather than quietly false.
+func equal(l, r interface{}) (bool, error) {
+	switch l.(type) {
+	case string:
+		_, ok := r.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare %v and %v", l, r)
+		}
+	case float64:
+		_, ok := r.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare %v and %v", l, r)
+		}
+	case bool:
+		_, ok := r.(bool)
+		if !ok {
+			return false, fmt.Errorf("cannot compare %v and %v", l, r)
+		}
+	default:
+		return false, fmt.Errorf("cannot compare %v", l)
+	}
+	return l == r, nil
+}
+
+// Numbers and strings can be ordered against their own kind.
+func order(op string, l, r interface{}) (bool, error) {
+	var cmp int
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare %v and %v", l, r)
+		}
+		if lv < rv {
+			cmp = -1
+		} else if lv > rv {
+			cmp = 1
+		}
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare %v and %v", l, r)
+		}
+		if lv < rv {
+			cmp = -1
+		} else if lv > rv {
+			cmp = 1
+		}
+	default:
+		return false, fmt.Errorf("cannot order %v", l)
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %s", op)
+}
@@ -0,0 +1,224 @@
+package conditions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Recursive descent parser for
+//
+//	expr    = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = primary [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) primary ]
+//	primary = number | string | "true" | "false" | attribute
+//	        | function "(" [ expr { "," expr } ] ")"
+//	        | "[" [ expr { "," expr } ] "]" | "(" expr ")"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// Consume the operator if it is next.
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q but got %q at %d", op, t.text, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseExpr(depth int) (node, error) {
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("condition is nested more than %d deep", MaxDepth)
+	}
+
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{"||", left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (node, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{"&&", left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary(depth int) (node, error) {
+	if p.accept("!") {
+		if depth+1 > MaxDepth {
+			return nil, fmt.Errorf("condition is nested more than %d deep", MaxDepth)
+		}
+		x, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &not{x}, nil
+	}
+	return p.parseCompare(depth)
+}
+
+func (p *parser) parseCompare(depth int) (node, error) {
+	left, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	op := ""
+	switch {
+	case t.kind == tokOp && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+		op = t.text
+	case t.kind == tokIdent && t.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	return &binary{op, left, right}, nil
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return &literal{f}, nil
+
+	case tokString:
+		return &literal{t.text}, nil
+
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literal{true}, nil
+		case "false":
+			return &literal{false}, nil
+		}
+
+		if p.accept("(") {
+			return p.parseCall(t, depth)
+		}
+
+		if err := checkAttribute(t); err != nil {
+			return nil, err
+		}
+		return &attribute{t.text}, nil
+
+	case tokOp:
+		switch t.text {
+		case "(":
+			x, err := p.parseExpr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "[":
+			items, err := p.parseList("]", depth)
+			if err != nil {
+				return nil, err
+			}
+			return &list{items}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func (p *parser) parseCall(name token, depth int) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at %d", name.text, name.pos)
+	}
+
+	args, err := p.parseList(")", depth)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("%s takes %d arguments but got %d", name.text, fn.arity, len(args))
+	}
+	return &call{name.text, args}, nil
+}
+
+// Parse comma separated expressions up to the closing bracket.
+func (p *parser) parseList(end string, depth int) ([]node, error) {
+	items := make([]node, 0)
+	if p.accept(end) {
+		return items, nil
+	}
+
+	for {
+		item, err := p.parseExpr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		if p.accept(end) {
+			return items, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Attributes must be a known server attribute or a caller supplied attr.*
+// so that typos are caught when the condition is stored.
+func checkAttribute(t token) error {
+	if builtinAttributes[t.text] {
+		return nil
+	}
+	if strings.HasPrefix(t.text, "attr.") && len(t.text) > len("attr.") {
+		return nil
+	}
+	return fmt.Errorf("unknown attribute %q at %d", t.text, t.pos)
+}
@@ -0,0 +1,282 @@
+// Package membership computes the members of rule based groups from set
+// expressions over other groups, such as
+//
+//	group:lab-a and group:night-shift
+//	area:facilities except (group:contractors or group:interns)
+//
+// "group:<guid>" is a group's members and "area:<guid>" everyone who is
+// in at least one group in the area. "and" intersects, "or" unites and
+// "except" removes; "and" binds tighter than the other two, which are
+// applied left to right. Parentheses group.
+package membership
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Where a rule reads the members of the groups and areas it names.
+type Source interface {
+	GroupMembers(guid string) ([]string, error)
+	AreaMembers(area string) ([]string, error)
+}
+
+// A parsed membership rule.
+type Rule struct {
+	src  string
+	root node
+}
+
+type set map[string]bool
+
+type node interface {
+	eval(s Source) (set, error)
+	refs(kind string, out *[]string)
+}
+
+type ref struct {
+	kind string // "group" or "area"
+	id   string
+}
+
+type binary struct {
+	op          string // "and", "or" or "except"
+	left, right node
+}
+
+func (r ref) eval(s Source) (set, error) {
+	var members []string
+	var err error
+	if r.kind == "group" {
+		members, err = s.GroupMembers(r.id)
+	} else {
+		members, err = s.AreaMembers(r.id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(set, len(members))
+	for _, m := range members {
+		result[m] = true
+	}
+	return result, nil
+}
+
+func (r ref) refs(kind string, out *[]string) {
+	if r.kind == kind {
+		*out = append(*out, r.id)
+	}
+}
+
+func (b binary) eval(s Source) (set, error) {
+	left, err := b.left.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	right, err := b.right.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(set)
+	switch b.op {
+	case "and":
+		for m := range left {
+			if right[m] {
+				result[m] = true
+			}
+		}
+	case "or":
+		for m := range left {
+			result[m] = true
+		}
+		for m := range right {
+			result[m] = true
+		}
+	case "except":
+		for m := range left {
+			if !right[m] {
+				result[m] = true
+			}
+		}
+	}
+	return result, nil
+}
+
+func (b binary) refs(kind string, out *[]string) {
+	b.left.refs(kind, out)
+	b.right.refs(kind, out)
+}
+
+// Parse a membership rule.
+func Parse(src string) (*Rule, error) {
+	p := &parser{tokens: tokenize(src)}
+	if len(p.tokens) == 0 {
+		return nil, errors.New("empty rule")
+	}
+
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return &Rule{src, root}, nil
+}
+
+// The rule as it was written.
+func (r *Rule) String() string {
+	return r.src
+}
+
+// The users the rule matches, sorted.
+func (r *Rule) Members(s Source) ([]string, error) {
+	result, err := r.root.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]string, 0, len(result))
+	for m := range result {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// The guids of the groups the rule names.
+func (r *Rule) Groups() []string {
+	groups := make([]string, 0)
+	r.root.refs("group", &groups)
+	return groups
+}
+
+// The guids of the areas the rule names.
+func (r *Rule) Areas() []string {
+	areas := make([]string, 0)
+	r.root.refs("area", &areas)
+	return areas
+}
+
+// The users to add to and remove from current to leave exactly the
+// desired members, each sorted and without duplicates.
+func Diff(current, desired []string) (add, remove []string) {
+	have := make(set)
+	for _, m := range current {
+		have[m] = true
+	}
+	want := make(set)
+	for _, m := range desired {
+		want[m] = true
+	}
+
+	add, remove = make([]string, 0), make([]string, 0)
+	for m := range want {
+		if !have[m] {
+			add = append(add, m)
+		}
+	}
+	for m := range have {
+		if !want[m] {
+			remove = append(remove, m)
+		}
+	}
+	sort.Strings(add)
+	sort.Strings(remove)
+	return add, remove
+}
+
+// Split a rule into parentheses and the words between them.
+func tokenize(src string) []string {
+	tokens := make([]string, 0)
+	for _, field := range strings.Fields(src) {
+		for field != "" {
+			i := strings.IndexAny(field, "()")
+			if i < 0 {
+				tokens = append(tokens, field)
+				break
+			}
+			if i > 0 {
+				tokens = append(tokens, field[:i])
+			}
+			tokens = append(tokens, field[i:i+1])
+			field = field[i+1:]
+		}
+	}
+	return tokens
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// expr := term (("or" | "except") term)*
+func (p *parser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "or" || op == "except"; op = p.peek() {
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op, left, right}
+	}
+	return left, nil
+}
+
+// term := atom ("and" atom)*
+func (p *parser) term() (node, error) {
+	left, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.pos++
+		right, err := p.atom()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{"and", left, right}
+	}
+	return left, nil
+}
+
+// atom := "(" expr ")" | "group:" guid | "area:" guid
+func (p *parser) atom() (node, error) {
+	tok := p.peek()
+	p.pos++
+	switch {
+	case tok == "":
+		return nil, errors.New("unexpected end of rule")
+	case tok == "(":
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return n, nil
+	case strings.HasPrefix(tok, "group:") && len(tok) > len("group:"):
+		return ref{"group", tok[len("group:"):]}, nil
+	case strings.HasPrefix(tok, "area:") && len(tok) > len("area:"):
+		return ref{"area", tok[len("area:"):]}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q, expected group:<guid>, area:<guid> or (", tok)
+}
@@ -0,0 +1,109 @@
+package membership
+
+import (
+	"reflect"
+	"testing"
+)
+
+// An in memory Source.
+type memSource struct {
+	groups map[string][]string
+	areas  map[string][]string
+}
+
+func (m memSource) GroupMembers(guid string) ([]string, error) {
+	return m.groups[guid], nil
+}
+
+func (m memSource) AreaMembers(area string) ([]string, error) {
+	return m.areas[area], nil
+}
+
+var source = memSource{
+	groups: map[string][]string{
+		"lab-a":       {"alice", "bob", "carol"},
+		"night-shift": {"bob", "carol", "dave"},
+		"interns":     {"carol"},
+	},
+	areas: map[string][]string{
+		"facilities": {"alice", "bob", "carol", "dave", "erin"},
+	},
+}
+
+func TestMembers(t *testing.T) {
+	cases := []struct {
+		rule     string
+		expected []string
+	}{
+		{"group:lab-a", []string{"alice", "bob", "carol"}},
+		{"group:lab-a and group:night-shift", []string{"bob", "carol"}},
+		{"group:lab-a or group:night-shift", []string{"alice", "bob", "carol", "dave"}},
+		{"area:facilities except group:night-shift", []string{"alice", "erin"}},
+		{"group:lab-a and group:night-shift except group:interns", []string{"bob"}},
+		{"group:interns or group:lab-a and group:night-shift", []string{"bob", "carol"}},
+		{"(group:interns or group:lab-a) and group:night-shift", []string{"bob", "carol"}},
+		{"area:facilities except (group:lab-a or group:night-shift)", []string{"erin"}},
+		{"group:unknown", []string{}},
+	}
+
+	for _, c := range cases {
+		rule, err := Parse(c.rule)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", c.rule, err)
+			continue
+		}
+		members, err := rule.Members(source)
+		if err != nil {
+			t.Errorf("Unexpected error evaluating %q: %v", c.rule, err)
+			continue
+		}
+		if !reflect.DeepEqual(members, c.expected) {
+			t.Errorf("Expected %q to match %v but got %v", c.rule, c.expected, members)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"lab-a",
+		"group:",
+		"group:lab-a and",
+		"(group:lab-a or group:interns",
+		"group:lab-a group:interns",
+		"group:lab-a xor group:interns",
+	} {
+		if _, err := Parse(rule); err == nil {
+			t.Errorf("Expected %q not to parse", rule)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	rule, err := Parse("area:facilities except (group:lab-a or group:interns)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if groups := rule.Groups(); !reflect.DeepEqual(groups, []string{"lab-a", "interns"}) {
+		t.Errorf("Expected groups lab-a and interns but got %v", groups)
+	}
+	if areas := rule.Areas(); !reflect.DeepEqual(areas, []string{"facilities"}) {
+		t.Errorf("Expected area facilities but got %v", areas)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	add, remove := Diff([]string{"bob", "alice", "carol"}, []string{"dave", "alice", "carol", "dave"})
+	if !reflect.DeepEqual(add, []string{"dave"}) {
+		t.Errorf("Expected to add [dave] but got %v", add)
+	}
+	if !reflect.DeepEqual(remove, []string{"bob"}) {
+		t.Errorf("Expected to remove [bob] but got %v", remove)
+	}
+
+	add, remove = Diff(nil, nil)
+	if len(add) != 0 || len(remove) != 0 {
+		t.Errorf("Expected no changes but got %v and %v", add, remove)
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Decisions are dropped once they expire, checked whenever the cache grows
+// past this many.
+const cacheSweepSize = 10000
+
+type cacheEntry struct {
+	allowed bool
+	expires time.Time
+}
+
+// Recent decisions, safe for concurrent use.
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func newCache() *cache {
+	return &cache{entries: make(map[string]cacheEntry)}
+}
+
+// Identifies a decision by who asked and everything it was checked
+// against.
+func cacheKey(netId string, req Requirement) string {
+	parts := []string{netId, req.Area, req.Verb, req.Resource}
+	names := make([]string, 0, len(req.Attrs))
+	for name := range req.Attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, name+"="+req.Attrs[name])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func (c *cache) get(key string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || timeNow().After(entry.expires) {
+		return false, false
+	}
+	return entry.allowed, true
+}
+
+func (c *cache) put(key string, allowed bool, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := timeNow()
+	if len(c.entries) >= cacheSweepSize {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = cacheEntry{allowed, now.Add(ttl)}
+}
+
+// The enforcer's decisions, made on first use so that an Enforcer that
+// isn't made with New can cache too.
+func (e *Enforcer) decisions() *cache {
+	e.cacheOnce.Do(func() {
+		e.cache = newCache()
+	})
+	return e.cache
+}
+
+// Forget every decision, for example after changing permissions.
+func (e *Enforcer) Flush() {
+	c := e.decisions()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cacheEntry)
+}
+
+// Overridden by tests.
+var timeNow = time.Now
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+
+	eden "github.com/byu-oit-ssengineering/tmt-eden"
+)
+
+// Wraps an eden handler so that it only runs if the user eden authorized
+// has the permission the route needs. The route's path parameters are
+// passed to the extractor.
+func (e *Enforcer) Eden(extract Extractor, next eden.HandlerFunc) eden.HandlerFunc {
+	return func(c *eden.Context) {
+		params := make(map[string]string)
+		for _, p := range c.Params {
+			params[p.Key] = p.Value
+		}
+
+		req, check, err := extract(c.Request, params)
+		if err != nil {
+			c.Respond(400, eden.Response{"ERROR", err.Error()})
+			return
+		}
+		if !check {
+			next(c)
+			return
+		}
+
+		d, err := e.decide(c.Request.Context(), c.User.NetId, c.User.Area, req)
+		if err != nil {
+			c.Respond(500, eden.Response{"ERROR", "An error occurred while checking permission"})
+			return
+		}
+		if !d.Allowed {
+			c.Respond(403, eden.Response{"ERROR", "You don't have permission to do that"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), decisionKey{}, d))
+		next(c)
+	}
+}
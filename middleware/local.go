@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	accessors "github.com/byu-oit-ssengineering/tmt-permissions/accessors"
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
+)
+
+// Checks permissions against the permissions database directly, the way
+// GET /permission does, for services that can reach it. Users of unknown
+// areas are denied.
+type LocalChecker struct {
+	DB *sql.DB
+}
+
+func (l LocalChecker) Check(ctx context.Context, netId, area, verb, resource string, attrs map[string]string) (bool, error) {
+	cctx := conditions.NewContext(time.Now(), "")
+	for name, value := range attrs {
+		cctx.SetAttr(name, value)
+	}
+
+	e, err := accessors.NewPermissionAccessor(l.DB).Explain(netId, area, resource, verb, cctx)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return e.Allowed, err
+}
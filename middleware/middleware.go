@@ -0,0 +1,195 @@
+// Package middleware enforces permissions in front of another service's
+// handlers. Each route is mapped to an Extractor that works out the verb
+// and resource a request needs; the user's permission is checked through
+// a Checker, either the client package or LocalChecker, and the request is
+// refused with a 403 unless it is allowed. Decisions are cached briefly
+// and handlers can read theirs with FromContext.
+//
+// With net/http, register routes on an Enforcer and wrap the mux:
+//
+//	m := middleware.New(c, identify)
+//	m.Handle("GET", "/shifts/:id", middleware.Param("view", "id"))
+//	http.ListenAndServe(":5000", m.Wrap(mux))
+//
+// With eden, wrap each handler, which takes the user from eden's
+// Authorize middleware:
+//
+//	r.GET("/shifts/:id", m.Eden(middleware.Param("view", "id"), a.GetShift))
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Answers whether a user may use verb on a resource in an area, as
+// GET /permission does. *client.Client, *pdp.PDP and LocalChecker are
+// Checkers.
+type Checker interface {
+	Check(ctx context.Context, netId, area, verb, resource string, attrs map[string]string) (bool, error)
+}
+
+// What a request needs. An empty Area means the user's own area. Attrs
+// are attributes conditions on policy rows can read as attr.<name>.
+type Requirement struct {
+	Area     string
+	Verb     string
+	Resource string
+	Attrs    map[string]string
+}
+
+// Works out what a request needs from the request and its path
+// parameters. Returning false lets the request through unchecked; an error
+// refuses it with a 400.
+type Extractor func(r *http.Request, params map[string]string) (Requirement, bool, error)
+
+// Requires verb on a fixed resource.
+func Static(verb, resource string) Extractor {
+	return func(*http.Request, map[string]string) (Requirement, bool, error) {
+		return Requirement{Verb: verb, Resource: resource}, true, nil
+	}
+}
+
+// Requires verb on the resource named by a path parameter.
+func Param(verb, name string) Extractor {
+	return func(r *http.Request, params map[string]string) (Requirement, bool, error) {
+		return Requirement{Verb: verb, Resource: params[name]}, true, nil
+	}
+}
+
+// Lets every request through, for routes such as health checks.
+func Public() Extractor {
+	return func(*http.Request, map[string]string) (Requirement, bool, error) {
+		return Requirement{}, false, nil
+	}
+}
+
+// Identifies the user making a request and the area they're working in.
+// Returning false refuses the request with a 401.
+type IdentifyFunc func(r *http.Request) (netId, area string, ok bool)
+
+// The outcome of a permission check, available to handlers through
+// FromContext.
+type Decision struct {
+	NetId    string
+	Area     string
+	Verb     string
+	Resource string
+	Allowed  bool
+	Cached   bool
+}
+
+type decisionKey struct{}
+
+// Gets the decision the middleware made for a request. ok is false for
+// requests that weren't checked.
+func FromContext(ctx context.Context) (Decision, bool) {
+	d, ok := ctx.Value(decisionKey{}).(Decision)
+	return d, ok
+}
+
+// Checks requests against the permissions their routes need.
+type Enforcer struct {
+	Checker  Checker
+	Identify IdentifyFunc  // used by Wrap; Eden takes the user from eden
+	TTL      time.Duration // how long decisions are cached; 0 disables the cache
+
+	routes    []route
+	cache     *cache
+	cacheOnce sync.Once
+}
+
+// Returns a new enforcer that caches decisions for 30 seconds.
+func New(checker Checker, identify IdentifyFunc) *Enforcer {
+	return &Enforcer{Checker: checker, Identify: identify, TTL: 30 * time.Second}
+}
+
+// Maps a route to what it needs. Patterns are paths whose segments may be
+// :name, matching any one segment, or a final *name, matching the rest of
+// the path. Routes are tried in the order they were added.
+func (e *Enforcer) Handle(method, pattern string, extract Extractor) {
+	e.routes = append(e.routes, newRoute(method, pattern, extract))
+}
+
+// Check a requirement for a user, or take the decision from the cache.
+func (e *Enforcer) decide(ctx context.Context, netId, area string, req Requirement) (Decision, error) {
+	if req.Area == "" {
+		req.Area = area
+	}
+
+	d := Decision{NetId: netId, Area: req.Area, Verb: req.Verb, Resource: req.Resource}
+	key := cacheKey(netId, req)
+	if e.TTL > 0 {
+		if allowed, ok := e.decisions().get(key); ok {
+			d.Allowed, d.Cached = allowed, true
+			return d, nil
+		}
+	}
+
+	allowed, err := e.Checker.Check(ctx, netId, req.Area, req.Verb, req.Resource, req.Attrs)
+	if err != nil {
+		return d, err
+	}
+	d.Allowed = allowed
+	if e.TTL > 0 {
+		e.decisions().put(key, allowed, e.TTL)
+	}
+	return d, nil
+}
+
+// Wraps a net/http handler so that requests only reach it if the user has
+// the permission their route needs. Requests matching no route are
+// refused; map them to Public to let them through.
+func (e *Enforcer) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		extract, params, ok := e.match(r)
+		if !ok {
+			writeError(w, 403, "No permission is mapped to this route")
+			return
+		}
+
+		req, check, err := extract(r, params)
+		if err != nil {
+			writeError(w, 400, err.Error())
+			return
+		}
+		if !check {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var netId, area string
+		if e.Identify != nil {
+			netId, area, ok = e.Identify(r)
+		}
+		if !ok {
+			writeError(w, 401, "Unable to identify the user")
+			return
+		}
+
+		d, err := e.decide(r.Context(), netId, area, req)
+		if err != nil {
+			writeError(w, 500, "An error occurred while checking permission")
+			return
+		}
+		if !d.Allowed {
+			writeError(w, 403, "You don't have permission to do that")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), decisionKey{}, d)))
+	})
+}
+
+// Writes an error the way eden responds with one.
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Status string
+		Data   string
+	}{"ERROR", msg})
+}
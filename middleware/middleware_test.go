@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// A checker allowing the permissions it's given, counting its calls.
+type fakeChecker struct {
+	allowed map[string]bool
+	err     error
+	calls   int
+}
+
+func (f *fakeChecker) Check(ctx context.Context, netId, area, verb, resource string, attrs map[string]string) (bool, error) {
+	f.calls++
+	return f.allowed[netId+" "+area+" "+verb+" "+resource], f.err
+}
+
+// Identifies users by the X-User header, all in area1.
+func identify(r *http.Request) (string, string, bool) {
+	netId := r.Header.Get("X-User")
+	return netId, "area1", netId != ""
+}
+
+// Serve a request through the enforcer, returning the response code and the
+// decision the handler saw.
+func serve(e *Enforcer, method, path, user string) (int, Decision) {
+	var d Decision
+	handler := e.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, _ = FromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(method, path, nil)
+	if user != "" {
+		r.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code, d
+}
+
+func TestWrap(t *testing.T) {
+	checker := &fakeChecker{allowed: map[string]bool{"alice area1 view shift1": true}}
+	e := New(checker, identify)
+	e.Handle("GET", "/shifts/:id", Param("view", "id"))
+	e.Handle("GET", "/health", Public())
+
+	code, d := serve(e, "GET", "/shifts/shift1", "alice")
+	if code != 200 || d != (Decision{NetId: "alice", Area: "area1", Verb: "view", Resource: "shift1", Allowed: true}) {
+		t.Errorf("Expected alice to be allowed but got %d, %v", code, d)
+	}
+	if code, _ := serve(e, "GET", "/shifts/shift1", "bob"); code != 403 {
+		t.Errorf("Expected bob to be denied but got %d", code)
+	}
+	if code, _ := serve(e, "GET", "/shifts/shift1", ""); code != 401 {
+		t.Errorf("Expected an unidentified user to get a 401 but got %d", code)
+	}
+	if code, _ := serve(e, "DELETE", "/shifts/shift1", "alice"); code != 403 {
+		t.Errorf("Expected an unmapped route to be refused but got %d", code)
+	}
+	if code, d := serve(e, "GET", "/health", ""); code != 200 || d != (Decision{}) {
+		t.Errorf("Expected a public route to be let through unchecked but got %d, %v", code, d)
+	}
+	if checker.calls != 2 {
+		t.Errorf("Expected 2 checks but got %d", checker.calls)
+	}
+}
+
+func TestCache(t *testing.T) {
+	checker := &fakeChecker{allowed: map[string]bool{"alice area1 view shift1": true}}
+	e := New(checker, identify)
+	e.Handle("GET", "/shifts/:id", Param("view", "id"))
+
+	serve(e, "GET", "/shifts/shift1", "alice")
+	if code, d := serve(e, "GET", "/shifts/shift1", "alice"); code != 200 || !d.Cached || checker.calls != 1 {
+		t.Errorf("Expected the second decision to be cached but got %d, %v after %d checks", code, d, checker.calls)
+	}
+
+	e.Flush()
+	if _, d := serve(e, "GET", "/shifts/shift1", "alice"); d.Cached || checker.calls != 2 {
+		t.Errorf("Expected a flushed decision to be checked again but got %v after %d checks", d, checker.calls)
+	}
+
+	e.TTL = 0
+	serve(e, "GET", "/shifts/shift1", "alice")
+	serve(e, "GET", "/shifts/shift1", "alice")
+	if checker.calls != 4 {
+		t.Errorf("Expected every request to be checked without a TTL but got %d checks", checker.calls)
+	}
+}
+
+func TestEnforcerLiteral(t *testing.T) {
+	checker := &fakeChecker{allowed: map[string]bool{"alice area1 view shift1": true}}
+	e := &Enforcer{Checker: checker, Identify: identify, TTL: time.Minute}
+	e.Handle("GET", "/shifts/:id", Param("view", "id"))
+
+	serve(e, "GET", "/shifts/shift1", "alice")
+	if code, d := serve(e, "GET", "/shifts/shift1", "alice"); code != 200 || !d.Cached || checker.calls != 1 {
+		t.Errorf("Expected the second decision to be cached but got %d, %v after %d checks", code, d, checker.calls)
+	}
+}
+
+func TestCheckerError(t *testing.T) {
+	e := New(&fakeChecker{err: errors.New("unavailable")}, identify)
+	e.Handle("GET", "/shifts", Static("view", "shifts"))
+
+	if code, _ := serve(e, "GET", "/shifts", "alice"); code != 500 {
+		t.Errorf("Expected a 500 but got %d", code)
+	}
+}
+
+func TestRouteMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		params        map[string]string
+	}{
+		{"/shifts/:id", "/shifts/shift1", map[string]string{"id": "shift1"}},
+		{"/shifts/:id", "/shifts/shift1/", map[string]string{"id": "shift1"}},
+		{"/shifts/:id", "/shifts", nil},
+		{"/shifts/:id", "/shifts/shift1/notes", nil},
+		{"/files/*path", "/files/a/b/c", map[string]string{"path": "a/b/c"}},
+		{"/areas/:area/groups", "/areas/area1/groups", map[string]string{"area": "area1"}},
+		{"/areas/:area/groups", "/areas/area1/admins", nil},
+	}
+
+	for _, test := range tests {
+		params, ok := newRoute("GET", test.pattern, nil).match("GET", test.path)
+		if ok != (test.params != nil) || len(params) != len(test.params) {
+			t.Errorf("Expected %s to match %s with %v but got %v, %v", test.path, test.pattern, test.params, params, ok)
+			continue
+		}
+		for name, value := range test.params {
+			if params[name] != value {
+				t.Errorf("Expected %s to be %q in %s but got %q", name, value, test.path, params[name])
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// A route mapped to what it needs.
+type route struct {
+	method   string
+	segments []string
+	extract  Extractor
+}
+
+func newRoute(method, pattern string, extract Extractor) route {
+	return route{method, strings.Split(strings.Trim(pattern, "/"), "/"), extract}
+}
+
+// Matches a path against the route and returns its parameters.
+func (rt route) match(method, path string) (map[string]string, bool) {
+	if rt.method != method {
+		return nil, false
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	params := make(map[string]string)
+	for i, s := range rt.segments {
+		if strings.HasPrefix(s, "*") {
+			params[s[1:]] = strings.Join(segments[i:], "/")
+			return params, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(s, ":") && segments[i] != "":
+			params[s[1:]] = segments[i]
+		case s != segments[i]:
+			return nil, false
+		}
+	}
+	if len(segments) != len(rt.segments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// Finds the first route a request matches.
+func (e *Enforcer) match(r *http.Request) (Extractor, map[string]string, bool) {
+	for _, rt := range e.routes {
+		if params, ok := rt.match(r.Method, r.URL.Path); ok {
+			return rt.extract, params, true
+		}
+	}
+	return nil, nil, false
+}
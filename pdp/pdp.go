@@ -0,0 +1,167 @@
+// Package pdp makes permission decisions: whether a user may use a verb on
+// a resource in an area. The service decides GET /permission with it, and
+// services that can't afford a request per check can run it in-process
+// against a Snapshot of the service's data, getting the same decisions:
+//
+//	p := pdp.New(snapshot)
+//	go p.Sync(ctx, client, time.Minute, nil)
+//	allowed, err := p.Check(ctx, netId, area, "edit", resource, nil)
+//
+// A user is allowed if they are a superuser, an admin of the area or one
+// of its ancestors, or one of their actors holds the permission. Their
+// actors are their groups in the area, the areas whose policy rows apply
+// in it and the user themselves. An actor holds a permission through a
+// policy row whose condition, if any, holds, or through a role of the area
+// bound to it whose pattern matches the resource.
+package pdp
+
+import (
+	"path"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
+)
+
+// An area and where it sits in the hierarchy. Parent is empty for a top
+// level area; the parent's area level policy rows apply in this area when
+// Inherit is set.
+type Area struct {
+	Guid    string
+	Parent  string
+	Inherit bool
+}
+
+// A user who administers an area and every area below it.
+type Admin struct {
+	NetId string
+	Area  string
+}
+
+// A user in a group, directly or through a group rule, and the group's
+// area.
+type Member struct {
+	Group string
+	Area  string
+	NetId string
+}
+
+// A policy row. Condition is empty when the row always applies. Area is
+// set for rows granted to a user for one area, such as by approving an
+// access request, and empty for rows that apply wherever the actor does.
+type Policy struct {
+	Actor     string
+	Verb      string
+	Resource  string
+	Condition string
+	Area      string
+}
+
+// A verb and resource pattern an actor holds through a role bound to it.
+// Area is the role's area, the only area the binding applies in.
+type Binding struct {
+	Actor    string
+	Verb     string
+	Resource string
+	Area     string
+}
+
+// A policy row or role binding through which an actor holds a permission.
+// Resource is the role's pattern for role bindings. Applies is false when
+// the policy row's condition doesn't hold.
+type Grant struct {
+	Actor     string
+	Source    string // "policy" or "role"
+	Resource  string
+	Condition string
+	Applies   bool
+}
+
+// Whether a user has a permission and why. Actors are the user's groups,
+// the areas whose policy rows apply and the user.
+type Decision struct {
+	Allowed   bool
+	Superuser bool
+	Admin     bool
+	Actors    []string
+	Grants    []Grant
+}
+
+// Tells whether a role's resource pattern covers a resource. Patterns are
+// matched with path.Match, so "shifts/*" covers "shifts/monday".
+func MatchResource(pattern, resource string) bool {
+	if pattern == resource {
+		return true
+	}
+
+	ok, err := path.Match(pattern, resource)
+	return err == nil && ok
+}
+
+// The areas whose admins are admins of area: the area itself and every
+// ancestor in its lineage, which starts with the area.
+func AdminAreas(area string, lineage []Area) []string {
+	areas := []string{area}
+	for i := 1; i < len(lineage); i++ {
+		areas = append(areas, lineage[i].Guid)
+	}
+	return areas
+}
+
+// The areas whose area level policy rows apply in area: the area itself,
+// then each ancestor for as long as the area below it inherits.
+func PolicyAreas(area string, lineage []Area) []string {
+	areas := []string{area}
+	for i := 1; i < len(lineage) && lineage[i-1].Inherit; i++ {
+		areas = append(areas, lineage[i].Guid)
+	}
+	return areas
+}
+
+// The actors a user's permissions in an area are checked for: their
+// groups in the area, the policy areas and the user.
+func Actors(netId, area string, groups []string, lineage []Area) []string {
+	actors := make([]string, 0, len(groups)+len(lineage)+1)
+	actors = append(actors, groups...)
+	actors = append(actors, PolicyAreas(area, lineage)...)
+	return append(actors, netId)
+}
+
+// Decides whether a user with the given actors may use verb on a resource
+// in an area, with conditions evaluated against ctx. Policy rows and
+// bindings for other actors, verbs, resources or areas are ignored, so
+// callers can pass more than they need. A condition that fails to evaluate, for example because
+// an attribute is missing, does not apply.
+func Decide(superuser, admin bool, area string, actors []string, policies []Policy, bindings []Binding, verb, resource string, ctx conditions.Context) Decision {
+	d := Decision{Superuser: superuser, Admin: admin, Actors: actors, Grants: make([]Grant, 0)}
+	if d.Actors == nil {
+		d.Actors = make([]string, 0)
+	}
+
+	isActor := make(map[string]bool, len(actors))
+	for _, actor := range actors {
+		isActor[actor] = true
+	}
+
+	for _, p := range policies {
+		if !isActor[p.Actor] || p.Verb != verb || p.Resource != resource || (p.Area != "" && p.Area != area) {
+			continue
+		}
+		g := Grant{Actor: p.Actor, Source: "policy", Resource: resource, Condition: p.Condition, Applies: p.Condition == ""}
+		if !g.Applies {
+			holds, err := conditions.Evaluate(g.Condition, ctx)
+			g.Applies = err == nil && holds
+		}
+		d.Grants = append(d.Grants, g)
+	}
+
+	for _, b := range bindings {
+		if isActor[b.Actor] && b.Area == area && b.Verb == verb && MatchResource(b.Resource, resource) {
+			d.Grants = append(d.Grants, Grant{Actor: b.Actor, Source: "role", Resource: b.Resource, Applies: true})
+		}
+	}
+
+	d.Allowed = d.Superuser || d.Admin
+	for _, g := range d.Grants {
+		d.Allowed = d.Allowed || g.Applies
+	}
+	return d
+}
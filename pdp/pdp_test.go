@@ -0,0 +1,219 @@
+package pdp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
+)
+
+// A campus with a facilities area whose policy rows apply in the lab below
+// it, and a desk group in the lab.
+var snapshot = Snapshot{
+	Areas: []Area{
+		{Guid: "campus"},
+		{Guid: "facilities", Parent: "campus"},
+		{Guid: "lab", Parent: "facilities", Inherit: true},
+	},
+	Superusers: []string{"root"},
+	Admins:     []Admin{{NetId: "dana", Area: "facilities"}},
+	Members: []Member{
+		{Group: "desk", Area: "lab", NetId: "alice"},
+		{Group: "desk", Area: "lab", NetId: "alice"},
+		{Group: "night", Area: "lab", NetId: "bob"},
+	},
+	Policies: []Policy{
+		{Actor: "desk", Verb: "edit", Resource: "shifts"},
+		{Actor: "night", Verb: "edit", Resource: "shifts", Condition: "time.hour >= 20"},
+		{Actor: "facilities", Verb: "view", Resource: "keys"},
+		{Actor: "carol", Verb: "edit", Resource: "shifts"},
+		{Actor: "erin", Verb: "edit", Resource: "shifts", Area: "lab"},
+	},
+	Bindings: []Binding{
+		{Actor: "desk", Verb: "view", Resource: "reports/*", Area: "lab"},
+		{Actor: "facilities", Verb: "view", Resource: "badges/*", Area: "facilities"},
+	},
+}
+
+func TestExplain(t *testing.T) {
+	p := New(snapshot)
+	noon := conditions.NewContext(time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC), "")
+
+	tests := []struct {
+		netId, area, verb, resource string
+		allowed                     bool
+	}{
+		{"alice", "lab", "edit", "shifts", true},
+		{"alice", "lab", "view", "reports/monday", true},
+		{"alice", "lab", "view", "reports", false},
+		{"alice", "lab", "view", "keys", true},
+		{"alice", "lab", "view", "badges/front", false},
+		{"alice", "facilities", "edit", "shifts", false},
+		{"bob", "lab", "edit", "shifts", false},
+		{"carol", "lab", "edit", "shifts", true},
+		{"erin", "lab", "edit", "shifts", true},
+		{"erin", "facilities", "edit", "shifts", false},
+		{"dana", "lab", "delete", "anything", true},
+		{"dana", "campus", "delete", "anything", false},
+		{"root", "campus", "delete", "anything", true},
+	}
+
+	for _, test := range tests {
+		d, err := p.Explain(test.netId, test.area, test.verb, test.resource, noon)
+		if err != nil || d.Allowed != test.allowed {
+			t.Errorf("Expected %s to use %s on %s in %s to be %v but got %v, %v", test.netId, test.verb, test.resource, test.area, test.allowed, d.Allowed, err)
+		}
+	}
+}
+
+func TestExplainGrants(t *testing.T) {
+	p := New(snapshot)
+	evening := conditions.NewContext(time.Date(2016, 3, 1, 21, 0, 0, 0, time.UTC), "")
+
+	d, err := p.Explain("bob", "lab", "edit", "shifts", evening)
+	expected := Decision{
+		Allowed: true,
+		Actors:  []string{"night", "lab", "facilities", "bob"},
+		Grants:  []Grant{{Actor: "night", Source: "policy", Resource: "shifts", Condition: "time.hour >= 20", Applies: true}},
+	}
+	if err != nil || !reflect.DeepEqual(d, expected) {
+		t.Errorf("Expected %v but got %v, %v", expected, d, err)
+	}
+
+	// alice is listed in desk twice but is only checked for it once
+	d, _ = p.Explain("alice", "lab", "view", "reports/monday", evening)
+	expected = Decision{
+		Allowed: true,
+		Actors:  []string{"desk", "lab", "facilities", "alice"},
+		Grants:  []Grant{{Actor: "desk", Source: "role", Resource: "reports/*", Applies: true}},
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("Expected %v but got %v", expected, d)
+	}
+}
+
+func TestUnknownArea(t *testing.T) {
+	allowed, err := New(snapshot).Check(context.Background(), "root", "nowhere", "edit", "shifts", nil)
+	if err != ErrUnknownArea || allowed {
+		t.Errorf("Expected ErrUnknownArea but got %v, %v", allowed, err)
+	}
+}
+
+func TestCheckAttrs(t *testing.T) {
+	p := New(Snapshot{
+		Areas:    []Area{{Guid: "lab"}},
+		Policies: []Policy{{Actor: "alice", Verb: "edit", Resource: "shifts", Condition: `attr.region == "west"`}},
+	})
+
+	if allowed, err := p.Check(context.Background(), "alice", "lab", "edit", "shifts", map[string]string{"region": "west"}); err != nil || !allowed {
+		t.Errorf("Expected the condition to hold but got %v, %v", allowed, err)
+	}
+	if allowed, err := p.Check(context.Background(), "alice", "lab", "edit", "shifts", nil); err != nil || allowed {
+		t.Errorf("Expected a missing attribute not to apply but got %v, %v", allowed, err)
+	}
+}
+
+// A source handing out snapshots in turn, then failing.
+type fakeSource struct {
+	snapshots []Snapshot
+}
+
+func (f *fakeSource) Snapshot(ctx context.Context) (Snapshot, error) {
+	if len(f.snapshots) == 0 {
+		return Snapshot{}, errors.New("unavailable")
+	}
+	s := f.snapshots[0]
+	f.snapshots = f.snapshots[1:]
+	return s, nil
+}
+
+func TestRefresh(t *testing.T) {
+	taken := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+	src := &fakeSource{[]Snapshot{{Taken: taken, Areas: []Area{{Guid: "lab"}}, Superusers: []string{"alice"}}}}
+	p := New(Snapshot{})
+
+	if err := p.Refresh(context.Background(), src); err != nil || !p.Taken().Equal(taken) {
+		t.Fatalf("Expected the snapshot to be loaded but got %v taken %v", err, p.Taken())
+	}
+	if allowed, _ := p.Check(context.Background(), "alice", "lab", "edit", "shifts", nil); !allowed {
+		t.Errorf("Expected alice to be a superuser in the new snapshot")
+	}
+
+	// A failed refresh keeps the last snapshot
+	if err := p.Refresh(context.Background(), src); err == nil || !p.Taken().Equal(taken) {
+		t.Errorf("Expected the refresh to fail and keep the snapshot but got %v taken %v", err, p.Taken())
+	}
+}
+
+func TestSync(t *testing.T) {
+	src := &fakeSource{[]Snapshot{{Areas: []Area{{Guid: "lab"}}}}}
+	p := New(Snapshot{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	failures := make(chan error, 10)
+	done := make(chan error)
+	go func() { done <- p.Sync(ctx, src, time.Millisecond, func(err error) { failures <- err }) }()
+
+	<-failures
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected Sync to stop when cancelled but got %v", err)
+	}
+	if _, err := p.Check(context.Background(), "alice", "lab", "edit", "shifts", nil); err != nil {
+		t.Errorf("Expected the first snapshot to have been loaded but got %v", err)
+	}
+}
+
+// A feed that reports each of latest in turn, then waits for ctx.
+type fakeFeed struct {
+	latest []int64
+	seen   chan int64
+}
+
+func (f *fakeFeed) Changes(ctx context.Context, since int64) (int64, error) {
+	f.seen <- since
+	if len(f.latest) == 0 {
+		<-ctx.Done()
+		return since, ctx.Err()
+	}
+	n := f.latest[0]
+	f.latest = f.latest[1:]
+	if n < 0 {
+		return since, errors.New("feed unavailable")
+	}
+	return n, nil
+}
+
+func TestWatch(t *testing.T) {
+	watchRetry = time.Millisecond
+	src := &fakeSource{[]Snapshot{{Seq: 7, Areas: []Area{{Guid: "lab"}}}}}
+	feed := &fakeFeed{latest: []int64{3, -1, 7}, seen: make(chan int64, 10)}
+	p := New(Snapshot{Seq: 3})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	failures := make(chan error, 10)
+	done := make(chan error)
+	go func() { done <- p.Watch(ctx, src, feed, func(err error) { failures <- err }) }()
+
+	// No changes, then a failure that is retried, then a change that
+	// loads the snapshot, which is followed from its own seq
+	for _, expected := range []int64{3, 3, 3, 7} {
+		if since := <-feed.seen; since != expected {
+			t.Fatalf("Expected the feed to be read from %d but got %d", expected, since)
+		}
+	}
+	if err := <-failures; err == nil || err.Error() != "feed unavailable" {
+		t.Errorf("Expected the feed failure to be reported but got %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected Watch to stop when cancelled but got %v", err)
+	}
+	if p.Seq() != 7 {
+		t.Errorf("Expected the snapshot at seq 7 to be loaded but got %d", p.Seq())
+	}
+}
@@ -0,0 +1,190 @@
+package pdp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/byu-oit-ssengineering/tmt-permissions/conditions"
+)
+
+// Returned for checks in an area the snapshot doesn't have, which the
+// service answers with a 400.
+var ErrUnknownArea = errors.New("pdp: no such area")
+
+// Everything decisions are made from, as served by GET /permission/snapshot.
+// Superusers are only the active ones; Bindings are the permissions of the
+// roles bound to each actor. Seq is the latest event in the change feed the
+// snapshot includes.
+type Snapshot struct {
+	Taken      time.Time
+	Seq        int64
+	Areas      []Area
+	Superusers []string
+	Admins     []Admin
+	Members    []Member
+	Policies   []Policy
+	Bindings   []Binding
+}
+
+// A snapshot indexed for decisions.
+type index struct {
+	taken      time.Time
+	seq        int64
+	areas      map[string]Area
+	superusers map[string]bool
+	admins     map[string]map[string]bool     // netId to areas
+	groups     map[string]map[string][]string // netId to area to groups
+	policies   map[string][]Policy            // verb and resource to rows
+	bindings   map[string][]Binding           // actor to bindings
+}
+
+func policyKey(verb, resource string) string {
+	return verb + "\x00" + resource
+}
+
+func newIndex(s Snapshot) *index {
+	idx := &index{
+		taken:      s.Taken,
+		seq:        s.Seq,
+		areas:      make(map[string]Area, len(s.Areas)),
+		superusers: make(map[string]bool, len(s.Superusers)),
+		admins:     make(map[string]map[string]bool),
+		groups:     make(map[string]map[string][]string),
+		policies:   make(map[string][]Policy),
+		bindings:   make(map[string][]Binding),
+	}
+
+	for _, a := range s.Areas {
+		idx.areas[a.Guid] = a
+	}
+	for _, netId := range s.Superusers {
+		idx.superusers[netId] = true
+	}
+	for _, a := range s.Admins {
+		if idx.admins[a.NetId] == nil {
+			idx.admins[a.NetId] = make(map[string]bool)
+		}
+		idx.admins[a.NetId][a.Area] = true
+	}
+
+	// Members come from both groupMembers and groupRuleMember, so a user
+	// can be listed in a group twice
+	seen := make(map[Member]bool, len(s.Members))
+	for _, m := range s.Members {
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		if idx.groups[m.NetId] == nil {
+			idx.groups[m.NetId] = make(map[string][]string)
+		}
+		idx.groups[m.NetId][m.Area] = append(idx.groups[m.NetId][m.Area], m.Group)
+	}
+
+	for _, p := range s.Policies {
+		key := policyKey(p.Verb, p.Resource)
+		idx.policies[key] = append(idx.policies[key], p)
+	}
+	for _, b := range s.Bindings {
+		idx.bindings[b.Actor] = append(idx.bindings[b.Actor], b)
+	}
+	return idx
+}
+
+// The area and its ancestors, nearest first, stopping at a parent the
+// snapshot doesn't have.
+func (idx *index) lineage(guid string) []Area {
+	lineage := make([]Area, 0)
+	seen := make(map[string]bool)
+	for guid != "" && !seen[guid] {
+		seen[guid] = true
+
+		a, ok := idx.areas[guid]
+		if !ok {
+			break
+		}
+		lineage = append(lineage, a)
+		guid = a.Parent
+	}
+	return lineage
+}
+
+func (idx *index) decide(netId, area, verb, resource string, ctx conditions.Context) (Decision, error) {
+	lineage := idx.lineage(area)
+	if len(lineage) == 0 {
+		return Decision{Actors: make([]string, 0), Grants: make([]Grant, 0)}, ErrUnknownArea
+	}
+
+	admin := false
+	for _, a := range AdminAreas(area, lineage) {
+		admin = admin || idx.admins[netId][a]
+	}
+
+	actors := Actors(netId, area, idx.groups[netId][area], lineage)
+	bindings := make([]Binding, 0)
+	for _, actor := range actors {
+		bindings = append(bindings, idx.bindings[actor]...)
+	}
+
+	return Decide(idx.superusers[netId], admin, area, actors, idx.policies[policyKey(verb, resource)], bindings, verb, resource, ctx), nil
+}
+
+// Makes decisions in-process from a snapshot, which can be replaced while
+// it's in use. Safe for concurrent use.
+type PDP struct {
+	mu  sync.RWMutex
+	idx *index
+}
+
+// Returns a PDP deciding from a snapshot.
+func New(s Snapshot) *PDP {
+	return &PDP{idx: newIndex(s)}
+}
+
+// Replaces the snapshot decisions are made from.
+func (p *PDP) Load(s Snapshot) {
+	idx := newIndex(s)
+	p.mu.Lock()
+	p.idx = idx
+	p.mu.Unlock()
+}
+
+// When the snapshot decisions are made from was taken.
+func (p *PDP) Taken() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.idx.taken
+}
+
+// The latest event in the change feed the snapshot decisions are made from
+// includes.
+func (p *PDP) Seq() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.idx.seq
+}
+
+// Explains whether a user may use verb on a resource in an area, with
+// conditions evaluated against ctx, as GET /permission/explain does.
+// Returns ErrUnknownArea for an area the snapshot doesn't have.
+func (p *PDP) Explain(netId, area, verb, resource string, ctx conditions.Context) (Decision, error) {
+	p.mu.RLock()
+	idx := p.idx
+	p.mu.RUnlock()
+	return idx.decide(netId, area, verb, resource, ctx)
+}
+
+// Tells whether a user may use verb on a resource in an area, as
+// GET /permission does. Conditions are evaluated at the current time
+// against attrs. A PDP can be used as a middleware Checker.
+func (p *PDP) Check(ctx context.Context, netId, area, verb, resource string, attrs map[string]string) (bool, error) {
+	cctx := conditions.NewContext(time.Now(), "")
+	for name, value := range attrs {
+		cctx.SetAttr(name, value)
+	}
+
+	d, err := p.Explain(netId, area, verb, resource, cctx)
+	return d.Allowed, err
+}
@@ -0,0 +1,84 @@
+package pdp
+
+import (
+	"context"
+	"time"
+)
+
+// Where a PDP gets fresh snapshots. *client.Client is a Source.
+type Source interface {
+	Snapshot(ctx context.Context) (Snapshot, error)
+}
+
+// Loads a fresh snapshot from src. The current one is kept if it fails.
+func (p *PDP) Refresh(ctx context.Context, src Source) error {
+	s, err := src.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	p.Load(s)
+	return nil
+}
+
+// Refreshes from src every interval until ctx is done, passing failures to
+// onError if it isn't nil. Decisions keep being made from the last good
+// snapshot while refreshes fail, so check Taken to bound how stale they
+// can be.
+func (p *PDP) Sync(ctx context.Context, src Source, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := p.Refresh(ctx, src); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Where a PDP learns that permissions changed. *client.Client is a Feed.
+type Feed interface {
+	// Waits a while for events after since, returning the latest one, or
+	// since if there were none.
+	Changes(ctx context.Context, since int64) (int64, error)
+}
+
+// How long Watch waits after a failure before trying again.
+var watchRetry = time.Second
+
+// Follows feed until ctx is done, loading a fresh snapshot from src
+// whenever there are changes after the one decisions are made from, and
+// passing failures to onError if it isn't nil. Unlike Sync, decisions are
+// only stale for as long as a change takes to reach the feed. Load a
+// snapshot first so there is somewhere to follow the feed from.
+func (p *PDP) Watch(ctx context.Context, src Source, feed Feed, onError func(error)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		latest, err := feed.Changes(ctx, p.Seq())
+		if err == nil && latest > p.Seq() {
+			err = p.Refresh(ctx, src)
+		}
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchRetry):
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	eden "github.com/byu-oit-ssengineering/tmt-eden"
 	apis "github.com/byu-oit-ssengineering/tmt-permissions/apis"
@@ -29,11 +30,26 @@ func main() {
 	// Permissions
 	r.GET("/permission", a.CheckPermission)
 	r.GET("/permission/verbs/:resourceGUID/:verb", a.GetGroupsByVerb)
+	r.GET("/permission/who", a.WhoCan)
+	r.GET("/permission/explain", a.ExplainPermission)
+	r.GET("/permission/snapshot", a.GetSnapshot)
 	r.GET("/permission/groups/:group", a.GetGroupPermissions)
 	r.GET("/permission/user/:netId/:area", a.GetUserPermissions)
 	r.POST("/permission", a.AddPermission)
+	r.POST("/permission/bulk", a.BulkPermissions)
 	r.DELETE("/permission/:actor/:verb/:resource", a.DeletePermission)
 
+	// Change feed
+	r.GET("/events", a.GetEvents)
+
+	// Relationship tuples
+	r.GET("/relations/check", a.CheckRelation)
+	r.GET("/relations/expand", a.ExpandRelation)
+	r.GET("/relations/namespaces/:name", a.GetNamespace)
+	r.PUT("/relations/namespaces/:name", a.SetNamespace)
+	r.POST("/relations", a.AddRelation)
+	r.DELETE("/relations/:tuple", a.DeleteRelation)
+
 	// Admin/Superuser
 	r.GET("/admin", a.GetAdmins)
 	r.GET("/admin/:netId/:area", a.IsAdmin)
@@ -46,24 +62,90 @@ func main() {
 	r.PUT("/superuser/:netId", a.Elevate)
 	r.DELETE("/superuser/:netId", a.DeleteSU)
 
+	// Two-person approval of sensitive grants
+	r.GET("/approvals", a.GetPendingApprovals)
+	r.POST("/approvals/:guid/approve", a.ApproveRequest)
+	r.POST("/approvals/:guid/reject", a.RejectRequest)
+	r.POST("/sensitive", a.MarkSensitive)
+	r.DELETE("/sensitive", a.UnmarkSensitive)
+
+	// Separation of duties
+	r.GET("/sod", a.GetSodRules)
+	r.GET("/sod/violations", a.GetSodViolations)
+	r.POST("/sod", a.AddSodRule)
+	r.DELETE("/sod/:guid", a.DeleteSodRule)
+
+	// Areas
+	r.GET("/areas", a.GetAreas)
+	r.GET("/areas/:area", a.GetArea)
+	r.POST("/areas", a.CreateArea)
+	r.PUT("/areas/:area", a.UpdateArea)
+	r.DELETE("/areas/:area", a.DecommissionArea)
+	r.GET("/areas/:area/export", a.ExportArea)
+	r.POST("/areas/:area/import", a.ImportArea)
+	r.POST("/areas/:area/plan", a.PlanArea)
+	r.POST("/areas/:area/apply", a.ApplyArea)
+
 	// Groups
 	r.GET("/groups/:guid", a.GetGroup)
-	r.GET("/groups", a.GetGroupsByArea)
+	r.GET("/groups", a.GetGroups)
 	r.GET("/permission/groups", a.GetUserGroups)
 	r.POST("/groups", a.CreateGroup)
-	r.PUT("/groups/:guid", a.RenameGroup)
+	r.PUT("/groups/:guid", a.UpdateGroup)
 	r.DELETE("/groups/:guid", a.DeleteGroup)
+	r.POST("/groups/:guid/move", a.MoveGroup)
+	r.POST("/groups/:guid/clone", a.CloneGroup)
 
 	// Groups Members
 	r.GET("/groupMembers/:groupGuid", a.GetGroupMembers)
 	r.GET("/groupMembers", a.GetGroupsByNetId)
 	r.POST("/groupMembers", a.AddGroupMember)
+	r.PUT("/groupMembers/:groupGuid", a.SyncGroupMembers)
 	r.DELETE("/groupMembers/:netId/:groupId", a.RemoveGroupMember)
 	r.DELETE("/groupMembers/:netId", a.RemoveFromAllGroups)
 
+	// Rule based group membership
+	r.GET("/groupRules/:groupGuid", a.GetGroupRule)
+	r.POST("/groupRules/preview", a.PreviewGroupRule)
+	r.PUT("/groupRules/:groupGuid", a.SetGroupRule)
+	r.DELETE("/groupRules/:groupGuid", a.DeleteGroupRule)
+
+	// Group owners
+	r.GET("/groupOwners/:groupGuid", a.GetGroupOwners)
+	r.POST("/groupOwners", a.AddGroupOwner)
+	r.DELETE("/groupOwners/:netId/:groupGuid", a.RemoveGroupOwner)
+
+	// Self-service access requests
+	r.GET("/accessRequests", a.GetAccessRequests)
+	r.POST("/accessRequests", a.CreateAccessRequest)
+	r.POST("/accessRequests/:guid/approve", a.ApproveAccessRequest)
+	r.POST("/accessRequests/:guid/deny", a.DenyAccessRequest)
+
+	// Access review campaigns
+	r.GET("/reviews", a.GetReviews)
+	r.GET("/reviews/:guid", a.GetReviewItems)
+	r.GET("/reviews/:guid/report", a.GetReviewReport)
+	r.POST("/reviews", a.StartReview)
+	r.POST("/reviews/:guid/close", a.CloseReview)
+	r.PUT("/reviews/:guid/items/:itemGuid", a.DecideReviewItem)
+
+	// Roles
+	r.GET("/roles", a.GetRoles)
+	r.GET("/roles/:guid", a.GetRole)
+	r.POST("/roles", a.CreateRole)
+	r.DELETE("/roles/:guid", a.DeleteRole)
+	r.POST("/roles/:guid/permissions", a.AddRolePermission)
+	r.DELETE("/roles/:guid/permissions/:verb", a.RemoveRolePermission)
+	r.POST("/roles/:guid/bindings", a.BindRole)
+	r.DELETE("/roles/:guid/bindings/:actor", a.UnbindRole)
+
 	// General response for the Cross-Origin OPTIONS preflight request
 	r.Register("OPTIONS", "/*path", Options)
 
+	// Expire temporary access, close overdue reviews, refresh group rules,
+	// forget old idempotency keys
+	go a.RunScheduledTasks(time.Minute)
+
 	// Run the server
 	if err := r.Run(":5000"); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,229 @@
+package relations
+
+// Evaluates checks and expansions against a Store.
+type Checker struct {
+	store Store
+}
+
+// Returns a new checker reading from store.
+func NewChecker(store Store) *Checker {
+	return &Checker{store}
+}
+
+// Tells whether user has relation to object.
+func (c *Checker) Check(object, relation, user string) (bool, error) {
+	return c.check(object, relation, user, 0, make(map[string]bool))
+}
+
+// path holds the usersets being evaluated above this one; coming back to
+// one of them is a cycle, which adds nothing.
+func (c *Checker) check(object, relation, user string, depth int, path map[string]bool) (bool, error) {
+	if depth > MaxDepth {
+		return false, ErrTooDeep
+	}
+	key := object + "#" + relation
+	if path[key] {
+		return false, nil
+	}
+	path[key] = true
+	defer delete(path, key)
+
+	rw, err := c.rewrite(object, relation)
+	if err != nil {
+		return false, err
+	}
+	return c.eval(object, relation, rw, user, depth, path)
+}
+
+func (c *Checker) rewrite(object, relation string) (Rewrite, error) {
+	ns, _ := SplitObject(object)
+	namespace, err := c.store.Namespace(ns)
+	if err != nil {
+		return Rewrite{}, err
+	}
+	return namespace.rewrite(relation)
+}
+
+func (c *Checker) eval(object, relation string, rw Rewrite, user string, depth int, path map[string]bool) (bool, error) {
+	switch {
+	case rw.ComputedUserset != "":
+		return c.check(object, rw.ComputedUserset, user, depth+1, path)
+
+	case rw.TupleToUserset != nil:
+		subjects, err := c.store.Read(object, rw.TupleToUserset.Tupleset)
+		if err != nil {
+			return false, err
+		}
+		for _, s := range subjects {
+			ok, err := c.check(target(s), rw.TupleToUserset.Computed, user, depth+1, path)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+
+	case rw.Union != nil:
+		for _, child := range rw.Union {
+			ok, err := c.eval(object, relation, child, user, depth+1, path)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+
+	case rw.Intersection != nil:
+		if len(rw.Intersection) == 0 {
+			return false, nil
+		}
+		for _, child := range rw.Intersection {
+			ok, err := c.eval(object, relation, child, user, depth+1, path)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case rw.Exclusion != nil:
+		ok, err := c.eval(object, relation, rw.Exclusion.Base, user, depth+1, path)
+		if err != nil || !ok {
+			return false, err
+		}
+		excluded, err := c.eval(object, relation, rw.Exclusion.Subtract, user, depth+1, path)
+		return err == nil && !excluded, err
+	}
+
+	// This: the stored subjects
+	subjects, err := c.store.Read(object, relation)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range subjects {
+		if !s.IsUserset() {
+			if s.User == user {
+				return true, nil
+			}
+			continue
+		}
+		ok, err := c.check(s.Object, s.Relation, user, depth+1, path)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// The object a tupleset subject points at: "folder:x" or "folder:x#..."
+// both mean the folder.
+func target(s Subject) string {
+	if s.IsUserset() {
+		return s.Object
+	}
+	return s.User
+}
+
+// The expansion of a userset into the tree of rewrites and subjects that
+// make it up. Op is "this", "computed", "tupleToUserset", "union",
+// "intersection", "exclusion" (the first child minus the second) or
+// "cycle" for a userset already being expanded further up the tree.
+type Tree struct {
+	Userset  string `json:",omitempty"`
+	Op       string
+	Users    []string `json:",omitempty"`
+	Children []*Tree  `json:",omitempty"`
+}
+
+// Expand object#relation into the tree of everyone it includes.
+func (c *Checker) Expand(object, relation string) (*Tree, error) {
+	return c.expand(object, relation, 0, make(map[string]bool))
+}
+
+func (c *Checker) expand(object, relation string, depth int, path map[string]bool) (*Tree, error) {
+	if depth > MaxDepth {
+		return nil, ErrTooDeep
+	}
+	key := object + "#" + relation
+	if path[key] {
+		return &Tree{Userset: key, Op: "cycle"}, nil
+	}
+	path[key] = true
+	defer delete(path, key)
+
+	rw, err := c.rewrite(object, relation)
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.expandRewrite(object, relation, rw, depth, path)
+	if err != nil {
+		return nil, err
+	}
+	t.Userset = key
+	return t, nil
+}
+
+func (c *Checker) expandRewrite(object, relation string, rw Rewrite, depth int, path map[string]bool) (*Tree, error) {
+	t := &Tree{}
+	var children []Rewrite
+	switch {
+	case rw.ComputedUserset != "":
+		t.Op = "computed"
+		child, err := c.expand(object, rw.ComputedUserset, depth+1, path)
+		if err != nil {
+			return nil, err
+		}
+		t.Children = append(t.Children, child)
+		return t, nil
+
+	case rw.TupleToUserset != nil:
+		t.Op = "tupleToUserset"
+		subjects, err := c.store.Read(object, rw.TupleToUserset.Tupleset)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range subjects {
+			child, err := c.expand(target(s), rw.TupleToUserset.Computed, depth+1, path)
+			if err != nil {
+				return nil, err
+			}
+			t.Children = append(t.Children, child)
+		}
+		return t, nil
+
+	case rw.Union != nil:
+		t.Op = "union"
+		children = rw.Union
+	case rw.Intersection != nil:
+		t.Op = "intersection"
+		children = rw.Intersection
+	case rw.Exclusion != nil:
+		t.Op = "exclusion"
+		children = []Rewrite{rw.Exclusion.Base, rw.Exclusion.Subtract}
+
+	default:
+		t.Op = "this"
+		subjects, err := c.store.Read(object, relation)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range subjects {
+			if !s.IsUserset() {
+				t.Users = append(t.Users, s.User)
+				continue
+			}
+			child, err := c.expand(s.Object, s.Relation, depth+1, path)
+			if err != nil {
+				return nil, err
+			}
+			t.Children = append(t.Children, child)
+		}
+		return t, nil
+	}
+
+	for _, rw := range children {
+		child, err := c.expandRewrite(object, relation, rw, depth+1, path)
+		if err != nil {
+			return nil, err
+		}
+		t.Children = append(t.Children, child)
+	}
+	return t, nil
+}
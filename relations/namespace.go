@@ -0,0 +1,123 @@
+package relations
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The configuration of a namespace: its relations and how each one is
+// computed. A namespace with AnyRelation accepts relations it doesn't list
+// and treats them as plain stored tuples.
+type Namespace struct {
+	Name        string
+	Relations   map[string]Rewrite
+	AnyRelation bool `json:",omitempty"`
+}
+
+// A userset rewrite. Exactly one field is set; the zero Rewrite means This.
+//
+//	This            the subjects stored for the relation itself
+//	ComputedUserset the subjects of another relation on the same object
+//	TupleToUserset  follow the Tupleset relation to other objects and take
+//	                the subjects of their Computed relation
+//	Union           subjects in any of the children
+//	Intersection    subjects in all of the children
+//	Exclusion       subjects in Base but not in Subtract
+type Rewrite struct {
+	This            bool            `json:",omitempty"`
+	ComputedUserset string          `json:",omitempty"`
+	TupleToUserset  *TupleToUserset `json:",omitempty"`
+	Union           []Rewrite       `json:",omitempty"`
+	Intersection    []Rewrite       `json:",omitempty"`
+	Exclusion       *Exclusion      `json:",omitempty"`
+}
+
+// e.g. {Tupleset: "parent", Computed: "viewer"} makes viewers of a
+// document's parent folder viewers of the document.
+type TupleToUserset struct {
+	Tupleset string
+	Computed string
+}
+
+type Exclusion struct {
+	Base     Rewrite
+	Subtract Rewrite
+}
+
+// The rewrite for a relation of the namespace.
+func (n Namespace) rewrite(relation string) (Rewrite, error) {
+	if rw, ok := n.Relations[relation]; ok {
+		return rw, nil
+	}
+	if n.AnyRelation {
+		return Rewrite{This: true}, nil
+	}
+	return Rewrite{}, fmt.Errorf("namespace %s has no relation %s", n.Name, relation)
+}
+
+// Check that the configuration is well formed and only refers to
+// relations it defines.
+func (n Namespace) Validate() error {
+	if n.Name == "" {
+		return errors.New("namespace has no name")
+	}
+	for name, rw := range n.Relations {
+		if name == "" {
+			return errors.New("relation has no name")
+		}
+		if err := n.validateRewrite(rw, 0); err != nil {
+			return fmt.Errorf("relation %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (n Namespace) validateRewrite(rw Rewrite, depth int) error {
+	if depth > MaxDepth {
+		return ErrTooDeep
+	}
+
+	set := 0
+	if rw.This {
+		set++
+	}
+	if rw.ComputedUserset != "" {
+		set++
+		if _, ok := n.Relations[rw.ComputedUserset]; !ok && !n.AnyRelation {
+			return fmt.Errorf("unknown relation %s", rw.ComputedUserset)
+		}
+	}
+	if rw.TupleToUserset != nil {
+		set++
+		if _, ok := n.Relations[rw.TupleToUserset.Tupleset]; !ok && !n.AnyRelation {
+			return fmt.Errorf("unknown relation %s", rw.TupleToUserset.Tupleset)
+		}
+		// Computed is looked up in whatever namespace the tupleset points at
+		if rw.TupleToUserset.Computed == "" {
+			return errors.New("tupleToUserset has no computed relation")
+		}
+	}
+	if rw.Union != nil {
+		set++
+	}
+	if rw.Intersection != nil {
+		set++
+	}
+	if rw.Exclusion != nil {
+		set++
+	}
+	if set > 1 {
+		return errors.New("a rewrite can only do one thing")
+	}
+
+	children := append(append([]Rewrite{}, rw.Union...), rw.Intersection...)
+	if rw.Exclusion != nil {
+		children = append(children, rw.Exclusion.Base, rw.Exclusion.Subtract)
+	}
+	for _, child := range children {
+		if err := n.validateRewrite(child, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
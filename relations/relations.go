@@ -0,0 +1,120 @@
+// Package relations answers relationship based questions such as "can
+// alice edit doc:readme" from tuples of the form object#relation@subject,
+// in the style of Zanzibar.
+//
+// Objects are "namespace:id". A subject is either a user ("alice") or a
+// userset ("group:eng#member", everyone with that relation to that object).
+// Each namespace's configuration says how its relations are computed from
+// the stored tuples through userset rewrites, e.g. that every owner of a
+// document is also an editor of it.
+package relations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// How deep Check and Expand follow usersets and rewrites before giving up.
+const MaxDepth = 25
+
+var (
+	ErrUnknownNamespace = errors.New("unknown namespace")
+	ErrTooDeep          = errors.New("relationship graph is too deep")
+)
+
+// A user, or the set of subjects with Relation to Object.
+type Subject struct {
+	User     string `json:",omitempty"`
+	Object   string `json:",omitempty"`
+	Relation string `json:",omitempty"`
+}
+
+func (s Subject) IsUserset() bool {
+	return s.Object != ""
+}
+
+func (s Subject) String() string {
+	if s.IsUserset() {
+		return s.Object + "#" + s.Relation
+	}
+	return s.User
+}
+
+// Parse "alice" or "group:eng#member".
+func ParseSubject(s string) (Subject, error) {
+	if i := strings.Index(s, "#"); i >= 0 {
+		object, relation := s[:i], s[i+1:]
+		if err := checkObject(object); err != nil {
+			return Subject{}, err
+		}
+		if relation == "" {
+			return Subject{}, fmt.Errorf("userset %q has no relation", s)
+		}
+		return Subject{Object: object, Relation: relation}, nil
+	}
+	if s == "" {
+		return Subject{}, errors.New("empty subject")
+	}
+	return Subject{User: s}, nil
+}
+
+// A relationship: Subject has Relation to Object.
+type Tuple struct {
+	Object   string
+	Relation string
+	Subject  Subject
+}
+
+func (t Tuple) String() string {
+	return t.Object + "#" + t.Relation + "@" + t.Subject.String()
+}
+
+// Parse "doc:readme#owner@alice".
+func ParseTuple(s string) (Tuple, error) {
+	at := strings.Index(s, "@")
+	hash := strings.Index(s, "#")
+	if at < 0 || hash < 0 || hash > at {
+		return Tuple{}, fmt.Errorf("tuple %q is not of the form object#relation@subject", s)
+	}
+
+	t := Tuple{Object: s[:hash], Relation: s[hash+1 : at]}
+	if err := checkObject(t.Object); err != nil {
+		return Tuple{}, err
+	}
+	if t.Relation == "" {
+		return Tuple{}, fmt.Errorf("tuple %q has no relation", s)
+	}
+
+	subject, err := ParseSubject(s[at+1:])
+	if err != nil {
+		return Tuple{}, err
+	}
+	t.Subject = subject
+	return t, nil
+}
+
+// Split "doc:readme" into "doc" and "readme".
+func SplitObject(object string) (namespace, id string) {
+	i := strings.Index(object, ":")
+	if i < 0 {
+		return "", object
+	}
+	return object[:i], object[i+1:]
+}
+
+func checkObject(object string) error {
+	ns, id := SplitObject(object)
+	if ns == "" || id == "" {
+		return fmt.Errorf("object %q is not of the form namespace:id", object)
+	}
+	return nil
+}
+
+// Where Check and Expand read tuples and namespace configuration from.
+type Store interface {
+	// The subjects directly related to object by relation.
+	Read(object, relation string) ([]Subject, error)
+	// The configuration of a namespace, or ErrUnknownNamespace.
+	Namespace(name string) (Namespace, error)
+}
@@ -0,0 +1,178 @@
+package relations
+
+import (
+	"reflect"
+	"testing"
+)
+
+// An in memory Store.
+type memStore struct {
+	tuples     []Tuple
+	namespaces map[string]Namespace
+}
+
+func (m *memStore) Read(object, relation string) ([]Subject, error) {
+	subjects := make([]Subject, 0)
+	for _, t := range m.tuples {
+		if t.Object == object && t.Relation == relation {
+			subjects = append(subjects, t.Subject)
+		}
+	}
+	return subjects, nil
+}
+
+func (m *memStore) Namespace(name string) (Namespace, error) {
+	n, ok := m.namespaces[name]
+	if !ok {
+		return n, ErrUnknownNamespace
+	}
+	return n, nil
+}
+
+func mustTuples(t *testing.T, tuples ...string) []Tuple {
+	parsed := make([]Tuple, 0, len(tuples))
+	for _, s := range tuples {
+		tuple, err := ParseTuple(s)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s: %v", s, err)
+		}
+		parsed = append(parsed, tuple)
+	}
+	return parsed
+}
+
+func testStore(t *testing.T) *memStore {
+	doc := Namespace{Name: "doc", Relations: map[string]Rewrite{
+		"owner":  Rewrite{},
+		"parent": Rewrite{},
+		"banned": Rewrite{},
+		"editor": Rewrite{Union: []Rewrite{{This: true}, {ComputedUserset: "owner"}}},
+		"viewer": Rewrite{Exclusion: &Exclusion{
+			Base: Rewrite{Union: []Rewrite{
+				{This: true},
+				{ComputedUserset: "editor"},
+				{TupleToUserset: &TupleToUserset{Tupleset: "parent", Computed: "viewer"}},
+			}},
+			Subtract: Rewrite{ComputedUserset: "banned"},
+		}},
+	}}
+	folder := Namespace{Name: "folder", Relations: map[string]Rewrite{"viewer": Rewrite{}}}
+	group := Namespace{Name: "group", Relations: map[string]Rewrite{"member": Rewrite{}}}
+
+	return &memStore{
+		tuples: mustTuples(t,
+			"doc:readme#owner@alice",
+			"doc:readme#editor@group:eng#member",
+			"doc:readme#parent@folder:shared",
+			"doc:readme#banned@mallory",
+			"folder:shared#viewer@carol",
+			"folder:shared#viewer@mallory",
+			"group:eng#member@bob",
+			"group:eng#member@group:leads#member",
+			"group:leads#member@dave",
+			"group:leads#member@group:eng#member", // a cycle
+		),
+		namespaces: map[string]Namespace{"doc": doc, "folder": folder, "group": group},
+	}
+}
+
+func TestParseTuple(t *testing.T) {
+	tuple, err := ParseTuple("doc:readme#viewer@group:eng#member")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := Tuple{"doc:readme", "viewer", Subject{Object: "group:eng", Relation: "member"}}
+	if tuple != expected {
+		t.Errorf("Expected %v but got %v", expected, tuple)
+	}
+	if tuple.String() != "doc:readme#viewer@group:eng#member" {
+		t.Errorf("Expected the tuple to print as it was parsed but got %s", tuple)
+	}
+
+	for _, bad := range []string{"", "doc:readme#viewer", "readme#viewer@alice", "doc:readme#@alice", "doc:readme#viewer@", "doc:readme#viewer@group:eng#"} {
+		if _, err := ParseTuple(bad); err == nil {
+			t.Errorf("Expected an error parsing %q", bad)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := NewChecker(testStore(t))
+
+	cases := []struct {
+		relation string
+		user     string
+		expected bool
+	}{
+		{"owner", "alice", true},
+		{"editor", "alice", true},    // owners are editors
+		{"editor", "bob", true},      // through group:eng
+		{"editor", "dave", true},     // through group:leads nested in group:eng
+		{"viewer", "alice", true},    // editors are viewers
+		{"viewer", "carol", true},    // through the parent folder
+		{"viewer", "mallory", false}, // banned
+		{"editor", "carol", false},
+		{"owner", "bob", false},
+	}
+
+	for _, tc := range cases {
+		ok, err := c.Check("doc:readme", tc.relation, tc.user)
+		if err != nil {
+			t.Errorf("Unexpected error checking %s for %s: %v", tc.relation, tc.user, err)
+			continue
+		}
+		if ok != tc.expected {
+			t.Errorf("Expected %s on doc:readme for %s to be %v", tc.relation, tc.user, tc.expected)
+		}
+	}
+
+	if _, err := c.Check("doc:readme", "approver", "alice"); err == nil {
+		t.Error("Expected an error checking an undefined relation")
+	}
+	if _, err := c.Check("sheet:1", "viewer", "alice"); err != ErrUnknownNamespace {
+		t.Errorf("Expected ErrUnknownNamespace but got %v", err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	c := NewChecker(testStore(t))
+
+	tree, err := c.Expand("doc:readme", "editor")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := &Tree{Userset: "doc:readme#editor", Op: "union", Children: []*Tree{
+		&Tree{Op: "this", Children: []*Tree{
+			&Tree{Userset: "group:eng#member", Op: "this", Users: []string{"bob"}, Children: []*Tree{
+				&Tree{Userset: "group:leads#member", Op: "this", Users: []string{"dave"}, Children: []*Tree{
+					&Tree{Userset: "group:eng#member", Op: "cycle"},
+				}},
+			}},
+		}},
+		&Tree{Op: "computed", Children: []*Tree{
+			&Tree{Userset: "doc:readme#owner", Op: "this", Users: []string{"alice"}},
+		}},
+	}}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, tree)
+	}
+}
+
+func TestValidateNamespace(t *testing.T) {
+	if err := testStore(t).namespaces["doc"].Validate(); err != nil {
+		t.Errorf("Unexpected error validating doc: %v", err)
+	}
+
+	bad := []Namespace{
+		Namespace{},
+		Namespace{Name: "doc", Relations: map[string]Rewrite{"editor": Rewrite{ComputedUserset: "owner"}}},
+		Namespace{Name: "doc", Relations: map[string]Rewrite{"viewer": Rewrite{TupleToUserset: &TupleToUserset{Tupleset: "parent", Computed: "viewer"}}}},
+		Namespace{Name: "doc", Relations: map[string]Rewrite{"owner": Rewrite{This: true, ComputedUserset: "owner"}}},
+	}
+	for _, n := range bad {
+		if err := n.Validate(); err == nil {
+			t.Errorf("Expected an error validating %+v", n)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+-- Tables the service reads and writes, for a new MySQL database. Existing
+-- databases are brought up to date with upgrade.sql.
+--
+-- Guids, net ids and area ids are VARCHAR(64); resources, verbs and names
+-- VARCHAR(255). Times are unix seconds. Inserts that must not repeat a row
+-- rely on the primary and unique keys here, so keep them when changing a
+-- table.
+
+-- Areas and who runs them
+
+CREATE TABLE IF NOT EXISTS areas (
+	guid        VARCHAR(64)  NOT NULL,
+	name        VARCHAR(255) NOT NULL,
+	description TEXT         NOT NULL,
+	parent      VARCHAR(64)  NOT NULL DEFAULT '', -- empty for a top level area
+	inherit     BOOLEAN      NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (guid),
+	KEY areaParent (parent)
+);
+
+CREATE TABLE IF NOT EXISTS admin (
+	netId VARCHAR(64) NOT NULL,
+	area  VARCHAR(64) NOT NULL,
+	PRIMARY KEY (netId, area),
+	KEY adminArea (area)
+);
+
+-- Superusers hold their rights only while elevated
+CREATE TABLE IF NOT EXISTS superuser (
+	netId  VARCHAR(64) NOT NULL,
+	active BOOLEAN     NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (netId)
+);
+
+-- Groups
+
+CREATE TABLE IF NOT EXISTS `groups` (
+	guid        VARCHAR(64)   NOT NULL,
+	area        VARCHAR(64)   NOT NULL,
+	name        VARCHAR(255)  NOT NULL,
+	description TEXT          NOT NULL,
+	tags        VARCHAR(1024) NOT NULL DEFAULT '',
+	created     BIGINT        NOT NULL DEFAULT 0,
+	createdBy   VARCHAR(64)   NOT NULL DEFAULT '',
+	PRIMARY KEY (guid),
+	-- Group names are unique within an area; Insert relies on this when two
+	-- creates race past its lookup
+	UNIQUE KEY groupName (area, name)
+);
+
+CREATE TABLE IF NOT EXISTS groupMembers (
+	netId     VARCHAR(64) NOT NULL,
+	groupGuid VARCHAR(64) NOT NULL,
+	PRIMARY KEY (netId, groupGuid),
+	KEY groupMembersGroup (groupGuid)
+);
+
+CREATE TABLE IF NOT EXISTS groupOwners (
+	netId     VARCHAR(64) NOT NULL,
+	groupGuid VARCHAR(64) NOT NULL,
+	PRIMARY KEY (netId, groupGuid),
+	KEY groupOwnersGroup (groupGuid)
+);
+
+-- A group's membership rule and the users it currently matches
+CREATE TABLE IF NOT EXISTS groupRule (
+	groupGuid  VARCHAR(64) NOT NULL,
+	expression TEXT        NOT NULL,
+	PRIMARY KEY (groupGuid)
+);
+
+CREATE TABLE IF NOT EXISTS groupRuleMember (
+	groupGuid VARCHAR(64) NOT NULL,
+	netId     VARCHAR(64) NOT NULL,
+	PRIMARY KEY (groupGuid, netId),
+	KEY groupRuleMemberNetId (netId)
+);
+
+-- Policy rows. A row can carry a condition and be limited to one area;
+-- both side tables share the row's key.
+
+CREATE TABLE IF NOT EXISTS policy (
+	actor    VARCHAR(64)  NOT NULL,
+	verb     VARCHAR(255) NOT NULL,
+	resource VARCHAR(255) NOT NULL,
+	PRIMARY KEY (actor, verb, resource),
+	KEY policyResource (verb, resource)
+);
+
+CREATE TABLE IF NOT EXISTS policyCondition (
+	actor      VARCHAR(64)  NOT NULL,
+	verb       VARCHAR(255) NOT NULL,
+	resource   VARCHAR(255) NOT NULL,
+	expression TEXT         NOT NULL,
+	PRIMARY KEY (actor, verb, resource)
+);
+
+CREATE TABLE IF NOT EXISTS policyArea (
+	actor    VARCHAR(64)  NOT NULL,
+	verb     VARCHAR(255) NOT NULL,
+	resource VARCHAR(255) NOT NULL,
+	area     VARCHAR(64)  NOT NULL,
+	PRIMARY KEY (actor, verb, resource),
+	KEY policyAreaArea (area)
+);
+
+-- Roles, their resource patterns and who they're bound to
+
+CREATE TABLE IF NOT EXISTS role (
+	guid VARCHAR(64)  NOT NULL,
+	area VARCHAR(64)  NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	PRIMARY KEY (guid),
+	KEY roleArea (area)
+);
+
+CREATE TABLE IF NOT EXISTS rolePermission (
+	roleGuid VARCHAR(64)  NOT NULL,
+	verb     VARCHAR(255) NOT NULL,
+	resource VARCHAR(255) NOT NULL, -- a pattern
+	PRIMARY KEY (roleGuid, verb, resource)
+);
+
+CREATE TABLE IF NOT EXISTS roleBinding (
+	roleGuid VARCHAR(64) NOT NULL,
+	actor    VARCHAR(64) NOT NULL,
+	PRIMARY KEY (roleGuid, actor),
+	KEY roleBindingActor (actor)
+);
+
+-- Relation tuples and the namespaces that configure them
+
+CREATE TABLE IF NOT EXISTS relationNamespace (
+	name   VARCHAR(255) NOT NULL,
+	config TEXT         NOT NULL,
+	PRIMARY KEY (name)
+);
+
+CREATE TABLE IF NOT EXISTS relationTuple (
+	object   VARCHAR(255) NOT NULL,
+	relation VARCHAR(255) NOT NULL,
+	subject  VARCHAR(255) NOT NULL,
+	PRIMARY KEY (object, relation, subject),
+	KEY relationTupleSubject (subject)
+);
+
+-- Separation of duties
+
+CREATE TABLE IF NOT EXISTS sodRule (
+	guid  VARCHAR(64)  NOT NULL,
+	area  VARCHAR(64)  NOT NULL,
+	verbA VARCHAR(255) NOT NULL,
+	verbB VARCHAR(255) NOT NULL,
+	mode  VARCHAR(16)  NOT NULL,
+	PRIMARY KEY (guid),
+	KEY sodRuleArea (area)
+);
+
+-- Changes that wait for a second approver, and the resources whose grants
+-- need one
+
+CREATE TABLE IF NOT EXISTS sensitiveResource (
+	resource VARCHAR(255) NOT NULL,
+	PRIMARY KEY (resource)
+);
+
+CREATE TABLE IF NOT EXISTS approval (
+	guid       VARCHAR(64)  NOT NULL,
+	type       VARCHAR(32)  NOT NULL,
+	requester  VARCHAR(64)  NOT NULL,
+	area       VARCHAR(64)  NOT NULL,
+	netId      VARCHAR(64)  NOT NULL DEFAULT '',
+	role       VARCHAR(64)  NOT NULL DEFAULT '',
+	actor      VARCHAR(64)  NOT NULL DEFAULT '',
+	verb       VARCHAR(255) NOT NULL DEFAULT '',
+	resource   VARCHAR(255) NOT NULL DEFAULT '',
+	expression TEXT         NOT NULL,
+	status     VARCHAR(16)  NOT NULL,
+	reviewer   VARCHAR(64)  NOT NULL DEFAULT '',
+	PRIMARY KEY (guid),
+	KEY approvalArea (area, status)
+);
+
+-- Users asking for access
+
+CREATE TABLE IF NOT EXISTS accessRequest (
+	guid      VARCHAR(64)  NOT NULL,
+	netId     VARCHAR(64)  NOT NULL,
+	area      VARCHAR(64)  NOT NULL,
+	type      VARCHAR(16)  NOT NULL,
+	groupGuid VARCHAR(64)  NOT NULL DEFAULT '',
+	verb      VARCHAR(255) NOT NULL DEFAULT '',
+	resource  VARCHAR(255) NOT NULL DEFAULT '',
+	reason    TEXT         NOT NULL,
+	status    VARCHAR(16)  NOT NULL,
+	reviewer  VARCHAR(64)  NOT NULL DEFAULT '',
+	duration  BIGINT       NOT NULL DEFAULT 0, -- hours, 0 for no expiry
+	expires   BIGINT       NOT NULL DEFAULT 0,
+	granted   BOOLEAN      NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (guid),
+	KEY accessRequestArea (area, status),
+	KEY accessRequestExpires (status, expires)
+);
+
+-- Access reviews
+
+CREATE TABLE IF NOT EXISTS reviewCampaign (
+	guid     VARCHAR(64) NOT NULL,
+	area     VARCHAR(64) NOT NULL,
+	creator  VARCHAR(64) NOT NULL,
+	deadline BIGINT      NOT NULL,
+	status   VARCHAR(16) NOT NULL,
+	PRIMARY KEY (guid),
+	KEY reviewCampaignArea (area)
+);
+
+CREATE TABLE IF NOT EXISTS reviewItem (
+	guid      VARCHAR(64)  NOT NULL,
+	campaign  VARCHAR(64)  NOT NULL,
+	type      VARCHAR(16)  NOT NULL,
+	netId     VARCHAR(64)  NOT NULL DEFAULT '',
+	groupGuid VARCHAR(64)  NOT NULL DEFAULT '',
+	verb      VARCHAR(255) NOT NULL DEFAULT '',
+	resource  VARCHAR(255) NOT NULL DEFAULT '',
+	reviewer  VARCHAR(64)  NOT NULL DEFAULT '', -- empty when it falls to the admins
+	decision  VARCHAR(16)  NOT NULL DEFAULT '',
+	decidedBy VARCHAR(64)  NOT NULL DEFAULT '',
+	PRIMARY KEY (guid),
+	KEY reviewItemCampaign (campaign)
+);
+
+-- The change feed. seq orders events and is what readers page by.
+CREATE TABLE IF NOT EXISTS event (
+	seq     BIGINT      NOT NULL AUTO_INCREMENT,
+	type    VARCHAR(64) NOT NULL,
+	data    TEXT        NOT NULL,
+	created BIGINT      NOT NULL,
+	PRIMARY KEY (seq),
+	KEY eventCreated (created)
+);
+
+-- The group each Idempotency-Key created, with a hash of the request that
+-- created it so a key reused for a different request is refused.
+CREATE TABLE IF NOT EXISTS idempotencyKey (
+	netId          VARCHAR(64)  NOT NULL,
+	idempotencyKey VARCHAR(255) NOT NULL,
+	resource       VARCHAR(64)  NOT NULL,
+	requestHash    CHAR(64)     NOT NULL,
+	created        BIGINT       NOT NULL,
+	PRIMARY KEY (netId, idempotencyKey),
+	KEY idempotencyKeyCreated (created)
+);
+
+-- Audit and error log written by Log
+CREATE TABLE IF NOT EXISTS log (
+	guid   VARCHAR(64) NOT NULL,
+	actor  VARCHAR(64) NOT NULL,
+	type   VARCHAR(16) NOT NULL,
+	data   TEXT        NOT NULL,
+	logged TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (guid),
+	KEY logActor (actor)
+);
@@ -0,0 +1,25 @@
+-- Brings a database created before schema.sql up to date. Run schema.sql
+-- first, which creates the tables that are missing and leaves the others
+-- alone, then the blocks below for the tables that were already there.
+-- MySQL can't skip a column or key that exists, so leave out statements a
+-- database doesn't need, and clear out duplicate rows a new key refuses
+-- before adding it.
+
+-- Group details, and names unique within an area
+ALTER TABLE `groups`
+	ADD COLUMN description TEXT          NOT NULL,
+	ADD COLUMN tags        VARCHAR(1024) NOT NULL DEFAULT '',
+	ADD COLUMN created     BIGINT        NOT NULL DEFAULT 0,
+	ADD COLUMN createdBy   VARCHAR(64)   NOT NULL DEFAULT '';
+ALTER TABLE `groups` ADD UNIQUE KEY groupName (area, name);
+
+-- Rows that grants and adds no longer repeat
+ALTER TABLE groupMembers ADD PRIMARY KEY (netId, groupGuid), ADD KEY groupMembersGroup (groupGuid);
+ALTER TABLE policy ADD PRIMARY KEY (actor, verb, resource), ADD KEY policyResource (verb, resource);
+ALTER TABLE admin ADD PRIMARY KEY (netId, area), ADD KEY adminArea (area);
+ALTER TABLE superuser ADD PRIMARY KEY (netId);
+
+-- Idempotency keys remember the request they were first sent with; keys
+-- saved before this can't be checked, so they are forgotten
+DELETE FROM idempotencyKey;
+ALTER TABLE idempotencyKey ADD COLUMN requestHash CHAR(64) NOT NULL AFTER resource;